@@ -0,0 +1,79 @@
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+)
+
+// Broker carries messages between the instances of the API. Every subscriber gets
+// every published message, the instance that published it included, in the order
+// they were published by that instance.
+type Broker interface {
+	Publish(data []byte) error
+	// Subscribe registers a handler, handlers are called one message at a time
+	Subscribe(handler func(data []byte))
+	Close() error
+}
+
+var (
+	ErrClosed    = errors.New("broker is closed")
+	ErrQueueFull = errors.New("broker queue is full")
+)
+
+// NewNodeID returns a name for an instance that is unique among the instances
+func NewNodeID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "api"
+	}
+
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// MemoryBroker hands messages straight to the handlers of the same process. It is all
+// a single instance needs, several servers in one process can share one too.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	handlers []func(data []byte)
+	closed   bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Publish calls the handlers before it returns
+func (b *MemoryBroker) Publish(data []byte) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	handlers := b.handlers
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(handler func(data []byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
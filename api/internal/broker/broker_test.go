@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryBrokerPublish(t *testing.T) {
+	b := NewMemoryBroker()
+
+	var first, second [][]byte
+	b.Subscribe(func(data []byte) { first = append(first, data) })
+	b.Subscribe(func(data []byte) { second = append(second, data) })
+
+	for _, message := range []string{"one", "two"} {
+		if err := b.Publish([]byte(message)); err != nil {
+			t.Fatalf("Publish(%q) = %v", message, err)
+		}
+	}
+
+	// the handlers have run when Publish returns
+	for _, got := range [][][]byte{first, second} {
+		if len(got) != 2 || string(got[0]) != "one" || string(got[1]) != "two" {
+			t.Fatalf("handler got %q, want [one two]", got)
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if err := b.Publish([]byte("three")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish after Close = %v, want ErrClosed", err)
+	}
+
+	if len(first) != 2 {
+		t.Fatalf("handler got %d messages after Close, want 2", len(first))
+	}
+}
+
+func TestNewNodeIDIsUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := NewNodeID()
+		if seen[id] {
+			t.Fatalf("NewNodeID returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,402 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// redisTimeout bounds dialling and every command but the subscription itself
+	redisTimeout = 5 * time.Second
+	// redisMaxBackoff is the longest wait before subscribing again
+	redisMaxBackoff = 30 * time.Second
+	// redisRetry is how long messages are dropped after the server could not be reached
+	redisRetry = time.Second
+	// redisQueueSize is how many messages wait to be published
+	redisQueueSize = 1024
+)
+
+// RedisBroker shares messages through a Redis pub/sub channel, so any server that
+// speaks the Redis protocol can connect the instances. It keeps one connection for
+// publishing and one for the subscription and dials them again when they break.
+// Messages published while the server cannot be reached are dropped and messages
+// published while the subscription is down do not reach this instance.
+type RedisBroker struct {
+	Logger   *log.Logger
+	Addr     string
+	Password string
+	Channel  string
+
+	// queue holds the messages for the publishing goroutine, stopped is closed when it
+	// has finished
+	queue   chan []byte
+	stopped chan struct{}
+	// mu guards the subscription, handlers and closed
+	mu       sync.Mutex
+	sub      *redisConn
+	handlers []func(data []byte)
+	closed   bool
+	done     chan struct{}
+}
+
+func NewRedisBroker(logger *log.Logger, addr string, password string, channel string) *RedisBroker {
+	b := &RedisBroker{
+		Logger:   logger,
+		Addr:     addr,
+		Password: password,
+		Channel:  channel,
+		queue:    make(chan []byte, redisQueueSize),
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go b.listen()
+	go b.publish()
+
+	return b
+}
+
+// Publish queues the message and returns without waiting for the server, it returns
+// ErrQueueFull when the message has to be dropped
+func (b *RedisBroker) Publish(data []byte) error {
+	select {
+	case <-b.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case b.queue <- data:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// publish sends the queued messages in order until the broker is closed, then sends
+// what is left if the server can be reached
+func (b *RedisBroker) publish() {
+	defer close(b.stopped)
+
+	var conn *redisConn
+	var retryAt time.Time
+	dropped := 0
+
+	defer func() {
+		if conn != nil {
+			conn.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case data := <-b.queue:
+			// messages are dropped for a while after a failure, so a server that
+			// cannot be reached does not slow the queue down
+			if conn == nil && time.Now().Before(retryAt) {
+				dropped++
+				continue
+			}
+
+			var err error
+			conn, err = b.send(conn, data)
+			if err != nil {
+				if dropped == 0 {
+					b.Logger.Printf("Cannot publish to Redis at %s: %s, dropping messages", b.Addr, err)
+				}
+				dropped++
+				retryAt = time.Now().Add(redisRetry)
+				continue
+			}
+
+			if dropped > 0 {
+				b.Logger.Printf("Publishing to Redis at %s again, dropped %d message(s)", b.Addr, dropped)
+				dropped = 0
+			}
+		case <-b.done:
+			deadline := time.Now().Add(redisTimeout)
+			for conn != nil && time.Now().Before(deadline) {
+				select {
+				case data := <-b.queue:
+					if _, err := conn.do("PUBLISH", b.Channel, string(data)); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+			return
+		}
+	}
+}
+
+// send publishes the message on conn, dialling the server when there is no connection
+// or it broke. It returns the connection to use for the next message.
+func (b *RedisBroker) send(conn *redisConn, data []byte) (*redisConn, error) {
+	for attempt := 0; ; attempt++ {
+		if conn == nil {
+			var err error
+			conn, err = dialRedis(b.Addr, b.Password)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := conn.do("PUBLISH", b.Channel, string(data))
+		if err == nil {
+			return conn, nil
+		}
+
+		// the server refused the command, the connection itself is fine
+		var replyErr redisError
+		if errors.As(err, &replyErr) {
+			return conn, err
+		}
+
+		conn.conn.Close()
+		conn = nil
+
+		// a connection that was idle for long may have been closed by the server
+		if attempt > 0 {
+			return nil, err
+		}
+	}
+}
+
+func (b *RedisBroker) Subscribe(handler func(data []byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close stops the subscription and waits for the publishing goroutine, which gives
+// up on the messages still queued after redisTimeout
+func (b *RedisBroker) Close() error {
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.closed = true
+	close(b.done)
+
+	// unblocks the read of the subscription
+	if b.sub != nil {
+		b.sub.conn.Close()
+		b.sub = nil
+	}
+
+	b.mu.Unlock()
+
+	<-b.stopped
+
+	return nil
+}
+
+// listen keeps the subscription up until the broker is closed
+func (b *RedisBroker) listen() {
+	backoff := time.Second
+
+	for {
+		subscribed, err := b.subscribe()
+		if subscribed {
+			backoff = time.Second
+		}
+
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		b.Logger.Printf("Redis subscription to %s failed: %s, retrying in %s", b.Addr, err, backoff)
+
+		select {
+		case <-b.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff < redisMaxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// subscribe reads the channel until the connection breaks, it reports whether the
+// subscription was made
+func (b *RedisBroker) subscribe() (bool, error) {
+	conn, err := dialRedis(b.Addr, b.Password)
+	if err != nil {
+		return false, err
+	}
+
+	defer conn.conn.Close()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return false, ErrClosed
+	}
+	b.sub = conn
+	b.mu.Unlock()
+
+	if _, err := conn.do("SUBSCRIBE", b.Channel); err != nil {
+		return false, err
+	}
+
+	b.Logger.Printf("Subscribed to Redis channel %s at %s", b.Channel, b.Addr)
+
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			return true, err
+		}
+
+		// a message arrives as ["message", channel, data]
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 3 {
+			continue
+		}
+
+		kind, _ := items[0].([]byte)
+		data, _ := items[2].([]byte)
+		if string(kind) != "message" || data == nil {
+			continue
+		}
+
+		b.dispatch(data)
+	}
+}
+
+func (b *RedisBroker) dispatch(data []byte) {
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+var errRedisProtocol = errors.New("redis: malformed reply")
+
+// redisConn speaks RESP, the protocol of Redis
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRedis(addr string, password string) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: redisTimeout, KeepAlive: 30 * time.Second}
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if password != "" {
+		if _, err := c.do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	defer c.conn.SetDeadline(time.Time{})
+
+	var command bytes.Buffer
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := c.conn.Write(command.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+// read parses one reply: a string, an int64, a []byte, nil or a []interface{} of them
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errRedisProtocol
+	}
+
+	value := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, redisError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if count < 0 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, errRedisProtocol
+	}
+}
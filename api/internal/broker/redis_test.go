@@ -0,0 +1,483 @@
+package broker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a Redis server in the test process that knows AUTH, PING, SUBSCRIBE
+// and PUBLISH, enough for RedisBroker
+type fakeRedis struct {
+	t        *testing.T
+	listener net.Listener
+	password string
+
+	mu    sync.Mutex
+	conns map[net.Conn]*sync.Mutex
+	// subs are the subscribed connections by channel, their lock orders the writes
+	subs map[string]map[net.Conn]*sync.Mutex
+	// hang makes the server read commands without answering them
+	hang bool
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+
+	s := &fakeRedis{
+		t:        t,
+		listener: listener,
+		password: password,
+		conns:    make(map[net.Conn]*sync.Mutex),
+		subs:     make(map[string]map[net.Conn]*sync.Mutex),
+	}
+
+	go s.serve()
+	t.Cleanup(s.close)
+
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = &sync.Mutex{}
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedis) close() {
+	s.listener.Close()
+	s.dropConnections()
+}
+
+// dropConnections closes every client connection, like a restarting server
+func (s *fakeRedis) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeRedis) setHang(hang bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hang = hang
+}
+
+// subscribers returns the number of connections subscribed to the channel
+func (s *fakeRedis) subscribers(channel string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs[channel])
+}
+
+func (s *fakeRedis) waitForSubscribers(channel string, count int) {
+	s.t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.subscribers(channel) != count {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("%d subscriber(s) on %s, want %d", s.subscribers(channel), channel, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		for _, subs := range s.subs {
+			delete(subs, conn)
+		}
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	s.mu.Lock()
+	lock := s.conns[conn]
+	s.mu.Unlock()
+
+	write := func(reply string) {
+		lock.Lock()
+		defer lock.Unlock()
+		conn.Write([]byte(reply))
+	}
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		hang := s.hang
+		s.mu.Unlock()
+
+		if hang {
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				write("+OK\r\n")
+			} else {
+				write("-WRONGPASS invalid password\r\n")
+			}
+		case "PING":
+			write("+PONG\r\n")
+		case "SUBSCRIBE":
+			s.mu.Lock()
+			if s.subs[args[1]] == nil {
+				s.subs[args[1]] = make(map[net.Conn]*sync.Mutex)
+			}
+			s.subs[args[1]][conn] = lock
+			s.mu.Unlock()
+
+			write("*3\r\n" + bulkString("subscribe") + bulkString(args[1]) + ":1\r\n")
+		case "PUBLISH":
+			message := "*3\r\n" + bulkString("message") + bulkString(args[1]) + bulkString(args[2])
+
+			s.mu.Lock()
+			for subscriber, subscriberLock := range s.subs[args[1]] {
+				subscriberLock.Lock()
+				subscriber.Write([]byte(message))
+				subscriberLock.Unlock()
+			}
+			receivers := len(s.subs[args[1]])
+			s.mu.Unlock()
+
+			write(fmt.Sprintf(":%d\r\n", receivers))
+		default:
+			write("-ERR unknown command '" + args[0] + "'\r\n")
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if line[0] != '*' {
+		return nil, errRedisProtocol
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(data[:size])
+	}
+
+	return args, nil
+}
+
+func bulkString(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func testLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestRedisConnRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  interface{}
+		err   error
+	}{
+		{"simple string", "+OK\r\n", "OK", nil},
+		{"integer", ":42\r\n", int64(42), nil},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), nil},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, nil},
+		{"binary bulk string", "$4\r\na\r\nb\r\n", []byte("a\r\nb"), nil},
+		{"null bulk string", "$-1\r\n", nil, nil},
+		{"null array", "*-1\r\n", nil, nil},
+		{"array", "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n:1\r\n", []interface{}{[]byte("message"), []byte("ch"), int64(1)}, nil},
+		{"nested array", "*1\r\n*1\r\n+OK\r\n", []interface{}{[]interface{}{"OK"}}, nil},
+		{"error", "-ERR wrong\r\n", nil, redisError("ERR wrong")},
+		{"unknown type", "!3\r\nabc\r\n", nil, errRedisProtocol},
+		{"missing carriage return", "+OK\n", nil, errRedisProtocol},
+		{"bad bulk length", "$x\r\n", nil, errRedisProtocol},
+		{"truncated bulk string", "$5\r\nhel", nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &redisConn{reader: bufio.NewReader(strings.NewReader(test.reply))}
+
+			got, err := c.read()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("read() error = %v, want %v", err, test.err)
+			}
+
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("read() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRedisConnDo(t *testing.T) {
+	server := newFakeRedis(t, "")
+
+	c, err := dialRedis(server.addr(), "")
+	if err != nil {
+		t.Fatalf("dialRedis() = %v", err)
+	}
+	defer c.conn.Close()
+
+	reply, err := c.do("PING")
+	if err != nil || reply != "PONG" {
+		t.Fatalf("do(PING) = %v, %v, want PONG", reply, err)
+	}
+
+	// arguments are sent as bulk strings, so they may hold anything
+	reply, err = c.do("PUBLISH", "channel", "a \"message\"\r\nwith lines")
+	if err != nil || reply != int64(0) {
+		t.Fatalf("do(PUBLISH) = %v, %v, want 0", reply, err)
+	}
+
+	_, err = c.do("FLUSHALL")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("do(FLUSHALL) error = %v, want a redisError", err)
+	}
+
+	// the connection can still be used after an error reply
+	if reply, err = c.do("PING"); err != nil || reply != "PONG" {
+		t.Fatalf("do(PING) after error = %v, %v, want PONG", reply, err)
+	}
+}
+
+func TestDialRedisAuth(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	c, err := dialRedis(server.addr(), "secret")
+	if err != nil {
+		t.Fatalf("dialRedis() with the password = %v", err)
+	}
+	c.conn.Close()
+
+	_, err = dialRedis(server.addr(), "wrong")
+	var replyErr redisError
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("dialRedis() with a wrong password = %v, want a redisError", err)
+	}
+}
+
+// collector gathers the messages a broker hands to its handler
+type collector struct {
+	mu       sync.Mutex
+	messages []string
+	arrived  chan struct{}
+}
+
+func newCollector(b Broker) *collector {
+	c := &collector{arrived: make(chan struct{}, 1024)}
+
+	b.Subscribe(func(data []byte) {
+		c.mu.Lock()
+		c.messages = append(c.messages, string(data))
+		c.mu.Unlock()
+		c.arrived <- struct{}{}
+	})
+
+	return c
+}
+
+// wait returns the messages once there are count of them
+func (c *collector) wait(t *testing.T, count int) []string {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		c.mu.Lock()
+		if len(c.messages) >= count {
+			messages := append([]string{}, c.messages...)
+			c.mu.Unlock()
+			return messages
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.arrived:
+		case <-timeout:
+			t.Fatalf("got %d message(s), want %d", len(c.messages), count)
+		}
+	}
+}
+
+func TestRedisBrokerPublishSubscribe(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	first := NewRedisBroker(testLogger(), server.addr(), "secret", "test")
+	defer first.Close()
+	second := NewRedisBroker(testLogger(), server.addr(), "secret", "test")
+	defer second.Close()
+
+	firstMessages := newCollector(first)
+	secondMessages := newCollector(second)
+
+	server.waitForSubscribers("test", 2)
+
+	want := []string{}
+	for i := 0; i < 50; i++ {
+		message := fmt.Sprintf("message %d", i)
+		want = append(want, message)
+
+		if err := first.Publish([]byte(message)); err != nil {
+			t.Fatalf("Publish() = %v", err)
+		}
+	}
+
+	// the publisher gets its own messages too, both in the order they were published
+	for name, messages := range map[string]*collector{"publisher": firstMessages, "other": secondMessages} {
+		if got := messages.wait(t, len(want)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRedisBrokerResubscribes(t *testing.T) {
+	server := newFakeRedis(t, "")
+
+	b := NewRedisBroker(testLogger(), server.addr(), "", "test")
+	defer b.Close()
+
+	messages := newCollector(b)
+	server.waitForSubscribers("test", 1)
+
+	server.dropConnections()
+	server.waitForSubscribers("test", 0)
+
+	// the subscription comes back after the first backoff
+	server.waitForSubscribers("test", 1)
+
+	if err := b.Publish([]byte("after restart")); err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+
+	if got := messages.wait(t, 1); got[0] != "after restart" {
+		t.Fatalf("got %q, want [after restart]", got)
+	}
+}
+
+func TestRedisBrokerPublishDoesNotWait(t *testing.T) {
+	server := newFakeRedis(t, "")
+
+	b := NewRedisBroker(testLogger(), server.addr(), "", "test")
+	server.waitForSubscribers("test", 1)
+
+	// the publishing goroutine waits for a reply that does not come
+	server.setHang(true)
+
+	start := time.Now()
+	full := 0
+	for i := 0; i < redisQueueSize+10; i++ {
+		if err := b.Publish([]byte("message")); errors.Is(err, ErrQueueFull) {
+			full++
+		} else if err != nil {
+			t.Fatalf("Publish() = %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Publish took %s while the server hung", elapsed)
+	}
+
+	if full == 0 {
+		t.Fatalf("no Publish returned ErrQueueFull with %d messages queued", redisQueueSize+10)
+	}
+
+	// the publishing goroutine gives up on the broken connection and sends the rest
+	// on a new one
+	server.setHang(false)
+	server.dropConnections()
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	if err := b.Publish([]byte("message")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestRedisBrokerUnreachable(t *testing.T) {
+	// nothing listens on the address of a closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	b := NewRedisBroker(testLogger(), addr, "", "test")
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		if err := b.Publish([]byte("message")); err != nil {
+			t.Fatalf("Publish() = %v, messages are dropped without an error", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Publish took %s while the server was down", elapsed)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+}
@@ -0,0 +1,657 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the runtime settings of the API server
+type Config struct {
+	Port                 int      `json:"port"`
+	DatabasePath         string   `json:"databasePath"`
+	AllowedOrigins       []string `json:"allowedOrigins"`
+	ImageDir             string   `json:"imageDir"`
+	MaxUploadSize        int64    `json:"maxUploadSize"`
+	MaxCommentDepth      int      `json:"maxCommentDepth"`
+	SessionTTL           Duration `json:"sessionTTL"`
+	SessionSweepInterval Duration `json:"sessionSweepInterval"`
+	ShutdownTimeout      Duration `json:"shutdownTimeout"`
+	TrendingTagsWindow   Duration `json:"trendingTagsWindow"`
+	FrontendURL          string   `json:"frontendURL"`
+	PasswordResetTTL     Duration `json:"passwordResetTTL"`
+	// PasswordResetMaxRequests is the number of reset emails an address can get per PasswordResetWindow
+	PasswordResetMaxRequests int `json:"passwordResetMaxRequests"`
+	// PasswordResetMaxIPRequests is the number of resets a client IP can request per PasswordResetWindow
+	PasswordResetMaxIPRequests int `json:"passwordResetMaxIPRequests"`
+	// PasswordResetWindow is how long reset requests are counted after the last one
+	PasswordResetWindow Duration `json:"passwordResetWindow"`
+	Mail                Mail     `json:"mail"`
+	// EmailVerificationTTL is how long a confirmation link stays valid
+	EmailVerificationTTL Duration `json:"emailVerificationTTL"`
+	// VerificationResendInterval is the minimum time between two confirmation emails
+	VerificationResendInterval Duration `json:"verificationResendInterval"`
+	// UnverifiedRestrictions lists the actions denied until the email is confirmed
+	UnverifiedRestrictions []string `json:"unverifiedRestrictions"`
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer string `json:"totpIssuer"`
+	// LoginMaxFailures is the number of failed logins after which an account is locked
+	LoginMaxFailures int `json:"loginMaxFailures"`
+	// LoginMaxIPFailures is the number of failed logins after which a client IP is blocked
+	LoginMaxIPFailures int `json:"loginMaxIPFailures"`
+	// LoginLockout is how long a lock lasts and how long failures are remembered
+	LoginLockout Duration `json:"loginLockout"`
+	// AccountDeletionGrace is how long a deleted account can still be restored
+	AccountDeletionGrace Duration `json:"accountDeletionGrace"`
+	// DeletionSweepInterval is how often the accounts past their grace period are deleted
+	DeletionSweepInterval Duration `json:"deletionSweepInterval"`
+	// AdminEmails lists the accounts that are made administrators on startup
+	AdminEmails []string `json:"adminEmails"`
+	// OIDC configures login with OpenID Connect providers
+	OIDC OIDC `json:"oidc"`
+	// Cookie sets the attributes of the session cookie
+	Cookie Cookie `json:"cookie"`
+	// Websocket configures the connections to /ws
+	Websocket Websocket `json:"websocket"`
+	// Broker connects the instances of the API when several run side by side
+	Broker Broker `json:"broker"`
+}
+
+// Actions that can be denied to accounts with an unconfirmed email
+var VerificationActions = []string{"post", "comment", "message", "group"}
+
+// Mail configures how emails are sent, the "log" driver only writes them to the
+// log and to Dir if it is set
+type Mail struct {
+	Driver       string `json:"driver"`
+	From         string `json:"from"`
+	Dir          string `json:"dir"`
+	SMTPHost     string `json:"smtpHost"`
+	SMTPPort     int    `json:"smtpPort"`
+	SMTPUsername string `json:"smtpUsername"`
+	SMTPPassword string `json:"smtpPassword"`
+}
+
+// OIDC lists the OpenID Connect providers users can log in with, a provider sends
+// the user back to CallbackBaseURL/oidc/{name}/callback
+type OIDC struct {
+	CallbackBaseURL string         `json:"callbackBaseURL"`
+	Providers       []OIDCProvider `json:"providers"`
+}
+
+// Cookie holds the attributes of the cookies set by the server, they are always HttpOnly
+type Cookie struct {
+	// Secure only sends the cookies over https, it is required with SameSite none
+	Secure bool `json:"secure"`
+	// SameSite is lax, strict or none
+	SameSite string `json:"sameSite"`
+	// Domain is left empty to bind the cookies to the API host
+	Domain string `json:"domain"`
+}
+
+// Values of Cookie.SameSite
+var CookieSameSiteModes = []string{"lax", "strict", "none"}
+
+// Websocket configures how messages are queued for every connection. When the
+// queue of a slow client is full, Overflow decides what happens.
+type Websocket struct {
+	// QueueSize is the number of messages that can wait for a client
+	QueueSize int `json:"queueSize"`
+	// Overflow is drop_oldest, drop_newest or disconnect
+	Overflow string `json:"overflow"`
+	// WriteTimeout is how long writing a message to a client may take
+	WriteTimeout Duration `json:"writeTimeout"`
+	// ReplayRetention is how long events are kept for clients that reconnect, 0 turns replay off
+	ReplayRetention Duration `json:"replayRetention"`
+	// ReplayLimit is the most events replayed at once, a client that missed more resyncs
+	ReplayLimit int `json:"replayLimit"`
+}
+
+// Values of Websocket.Overflow
+var WebsocketOverflowPolicies = []string{"drop_oldest", "drop_newest", "disconnect"}
+
+// Broker configures how websocket events reach users connected to another instance.
+// The "memory" driver keeps them in the process, "redis" shares them through a Redis
+// pub/sub channel.
+type Broker struct {
+	Driver        string `json:"driver"`
+	RedisAddr     string `json:"redisAddr"`
+	RedisPassword string `json:"redisPassword"`
+	// Channel is the pub/sub channel the instances share
+	Channel string `json:"channel"`
+	// NodeID names this instance, a random one is chosen when it is empty
+	NodeID string `json:"nodeId"`
+}
+
+type OIDCProvider struct {
+	// Name identifies the provider in the URLs
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+	// Scopes are requested besides openid
+	Scopes []string `json:"scopes"`
+}
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Duration is a time.Duration read from strings such as "1h" or "90s"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"1h\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the settings used when neither the file nor the environment override them
+func Default() *Config {
+	return &Config{
+		Port:                       8000,
+		DatabasePath:               "database.db",
+		AllowedOrigins:             []string{"http://localhost:3000"},
+		ImageDir:                   "images",
+		MaxUploadSize:              20 << 18,
+		MaxCommentDepth:            3,
+		SessionTTL:                 Duration{time.Hour},
+		SessionSweepInterval:       Duration{10 * time.Minute},
+		ShutdownTimeout:            Duration{10 * time.Second},
+		TrendingTagsWindow:         Duration{24 * time.Hour},
+		FrontendURL:                "http://localhost:3000",
+		PasswordResetTTL:           Duration{time.Hour},
+		PasswordResetMaxRequests:   3,
+		PasswordResetMaxIPRequests: 20,
+		PasswordResetWindow:        Duration{time.Hour},
+		EmailVerificationTTL:       Duration{48 * time.Hour},
+		VerificationResendInterval: Duration{time.Minute},
+		UnverifiedRestrictions:     []string{"post", "comment", "message"},
+		TOTPIssuer:                 "social-hub",
+		LoginMaxFailures:           10,
+		LoginMaxIPFailures:         50,
+		LoginLockout:               Duration{15 * time.Minute},
+		AccountDeletionGrace:       Duration{14 * 24 * time.Hour},
+		DeletionSweepInterval:      Duration{time.Hour},
+		AdminEmails:                []string{},
+		OIDC: OIDC{
+			CallbackBaseURL: "http://localhost:8000",
+			Providers:       []OIDCProvider{},
+		},
+		Cookie: Cookie{
+			SameSite: "lax",
+		},
+		Websocket: Websocket{
+			QueueSize:       64,
+			Overflow:        "disconnect",
+			WriteTimeout:    Duration{10 * time.Second},
+			ReplayRetention: Duration{24 * time.Hour},
+			ReplayLimit:     500,
+		},
+		Broker: Broker{
+			Driver:  "memory",
+			Channel: "social-network",
+		},
+		Mail: Mail{
+			Driver:   "log",
+			From:     "no-reply@localhost",
+			SMTPPort: 587,
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults, applies
+// environment overrides and validates the result. A missing file is only an
+// error when the path was set explicitly.
+func Load(path string, explicit bool) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		err := config.loadFile(path)
+		if err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+			return nil, err
+		}
+	}
+
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// LoadFromEnv loads the file named by API_CONFIG, or config.json if it is unset
+func LoadFromEnv() (*Config, error) {
+	path, explicit := os.LookupEnv("API_CONFIG")
+	if !explicit {
+		path = "config.json"
+	}
+
+	return Load(path, explicit)
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	env := &envLoader{}
+
+	env.envInt("API_PORT", &c.Port)
+	env.envString("API_DATABASE_PATH", &c.DatabasePath)
+	env.envList("API_ALLOWED_ORIGINS", &c.AllowedOrigins)
+	env.envString("API_IMAGE_DIR", &c.ImageDir)
+	env.envInt64("API_MAX_UPLOAD_SIZE", &c.MaxUploadSize)
+	env.envInt("API_MAX_COMMENT_DEPTH", &c.MaxCommentDepth)
+	env.envDuration("API_SESSION_TTL", &c.SessionTTL)
+	env.envDuration("API_SESSION_SWEEP_INTERVAL", &c.SessionSweepInterval)
+	env.envDuration("API_SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	env.envDuration("API_TRENDING_TAGS_WINDOW", &c.TrendingTagsWindow)
+	env.envString("API_FRONTEND_URL", &c.FrontendURL)
+	env.envDuration("API_PASSWORD_RESET_TTL", &c.PasswordResetTTL)
+	env.envInt("API_PASSWORD_RESET_MAX_REQUESTS", &c.PasswordResetMaxRequests)
+	env.envInt("API_PASSWORD_RESET_MAX_IP_REQUESTS", &c.PasswordResetMaxIPRequests)
+	env.envDuration("API_PASSWORD_RESET_WINDOW", &c.PasswordResetWindow)
+	env.envDuration("API_EMAIL_VERIFICATION_TTL", &c.EmailVerificationTTL)
+	env.envDuration("API_VERIFICATION_RESEND_INTERVAL", &c.VerificationResendInterval)
+	env.envList("API_UNVERIFIED_RESTRICTIONS", &c.UnverifiedRestrictions)
+	env.envString("API_TOTP_ISSUER", &c.TOTPIssuer)
+	env.envInt("API_LOGIN_MAX_FAILURES", &c.LoginMaxFailures)
+	env.envInt("API_LOGIN_MAX_IP_FAILURES", &c.LoginMaxIPFailures)
+	env.envDuration("API_LOGIN_LOCKOUT", &c.LoginLockout)
+	env.envDuration("API_ACCOUNT_DELETION_GRACE", &c.AccountDeletionGrace)
+	env.envDuration("API_DELETION_SWEEP_INTERVAL", &c.DeletionSweepInterval)
+	env.envList("API_ADMIN_EMAILS", &c.AdminEmails)
+	env.envString("API_OIDC_CALLBACK_BASE_URL", &c.OIDC.CallbackBaseURL)
+
+	// every provider named in API_OIDC_PROVIDERS is read from API_OIDC_<NAME>_* variables
+	if value, ok := os.LookupEnv("API_OIDC_PROVIDERS"); ok {
+		c.OIDC.Providers = []OIDCProvider{}
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+
+			prefix := "API_OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+			provider := OIDCProvider{
+				Name:         name,
+				DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+				Issuer:       os.Getenv(prefix + "ISSUER"),
+				ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+				ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			}
+
+			if scopes, ok := os.LookupEnv(prefix + "SCOPES"); ok {
+				provider.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+			}
+
+			c.OIDC.Providers = append(c.OIDC.Providers, provider)
+		}
+	}
+
+	env.envBool("API_COOKIE_SECURE", &c.Cookie.Secure)
+
+	if value, ok := os.LookupEnv("API_COOKIE_SAMESITE"); ok {
+		c.Cookie.SameSite = strings.ToLower(value)
+	}
+
+	env.envString("API_COOKIE_DOMAIN", &c.Cookie.Domain)
+	env.envInt("API_WS_QUEUE_SIZE", &c.Websocket.QueueSize)
+	env.envString("API_WS_OVERFLOW", &c.Websocket.Overflow)
+	env.envDuration("API_WS_WRITE_TIMEOUT", &c.Websocket.WriteTimeout)
+	env.envDuration("API_WS_REPLAY_RETENTION", &c.Websocket.ReplayRetention)
+	env.envInt("API_WS_REPLAY_LIMIT", &c.Websocket.ReplayLimit)
+	env.envString("API_BROKER_DRIVER", &c.Broker.Driver)
+	env.envString("API_BROKER_REDIS_ADDR", &c.Broker.RedisAddr)
+	env.envString("API_BROKER_REDIS_PASSWORD", &c.Broker.RedisPassword)
+	env.envString("API_BROKER_CHANNEL", &c.Broker.Channel)
+	env.envString("API_BROKER_NODE_ID", &c.Broker.NodeID)
+	env.envString("API_MAIL_DRIVER", &c.Mail.Driver)
+	env.envString("API_MAIL_FROM", &c.Mail.From)
+	env.envString("API_MAIL_DIR", &c.Mail.Dir)
+	env.envString("API_SMTP_HOST", &c.Mail.SMTPHost)
+	env.envInt("API_SMTP_PORT", &c.Mail.SMTPPort)
+	env.envString("API_SMTP_USERNAME", &c.Mail.SMTPUsername)
+	env.envString("API_SMTP_PASSWORD", &c.Mail.SMTPPassword)
+
+	return env.err
+}
+
+// envLoader overrides settings with the environment variables that are set and
+// keeps the first value that cannot be parsed as err
+type envLoader struct {
+	err error
+}
+
+func (e *envLoader) envString(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*target = value
+	}
+}
+
+func (e *envLoader) envInt(name string, target *int) {
+	if value, ok := os.LookupEnv(name); ok {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail(name, err)
+			return
+		}
+		*target = number
+	}
+}
+
+func (e *envLoader) envInt64(name string, target *int64) {
+	if value, ok := os.LookupEnv(name); ok {
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			e.fail(name, err)
+			return
+		}
+		*target = number
+	}
+}
+
+func (e *envLoader) envDuration(name string, target *Duration) {
+	if value, ok := os.LookupEnv(name); ok {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			e.fail(name, err)
+			return
+		}
+		*target = Duration{duration}
+	}
+}
+
+func (e *envLoader) envBool(name string, target *bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			e.fail(name, err)
+			return
+		}
+		*target = flag
+	}
+}
+
+// envList reads a comma separated list, an empty variable clears the list
+func (e *envLoader) envList(name string, target *[]string) {
+	if value, ok := os.LookupEnv(name); ok {
+		*target = []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+	}
+}
+
+func (e *envLoader) fail(name string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("%s: %w", name, err)
+	}
+}
+
+// Validate reports the first invalid setting
+func (c *Config) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+
+	if c.DatabasePath == "" {
+		return errors.New("database path is required")
+	}
+
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("at least one allowed origin is required")
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("invalid allowed origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+
+	if c.ImageDir == "" {
+		return errors.New("image directory is required")
+	}
+
+	if c.MaxUploadSize <= 0 {
+		return fmt.Errorf("invalid max upload size %d", c.MaxUploadSize)
+	}
+
+	if c.MaxCommentDepth < 0 {
+		return fmt.Errorf("invalid max comment depth %d", c.MaxCommentDepth)
+	}
+
+	if c.SessionTTL.Duration < time.Minute {
+		return fmt.Errorf("session TTL %s is shorter than a minute", c.SessionTTL)
+	}
+
+	if c.SessionSweepInterval.Duration <= 0 {
+		return fmt.Errorf("invalid session sweep interval %s", c.SessionSweepInterval)
+	}
+
+	if c.ShutdownTimeout.Duration <= 0 {
+		return fmt.Errorf("invalid shutdown timeout %s", c.ShutdownTimeout)
+	}
+
+	if c.TrendingTagsWindow.Duration <= 0 {
+		return fmt.Errorf("invalid trending tags window %s", c.TrendingTagsWindow)
+	}
+
+	if u, err := url.Parse(c.FrontendURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid frontend URL %q", c.FrontendURL)
+	}
+
+	if c.PasswordResetTTL.Duration < time.Minute {
+		return fmt.Errorf("password reset TTL %s is shorter than a minute", c.PasswordResetTTL)
+	}
+
+	if c.PasswordResetMaxRequests < 1 {
+		return fmt.Errorf("invalid password reset max requests %d", c.PasswordResetMaxRequests)
+	}
+
+	if c.PasswordResetMaxIPRequests < c.PasswordResetMaxRequests {
+		return fmt.Errorf("password reset max IP requests %d is less than password reset max requests %d", c.PasswordResetMaxIPRequests, c.PasswordResetMaxRequests)
+	}
+
+	if c.PasswordResetWindow.Duration < time.Second {
+		return fmt.Errorf("password reset window %s is shorter than a second", c.PasswordResetWindow)
+	}
+
+	if c.EmailVerificationTTL.Duration < time.Minute {
+		return fmt.Errorf("email verification TTL %s is shorter than a minute", c.EmailVerificationTTL)
+	}
+
+	if c.VerificationResendInterval.Duration < 0 {
+		return fmt.Errorf("invalid verification resend interval %s", c.VerificationResendInterval)
+	}
+
+	for _, action := range c.UnverifiedRestrictions {
+		known := false
+		for _, a := range VerificationActions {
+			known = known || a == action
+		}
+		if !known {
+			return fmt.Errorf("unknown unverified restriction %q, expected one of %s", action, strings.Join(VerificationActions, ", "))
+		}
+	}
+
+	if c.TOTPIssuer == "" || strings.Contains(c.TOTPIssuer, ":") {
+		return fmt.Errorf("invalid TOTP issuer %q", c.TOTPIssuer)
+	}
+
+	if c.LoginMaxFailures < 1 {
+		return fmt.Errorf("invalid login max failures %d", c.LoginMaxFailures)
+	}
+
+	if c.LoginMaxIPFailures < c.LoginMaxFailures {
+		return fmt.Errorf("login max IP failures %d is less than login max failures %d", c.LoginMaxIPFailures, c.LoginMaxFailures)
+	}
+
+	if c.LoginLockout.Duration < time.Second {
+		return fmt.Errorf("login lockout %s is shorter than a second", c.LoginLockout)
+	}
+
+	if c.AccountDeletionGrace.Duration < 0 {
+		return fmt.Errorf("invalid account deletion grace period %s", c.AccountDeletionGrace)
+	}
+
+	if c.DeletionSweepInterval.Duration <= 0 {
+		return fmt.Errorf("invalid deletion sweep interval %s", c.DeletionSweepInterval)
+	}
+
+	for _, email := range c.AdminEmails {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("invalid admin email %q", email)
+		}
+	}
+
+	if err := c.OIDC.validate(); err != nil {
+		return err
+	}
+
+	knownSameSite := false
+	for _, mode := range CookieSameSiteModes {
+		knownSameSite = knownSameSite || mode == c.Cookie.SameSite
+	}
+	if !knownSameSite {
+		return fmt.Errorf("unknown cookie SameSite mode %q, expected one of %s", c.Cookie.SameSite, strings.Join(CookieSameSiteModes, ", "))
+	}
+
+	// browsers drop SameSite=None cookies that are not Secure
+	if c.Cookie.SameSite == "none" && !c.Cookie.Secure {
+		return errors.New("cookie SameSite none requires secure cookies")
+	}
+
+	if c.Websocket.QueueSize < 1 {
+		return fmt.Errorf("invalid websocket queue size %d", c.Websocket.QueueSize)
+	}
+
+	knownOverflow := false
+	for _, policy := range WebsocketOverflowPolicies {
+		knownOverflow = knownOverflow || policy == c.Websocket.Overflow
+	}
+	if !knownOverflow {
+		return fmt.Errorf("unknown websocket overflow policy %q, expected one of %s", c.Websocket.Overflow, strings.Join(WebsocketOverflowPolicies, ", "))
+	}
+
+	if c.Websocket.WriteTimeout.Duration < time.Second {
+		return fmt.Errorf("websocket write timeout %s is shorter than a second", c.Websocket.WriteTimeout)
+	}
+
+	if c.Websocket.ReplayRetention.Duration < 0 {
+		return fmt.Errorf("invalid websocket replay retention %s", c.Websocket.ReplayRetention)
+	}
+
+	if c.Websocket.ReplayLimit < 1 {
+		return fmt.Errorf("invalid websocket replay limit %d", c.Websocket.ReplayLimit)
+	}
+
+	switch c.Broker.Driver {
+	case "memory":
+	case "redis":
+		if c.Broker.RedisAddr == "" {
+			return errors.New("Redis address is required for the redis broker driver")
+		}
+		if c.Broker.Channel == "" {
+			return errors.New("broker channel is required for the redis broker driver")
+		}
+	default:
+		return fmt.Errorf("unknown broker driver %q, expected memory or redis", c.Broker.Driver)
+	}
+
+	if c.Mail.From == "" {
+		return errors.New("mail sender address is required")
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			return errors.New("SMTP host is required for the smtp mail driver")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			return fmt.Errorf("invalid SMTP port %d", c.Mail.SMTPPort)
+		}
+	default:
+		return fmt.Errorf("unknown mail driver %q, expected log or smtp", c.Mail.Driver)
+	}
+
+	return nil
+}
+
+func (o *OIDC) validate() error {
+	if u, err := url.Parse(o.CallbackBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid OIDC callback base URL %q", o.CallbackBaseURL)
+	}
+
+	names := map[string]bool{}
+
+	for _, provider := range o.Providers {
+		if !oidcProviderName.MatchString(provider.Name) || names[provider.Name] {
+			return fmt.Errorf("invalid or duplicate OIDC provider name %q", provider.Name)
+		}
+		names[provider.Name] = true
+
+		// plain http is only accepted for a provider on this machine, such as a mock provider
+		u, err := url.Parse(provider.Issuer)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && !(u.Scheme == "http" && isLoopback(u.Hostname()))) {
+			return fmt.Errorf("invalid issuer %q of OIDC provider %s, expected an https URL", provider.Issuer, provider.Name)
+		}
+
+		if provider.ClientID == "" {
+			return fmt.Errorf("client ID of OIDC provider %s is required", provider.Name)
+		}
+	}
+
+	return nil
+}
+
+func isLoopback(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Addr returns the listen address of the server
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
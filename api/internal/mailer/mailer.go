@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(message *Message) error
+}
+
+// SMTPMailer sends emails through an SMTP server, the connection is upgraded with
+// STARTTLS when the server supports it
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		Addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		Auth: auth,
+		From: from,
+	}
+}
+
+func (m *SMTPMailer) Send(message *Message) error {
+	return smtp.SendMail(m.Addr, m.Auth, m.From, []string{message.To}, format(m.From, message))
+}
+
+// LogMailer writes emails to the log and, when Dir is set, to one file per email.
+// It is meant for local development and tests.
+type LogMailer struct {
+	Logger *log.Logger
+	Dir    string
+	From   string
+
+	mu    sync.Mutex
+	count int
+}
+
+func NewLogMailer(logger *log.Logger, dir string, from string) *LogMailer {
+	return &LogMailer{
+		Logger: logger,
+		Dir:    dir,
+		From:   from,
+	}
+}
+
+func (m *LogMailer) Send(message *Message) error {
+	m.Logger.Printf("Mail to %s: %s\n%s", message.To, message.Subject, message.Body)
+
+	if m.Dir == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	m.count++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().Format("20060102-150405"), m.count)
+	m.mu.Unlock()
+
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0644)
+}
+
+// format builds the RFC 5322 message, header values cannot contain line breaks
+func format(from string, message *Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(message.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// List or create the personal access tokens of the user
+func (app *Application) AccessTokens(rw http.ResponseWriter, r *http.Request) {
+	userId, err := app.UserService.GetUserID(r)
+	if err != nil {
+		app.Logger.Printf("Cannot get user ID: %s", err)
+		http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		tokens, err := app.AccessTokenService.GetTokens(userId)
+		if err != nil {
+			http.Error(rw, "cannot get access tokens", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&tokens)
+
+	case "POST":
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		JSONdata := services.AccessTokenRequestJSON{}
+		err := decoder.Decode(&JSONdata)
+		if err != nil {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "JSON error", http.StatusBadRequest)
+			return
+		}
+
+		token, err := app.AccessTokenService.CreateToken(userId, JSONdata)
+
+		switch {
+		case errors.Is(err, services.ErrInvalidTokenName),
+			errors.Is(err, services.ErrInvalidTokenScope):
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrTooManyTokens):
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(rw, "cannot create access token", http.StatusInternalServerError)
+			return
+		}
+
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(&token)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Revoke a personal access token, connections made with it are closed
+func (app *Application) RevokeAccessToken(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		tokenId, err := strconv.ParseInt(mux.Vars(r)["tokenId"], 10, 64)
+		if err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		err = app.AccessTokenService.RevokeToken(userId, tokenId)
+		if errors.Is(err, services.ErrAccessTokenMissing) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(rw, "cannot revoke access token", http.StatusInternalServerError)
+			return
+		}
+
+		app.WS.DisconnectTokens([]int64{tokenId})
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
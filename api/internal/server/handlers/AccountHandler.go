@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// deleteAccountJSON confirms the deletion with the password, users without one send a
+// two-factor code or nothing after logging in at /oidc/{provider}/reauth
+type deleteAccountJSON struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// ExportAccount sends a zip archive with all personal data of the user
+func (app *Application) ExportAccount(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/zip")
+		rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d.zip"`, userId))
+
+		// the archive is streamed, an error after the first bytes can only cut the response short
+		err = app.AccountService.ExportAccount(userId, rw)
+		if err != nil {
+			app.Logger.Printf("Cannot export account of user %d: %s", userId, err)
+		}
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// DeleteAccount schedules the account for deletion and logs the user out everywhere,
+// logging in again during the grace period allows cancelling it
+func (app *Application) DeleteAccount(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		JSONdata := &deleteAccountJSON{}
+		err = decoder.Decode(JSONdata)
+
+		if err != nil {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		deletion, err := app.AccountService.ScheduleDeletion(userId, JSONdata.Password, JSONdata.Code, utils.ClientIP(r))
+
+		var throttled *services.TooManyAttemptsError
+
+		switch {
+		case errors.As(err, &throttled):
+			app.writeTwoFactorError(rw, err)
+			return
+		case errors.Is(err, services.ErrIncorrectPassword),
+			errors.Is(err, services.ErrInvalidTwoFactorCode),
+			errors.Is(err, services.ErrConfirmationRequired):
+			http.Error(rw, err.Error(), http.StatusUnauthorized)
+			return
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrDeletionScheduled):
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			app.Logger.Printf("Cannot schedule account deletion: %s", err)
+			http.Error(rw, "cannot delete account", http.StatusInternalServerError)
+			return
+		}
+
+		sessionIDs, err := app.UserService.RevokeAllSessions(userId)
+		if err != nil {
+			app.Logger.Printf("Cannot revoke sessions: %s", err)
+		}
+
+		app.WS.DisconnectSessions(sessionIDs)
+		app.UserService.ClearCookie(rw)
+
+		tokenIDs, err := app.AccessTokenService.RevokeAllTokens(userId)
+		if err != nil {
+			app.Logger.Printf("Cannot revoke access tokens: %s", err)
+		}
+
+		app.WS.DisconnectTokens(tokenIDs)
+
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(&deletion)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// AccountDeletion shows the scheduled deletion of the account
+func (app *Application) AccountDeletion(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		deletion, err := app.AccountService.GetDeletion(userId)
+
+		if errors.Is(err, services.ErrNoDeletionScheduled) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(rw, "cannot get account deletion", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&deletion)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// CancelAccountDeletion keeps the account during the grace period
+func (app *Application) CancelAccountDeletion(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		err = app.AccountService.CancelDeletion(userId)
+
+		if errors.Is(err, services.ErrNoDeletionScheduled) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			http.Error(rw, "cannot cancel account deletion", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// RunAccountDeletionSweeper periodically deletes the accounts whose grace period is
+// over and disconnects their websocket clients, until done is closed
+func (app *Application) RunAccountDeletionSweeper(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// the sessions of the accounts deleted before an error are gone too
+			sessionIDs, err := app.AccountService.DeleteDueAccounts()
+			if err != nil {
+				app.Logger.Printf("Cannot delete due accounts: %s", err)
+			}
+
+			app.WS.DisconnectSessions(sessionIDs)
+		}
+	}
+}
@@ -1,33 +1,145 @@
 package handlers
 
 import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"SocialNetworkRestApi/api/internal/config"
+	"SocialNetworkRestApi/api/internal/mailer"
+	"SocialNetworkRestApi/api/internal/server/utils"
 	"SocialNetworkRestApi/api/internal/server/websocket"
 	"SocialNetworkRestApi/api/pkg/models"
 	"SocialNetworkRestApi/api/pkg/services"
 	"log"
+	"net/http"
 )
 
 type Application struct {
-	Logger              *log.Logger
-	WS                  *websocket.WebsocketServer
-	UserService         services.IUserService
-	NotificationService services.INotificationService
-	PostService         services.IPostService
-	CommentService      services.ICommentService
-	ChatService         services.IChatService
-	GroupService        services.IGroupService
-	GroupMemberService  services.IGroupMemberService
-	GroupEventService   services.IGroupEventService
+	Logger                   *log.Logger
+	Config                   *config.Config
+	WS                       *websocket.WebsocketServer
+	UserService              services.IUserService
+	NotificationService      services.INotificationService
+	PostService              services.IPostService
+	CommentService           services.ICommentService
+	ChatService              services.IChatService
+	GroupService             services.IGroupService
+	GroupMemberService       services.IGroupMemberService
+	GroupEventService        services.IGroupEventService
+	ReactionService          services.IReactionService
+	SearchService            services.ISearchService
+	PasswordResetService     services.IPasswordResetService
+	EmailVerificationService services.IEmailVerificationService
+	TwoFactorService         services.ITwoFactorService
+	AccountService           services.IAccountService
+	BlockService             services.IBlockService
+	ModerationService        services.IModerationService
+	AccessTokenService       services.IAccessTokenService
+	OIDCService              services.IOIDCService
+	ReplayService            services.IReplayService
 }
 
-func InitApp(repositories *models.Repositories, logger *log.Logger) *Application {
+// newMailer returns the mail sender selected by the configuration
+func newMailer(logger *log.Logger, config config.Mail) mailer.Mailer {
+	if config.Driver == "smtp" {
+		return mailer.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.From)
+	}
+
+	return mailer.NewLogMailer(logger, config.Dir, config.From)
+}
+
+// newBroker returns the broker the websocket server shares events through
+func newBroker(logger *log.Logger, config config.Broker) broker.Broker {
+	if config.Driver == "redis" {
+		return broker.NewRedisBroker(logger, config.RedisAddr, config.RedisPassword, config.Channel)
+	}
+
+	return broker.NewMemoryBroker()
+}
+
+// oidcProviders converts the configured OpenID Connect providers for the OIDC service
+func oidcProviders(config config.OIDC) []services.OIDCProviderConfig {
+	providers := []services.OIDCProviderConfig{}
+	for _, provider := range config.Providers {
+		providers = append(providers, services.OIDCProviderConfig{
+			Name:         provider.Name,
+			DisplayName:  provider.DisplayName,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			Scopes:       provider.Scopes,
+		})
+	}
+
+	return providers
+}
+
+// cookieOptions converts the configured cookie attributes for the user service
+func cookieOptions(config config.Cookie) services.CookieOptions {
+	sameSite := http.SameSiteLaxMode
+	switch config.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return services.CookieOptions{
+		Secure:   config.Secure,
+		SameSite: sameSite,
+		Domain:   config.Domain,
+	}
+}
+
+func InitApp(repositories *models.Repositories, logger *log.Logger, config *config.Config) *Application {
+
+	imageService := utils.NewImageService(config.ImageDir)
+	mailSender := newMailer(logger, config.Mail)
+
+	node := config.Broker.NodeID
+	if node == "" {
+		node = broker.NewNodeID()
+	}
+
+	emailVerificationService := services.InitEmailVerificationService(
+		logger,
+		repositories.UserRepo,
+		repositories.EmailVerificationRepo,
+		mailSender,
+		config.EmailVerificationTTL.Duration,
+		config.VerificationResendInterval.Duration,
+		config.FrontendURL,
+		config.UnverifiedRestrictions,
+	)
+
+	loginThrottleService := services.InitLoginThrottleService(
+		logger,
+		repositories.LoginFailureRepo,
+		config.LoginMaxFailures,
+		config.LoginMaxIPFailures,
+		config.LoginLockout.Duration,
+	)
+
+	twoFactorService := services.InitTwoFactorService(
+		logger,
+		repositories.UserRepo,
+		repositories.TwoFactorRepo,
+		repositories.OIDCRepo,
+		loginThrottleService,
+		config.TOTPIssuer,
+	)
 
 	userServices := services.InitUserService(
 		logger,
 		repositories.UserRepo,
 		repositories.SessionRepo,
+		repositories.AccessTokenRepo,
 		repositories.FollowerRepo,
 		repositories.NotificationRepo,
+		imageService,
+		emailVerificationService,
+		twoFactorService,
+		loginThrottleService,
+		config.SessionTTL.Duration,
+		cookieOptions(config.Cookie),
 	)
 
 	notificationServices := services.InitNotificationService(
@@ -39,6 +151,10 @@ func InitApp(repositories *models.Repositories, logger *log.Logger) *Application
 		repositories.GroupMemberRepo,
 		repositories.EventRepo,
 		repositories.EventAttendanceRepo,
+		repositories.PostRepo,
+		repositories.CommentRepo,
+		repositories.MentionRepo,
+		repositories.BlockRepo,
 	)
 
 	chatServices := services.InitChatService(
@@ -46,6 +162,8 @@ func InitApp(repositories *models.Repositories, logger *log.Logger) *Application
 		repositories.UserRepo,
 		repositories.MessageRepo,
 		repositories.GroupRepo,
+		emailVerificationService,
+		repositories.BlockRepo,
 	)
 
 	groupEventServices := services.InitGroupEventService(
@@ -58,10 +176,24 @@ func InitApp(repositories *models.Repositories, logger *log.Logger) *Application
 		repositories.NotificationRepo,
 	)
 
+	replayService := services.InitReplayService(
+		logger,
+		repositories.ReplayRepo,
+		config.Websocket.ReplayRetention.Duration,
+		config.Websocket.ReplayLimit,
+	)
+
 	return &Application{
 		Logger: logger,
+		Config: config,
 		WS: websocket.InitWebsocket(
 			logger,
+			config.AllowedOrigins,
+			config.Websocket.QueueSize,
+			websocket.OverflowPolicy(config.Websocket.Overflow),
+			config.Websocket.WriteTimeout.Duration,
+			newBroker(logger, config.Broker),
+			node,
 			userServices,
 			notificationServices,
 			chatServices,
@@ -70,32 +202,100 @@ func InitApp(repositories *models.Repositories, logger *log.Logger) *Application
 				repositories.GroupRepo,
 				repositories.GroupMemberRepo,
 				repositories.UserRepo,
+				imageService,
+				emailVerificationService,
 			),
 			services.InitGroupMemberService(
 				logger,
 				repositories.UserRepo,
 				repositories.NotificationRepo,
 				repositories.GroupRepo,
-				repositories.GroupMemberRepo),
+				repositories.GroupMemberRepo,
+				repositories.BlockRepo),
 			groupEventServices,
+			replayService,
 		),
 		UserService:         userServices,
 		NotificationService: notificationServices,
-		PostService:         services.InitPostService(logger, repositories.GroupRepo, repositories.PostRepo, repositories.AllowedPostRepo),
-		CommentService:      services.InitCommentService(logger, repositories.CommentRepo, repositories.UserRepo),
+		PostService:         services.InitPostService(logger, repositories.GroupRepo, repositories.PostRepo, repositories.AllowedPostRepo, repositories.CommentRepo, repositories.ReactionRepo, repositories.TagRepo, imageService, emailVerificationService, config.TrendingTagsWindow.Duration),
+		CommentService:      services.InitCommentService(logger, repositories.CommentRepo, repositories.PostRepo, repositories.UserRepo, repositories.ReactionRepo, imageService, emailVerificationService, config.MaxCommentDepth),
 		ChatService:         chatServices,
 		GroupService: services.InitGroupService(
 			logger,
 			repositories.GroupRepo,
 			repositories.GroupMemberRepo,
 			repositories.UserRepo,
+			imageService,
+			emailVerificationService,
 		),
 		GroupMemberService: services.InitGroupMemberService(
 			logger,
 			repositories.UserRepo,
 			repositories.NotificationRepo,
 			repositories.GroupRepo,
-			repositories.GroupMemberRepo),
+			repositories.GroupMemberRepo,
+			repositories.BlockRepo),
 		GroupEventService: groupEventServices,
+		ReactionService: services.InitReactionService(
+			logger,
+			repositories.PostRepo,
+			repositories.CommentRepo,
+			repositories.ReactionRepo,
+		),
+		SearchService:            services.InitSearchService(logger, repositories.SearchRepo),
+		EmailVerificationService: emailVerificationService,
+		TwoFactorService:         twoFactorService,
+		AccountService: services.InitAccountService(
+			logger,
+			repositories.UserRepo,
+			repositories.AccountRepo,
+			imageService,
+			twoFactorService,
+			config.AccountDeletionGrace.Duration,
+		),
+		BlockService: services.InitBlockService(
+			logger,
+			repositories.UserRepo,
+			repositories.BlockRepo,
+		),
+		AccessTokenService: services.InitAccessTokenService(
+			logger,
+			repositories.AccessTokenRepo,
+		),
+		ModerationService: services.InitModerationService(
+			logger,
+			repositories.UserRepo,
+			repositories.SessionRepo,
+			repositories.AccessTokenRepo,
+			repositories.PostRepo,
+			repositories.CommentRepo,
+			repositories.MessageRepo,
+			repositories.GroupMemberRepo,
+			repositories.ReportRepo,
+			repositories.ModerationRepo,
+			imageService,
+		),
+		PasswordResetService: services.InitPasswordResetService(
+			logger,
+			repositories.UserRepo,
+			repositories.SessionRepo,
+			repositories.PasswordResetRepo,
+			mailSender,
+			config.PasswordResetTTL.Duration,
+			config.FrontendURL,
+			config.PasswordResetMaxRequests,
+			config.PasswordResetMaxIPRequests,
+			config.PasswordResetWindow.Duration,
+		),
+		OIDCService: services.InitOIDCService(
+			logger,
+			repositories.UserRepo,
+			repositories.OIDCRepo,
+			userServices,
+			emailVerificationService,
+			oidcProviders(config.OIDC),
+			config.OIDC.CallbackBaseURL,
+		),
+		ReplayService: replayService,
 	}
 }
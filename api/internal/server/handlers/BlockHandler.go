@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Block or unblock the user with the id in the path
+func (app *Application) Block(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	blockedID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		app.Logger.Printf("DATA PARSE error: %v", err)
+		http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := app.UserService.GetUserID(r)
+	if err != nil {
+		app.Logger.Printf("Cannot get user ID: %s", err)
+		http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		err = app.BlockService.BlockUser(userID, blockedID)
+
+		if errors.Is(err, services.ErrCannotBlockSelf) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if errors.Is(err, services.ErrBlockUserMissing) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+	case "DELETE":
+		err = app.BlockService.UnblockUser(userID, blockedID)
+
+		if errors.Is(err, services.ErrUserNotBlocked) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		app.Logger.Printf("Cannot update block: %s", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Write([]byte("ok"))
+}
+
+// List the users blocked by the user
+func (app *Application) BlockedUsers(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userID, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		users, err := app.BlockService.GetBlockedUsers(userID)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(rw).Encode(users)
+
+		if err != nil {
+			app.Logger.Printf("Cannot encode blocked users: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -35,11 +38,25 @@ func (app *Application) Comments(rw http.ResponseWriter, r *http.Request) {
 			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
 		}
 
-		comments, err := app.CommentService.GetPostComments(postId, offset)
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		comments, err := app.CommentService.GetPostComments(postId, offset, userId)
+
+		if err == sql.ErrNoRows {
+			http.Error(rw, "Post not found", http.StatusNotFound)
+			return
+		}
 
 		if err != nil {
 			app.Logger.Printf("JSON error: %v", err)
 			http.Error(rw, "JSON error", http.StatusBadRequest)
+			return
 		}
 
 		json.NewEncoder(rw).Encode(&comments)
@@ -57,9 +74,9 @@ func (app *Application) Comment(rw http.ResponseWriter, r *http.Request) {
 	case "POST":
 
 		// Limit the size of the request body to 5MB
-		r.Body = http.MaxBytesReader(rw, r.Body, 20<<18+512)
+		r.Body = http.MaxBytesReader(rw, r.Body, app.Config.MaxUploadSize+512)
 
-		err := r.ParseMultipartForm(20 << 18)
+		err := r.ParseMultipartForm(app.Config.MaxUploadSize)
 
 		if err != nil {
 			app.Logger.Printf("Failed parsing form: %v", err)
@@ -73,6 +90,17 @@ func (app *Application) Comment(rw http.ResponseWriter, r *http.Request) {
 			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
 		}
 
+		// replies carry the id of the comment they answer
+		var parentCommentId int64
+		if parentIdStr := r.FormValue("parentCommentId"); parentIdStr != "" {
+			parentCommentId, err = strconv.ParseInt(parentIdStr, 10, 64)
+			if parentCommentId < 0 || err != nil {
+				app.Logger.Printf("DATA PARSE error: %v", err)
+				http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+				return
+			}
+		}
+
 		content := r.FormValue("content")
 
 		file, header, err := r.FormFile("image")
@@ -97,17 +125,41 @@ func (app *Application) Comment(rw http.ResponseWriter, r *http.Request) {
 		}
 
 		comment := &models.Comment{
-			PostId:    postId,
-			UserId:    userId,
-			Content:   content,
-			ImagePath: imagePath,
+			PostId:          postId,
+			UserId:          userId,
+			ParentCommentId: parentCommentId,
+			Content:         content,
+			ImagePath:       imagePath,
 		}
 
 		err = app.CommentService.CreateComment(comment)
 
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if err == sql.ErrNoRows {
+			http.Error(rw, "Post not found", http.StatusNotFound)
+			return
+		}
+
 		if err != nil {
 			app.Logger.Printf("Creating comment failed: %v", err)
-			http.Error(rw, "Error", http.StatusBadRequest)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		app.broadcastMentions(app.NotificationService.CreateCommentMentions(userId, comment.Id))
+
+		if comment.ParentCommentId > 0 {
+			parent, err := app.CommentService.GetById(comment.ParentCommentId)
+			if err == nil && parent.UserId != userId {
+				err = app.WS.BroadcastCommentReply(comment, parent.UserId)
+			}
+			if err != nil {
+				app.Logger.Printf("Cannot send reply notification: %v", err)
+			}
 		}
 
 		rw.Write([]byte("ok"))
@@ -118,3 +170,54 @@ func (app *Application) Comment(rw http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// CommentReplies returns a page of replies to a comment
+func (app *Application) CommentReplies(rw http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		vars := mux.Vars(r)
+
+		commentId, err := strconv.ParseInt(vars["commentId"], 10, 64)
+
+		if commentId < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		offset, err := strconv.ParseInt(vars["offset"], 10, 64)
+
+		if offset < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		replies, err := app.CommentService.GetCommentReplies(commentId, offset, userId)
+
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(rw, "Comment not found", http.StatusNotFound)
+			return
+		case err != nil:
+			app.Logger.Printf("Failed fetching replies: %v", err)
+			http.Error(rw, "Cannot get replies", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&replies)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
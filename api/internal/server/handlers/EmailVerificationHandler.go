@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type confirmEmailJSON struct {
+	Token string `json:"token"`
+}
+
+// ConfirmEmail verifies the email address with the token from the confirmation email
+func (app *Application) ConfirmEmail(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		JSONdata := &confirmEmailJSON{}
+		err := decoder.Decode(JSONdata)
+
+		if err != nil || JSONdata.Token == "" {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		err = app.EmailVerificationService.ConfirmEmail(JSONdata.Token)
+
+		if errors.Is(err, services.ErrInvalidVerificationLink) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Cannot confirm email: %s", err)
+			http.Error(rw, "cannot confirm email", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// ResendVerification sends the confirmation email of the current user again
+func (app *Application) ResendVerification(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		err = app.EmailVerificationService.ResendVerification(userId)
+
+		switch {
+		case errors.Is(err, services.ErrEmailAlreadyVerified):
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, services.ErrVerificationThrottled):
+			http.Error(rw, err.Error(), http.StatusTooManyRequests)
+			return
+		case err != nil:
+			app.Logger.Printf("Cannot resend verification: %s", err)
+			http.Error(rw, "cannot send verification email", http.StatusInternalServerError)
+			return
+		}
+
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -71,7 +73,7 @@ func (app *Application) CreateGroupEvent(rw http.ResponseWriter, r *http.Request
 			return
 		}
 
-		err = app.WS.BroadcastGroupNotifications(notifications)
+		err = app.WS.BroadcastNotifications(notifications)
 
 		if err != nil {
 			http.Error(rw, "err", http.StatusBadRequest)
@@ -183,7 +185,7 @@ func (app *Application) EventReaction(rw http.ResponseWriter, r *http.Request) {
 		if notification != nil {
 			err = app.NotificationService.HandleEventInvite(notification.Id, JSONdata.IsAttending)
 
-			if err != nil && err.Error() != "event invite already handled" {
+			if err != nil && !errors.Is(err, services.ErrEventInviteHandled) {
 				app.Logger.Printf("Failed updating notification: %v", err)
 				http.Error(rw, "JSON error", http.StatusBadRequest)
 			}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -215,6 +217,11 @@ func (app *Application) CreateGroup(rw http.ResponseWriter, r *http.Request) {
 
 		result, err := app.GroupService.CreateGroup(JSONdata, userId)
 
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
 		if err != nil {
 			http.Error(rw, "err", http.StatusBadRequest)
 			return
@@ -281,7 +288,7 @@ func (app *Application) GroupPosts(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		feed, err := app.PostService.GetGroupPosts(groupId, offsetInt)
+		feed, err := app.PostService.GetGroupPosts(groupId, offsetInt, userId)
 
 		if err != nil {
 			app.Logger.Printf("Failed fetching posts: %v", err)
@@ -327,7 +334,7 @@ func (app *Application) AddMembers(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = app.WS.BroadcastGroupNotifications(notifications)
+		err = app.WS.BroadcastNotifications(notifications)
 
 		if err != nil {
 			app.Logger.Printf("Failed broadcasting notifications: %v", err)
@@ -351,7 +358,7 @@ func (app *Application) UpdateGroupImage(rw http.ResponseWriter, r *http.Request
 
 		app.Logger.Println("Request size: ", r.ContentLength)
 		// Limit the size of the request body to 5MB
-		r.Body = http.MaxBytesReader(rw, r.Body, 20<<18)
+		r.Body = http.MaxBytesReader(rw, r.Body, app.Config.MaxUploadSize)
 
 		vars := mux.Vars(r)
 		groupId := vars["groupId"]
@@ -369,7 +376,7 @@ func (app *Application) UpdateGroupImage(rw http.ResponseWriter, r *http.Request
 			return
 		}
 
-		err = r.ParseMultipartForm(20 << 18)
+		err = r.ParseMultipartForm(app.Config.MaxUploadSize)
 		if err != nil {
 			app.Logger.Printf("Cannot parse multipart form: %s", err)
 			http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
@@ -419,7 +426,7 @@ func (app *Application) GetMembersToAdd(rw http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		members, err := app.GroupMemberService.GetMembersToAdd(userID, groupIdInt)
+		members, err := app.GroupMemberService.GetMembersToAdd(groupIdInt, userID)
 		if err != nil {
 			app.Logger.Printf("Cannot get members to add: %s", err)
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
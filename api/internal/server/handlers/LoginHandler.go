@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
 )
 
 type signinJSON struct {
@@ -13,6 +17,11 @@ type signinJSON struct {
 	Password string `json:"password"`
 }
 
+type twoFactorRequiredJSON struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	Challenge         string `json:"challenge"`
+}
+
 func (app *Application) Login(rw http.ResponseWriter, r *http.Request) {
 
 	if r.Header.Get("Content-Type") != "application/json" {
@@ -43,14 +52,23 @@ func (app *Application) Login(rw http.ResponseWriter, r *http.Request) {
 		Password: JSONdata.Password,
 	}
 
-	sessionToken, err := app.UserService.UserLogin(userData)
+	result, err := app.UserService.UserLogin(userData, r)
 	if err != nil {
-		app.Logger.Printf("Cannot login user: %s", err)
-		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		app.writeLoginError(rw, result, err)
 		return
 	}
 
-	app.UserService.SetCookie(rw, sessionToken)
+	// the session is only created once the challenge is answered on /login/2fa
+	if result.TwoFactorChallenge != "" {
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(&twoFactorRequiredJSON{
+			TwoFactorRequired: true,
+			Challenge:         result.TwoFactorChallenge,
+		})
+		return
+	}
+
+	app.UserService.SetCookie(rw, result.SessionToken)
 
 	_, err = fmt.Fprintf(rw, "Successful login, cookie set")
 	if err != nil {
@@ -60,3 +78,24 @@ func (app *Application) Login(rw http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// writeLoginError answers a failed login the same way whether the email exists or not
+func (app *Application) writeLoginError(rw http.ResponseWriter, result *services.LoginResult, err error) {
+	if result != nil && result.LockedUserId > 0 {
+		app.notifyAccountLocked(result.LockedUserId)
+	}
+
+	switch {
+	case errors.Is(err, services.ErrTooManyLoginAttempts):
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		http.Error(rw, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidCredentials):
+		app.Logger.Printf("Cannot login user: %s", err)
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrAccountSuspended):
+		http.Error(rw, err.Error(), http.StatusForbidden)
+	default:
+		app.Logger.Printf("Cannot login user: %s", err)
+		http.Error(rw, "cannot login", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// writeModerationError answers the errors of the reporting and moderation endpoints
+func (app *Application) writeModerationError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReportEntity),
+		errors.Is(err, services.ErrInvalidReportReason),
+		errors.Is(err, services.ErrInvalidReportStatus),
+		errors.Is(err, services.ErrReportDetailsTooLong),
+		errors.Is(err, services.ErrModerationNoteTooLong),
+		errors.Is(err, services.ErrCannotReportOwn),
+		errors.Is(err, services.ErrInvalidModeration),
+		errors.Is(err, services.ErrCannotModerateSelf),
+		errors.Is(err, services.ErrCannotSuspendAdmin),
+		errors.Is(err, services.ErrInvalidRole):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrReportTargetNotFound),
+		errors.Is(err, services.ErrReportNotFound),
+		errors.Is(err, services.ErrModerationNotFound):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrReportAlreadyPending):
+		http.Error(rw, err.Error(), http.StatusConflict)
+	default:
+		app.Logger.Printf("Moderation error: %s", err)
+		http.Error(rw, "moderation error", http.StatusInternalServerError)
+	}
+}
+
+// decodeModerationJSON reads a small JSON body into data
+func (app *Application) decodeModerationJSON(rw http.ResponseWriter, r *http.Request, data interface{}) bool {
+	r.Body = http.MaxBytesReader(rw, r.Body, 4096)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(data)
+	if err != nil {
+		app.Logger.Printf("JSON error: %v", err)
+		http.Error(rw, "JSON error", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// pageOffset reads the optional offset query parameter
+func pageOffset(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get("offset")
+	if value == "" {
+		return 0, nil
+	}
+
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid offset")
+	}
+
+	return offset, nil
+}
+
+// Report a post, comment, message, group or profile to the moderators
+func (app *Application) Report(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := services.ReportRequestJSON{}
+		if !app.decodeModerationJSON(rw, r, &JSONdata) {
+			return
+		}
+
+		report, err := app.ModerationService.CreateReport(userId, JSONdata)
+		if err != nil {
+			app.writeModerationError(rw, err)
+			return
+		}
+
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(&report)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// List the moderation queue, the status parameter selects other reports than the unhandled ones
+func (app *Application) AdminReports(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		offset, err := pageOffset(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reports, err := app.ModerationService.GetReports(r.URL.Query().Get("status"), offset)
+		if err != nil {
+			app.writeModerationError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&reports)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Show a report or move it to another status
+func (app *Application) AdminReport(rw http.ResponseWriter, r *http.Request) {
+	reportId, err := strconv.ParseInt(mux.Vars(r)["reportId"], 10, 64)
+	if err != nil {
+		app.Logger.Printf("DATA PARSE error: %v", err)
+		http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+		return
+	}
+
+	var report *services.ReportJSON
+
+	switch r.Method {
+	case "GET":
+		report, err = app.ModerationService.GetReport(reportId)
+
+	case "PUT":
+		userId, userErr := app.UserService.GetUserID(r)
+		if userErr != nil {
+			app.Logger.Printf("Cannot get user ID: %s", userErr)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := services.ReportStatusJSON{}
+		if !app.decodeModerationJSON(rw, r, &JSONdata) {
+			return
+		}
+
+		report, err = app.ModerationService.UpdateReportStatus(userId, reportId, JSONdata)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		app.writeModerationError(rw, err)
+		return
+	}
+
+	json.NewEncoder(rw).Encode(&report)
+}
+
+// Take a moderation action, suspended users are disconnected right away
+func (app *Application) AdminAction(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := services.ModerationActionRequestJSON{}
+		if !app.decodeModerationJSON(rw, r, &JSONdata) {
+			return
+		}
+
+		result, err := app.ModerationService.TakeAction(userId, JSONdata)
+		if err != nil {
+			app.writeModerationError(rw, err)
+			return
+		}
+
+		app.WS.DisconnectSessions(result.SessionIds)
+		app.WS.DisconnectTokens(result.TokenIds)
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// List the audit trail of moderator actions, newest first
+func (app *Application) AdminAuditTrail(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		offset, err := pageOffset(r)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		actions, err := app.ModerationService.GetAuditTrail(offset)
+		if err != nil {
+			app.writeModerationError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&actions)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+type connectionCountJSON struct {
+	UserId      int64 `json:"userId"`
+	Connections int   `json:"connections"`
+}
+
+// List the number of open websocket connections of every connected user
+func (app *Application) AdminConnections(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		counts := app.WS.ConnectionCounts()
+
+		connections := []*connectionCountJSON{}
+		for userId, count := range counts {
+			connections = append(connections, &connectionCountJSON{UserId: userId, Connections: count})
+		}
+
+		sort.Slice(connections, func(i, j int) bool {
+			return connections[i].UserId < connections[j].UserId
+		})
+
+		json.NewEncoder(rw).Encode(&connections)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Show the number of open websocket connections and the messages and clients lost to full send queues
+func (app *Application) AdminWebsocketStats(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		stats := app.WS.Stats()
+
+		json.NewEncoder(rw).Encode(&stats)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+type roleJSON struct {
+	Role string `json:"role"`
+}
+
+// Make a user an administrator or a regular user
+func (app *Application) AdminUserRole(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "PUT":
+		targetId, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := roleJSON{}
+		if !app.decodeModerationJSON(rw, r, &JSONdata) {
+			return
+		}
+
+		err = app.ModerationService.SetRole(userId, targetId, JSONdata.Role)
+		if err != nil {
+			app.writeModerationError(rw, err)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"SocialNetworkRestApi/api/pkg/models"
 	"encoding/json"
 	"net/http"
 )
@@ -30,3 +31,28 @@ func (app *Application) Notifications(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// notifyAccountLocked tells the user that their account was locked after too many failed logins
+func (app *Application) notifyAccountLocked(userId int64) {
+	notification, err := app.NotificationService.CreateAccountLocked(userId)
+	if err != nil {
+		app.Logger.Printf("Cannot create account locked notification: %s", err)
+		return
+	}
+
+	if err = app.WS.BroadcastNotifications([]*models.NotificationJSON{notification}); err != nil {
+		app.Logger.Printf("Failed broadcasting account locked notification: %s", err)
+	}
+}
+
+// broadcastMentions sends the mention notifications created for new content
+func (app *Application) broadcastMentions(notifications []*models.NotificationJSON, err error) {
+	if err != nil {
+		app.Logger.Printf("Cannot create mentions: %s", err)
+		return
+	}
+
+	if err = app.WS.BroadcastNotifications(notifications); err != nil {
+		app.Logger.Printf("Failed broadcasting mentions: %s", err)
+	}
+}
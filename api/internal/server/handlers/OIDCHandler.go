@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcStateCookie binds a login at a provider to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// List the providers users can log in with
+func (app *Application) OIDCProviders(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		providers := app.OIDCService.GetProviders()
+
+		json.NewEncoder(rw).Encode(&providers)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Send the user to the provider to log in
+func (app *Application) OIDCLogin(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		app.beginOIDCAuthorization(rw, r, 0, false)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Send the logged in user to the provider to link it to the account
+func (app *Application) OIDCLink(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		app.beginOIDCAuthorization(rw, r, userId, false)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Send the logged in user to log in at a linked provider again, it confirms the next
+// account deletion or disabling two-factor authentication of users without a password
+func (app *Application) OIDCReauth(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		app.beginOIDCAuthorization(rw, r, userId, true)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+func (app *Application) beginOIDCAuthorization(rw http.ResponseWriter, r *http.Request, userId int64, reauth bool) {
+	var authorization *services.OIDCAuthorization
+	var err error
+
+	if reauth {
+		authorization, err = app.OIDCService.BeginReauthentication(mux.Vars(r)["provider"], userId)
+	} else {
+		authorization, err = app.OIDCService.BeginAuthorization(mux.Vars(r)["provider"], userId)
+	}
+
+	switch {
+	case errors.Is(err, services.ErrOIDCProviderUnknown):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrOIDCLoginFailed):
+		http.Error(rw, err.Error(), http.StatusBadGateway)
+		return
+	case err != nil:
+		http.Error(rw, "cannot start login", http.StatusInternalServerError)
+		return
+	}
+
+	cookie := app.UserService.NewCookie(oidcStateCookie, authorization.State, "/oidc", 600)
+	// a strict cookie would not come along when the provider redirects back
+	if cookie.SameSite == http.SameSiteStrictMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(rw, cookie)
+
+	http.Redirect(rw, r, authorization.URL, http.StatusFound)
+}
+
+// oidcErrorCode names a failed login for the frontend
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrOIDCStateInvalid):
+		return "expired"
+	case errors.Is(err, services.ErrOIDCEmailMissing):
+		return "email_missing"
+	case errors.Is(err, services.ErrOIDCEmailTaken):
+		return "email_taken"
+	case errors.Is(err, services.ErrIdentityTaken):
+		return "identity_taken"
+	case errors.Is(err, services.ErrOIDCReauthFailed):
+		return "reauth_failed"
+	case errors.Is(err, services.ErrAccountSuspended):
+		return "suspended"
+	default:
+		return "failed"
+	}
+}
+
+// The provider sends the user back here, the user is logged in or the provider
+// is linked and the browser is sent on to the frontend
+func (app *Application) OIDCCallback(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		frontendURL := strings.TrimRight(app.Config.FrontendURL, "/")
+		provider := mux.Vars(r)["provider"]
+		query := r.URL.Query()
+
+		http.SetCookie(rw, app.UserService.NewCookie(oidcStateCookie, "", "/oidc", -1))
+
+		state := query.Get("state")
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || state == "" || cookie.Value != state {
+			http.Redirect(rw, r, frontendURL+"/login?error=expired", http.StatusFound)
+			return
+		}
+
+		if query.Get("error") != "" {
+			app.Logger.Printf("OIDC provider %s: %s", provider, query.Get("error"))
+		}
+
+		result, err := app.OIDCService.CompleteAuthorization(provider, state, query.Get("code"), r)
+		if err != nil {
+			http.Redirect(rw, r, frontendURL+"/login?error="+oidcErrorCode(err), http.StatusFound)
+			return
+		}
+
+		switch {
+		case result.LinkedUserId != 0:
+			http.Redirect(rw, r, frontendURL+"/profile?linked="+url.QueryEscape(provider), http.StatusFound)
+		case result.ReauthenticatedUserId != 0:
+			http.Redirect(rw, r, frontendURL+"/profile?reauthenticated="+url.QueryEscape(provider), http.StatusFound)
+		case result.Login.TwoFactorChallenge != "":
+			// the session is only created once the challenge is answered on /login/2fa
+			http.Redirect(rw, r, frontendURL+"/login?challenge="+url.QueryEscape(result.Login.TwoFactorChallenge), http.StatusFound)
+		default:
+			app.UserService.SetCookie(rw, result.Login.SessionToken)
+			http.Redirect(rw, r, frontendURL+"/", http.StatusFound)
+		}
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// List the providers linked to the account of the user
+func (app *Application) OIDCIdentities(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		identities, err := app.OIDCService.GetIdentities(userId)
+		if err != nil {
+			http.Error(rw, "cannot get linked logins", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&identities)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Unlink a provider from the account of the user
+func (app *Application) OIDCUnlink(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		identityId, err := strconv.ParseInt(mux.Vars(r)["identityId"], 10, 64)
+		if err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		err = app.OIDCService.UnlinkIdentity(userId, identityId)
+
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, services.ErrLastLoginMethod):
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(rw, "cannot unlink login", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
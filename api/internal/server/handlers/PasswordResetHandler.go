@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type forgotPasswordJSON struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordJSON struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+// ForgotPassword emails a reset link, the response is the same whether the email is registered or not.
+// Too many requests for an email or from an IP are answered with 429.
+func (app *Application) ForgotPassword(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		JSONdata := &forgotPasswordJSON{}
+		err := decoder.Decode(JSONdata)
+
+		if err != nil || JSONdata.Email == "" {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		wait, err := app.PasswordResetService.RequestPasswordReset(JSONdata.Email, utils.ClientIP(r))
+
+		if errors.Is(err, services.ErrTooManyResetRequests) {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(rw, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Cannot request password reset: %s", err)
+			http.Error(rw, "cannot send reset email", http.StatusInternalServerError)
+			return
+		}
+
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// ResetPassword sets a new password with the token from the reset email and ends all sessions of the user
+func (app *Application) ResetPassword(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		JSONdata := &resetPasswordJSON{}
+		err := decoder.Decode(JSONdata)
+
+		if err != nil || JSONdata.Token == "" {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "token is required", http.StatusBadRequest)
+			return
+		}
+
+		if JSONdata.Password != JSONdata.ConfirmPassword {
+			http.Error(rw, "passwords do not match", http.StatusBadRequest)
+			return
+		}
+
+		sessionIDs, err := app.PasswordResetService.ResetPassword(JSONdata.Token, JSONdata.Password)
+
+		if errors.Is(err, services.ErrInvalidResetToken) || errors.Is(err, services.ErrWeakPassword) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Cannot reset password: %s", err)
+			http.Error(rw, "cannot reset password", http.StatusInternalServerError)
+			return
+		}
+
+		app.WS.DisconnectSessions(sessionIDs)
+		app.UserService.ClearCookie(rw)
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
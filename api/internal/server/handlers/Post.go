@@ -3,7 +3,10 @@ package handlers
 import (
 	"SocialNetworkRestApi/api/pkg/enums"
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -24,9 +27,9 @@ func (app *Application) Post(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
 		// Limit the size of the request body to 5MB
-		r.Body = http.MaxBytesReader(rw, r.Body, 20<<18+512)
+		r.Body = http.MaxBytesReader(rw, r.Body, app.Config.MaxUploadSize+512)
 
-		err := r.ParseMultipartForm(20 << 18)
+		err := r.ParseMultipartForm(app.Config.MaxUploadSize)
 
 		if err != nil {
 			app.Logger.Printf("Failed parsing form: %v", err)
@@ -93,12 +96,19 @@ func (app *Application) Post(rw http.ResponseWriter, r *http.Request) {
 
 		err = app.PostService.CreatePost(post)
 
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
 		if err != nil {
 			app.Logger.Printf("Cannot create post: %s", err)
 			http.Error(rw, "err", http.StatusBadRequest)
 			return
 		}
 
+		app.broadcastMentions(app.NotificationService.CreatePostMentions(userId, post.Id))
+
 		rw.Write([]byte("ok"))
 
 	default:
@@ -150,12 +160,19 @@ func (app *Application) GroupPost(rw http.ResponseWriter, r *http.Request) {
 
 		err = app.PostService.CreateGroupPost(post)
 
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
 		if err != nil {
 			app.Logger.Printf("Cannot create post: %s", err)
 			http.Error(rw, "err", http.StatusBadRequest)
 			return
 		}
 
+		app.broadcastMentions(app.NotificationService.CreatePostMentions(userId, post.Id))
+
 		rw.Write([]byte("ok"))
 
 	default:
@@ -164,3 +181,152 @@ func (app *Application) GroupPost(rw http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+func (app *Application) UpdatePost(rw http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "PUT":
+		vars := mux.Vars(r)
+
+		postId, err := strconv.ParseInt(vars["postId"], 10, 64)
+
+		if postId < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		// Limit the size of the request body to 5MB
+		r.Body = http.MaxBytesReader(rw, r.Body, app.Config.MaxUploadSize+512)
+
+		err = r.ParseMultipartForm(app.Config.MaxUploadSize)
+
+		if err != nil {
+			app.Logger.Printf("Failed parsing form: %v", err)
+			http.Error(rw, "Parsing form error", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// an empty privacyType keeps the current privacy of the post
+		var privacyType enums.PrivacyType
+		switch r.FormValue("privacyType") {
+		case "":
+			privacyType = enums.None
+		case "1":
+			privacyType = enums.Public
+		case "2":
+			privacyType = enums.Private
+		case "3":
+			privacyType = enums.SubPrivate
+		default:
+			app.Logger.Printf("Invalid privacyType value: %s", r.FormValue("privacyType"))
+			http.Error(rw, "Invalid privacyType value", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("image")
+		var imagePath string
+
+		if err == nil {
+			defer file.Close()
+
+			imagePath, err = app.PostService.SavePostImage(file, header)
+			if err != nil {
+				app.Logger.Printf("Failed saving image: %v", err)
+				http.Error(rw, "Save image error", http.StatusBadRequest)
+				return
+			}
+		}
+
+		post := &models.Post{
+			Id:          postId,
+			Content:     r.FormValue("content"),
+			ImagePath:   imagePath,
+			PrivacyType: privacyType,
+		}
+
+		// without selectedReceivers a sub-private post keeps its audience
+		if _, ok := r.PostForm["selectedReceivers"]; ok {
+			post.Receivers = strings.Split(r.PostForm.Get("selectedReceivers"), ",")
+		}
+
+		err = app.PostService.UpdatePost(userId, post, r.FormValue("removeImage") == "true")
+
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(rw, "Post not found", http.StatusNotFound)
+			return
+		case err == services.ErrNotPostAuthor:
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			app.Logger.Printf("Cannot update post: %s", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// only users mentioned for the first time are notified
+		app.broadcastMentions(app.NotificationService.CreatePostMentions(userId, postId))
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+
+}
+
+func (app *Application) DeletePost(rw http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "DELETE":
+		vars := mux.Vars(r)
+
+		postId, err := strconv.ParseInt(vars["postId"], 10, 64)
+
+		if postId < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		err = app.PostService.DeletePost(userId, postId)
+
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(rw, "Post not found", http.StatusNotFound)
+			return
+		case err == services.ErrNotPostAuthor:
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			app.Logger.Printf("Cannot delete post: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+
+}
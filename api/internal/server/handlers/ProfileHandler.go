@@ -273,7 +273,7 @@ func (app *Application) UpdateUserImage(rw http.ResponseWriter, r *http.Request)
 	case "POST":
 		// Limit the size of the request body to 5MB
 		//app.Logger.Printf("Request body size: %d", r.ContentLength)
-		r.Body = http.MaxBytesReader(rw, r.Body, 20<<18+512)
+		r.Body = http.MaxBytesReader(rw, r.Body, app.Config.MaxUploadSize+512)
 
 		userID, err := app.UserService.GetUserID(r)
 		if err != nil {
@@ -282,7 +282,7 @@ func (app *Application) UpdateUserImage(rw http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		err = r.ParseMultipartForm(20 << 18)
+		err = r.ParseMultipartForm(app.Config.MaxUploadSize)
 		if err != nil {
 			app.Logger.Printf("Cannot parse multipart form: %s", err)
 			http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
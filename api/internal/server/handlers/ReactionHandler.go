@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type reactionJSON struct {
+	Reaction string `json:"reaction"`
+}
+
+type toggleReactionFunc func(userId int64, id int64, reactionName string) (*services.ReactionToggle, error)
+
+// PostReaction toggles a reaction of the current user on a post
+func (app *Application) PostReaction(rw http.ResponseWriter, r *http.Request) {
+	app.toggleReaction(rw, r, "postId", app.ReactionService.TogglePostReaction)
+}
+
+// CommentReaction toggles a reaction of the current user on a comment
+func (app *Application) CommentReaction(rw http.ResponseWriter, r *http.Request) {
+	app.toggleReaction(rw, r, "commentId", app.ReactionService.ToggleCommentReaction)
+}
+
+func (app *Application) toggleReaction(rw http.ResponseWriter, r *http.Request, idVar string, toggle toggleReactionFunc) {
+
+	switch r.Method {
+	case "POST":
+		vars := mux.Vars(r)
+
+		id, err := strconv.ParseInt(vars[idVar], 10, 64)
+
+		if id < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		var JSONdata reactionJSON
+		err = decoder.Decode(&JSONdata)
+
+		if err != nil {
+			app.Logger.Printf("JSON error: %v", err)
+			http.Error(rw, "JSON error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		reaction, err := toggle(userId, id, JSONdata.Reaction)
+
+		switch {
+		case err == sql.ErrNoRows:
+			http.Error(rw, "Not found", http.StatusNotFound)
+			return
+		case err == services.ErrUnknownReaction:
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			app.Logger.Printf("Cannot toggle reaction: %s", err)
+			http.Error(rw, "Cannot toggle reaction", http.StatusInternalServerError)
+			return
+		}
+
+		if reaction.Added && reaction.AuthorId != userId {
+			err = app.WS.BroadcastReaction(userId, reaction)
+			if err != nil {
+				app.Logger.Printf("Cannot send reaction notification: %s", err)
+			}
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(&reaction.Summary)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
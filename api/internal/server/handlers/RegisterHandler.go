@@ -58,7 +58,7 @@ func (app *Application) Register(rw http.ResponseWriter, r *http.Request) {
 		About:     JSONdata.About,
 	}
 
-	sessionToken, err := app.UserService.UserRegister(userData)
+	sessionToken, err := app.UserService.UserRegister(userData, r)
 	if err != nil {
 		app.Logger.Printf("Cannot register user: %s", err)
 		http.Error(rw, err.Error(), http.StatusBadRequest)
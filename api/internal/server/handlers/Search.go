@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"SocialNetworkRestApi/api/pkg/services"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
+// Search looks up groups and users by name, FullTextSearch replaces it
 func (app *Application) Search(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -41,3 +44,39 @@ func (app *Application) Search(rw http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// FullTextSearch ranks posts, comments, groups and users matching the q parameter,
+// optionally limited to one type, and pages through them with cursor
+func (app *Application) FullTextSearch(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		params := r.URL.Query()
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		results, err := app.SearchService.Search(userId, params.Get("q"), params.Get("type"), params.Get("cursor"))
+
+		if errors.Is(err, services.ErrEmptySearch) || errors.Is(err, services.ErrInvalidSearchType) || errors.Is(err, services.ErrInvalidCursor) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Search failed: %v", err)
+			http.Error(rw, "Search error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&results)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
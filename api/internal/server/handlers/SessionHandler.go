@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// List the active sessions of the user
+func (app *Application) Sessions(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		principal, err := services.RequestPrincipal(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get session: %s", err)
+			http.Error(rw, "Cannot get session", http.StatusUnauthorized)
+			return
+		}
+
+		sessions, err := app.UserService.GetUserSessions(principal.UserID, principal.SessionID)
+		if err != nil {
+			app.Logger.Printf("Cannot get user sessions: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(rw).Encode(sessions)
+
+		if err != nil {
+			app.Logger.Printf("Cannot encode user sessions: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Revoke a single session of the user and disconnect its websocket clients
+func (app *Application) RevokeSession(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		vars := mux.Vars(r)
+
+		sessionID, err := strconv.ParseInt(vars["sessionId"], 10, 64)
+		if err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		principal, err := services.RequestPrincipal(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get session: %s", err)
+			http.Error(rw, "Cannot get session", http.StatusUnauthorized)
+			return
+		}
+
+		err = app.UserService.RevokeSession(principal.UserID, sessionID)
+
+		if err == services.ErrSessionNotFound {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Cannot revoke session: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		app.WS.DisconnectSessions([]int64{sessionID})
+
+		if sessionID == principal.SessionID {
+			app.UserService.ClearCookie(rw)
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// Log the user out everywhere, including the current session
+func (app *Application) RevokeAllSessions(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "DELETE":
+		userID, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		sessionIDs, err := app.UserService.RevokeAllSessions(userID)
+		if err != nil {
+			app.Logger.Printf("Cannot revoke sessions: %s", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		app.WS.DisconnectSessions(sessionIDs)
+		app.UserService.ClearCookie(rw)
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// RunSessionSweeper periodically removes expired sessions and disconnects their
+// websocket clients, until done is closed
+func (app *Application) RunSessionSweeper(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			sessionIDs, err := app.UserService.SweepExpiredSessions()
+			if err != nil {
+				app.Logger.Printf("Cannot sweep expired sessions: %s", err)
+				continue
+			}
+
+			if len(sessionIDs) > 0 {
+				app.Logger.Printf("Swept %d expired session(s)", len(sessionIDs))
+				app.WS.DisconnectSessions(sessionIDs)
+			}
+		}
+	}
+}
+
+type csrfJSON struct {
+	Token string `json:"token"`
+}
+
+// Get the token to send in the X-CSRF-Token header with requests that change anything
+func (app *Application) CSRFToken(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		principal, err := services.RequestPrincipal(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get session: %s", err)
+			http.Error(rw, "Cannot get session", http.StatusUnauthorized)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&csrfJSON{Token: principal.CSRFToken})
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// TagPosts returns the posts with a hashtag that the current user may see
+func (app *Application) TagPosts(rw http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		vars := mux.Vars(r)
+
+		offset, err := strconv.ParseInt(vars["offset"], 10, 64)
+
+		if offset < 0 || err != nil {
+			app.Logger.Printf("DATA PARSE error: %v", err)
+			http.Error(rw, "DATA PARSE error", http.StatusBadRequest)
+			return
+		}
+
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		feed, err := app.PostService.GetTagPosts(vars["tag"], offset, userId)
+
+		if errors.Is(err, services.ErrInvalidTag) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			app.Logger.Printf("Cannot get tag posts: %v", err)
+			http.Error(rw, "JSON error", http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&feed)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// TrendingTags returns the most used hashtags of the trending window
+func (app *Application) TrendingTags(rw http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+
+		if err != nil {
+			app.Logger.Printf("Failed fetching user: %v", err)
+			http.Error(rw, "Get user error", http.StatusBadRequest)
+			return
+		}
+
+		tags, err := app.PostService.GetTrendingTags(userId)
+
+		if err != nil {
+			app.Logger.Printf("Cannot get trending tags: %v", err)
+			http.Error(rw, "JSON error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&tags)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
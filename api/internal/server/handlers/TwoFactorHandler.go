@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/services"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type twoFactorLoginJSON struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+type twoFactorCodeJSON struct {
+	Code string `json:"code"`
+}
+
+type disableTwoFactorJSON struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type recoveryCodesJSON struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// writeTwoFactorError maps the errors of the two-factor service to responses
+func (app *Application) writeTwoFactorError(rw http.ResponseWriter, err error) {
+	var throttled *services.TooManyAttemptsError
+
+	switch {
+	case errors.As(err, &throttled):
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.Wait.Seconds()))))
+		http.Error(rw, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidTwoFactorCode),
+		errors.Is(err, services.ErrIncorrectPassword),
+		errors.Is(err, services.ErrConfirmationRequired),
+		errors.Is(err, services.ErrInvalidChallenge):
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotPending):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrAccountSuspended):
+		http.Error(rw, err.Error(), http.StatusForbidden)
+	default:
+		app.Logger.Printf("Two-factor error: %s", err)
+		http.Error(rw, "two-factor authentication failed", http.StatusInternalServerError)
+	}
+}
+
+// decodeTwoFactorJSON reads a small JSON body into data
+func (app *Application) decodeTwoFactorJSON(rw http.ResponseWriter, r *http.Request, data interface{}) bool {
+	r.Body = http.MaxBytesReader(rw, r.Body, 1024)
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(data)
+	if err != nil {
+		app.Logger.Printf("JSON error: %v", err)
+		http.Error(rw, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// TwoFactorLogin completes a login with the challenge returned by /login and a code
+// from the authenticator app or a recovery code
+func (app *Application) TwoFactorLogin(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		JSONdata := &twoFactorLoginJSON{}
+		if !app.decodeTwoFactorJSON(rw, r, JSONdata) {
+			return
+		}
+
+		if JSONdata.Challenge == "" || JSONdata.Code == "" {
+			http.Error(rw, "challenge and code are required", http.StatusBadRequest)
+			return
+		}
+
+		result, err := app.UserService.CompleteTwoFactorLogin(JSONdata.Challenge, JSONdata.Code, r)
+		if result != nil && result.LockedUserId > 0 {
+			app.notifyAccountLocked(result.LockedUserId)
+		}
+
+		if errors.Is(err, services.ErrTooManyLoginAttempts) {
+			app.writeLoginError(rw, result, err)
+			return
+		}
+
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		app.UserService.SetCookie(rw, result.SessionToken)
+
+		rw.Write([]byte("Successful login, cookie set"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// TwoFactorStatus tells whether the current user has two-factor authentication enabled
+func (app *Application) TwoFactorStatus(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		status, err := app.TwoFactorService.GetStatus(userId)
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&status)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// SetupTwoFactor creates a new secret, the response has the otpauth:// URI to show as a QR code
+func (app *Application) SetupTwoFactor(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		setup, err := app.TwoFactorService.BeginSetup(userId)
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&setup)
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a code from the app and
+// returns the recovery codes
+func (app *Application) ConfirmTwoFactor(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := &twoFactorCodeJSON{}
+		if !app.decodeTwoFactorJSON(rw, r, JSONdata) {
+			return
+		}
+
+		codes, err := app.TwoFactorService.ConfirmSetup(userId, JSONdata.Code, utils.ClientIP(r))
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&recoveryCodesJSON{RecoveryCodes: codes})
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// DisableTwoFactor turns two-factor authentication off and deletes the recovery codes
+func (app *Application) DisableTwoFactor(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := &disableTwoFactorJSON{}
+		if !app.decodeTwoFactorJSON(rw, r, JSONdata) {
+			return
+		}
+
+		err = app.TwoFactorService.Disable(userId, JSONdata.Password, JSONdata.Code, utils.ClientIP(r))
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		rw.Write([]byte("ok"))
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, the old ones stop working
+func (app *Application) RegenerateRecoveryCodes(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		userId, err := app.UserService.GetUserID(r)
+		if err != nil {
+			app.Logger.Printf("Cannot get user ID: %s", err)
+			http.Error(rw, "Cannot get user ID", http.StatusUnauthorized)
+			return
+		}
+
+		JSONdata := &twoFactorCodeJSON{}
+		if !app.decodeTwoFactorJSON(rw, r, JSONdata) {
+			return
+		}
+
+		codes, err := app.TwoFactorService.RegenerateRecoveryCodes(userId, JSONdata.Code, utils.ClientIP(r))
+		if err != nil {
+			app.writeTwoFactorError(rw, err)
+			return
+		}
+
+		json.NewEncoder(rw).Encode(&recoveryCodesJSON{RecoveryCodes: codes})
+
+	default:
+		http.Error(rw, "method is not supported", http.StatusNotFound)
+		return
+	}
+}
@@ -1,24 +1,64 @@
 package router
 
 import (
+	"SocialNetworkRestApi/api/internal/config"
 	"SocialNetworkRestApi/api/internal/server/handlers"
 	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/models"
 
 	"github.com/gorilla/mux"
 )
 
-func New(app *handlers.Application) *mux.Router {
+func New(app *handlers.Application, config *config.Config) *mux.Router {
 	r := mux.NewRouter()
 
-	r.Use(utils.CorsMiddleware)
+	r.Use(utils.CorsMiddleware(config.AllowedOrigins))
 
 	r.HandleFunc("/", app.UserService.Authenticate(app.Home)).Methods("GET")
-	r.HandleFunc("/ws", app.UserService.Authenticate(app.WS.WShandler))
+	r.HandleFunc("/ws", app.UserService.AuthenticateScope(models.ScopeChat, app.WS.WShandler))
 	//Session
 	r.HandleFunc("/auth", app.UserService.Authenticate(nil)).Methods("GET")
 	r.HandleFunc("/login", app.Login).Methods("POST", "OPTIONS")
+	r.HandleFunc("/login/2fa", app.TwoFactorLogin).Methods("POST", "OPTIONS")
 	r.HandleFunc("/signup", app.Register).Methods("POST", "OPTIONS")
-	r.HandleFunc("/logout", app.UserService.Authenticate(app.Logout)).Methods("GET")
+	r.HandleFunc("/password/forgot", app.ForgotPassword).Methods("POST", "OPTIONS")
+	r.HandleFunc("/password/reset", app.ResetPassword).Methods("POST", "OPTIONS")
+	r.HandleFunc("/verify-email", app.ConfirmEmail).Methods("POST", "OPTIONS")
+	r.HandleFunc("/verify-email/resend", app.UserService.Authenticate(app.ResendVerification)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/2fa", app.UserService.Authenticate(app.UserService.RequireSession(app.TwoFactorStatus))).Methods("GET")
+	r.HandleFunc("/2fa/setup", app.UserService.Authenticate(app.SetupTwoFactor)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/2fa/confirm", app.UserService.Authenticate(app.ConfirmTwoFactor)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/2fa/disable", app.UserService.Authenticate(app.DisableTwoFactor)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/2fa/recovery-codes", app.UserService.Authenticate(app.RegenerateRecoveryCodes)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/logout", app.UserService.Authenticate(app.UserService.RequireSession(app.Logout))).Methods("GET")
+	r.HandleFunc("/sessions", app.UserService.Authenticate(app.UserService.RequireSession(app.Sessions))).Methods("GET")
+	r.HandleFunc("/sessions", app.UserService.Authenticate(app.RevokeAllSessions)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/sessions/{sessionId:[0-9]+?}", app.UserService.Authenticate(app.RevokeSession)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/csrf", app.UserService.Authenticate(app.UserService.RequireSession(app.CSRFToken))).Methods("GET")
+	r.HandleFunc("/tokens", app.UserService.Authenticate(app.UserService.RequireSession(app.AccessTokens))).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/tokens/{tokenId:[0-9]+?}", app.UserService.Authenticate(app.RevokeAccessToken)).Methods("DELETE", "OPTIONS")
+	//OpenID Connect
+	r.HandleFunc("/oidc/providers", app.OIDCProviders).Methods("GET")
+	r.HandleFunc("/oidc/identities", app.UserService.Authenticate(app.UserService.RequireSession(app.OIDCIdentities))).Methods("GET")
+	r.HandleFunc("/oidc/identities/{identityId:[0-9]+?}", app.UserService.Authenticate(app.OIDCUnlink)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/oidc/{provider}/login", app.OIDCLogin).Methods("GET")
+	r.HandleFunc("/oidc/{provider}/link", app.UserService.Authenticate(app.UserService.RequireSession(app.OIDCLink))).Methods("GET")
+	r.HandleFunc("/oidc/{provider}/reauth", app.UserService.Authenticate(app.UserService.RequireSession(app.OIDCReauth))).Methods("GET")
+	r.HandleFunc("/oidc/{provider}/callback", app.OIDCCallback).Methods("GET")
+	//Account
+	r.HandleFunc("/account", app.UserService.Authenticate(app.DeleteAccount)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/account/export", app.UserService.Authenticate(app.UserService.RequireSession(app.ExportAccount))).Methods("GET")
+	r.HandleFunc("/account/deletion", app.UserService.Authenticate(app.UserService.RequireSession(app.AccountDeletion))).Methods("GET")
+	r.HandleFunc("/account/deletion", app.UserService.Authenticate(app.CancelAccountDeletion)).Methods("DELETE", "OPTIONS")
+	//Moderation
+	r.HandleFunc("/reports", app.UserService.Authenticate(app.Report)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/reports", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminReports))).Methods("GET")
+	r.HandleFunc("/admin/reports/{reportId:[0-9]+?}", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminReport))).Methods("GET", "PUT", "OPTIONS")
+	r.HandleFunc("/admin/actions", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminAction))).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/audit", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminAuditTrail))).Methods("GET")
+	r.HandleFunc("/admin/connections", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminConnections))).Methods("GET")
+	r.HandleFunc("/admin/websocket", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminWebsocketStats))).Methods("GET")
+	r.HandleFunc("/admin/users/{id:[0-9]+?}/role", app.UserService.Authenticate(app.UserService.RequireAdmin(app.AdminUserRole))).Methods("PUT", "OPTIONS")
 	//Profile
 	r.HandleFunc("/profile", app.UserService.Authenticate(app.Profile)).Methods("GET")
 	r.HandleFunc("/profile/{id:[0-9]+?}", app.UserService.Authenticate(app.Profile)).Methods("GET")
@@ -28,14 +68,23 @@ func New(app *handlers.Application) *mux.Router {
 	r.HandleFunc("/following/{id:[0-9]+?}", app.UserService.Authenticate(app.OtherFollowing)).Methods("GET")
 	r.HandleFunc("/followers", app.UserService.Authenticate(app.Followers)).Methods("GET")
 	r.HandleFunc("/followers/{id:[0-9]+?}", app.UserService.Authenticate(app.OtherFollowers)).Methods("GET")
+	r.HandleFunc("/block/{id:[0-9]+?}", app.UserService.Authenticate(app.Block)).Methods("POST", "DELETE", "OPTIONS")
+	r.HandleFunc("/blocks", app.UserService.Authenticate(app.BlockedUsers)).Methods("GET")
 	//Posts
 	r.HandleFunc("/feedposts/{offset:[0-9]+?}", app.UserService.Authenticate(app.FeedPosts)).Methods("GET")
 	r.HandleFunc("/comments/{postId:[0-9]+?}/{offset:[0-9]+?}", app.UserService.Authenticate(app.Comments)).Methods("GET")
-	r.HandleFunc("/insertcomment", app.UserService.Authenticate(app.Comment)).Methods("POST")
-	r.HandleFunc("/post", app.UserService.Authenticate(app.Post)).Methods("POST")
+	r.HandleFunc("/comment/{commentId:[0-9]+?}/replies/{offset:[0-9]+?}", app.UserService.Authenticate(app.CommentReplies)).Methods("GET")
+	r.HandleFunc("/insertcomment", app.UserService.AuthenticateScope(models.ScopePost, app.Comment)).Methods("POST")
+	r.HandleFunc("/post", app.UserService.AuthenticateScope(models.ScopePost, app.Post)).Methods("POST")
+	r.HandleFunc("/post/{postId:[0-9]+?}", app.UserService.AuthenticateScope(models.ScopePost, app.UpdatePost)).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/post/{postId:[0-9]+?}", app.UserService.AuthenticateScope(models.ScopePost, app.DeletePost)).Methods("DELETE")
+	r.HandleFunc("/post/{postId:[0-9]+?}/reactions", app.UserService.AuthenticateScope(models.ScopePost, app.PostReaction)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/comment/{commentId:[0-9]+?}/reactions", app.UserService.AuthenticateScope(models.ScopePost, app.CommentReaction)).Methods("POST", "OPTIONS")
 	r.HandleFunc("/profileposts/{offset:[0-9]+?}", app.UserService.Authenticate(app.ProfilePosts)).Methods("GET")
 	r.HandleFunc("/userposts/{userId:[0-9]+?}/{offset:[0-9]+?}", app.UserService.Authenticate(app.UserPosts)).Methods("GET")
-	r.HandleFunc("/groups/{groupId:[0-9]+?}/post", app.UserService.Authenticate(app.GroupPost)).Methods("POST")
+	r.HandleFunc("/groups/{groupId:[0-9]+?}/post", app.UserService.AuthenticateScope(models.ScopePost, app.GroupPost)).Methods("POST")
+	r.HandleFunc("/tags/trending", app.UserService.Authenticate(app.TrendingTags)).Methods("GET")
+	r.HandleFunc("/tags/{tag}/{offset:[0-9]+?}", app.UserService.Authenticate(app.TagPosts)).Methods("GET")
 	//Groups
 	r.HandleFunc("/creategroup", app.UserService.Authenticate(app.CreateGroup)).Methods("POST")
 	r.HandleFunc("/usergroups", app.UserService.Authenticate(app.UserGroups)).Methods("GET")
@@ -53,6 +102,7 @@ func New(app *handlers.Application) *mux.Router {
 	r.HandleFunc("/event/{eventId:[0-9]+?}", app.UserService.Authenticate(app.Event)).Methods("GET")
 	r.HandleFunc("/eventreaction", app.UserService.Authenticate(app.EventReaction)).Methods("POST")
 	//Search
+	r.HandleFunc("/search", app.UserService.Authenticate(app.FullTextSearch)).Methods("GET")
 	r.HandleFunc("/search/{searchcriteria}", app.UserService.Authenticate(app.Search)).Methods("GET")
 	r.HandleFunc("/notifications", app.UserService.Authenticate(app.Notifications)).Methods("GET")
 	return r
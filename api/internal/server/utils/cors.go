@@ -4,17 +4,52 @@ import (
 	"net/http"
 )
 
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-
-		if r.Method == "OPTIONS" {
-			return
+// OriginAllowed reports whether a request from origin may use the API. Browsers send
+// the Origin header with every cross-origin and websocket request, a request without
+// one comes from a script or another non-browser client.
+func OriginAllowed(allowedOrigins []string, origin string) bool {
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if origin == allowed {
+			return true
 		}
+	}
+
+	return false
+}
+
+// CorsMiddleware allows credentialed requests from the given origins and refuses
+// state-changing requests from any other origin
+func CorsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := OriginAllowed(allowedOrigins, origin)
+			if allowed && origin != "" {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+
+			if r.Method == "OPTIONS" {
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead:
+			default:
+				if !allowed {
+					http.Error(w, "origin not allowed", http.StatusForbidden)
+					return
+				}
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -6,33 +6,43 @@ import (
 	"log"
 	"mime/multipart"
 	"os"
+	"path/filepath"
 	"strings"
 
 	uuid "github.com/satori/go.uuid"
 )
 
 type IImageService interface {
-	SaveImage(file multipart.File, fileHeader multipart.FileHeader) (string, error)
+	SaveImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error)
+	DeleteImage(fileName string) error
+	OpenImage(fileName string) (io.ReadCloser, error)
 }
 
-func SaveImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
+// ImageService stores uploaded images in Dir
+type ImageService struct {
+	Dir string
+}
+
+func NewImageService(dir string) *ImageService {
+	return &ImageService{Dir: dir}
+}
+
+func (s *ImageService) SaveImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
 	// Get the file extension
 	fileExtension := strings.Split(fileHeader.Filename, ".")[1]
 
 	// Generate new file name
 	newFileName := fmt.Sprintf("%s.%s", uuid.NewV4().String(), fileExtension)
 
-	imagePath := "images"
-
 	// Create folder if not exists
-	err := os.MkdirAll(imagePath, os.ModePerm)
+	err := os.MkdirAll(s.Dir, os.ModePerm)
 	if err != nil {
 		log.Println("Error with creating folder", err)
 		return "", err
 	}
 
 	// Create new file
-	newFile, err := os.Create(fmt.Sprintf("%s/%s", imagePath, newFileName))
+	newFile, err := os.Create(filepath.Join(s.Dir, newFileName))
 	if err != nil {
 		log.Println(err)
 		return "", err
@@ -47,3 +57,23 @@ func SaveImage(file multipart.File, fileHeader *multipart.FileHeader) (string, e
 
 	return newFileName, nil
 }
+
+// DeleteImage removes a previously saved image, a missing file is not an error
+func (s *ImageService) DeleteImage(fileName string) error {
+	if fileName == "" {
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(s.Dir, filepath.Base(fileName)))
+	if err != nil && !os.IsNotExist(err) {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// OpenImage opens a previously saved image for reading
+func (s *ImageService) OpenImage(fileName string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.Dir, filepath.Base(fileName)))
+}
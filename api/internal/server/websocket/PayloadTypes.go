@@ -9,6 +9,33 @@ import (
 type Payload struct {
 	Type string          `json:"type"`
 	Data json.RawMessage `json:"data"`
+	// RequestID is chosen by the client and echoed back in the ack or error frame
+	RequestID string `json:"request_id,omitempty"`
+	// Seq numbers the events of a user, clients resume from the last one they saw
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// AckPayload confirms that a payload sent with a request ID was handled
+type AckPayload struct {
+	Type string `json:"type"`
+}
+
+// ResumePayload asks for the events after LastSeq, sent when a client reconnects
+type ResumePayload struct {
+	LastSeq int64 `json:"last_seq"`
+}
+
+// ResyncPayload tells the client the missed events are gone, it loads everything
+// again and continues from Seq
+type ResyncPayload struct {
+	Seq int64 `json:"seq"`
+}
+
+// ErrorPayload tells the client why its payload was not handled
+type ErrorPayload struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type RequestPayload struct {
@@ -28,6 +55,11 @@ type NotificationPayload struct {
 	EventID          int       `json:"event_id"`
 	EventName        string    `json:"event_name"`
 	EventDate        time.Time `json:"event_datetime"`
+	PostID           int       `json:"post_id,omitempty"`
+	CommentID        int       `json:"comment_id,omitempty"`
+	ParentCommentID  int       `json:"parent_comment_id,omitempty"`
+	MessageID        int       `json:"message_id,omitempty"`
+	Reaction         string    `json:"reaction,omitempty"`
 }
 
 type MessagePayload struct {
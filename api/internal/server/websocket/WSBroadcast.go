@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
 	"encoding/json"
 )
 
@@ -11,15 +12,11 @@ func (w *WebsocketServer) BroadcastFollowRequest(c *Client, followRequestId int6
 		return err
 	}
 
-	recipientClient := w.getClientByUserID(otherId)
-
-	if recipientClient == nil {
+	if !w.reachable(otherId) {
 		w.Logger.Printf("Recipient client not found (recipient offline)")
 		return nil
 	}
 
-	w.Logger.Printf("Recipient client found (recipient online)")
-
 	dataToSend, err := json.Marshal(
 		&NotificationPayload{
 			NotificationType: "follow_request",
@@ -33,74 +30,77 @@ func (w *WebsocketServer) BroadcastFollowRequest(c *Client, followRequestId int6
 		return err
 	}
 
-	recipientClient.gate <- Payload{
+	w.sendToUser(otherId, Payload{
 		Type: "notification",
 		Data: dataToSend,
-	}
+	}, nil)
 
 	w.Logger.Printf("Sent notification to recipient")
 
 	return nil
 }
 
+// BroadcastSingleMessage sends a direct message to the recipient and to the other
+// clients of the sender, so every open app shows the conversation
 func (w *WebsocketServer) BroadcastSingleMessage(c *Client, message *models.Message) error {
 
-	recipientClient := w.getClientByUserID(message.RecipientId)
-
-	if recipientClient == nil {
+	if !w.reachable(message.RecipientId) && w.ConnectionCount(c.clientID) <= 1 {
 		w.Logger.Printf("Recipient client not found (recipient offline)")
-	} else {
-		w.Logger.Printf("Recipient client found (recipient online)")
+		return nil
+	}
 
-		userData, err := w.userService.GetUserByID(c.clientID)
-		if err != nil {
-			return err
-		}
+	userData, err := w.userService.GetUserByID(c.clientID)
+	if err != nil {
+		return err
+	}
 
-		if userData.Nickname == "" {
-			userData.Nickname = userData.FirstName + " " + userData.LastName
-		}
+	if userData.Nickname == "" {
+		userData.Nickname = userData.FirstName + " " + userData.LastName
+	}
 
-		recipientData, err := w.userService.GetUserData(c.clientID, message.RecipientId)
-		if err != nil {
-			return err
-		}
+	recipientData, err := w.userService.GetUserData(c.clientID, message.RecipientId)
+	if err != nil {
+		return err
+	}
 
-		if recipientData.Nickname == "" {
-			recipientData.Nickname = recipientData.FirstName + " " + recipientData.LastName
-		}
+	if recipientData.Nickname == "" {
+		recipientData.Nickname = recipientData.FirstName + " " + recipientData.LastName
+	}
 
-		dataToSend, err := json.Marshal(
-			&MessagePayload{
-				MessageID:     int(message.Id),
-				SenderID:      int(c.clientID),
-				SenderName:    userData.Nickname,
-				SenderImage:   userData.ImagePath,
-				RecipientID:   recipientData.UserID,
-				RecipientName: recipientData.Nickname,
-				//GroupID:       data.GroupID,
-				//GroupName:     data.GroupName,
-				Content:   message.Content,
-				Timestamp: message.SentAt,
-			},
-		)
+	dataToSend, err := json.Marshal(
+		&MessagePayload{
+			MessageID:     int(message.Id),
+			SenderID:      int(c.clientID),
+			SenderName:    userData.Nickname,
+			SenderImage:   userData.ImagePath,
+			RecipientID:   recipientData.UserID,
+			RecipientName: recipientData.Nickname,
+			//GroupID:       data.GroupID,
+			//GroupName:     data.GroupName,
+			Content:   message.Content,
+			Timestamp: message.SentAt,
+		},
+	)
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return err
+	}
 
-		recipientClient.gate <- Payload{
-			Type: "message",
-			Data: dataToSend,
-		}
+	payload := Payload{
+		Type: "message",
+		Data: dataToSend,
+	}
 
-		w.Logger.Printf("Sent message to recipient")
+	w.sendToUser(message.RecipientId, payload, nil)
+	w.sendToUser(c.clientID, payload, c)
 
-	}
+	w.Logger.Printf("Sent message to user %v", message.RecipientId)
 
 	return nil
 }
 
+// BroadcastGroupMessage sends a group message to every member, those offline get it replayed,
+// including the other clients of the sender
 func (w *WebsocketServer) BroadcastGroupMessage(c *Client, message *models.Message) error {
 
 	recipientUsers, err := w.groupMemberService.GetGroupMembers(int64(message.GroupId))
@@ -108,13 +108,15 @@ func (w *WebsocketServer) BroadcastGroupMessage(c *Client, message *models.Messa
 		return err
 	}
 
-	recipientClients := []*Client{}
-
+	online := 0
 	for _, member := range recipientUsers {
-		getGlient := w.getClientByUserID(int64(member.Id))
-		if getGlient != nil {
-			recipientClients = append(recipientClients, getGlient)
-		}
+		online += w.ConnectionCount(int64(member.Id))
+	}
+
+	// the client that sent the message does not get it back
+	if online <= 1 && !w.replayService.Enabled() {
+		w.Logger.Printf("Recipient clients not found (all recipients offline)")
+		return nil
 	}
 
 	groupName, err := w.groupService.GetGroupById(message.GroupId)
@@ -122,85 +124,68 @@ func (w *WebsocketServer) BroadcastGroupMessage(c *Client, message *models.Messa
 		return err
 	}
 
-	if len(recipientClients) == 0 {
-		w.Logger.Printf("Recipient clients not found (all recipients offline)")
-	} else {
-		w.Logger.Printf("Recipient clients found (%d recipients online)", len(recipientClients))
-
-		userData, err := w.userService.GetUserByID(c.clientID)
-		if err != nil {
-			return err
-		}
-
-		if userData.Nickname == "" {
-			userData.Nickname = userData.FirstName + " " + userData.LastName
-		}
+	userData, err := w.userService.GetUserByID(c.clientID)
+	if err != nil {
+		return err
+	}
 
-		for _, recipientClient := range recipientClients {
+	if userData.Nickname == "" {
+		userData.Nickname = userData.FirstName + " " + userData.LastName
+	}
 
-			if recipientClient.clientID == c.clientID {
-				// skip the sender
-				continue
-			}
+	for _, member := range recipientUsers {
 
-			// pick the recipient user from the list of group members
-			var recipientUser *models.User
-			for _, user := range recipientUsers {
-				if int64(user.Id) == recipientClient.clientID {
-					recipientUser = &models.User{
-						Id:       int64(user.Id),
-						Nickname: user.Nickname,
-					}
-					break
-				}
-			}
+		if !w.reachable(int64(member.Id)) {
+			continue
+		}
 
-			if recipientUser.Nickname == "" {
-				recipientUser.Nickname = recipientUser.FirstName + " " + recipientUser.LastName
-			}
+		recipientUser := &models.User{
+			Id:       int64(member.Id),
+			Nickname: member.Nickname,
+		}
 
-			dataToSend, err := json.Marshal(
-				&MessagePayload{
-					MessageID:     int(message.Id),
-					SenderID:      int(c.clientID),
-					SenderName:    userData.Nickname,
-					SenderImage:   userData.ImagePath,
-					RecipientID:   int(recipientUser.Id),
-					RecipientName: recipientUser.Nickname,
-					GroupID:       int(message.GroupId),
-					GroupName:     groupName.Title,
-					Content:       message.Content,
-					Timestamp:     message.SentAt,
-				},
-			)
+		if recipientUser.Nickname == "" {
+			recipientUser.Nickname = recipientUser.FirstName + " " + recipientUser.LastName
+		}
 
-			if err != nil {
-				return err
-			}
+		dataToSend, err := json.Marshal(
+			&MessagePayload{
+				MessageID:     int(message.Id),
+				SenderID:      int(c.clientID),
+				SenderName:    userData.Nickname,
+				SenderImage:   userData.ImagePath,
+				RecipientID:   int(recipientUser.Id),
+				RecipientName: recipientUser.Nickname,
+				GroupID:       int(message.GroupId),
+				GroupName:     groupName.Title,
+				Content:       message.Content,
+				Timestamp:     message.SentAt,
+			},
+		)
 
-			recipientClient.gate <- Payload{
-				Type: "message",
-				Data: dataToSend,
-			}
+		if err != nil {
+			return err
 		}
 
-		w.Logger.Printf("Sent message to recipient")
-
+		w.sendToUser(recipientUser.Id, Payload{
+			Type: "message",
+			Data: dataToSend,
+		}, c)
 	}
 
+	w.Logger.Printf("Sent message to recipient")
+
 	return nil
 }
 
-func (w *WebsocketServer) BroadcastGroupNotifications(notifications []*models.NotificationJSON) error {
+// BroadcastNotifications sends stored notifications to the receivers, those offline get them replayed
+func (w *WebsocketServer) BroadcastNotifications(notifications []*models.NotificationJSON) error {
 
 	for _, notification := range notifications {
 
-		recipientClient := w.getClientByUserID(notification.ReceiverId)
-
-		if recipientClient == nil {
+		if !w.reachable(notification.ReceiverId) {
 			w.Logger.Printf("Recipient client not found (recipient offline)")
 		} else {
-			w.Logger.Printf("Recipient client found (recipient online)")
 
 			dataToSend, err := json.Marshal(
 				&NotificationPayload{
@@ -213,6 +198,9 @@ func (w *WebsocketServer) BroadcastGroupNotifications(notifications []*models.No
 					EventID:          int(notification.EventId),
 					EventName:        notification.EventName,
 					EventDate:        notification.EventDate,
+					PostID:           int(notification.PostId),
+					CommentID:        int(notification.CommentId),
+					MessageID:        int(notification.MessageId),
 				},
 			)
 
@@ -220,10 +208,10 @@ func (w *WebsocketServer) BroadcastGroupNotifications(notifications []*models.No
 				return err
 			}
 
-			recipientClient.gate <- Payload{
+			w.sendToUser(notification.ReceiverId, Payload{
 				Type: "notification",
 				Data: dataToSend,
-			}
+			}, nil)
 
 			w.Logger.Printf("Sent event notification to recipient")
 
@@ -248,12 +236,9 @@ func (w *WebsocketServer) BroadcastGroupJoinRequest(c *Client, groupReqId int64,
 		return err
 	}
 
-	creatorClient := w.getClientByUserID(creatorUser.Id)
-
-	if creatorClient == nil {
+	if !w.reachable(creatorUser.Id) {
 		w.Logger.Printf("Group creator client not found (creator offline)")
 	} else {
-		w.Logger.Printf("Group creator client found (creator online)")
 
 		groupData, err := w.groupService.GetGroupById(groupId)
 		if err != nil {
@@ -275,10 +260,10 @@ func (w *WebsocketServer) BroadcastGroupJoinRequest(c *Client, groupReqId int64,
 			return err
 		}
 
-		creatorClient.gate <- Payload{
+		w.sendToUser(creatorUser.Id, Payload{
 			Type: "notification",
 			Data: dataToSend,
-		}
+		}, nil)
 
 		w.Logger.Printf("Sent group join request to creator")
 
@@ -286,3 +271,92 @@ func (w *WebsocketServer) BroadcastGroupJoinRequest(c *Client, groupReqId int64,
 
 	return nil
 }
+
+// BroadcastReaction notifies the author of a post or comment about a new reaction
+func (w *WebsocketServer) BroadcastReaction(senderId int64, reaction *services.ReactionToggle) error {
+
+	if !w.reachable(reaction.AuthorId) {
+		w.Logger.Printf("Recipient client not found (recipient offline)")
+		return nil
+	}
+
+	userData, err := w.userService.GetUserByID(senderId)
+	if err != nil {
+		return err
+	}
+
+	if userData.Nickname == "" {
+		userData.Nickname = userData.FirstName + " " + userData.LastName
+	}
+
+	notificationType := "post_reaction"
+	if reaction.CommentId > 0 {
+		notificationType = "comment_reaction"
+	}
+
+	dataToSend, err := json.Marshal(
+		&NotificationPayload{
+			NotificationType: notificationType,
+			SenderID:         int(senderId),
+			SenderName:       userData.Nickname,
+			PostID:           int(reaction.PostId),
+			CommentID:        int(reaction.CommentId),
+			Reaction:         reaction.Reaction,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	w.sendToUser(reaction.AuthorId, Payload{
+		Type: "notification",
+		Data: dataToSend,
+	}, nil)
+
+	w.Logger.Printf("Sent reaction notification to recipient")
+
+	return nil
+}
+
+// BroadcastCommentReply notifies the author of a comment about a reply to it
+func (w *WebsocketServer) BroadcastCommentReply(reply *models.Comment, parentAuthorId int64) error {
+
+	if !w.reachable(parentAuthorId) {
+		w.Logger.Printf("Recipient client not found (recipient offline)")
+		return nil
+	}
+
+	userData, err := w.userService.GetUserByID(reply.UserId)
+	if err != nil {
+		return err
+	}
+
+	if userData.Nickname == "" {
+		userData.Nickname = userData.FirstName + " " + userData.LastName
+	}
+
+	dataToSend, err := json.Marshal(
+		&NotificationPayload{
+			NotificationType: "comment_reply",
+			SenderID:         int(reply.UserId),
+			SenderName:       userData.Nickname,
+			PostID:           int(reply.PostId),
+			CommentID:        int(reply.Id),
+			ParentCommentID:  int(reply.ParentCommentId),
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	w.sendToUser(parentAuthorId, Payload{
+		Type: "notification",
+		Data: dataToSend,
+	}, nil)
+
+	w.Logger.Printf("Sent reply notification to recipient")
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Kinds of the messages the instances exchange through the broker
+const (
+	brokerEvent      = "event"
+	brokerPresence   = "presence"
+	brokerDisconnect = "disconnect"
+)
+
+var (
+	// presenceInterval is how often an instance announces all of its connections
+	presenceInterval = 10 * time.Second
+	// presenceTimeout is how long the connections of an instance that went silent still count
+	presenceTimeout = 3 * presenceInterval
+)
+
+// brokerMessage is what the instances tell each other: an event for the clients of a
+// user, the connections of the users on an instance or credentials that were revoked.
+// Every instance handles its own messages before publishing them and ignores them
+// when they come back.
+type brokerMessage struct {
+	Kind    string   `json:"kind"`
+	Node    string   `json:"node"`
+	UserID  int64    `json:"user_id,omitempty"`
+	Payload *Payload `json:"payload,omitempty"`
+	// Skip is the client on Node that does not get the event
+	Skip uint64 `json:"skip,omitempty"`
+	// Connections counts the connections of users on Node, of all of them when Full is set
+	Connections map[int64]int `json:"connections,omitempty"`
+	Full        bool          `json:"full,omitempty"`
+	Sessions    []int64       `json:"sessions,omitempty"`
+	Tokens      []int64       `json:"tokens,omitempty"`
+}
+
+// nodePresence is what another instance last told about its connections
+type nodePresence struct {
+	connections map[int64]int
+	seen        time.Time
+}
+
+func (p *nodePresence) alive() bool {
+	return time.Since(p.seen) < presenceTimeout
+}
+
+// publish sends the message to the other instances
+func (w *WebsocketServer) publish(message *brokerMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		w.Logger.Printf("Error marshalling %s message: %v", message.Kind, err)
+		return
+	}
+
+	if err := w.broker.Publish(data); err != nil && !errors.Is(err, broker.ErrClosed) {
+		w.Logger.Printf("Cannot publish %s message: %v", message.Kind, err)
+	}
+}
+
+// receive handles a message of another instance
+func (w *WebsocketServer) receive(data []byte) {
+	message := &brokerMessage{}
+	if err := json.Unmarshal(data, message); err != nil {
+		w.Logger.Printf("Error unmarshalling broker message: %v", err)
+		return
+	}
+
+	if message.Node == w.node {
+		return
+	}
+
+	switch message.Kind {
+	case brokerEvent:
+		if message.Payload != nil {
+			w.deliver(message)
+		}
+	case brokerPresence:
+		w.updatePresence(message)
+	case brokerDisconnect:
+		w.disconnectSessions(message.Sessions)
+		w.disconnectTokens(message.Tokens)
+	}
+}
+
+// publishPresence tells the other instances how many connections the user has here
+func (w *WebsocketServer) publishPresence(userID int64) {
+	w.RLock()
+	count := len(w.users[userID])
+	w.RUnlock()
+
+	w.publish(&brokerMessage{
+		Kind:        brokerPresence,
+		Node:        w.node,
+		Connections: map[int64]int{userID: count},
+	})
+}
+
+// announce tells the other instances about all connections here
+func (w *WebsocketServer) announce() {
+	w.RLock()
+	connections := make(map[int64]int, len(w.users))
+	for userID, clients := range w.users {
+		connections[userID] = len(clients)
+	}
+	w.RUnlock()
+
+	w.publish(&brokerMessage{
+		Kind:        brokerPresence,
+		Node:        w.node,
+		Connections: connections,
+		Full:        true,
+	})
+}
+
+// announcePresence announces the connections here regularly, so the other instances
+// can correct what they missed and notice when this one is gone
+func (w *WebsocketServer) announcePresence() {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	w.announce()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.announce()
+			w.forgetSilentNodes()
+		}
+	}
+}
+
+func (w *WebsocketServer) updatePresence(message *brokerMessage) {
+	w.Lock()
+	presence, known := w.remote[message.Node]
+	if !known || message.Full {
+		presence = &nodePresence{connections: make(map[int64]int)}
+		w.remote[message.Node] = presence
+	}
+	for userID, count := range message.Connections {
+		if count > 0 {
+			presence.connections[userID] = count
+		} else {
+			delete(presence.connections, userID)
+		}
+	}
+	presence.seen = time.Now()
+	w.Unlock()
+
+	// a new instance learns about this one now instead of at the next announcement
+	if !known {
+		w.Logger.Printf("Instance %s joined", message.Node)
+		w.announce()
+	}
+}
+
+func (w *WebsocketServer) forgetSilentNodes() {
+	w.Lock()
+	defer w.Unlock()
+	for node, presence := range w.remote {
+		if !presence.alive() {
+			w.Logger.Printf("Instance %s went silent", node)
+			delete(w.remote, node)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package websocket
+
+import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBrokerCarriesEventsPresenceAndDisconnects(t *testing.T) {
+	shared := broker.NewMemoryBroker()
+	replayRepo := newMemoryReplayRepo()
+
+	a := newTestServer(t, "a", shared, replayRepo)
+	b := newTestServer(t, "b", shared, replayRepo)
+
+	conn := b.dial(t, 1, 10)
+	sendResume(t, conn, 0, "r1")
+	expectAck(t, conn, "r1")
+
+	// presence
+	eventually(t, "instance a sees the client on b", func() bool { return a.ws.ConnectionCount(1) == 1 })
+	if counts := a.ws.ConnectionCounts(); counts[1] != 1 {
+		t.Fatalf("ConnectionCounts() on a = %v, want user 1 once", counts)
+	}
+
+	// events
+	for i := 1; i <= 10; i++ {
+		a.ws.sendToUser(1, event(i), nil)
+	}
+	expectEvents(t, conn, 1, 10)
+
+	// disconnects
+	other := b.dial(t, 2, 20)
+	eventually(t, "instance a sees the second client", func() bool { return a.ws.ConnectionCount(2) == 1 })
+
+	a.ws.DisconnectSessions([]int64{10})
+	expectClose(t, conn, websocket.ClosePolicyViolation, "session revoked")
+	eventually(t, "instance a sees the client leave", func() bool { return a.ws.ConnectionCount(1) == 0 })
+
+	// an instance that shuts down takes its users with it
+	if err := b.shutdown(t); err != nil {
+		t.Fatalf("Shutdown() of b = %v", err)
+	}
+	expectClose(t, other, websocket.CloseGoingAway, "server shutting down")
+
+	if count := a.ws.ConnectionCount(2); count != 0 {
+		t.Fatalf("ConnectionCount(2) on a = %d after b shut down, want 0", count)
+	}
+}
@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -9,11 +11,50 @@ import (
 
 type ClientList map[*Client]bool
 
+// OverflowPolicy decides what happens to a message for a client whose queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest waiting message to make room
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest discards the new message
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDisconnect closes the connection, the app reconnects and loads the current state
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
 type Client struct {
+	// id tells the clients of an instance apart, clientID is the user
+	id         uint64
 	connection *websocket.Conn
 	clientID   int64
+	sessionID  int64
+	tokenID    int64
 	manager    *WebsocketServer
-	gate       chan Payload
+	// gate queues the messages for the write goroutine, only send and sendWait put
+	// messages on it so that a slow client cannot block the sender
+	gate chan Payload
+	// closing is closed by stop, the write goroutine then sends the close frame made
+	// of closeCode and closeReason, after the queued messages when drain is set, and
+	// closes the connection
+	closing     chan struct{}
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+	drain       bool
+	evictOnce   sync.Once
+	// resuming holds back new events while missed ones are replayed, heldTo is the
+	// last event held back and liveFrom the first event sent to the client as it
+	// happened. They and replayedTo are guarded by eventsLock.
+	resuming bool
+	heldTo   int64
+	liveFrom int64
+	// replayedTo is the last event replayed to the client, it is not sent again
+	replayedTo int64
+	// heldFrom is the last event before the client connected, holdOnce ends the hold
+	// on the events of a new client
+	heldFrom int64
+	holdOnce sync.Once
 }
 
 var (
@@ -26,14 +67,20 @@ var (
 	// The reason why it has to be less than PingRequency is becuase otherwise it will send a new Ping before getting response
 	pingInterval         = (pongWait * 9) / 10
 	maxMessageSize int64 = 512
+	// resumeWait is how long the events of a new client are held back for a resume
+	resumeWait = 2 * time.Second
 )
 
-func NewClient(conn *websocket.Conn, userID int64, manager *WebsocketServer) *Client {
+func NewClient(conn *websocket.Conn, userID int64, sessionID int64, tokenID int64, manager *WebsocketServer) *Client {
 	return &Client{
+		id:         atomic.AddUint64(&manager.lastClientID, 1),
 		connection: conn,
 		clientID:   userID,
+		sessionID:  sessionID,
+		tokenID:    tokenID,
 		manager:    manager,
-		gate:       make(chan Payload),
+		gate:       make(chan Payload, manager.queueSize),
+		closing:    make(chan struct{}),
 	}
 }
 
@@ -64,13 +111,19 @@ func (c *Client) monitor() {
 		var request Payload
 		if err := json.Unmarshal(payload, &request); err != nil {
 			c.manager.Logger.Printf("Error unmarshalling payload: %v", err)
+			c.manager.reply(request, c, err)
 			continue
 		}
 
-		if err := c.manager.routePayloads(request, c); err != nil {
+		if request.Type != Resume {
+			c.manager.endHold(c)
+		}
+
+		err = c.manager.routePayloads(request, c)
+		if err != nil {
 			c.manager.Logger.Printf("Error routing payload: %v", err)
-			continue
 		}
+		c.manager.reply(request, c, err)
 	}
 }
 
@@ -91,27 +144,21 @@ func (c *Client) write() {
 	defer func() {
 		c.manager.Logger.Printf("Closing connection for client %v", c.clientID)
 		ticker.Stop()
+		c.connection.Close()
 		c.manager.removeClient(c)
+		c.manager.writers.Done()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.gate:
-			// OK is false when channel is closed
-			if !ok {
-				// Server closed the channel
-				if err := c.connection.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
-					c.manager.Logger.Printf("Error writing close message: %v", err)
-				}
-				return
-			}
+		case message := <-c.gate:
 			data, err := json.Marshal(message)
 			if err != nil {
 				c.manager.Logger.Printf("Error marshalling message: %v", err)
 				return
 			}
 			c.manager.Logger.Printf("Writing message '%v' to client %v", message.Type, c.clientID)
-			if err := c.connection.WriteMessage(websocket.TextMessage, data); err != nil {
+			if err := c.writeMessage(websocket.TextMessage, data); err != nil {
 				c.manager.Logger.Printf("Error writing message: %v", err)
 				return
 			}
@@ -119,10 +166,152 @@ func (c *Client) write() {
 			if pingOn {
 				c.manager.Logger.Printf("Sending ping to client %v", c.clientID)
 			}
-			if err := c.connection.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			if err := c.writeMessage(websocket.PingMessage, []byte{}); err != nil {
 				c.manager.Logger.Printf("Error writing ping message: %v", err)
 				return
 			}
+		case <-c.closing:
+			c.flush()
+			return
 		}
 	}
 }
+
+// stop makes the write goroutine close the connection with the code and reason, after
+// writing the queued messages when drain is set. A code of 0 sends no close frame, for
+// peers that are gone already. Only the first call counts.
+func (c *Client) stop(code int, reason string, drain bool) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		c.drain = drain
+		close(c.closing)
+	})
+}
+
+// closed reports whether the client was stopped
+func (c *Client) closed() bool {
+	select {
+	case <-c.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// send queues the payload without blocking, a full queue is handled by the overflow
+// policy of the server. It reports whether the payload was queued.
+func (c *Client) send(payload Payload) bool {
+	select {
+	case <-c.closing:
+		return false
+	default:
+	}
+
+	select {
+	case c.gate <- payload:
+		return true
+	default:
+	}
+
+	switch c.manager.overflow {
+	case OverflowDropNewest:
+		c.manager.dropMessage(c, payload)
+		return false
+	case OverflowDropOldest:
+		// the write goroutine may take a message at the same time, so try again until there is room
+		for {
+			select {
+			case oldest := <-c.gate:
+				c.manager.dropMessage(c, oldest)
+			default:
+			}
+
+			select {
+			case c.gate <- payload:
+				return true
+			default:
+			}
+		}
+	default:
+		c.manager.dropMessage(c, payload)
+		c.evict()
+		return false
+	}
+}
+
+// sendWait queues the payload, waiting for room in the queue up to the write timeout.
+// It is used for replays, which can be longer than the queue. A client that does not
+// make room in time is evicted.
+func (c *Client) sendWait(payload Payload) bool {
+	timer := time.NewTimer(c.manager.writeTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.gate <- payload:
+		return true
+	case <-c.closing:
+		return false
+	case <-timer.C:
+		c.manager.dropMessage(c, payload)
+		c.evict()
+		return false
+	}
+}
+
+// evict disconnects a client that cannot keep up with its messages
+func (c *Client) evict() {
+	c.evictOnce.Do(func() {
+		atomic.AddUint64(&c.manager.evicted, 1)
+		c.manager.Logger.Printf("Evicting slow client %v", c.clientID)
+
+		c.stop(websocket.CloseTryAgainLater, "client too slow", false)
+		// the sender does not wait for the server lock
+		go c.manager.removeClient(c)
+	})
+}
+
+// writeMessage writes to the connection, giving up after the write timeout of the server
+func (c *Client) writeMessage(messageType int, data []byte) error {
+	if err := c.connection.SetWriteDeadline(time.Now().Add(c.manager.writeTimeout)); err != nil {
+		return err
+	}
+	return c.connection.WriteMessage(messageType, data)
+}
+
+// flush writes the messages still waiting on the gate if the client drains and sends
+// the close frame given to stop
+func (c *Client) flush() {
+	if c.drain {
+	queued:
+		for {
+			select {
+			case message := <-c.gate:
+				data, err := json.Marshal(message)
+				if err != nil {
+					c.manager.Logger.Printf("Error marshalling message: %v", err)
+					continue
+				}
+				if err := c.writeMessage(websocket.TextMessage, data); err != nil {
+					c.manager.Logger.Printf("Error writing message: %v", err)
+					return
+				}
+			default:
+				break queued
+			}
+		}
+	}
+
+	if c.closeCode == 0 {
+		return
+	}
+
+	err := c.connection.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+		time.Now().Add(time.Second),
+	)
+	if err != nil {
+		c.manager.Logger.Printf("Error writing close message: %v", err)
+	}
+}
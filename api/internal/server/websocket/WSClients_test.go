@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newBareClient returns a client without a connection, whose queue nobody empties
+func newBareClient(overflow OverflowPolicy, queueSize int) (*WebsocketServer, *Client) {
+	w := newTestWebsocketServer("a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+	w.overflow = overflow
+	w.queueSize = queueSize
+	w.writeTimeout = 50 * time.Millisecond
+	return w, NewClient(nil, 1, 10, 0, w)
+}
+
+func queued(c *Client) []int64 {
+	seqs := []int64{}
+	for {
+		select {
+		case payload := <-c.gate:
+			seqs = append(seqs, payload.Seq)
+		default:
+			return seqs
+		}
+	}
+}
+
+func TestSendOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		queued   string
+		evicted  uint64
+	}{
+		{OverflowDropNewest, "[1 2]", 0},
+		{OverflowDropOldest, "[2 3]", 0},
+		{OverflowDisconnect, "[1 2]", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.overflow), func(t *testing.T) {
+			w, c := newBareClient(test.overflow, 2)
+
+			for seq := int64(1); seq <= 3; seq++ {
+				c.send(Payload{Type: "notification", Seq: seq})
+			}
+
+			stats := w.Stats()
+			if stats.DroppedMessages != 1 || stats.EvictedClients != test.evicted {
+				t.Fatalf("dropped %d and evicted %d, want 1 and %d", stats.DroppedMessages, stats.EvictedClients, test.evicted)
+			}
+
+			if c.closed() != (test.evicted == 1) {
+				t.Fatalf("closed() = %v, want %v", c.closed(), test.evicted == 1)
+			}
+
+			if got := fmt.Sprint(queued(c)); got != test.queued {
+				t.Fatalf("queued %s, want %s", got, test.queued)
+			}
+		})
+	}
+}
+
+func TestEvictedClientGetsTryAgainLater(t *testing.T) {
+	_, c := newBareClient(OverflowDisconnect, 1)
+
+	c.send(Payload{Seq: 1})
+	c.send(Payload{Seq: 2})
+
+	if c.closeCode != websocket.CloseTryAgainLater || c.drain {
+		t.Fatalf("stopped with %d (drain %v), want %d without drain", c.closeCode, c.drain, websocket.CloseTryAgainLater)
+	}
+
+	// a stopped client takes no more messages
+	if c.send(Payload{Seq: 3}) {
+		t.Fatalf("send() after evict queued the message")
+	}
+}
+
+func TestSendWaitEvictsClientThatDoesNotMakeRoom(t *testing.T) {
+	w, c := newBareClient(OverflowDropNewest, 1)
+
+	if !c.sendWait(Payload{Seq: 1}) {
+		t.Fatalf("sendWait() with room in the queue = false")
+	}
+
+	start := time.Now()
+	if c.sendWait(Payload{Seq: 2}) {
+		t.Fatalf("sendWait() on a full queue = true")
+	}
+
+	if elapsed := time.Since(start); elapsed < w.writeTimeout {
+		t.Fatalf("sendWait() gave up after %s, before the write timeout", elapsed)
+	}
+
+	if !c.closed() || w.Stats().EvictedClients != 1 {
+		t.Fatalf("client was not evicted")
+	}
+}
@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"time"
@@ -13,6 +15,7 @@ var (
 	ErrPayloadTypeNotSupported = errors.New("this payload type is not supported")
 	ErrorInvalidPayload        = errors.New("invalid payload")
 	ErrorInvalidNotification   = errors.New("invalid notification")
+	ErrPayloadNotAllowed       = errors.New("this payload type is not allowed for access tokens")
 )
 
 const (
@@ -24,8 +27,35 @@ const (
 	GroupRequest    = "group_request"
 	Response        = "response"
 	MessagesRead    = "messages_read"
+	Resume          = "resume"
 )
 
+// Frames answering a payload of the client
+const (
+	AckFrame    = "ack"
+	ErrorFrame  = "error"
+	ResyncFrame = "resync"
+)
+
+// Codes of error frames
+const (
+	CodeInvalidPayload  = "invalid_payload"
+	CodeUnsupportedType = "unsupported_type"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeInternal        = "internal_error"
+)
+
+// chatPayloads are the payloads a client connected with an access token may send
+var chatPayloads = map[string]bool{
+	RequestChatlist: true,
+	MessageHistory:  true,
+	Message:         true,
+	MessagesRead:    true,
+	Resume:          true,
+}
+
 func (w *WebsocketServer) setupHandlers() {
 	w.handlers[FollowRequest] = w.FollowRequestHandler
 	w.handlers[Unfollow] = w.UnfollowHandler
@@ -35,6 +65,7 @@ func (w *WebsocketServer) setupHandlers() {
 	w.handlers[GroupRequest] = w.GroupRequestHandler
 	w.handlers[Response] = w.ResponseHandler
 	w.handlers[MessagesRead] = w.MessagesReadHandler
+	w.handlers[Resume] = w.ResumeHandler
 }
 
 func (w *WebsocketServer) routePayloads(payload Payload, client *Client) error {
@@ -43,12 +74,86 @@ func (w *WebsocketServer) routePayloads(payload Payload, client *Client) error {
 		w.Logger.Printf("No handler for event %s", payload.Type)
 		return ErrPayloadTypeNotSupported
 	}
+	if client.tokenID != 0 && !chatPayloads[payload.Type] {
+		w.Logger.Printf("Access token %d cannot send %s", client.tokenID, payload.Type)
+		return ErrPayloadNotAllowed
+	}
 	if err := handler(payload, client); err != nil {
 		return err
 	}
 	return nil
 }
 
+// errorCode names the error for the client, errors the client cannot act on are
+// not described beyond their code
+func errorCode(err error) (string, string) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return CodeInvalidPayload, ErrorInvalidPayload.Error()
+	case errors.Is(err, ErrorInvalidPayload),
+		errors.Is(err, services.ErrNoMessageTarget):
+		return CodeInvalidPayload, err.Error()
+	case errors.Is(err, ErrPayloadTypeNotSupported):
+		return CodeUnsupportedType, err.Error()
+	case errors.Is(err, ErrPayloadNotAllowed),
+		errors.Is(err, services.ErrUserBlocked),
+		errors.Is(err, services.ErrEmailNotVerified),
+		errors.Is(err, services.ErrNotGroupCreator):
+		return CodeForbidden, err.Error()
+	case errors.Is(err, sql.ErrNoRows):
+		return CodeNotFound, "not found"
+	case errors.Is(err, ErrorInvalidNotification),
+		errors.Is(err, services.ErrMessageNotFound):
+		return CodeNotFound, err.Error()
+	case errors.Is(err, services.ErrFollowRequestExists),
+		errors.Is(err, services.ErrFollowRequestHandled),
+		errors.Is(err, services.ErrFollowRequestAccepted),
+		errors.Is(err, services.ErrAlreadyGroupMember),
+		errors.Is(err, services.ErrGroupRequestPending),
+		errors.Is(err, services.ErrGroupRequestHandled),
+		errors.Is(err, services.ErrGroupRequestAccepted),
+		errors.Is(err, services.ErrEventInviteHandled),
+		errors.Is(err, services.ErrEventInviteProcessed),
+		errors.Is(err, services.ErrGroupInviteHandled),
+		errors.Is(err, services.ErrGroupInviteAccepted):
+		return CodeConflict, err.Error()
+	default:
+		return CodeInternal, "payload could not be handled"
+	}
+}
+
+// reply answers a payload of the client. Errors are always reported, an ack is only
+// sent when the client asked for one by giving a request ID.
+func (w *WebsocketServer) reply(request Payload, c *Client, err error) {
+	var frame Payload
+	var data interface{}
+
+	if err != nil {
+		code, message := errorCode(err)
+		frame.Type = ErrorFrame
+		data = ErrorPayload{Type: request.Type, Code: code, Message: message}
+	} else if request.RequestID != "" {
+		frame.Type = AckFrame
+		data = AckPayload{Type: request.Type}
+	} else {
+		return
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		w.Logger.Printf("Error marshalling %s frame: %v", frame.Type, err)
+		return
+	}
+
+	frame.Data = encoded
+	frame.RequestID = request.RequestID
+	// a replay may just have filled the queue, the answer waits for room
+	c.sendWait(frame)
+}
+
 func (w *WebsocketServer) ResponseHandler(p Payload, c *Client) error {
 	data := &RequestPayload{}
 	err := json.Unmarshal(p.Data, &data)
@@ -109,10 +214,10 @@ func (w *WebsocketServer) ResponseHandler(p Payload, c *Client) error {
 	if NotificationDetails.NotificationType == "event_invite" {
 		w.Logger.Printf("User %v reacted to event invite %v", c.clientID, data.ID)
 		err = w.notificationService.HandleEventInvite(int64(data.ID), data.Reaction)
-		if err != nil && err.Error() != "event invite already handled" {
+		if err != nil && !errors.Is(err, services.ErrEventInviteHandled) {
 			return err
 		}
-		if err != nil && err.Error() == "event invite already handled" {
+		if err != nil && errors.Is(err, services.ErrEventInviteHandled) {
 			attendance := &models.EventAttendance{
 				EventId:     NotificationDetails.EntityId,
 				UserId:      int64(c.clientID),
@@ -124,6 +229,11 @@ func (w *WebsocketServer) ResponseHandler(p Payload, c *Client) error {
 		return nil
 	}
 
+	if NotificationDetails.NotificationType == "mention" || NotificationDetails.NotificationType == "account_locked" {
+		w.Logger.Printf("User %v dismissed %v notification %v", c.clientID, NotificationDetails.NotificationType, data.ID)
+		return w.notificationService.DismissNotification(int64(data.ID))
+	}
+
 	w.Logger.Printf("Notification type %v not handled", NotificationDetails.NotificationType)
 
 	return errors.New("unknown notification type: " + NotificationDetails.NotificationType)
@@ -146,27 +256,12 @@ func (w *WebsocketServer) FollowRequestHandler(p Payload, c *Client) error {
 		w.Logger.Printf("User %v now follows public user %v", c.clientID, data.ID)
 
 		// sendNewChatlist
-		userChatList, groupChatList, err := w.chatService.GetChatlist(int64(c.clientID))
+		chatlist, err := w.chatlistPayload(c.clientID)
 		if err != nil {
 			return err
 		}
 
-		dataToSend, err := json.Marshal(
-			&ChatListPayload{
-				UserID:        int(c.clientID),
-				UserChatlist:  userChatList,
-				GroupChatlist: groupChatList,
-			},
-		)
-
-		if err != nil {
-			return err
-		}
-
-		c.gate <- Payload{
-			Type: "chatlist",
-			Data: dataToSend,
-		}
+		w.sendToUser(c.clientID, chatlist, nil)
 
 		w.Logger.Printf("Sent new chatlist to sender %v", c.clientID)
 
@@ -202,33 +297,43 @@ func (w *WebsocketServer) UnfollowHandler(p Payload, c *Client) error {
 	return nil
 }
 
-func (w *WebsocketServer) RequestChatlistHandler(p Payload, c *Client) error {
-	w.Logger.Printf("User %v has requested chatlist", c.clientID)
-
-	userChatList, groupChatList, err := w.chatService.GetChatlist(int64(c.clientID))
+// chatlistPayload builds the chatlist of the user with the unread counts
+func (w *WebsocketServer) chatlistPayload(userID int64) (Payload, error) {
+	userChatList, groupChatList, err := w.chatService.GetChatlist(userID)
 	if err != nil {
-		return err
+		return Payload{}, err
 	}
 
 	w.Logger.Printf("Chatlist successfully retrieved (%v user chats, %v group chats)", len(userChatList), len(groupChatList))
 
 	dataToSend, err := json.Marshal(
 		&ChatListPayload{
-			UserID:        int(c.clientID),
+			UserID:        int(userID),
 			UserChatlist:  userChatList,
 			GroupChatlist: groupChatList,
 		},
 	)
 
 	if err != nil {
-		return err
+		return Payload{}, err
 	}
 
-	c.gate <- Payload{
+	return Payload{
 		Type: "chatlist",
 		Data: dataToSend,
+	}, nil
+}
+
+func (w *WebsocketServer) RequestChatlistHandler(p Payload, c *Client) error {
+	w.Logger.Printf("User %v has requested chatlist", c.clientID)
+
+	chatlist, err := w.chatlistPayload(c.clientID)
+	if err != nil {
+		return err
 	}
 
+	c.send(chatlist)
+
 	w.Logger.Printf("Sent chatlist to user %v", c.clientID)
 
 	return nil
@@ -263,10 +368,10 @@ func (w *WebsocketServer) MessageHistoryHandler(p Payload, c *Client) error {
 		return err
 	}
 
-	c.gate <- Payload{
+	c.send(Payload{
 		Type: "message_history",
 		Data: dataToSend,
-	}
+	})
 
 	return nil
 }
@@ -293,6 +398,16 @@ func (w *WebsocketServer) NewMessageHandler(p Payload, c *Client) error {
 
 	messageData.Id = messageID
 
+	defer func() {
+		notifications, err := w.notificationService.CreateMessageMentions(messageData)
+		if err == nil {
+			err = w.BroadcastNotifications(notifications)
+		}
+		if err != nil {
+			w.Logger.Printf("Error sending mentions: %v", err)
+		}
+	}()
+
 	if data.GroupID == 0 && data.RecipientID > 0 {
 
 		w.Logger.Printf("User %v sent message %v to user %v", c.clientID, data.MessageID, data.RecipientID)
@@ -365,7 +480,7 @@ func (w *WebsocketServer) MessagesReadHandler(p Payload, c *Client) error {
 
 	err = w.chatService.HandleMessagesRead(c.clientID, int64(data.LastMessage))
 	if err != nil {
-		if err.Error() == "not recipient" {
+		if errors.Is(err, services.ErrNotRecipient) {
 			// do not mark messages as read if user is not recipient
 			return nil
 		}
@@ -374,5 +489,85 @@ func (w *WebsocketServer) MessagesReadHandler(p Payload, c *Client) error {
 
 	w.Logger.Printf("User %v has read message %v from user %v", c.clientID, data.LastMessage, data.ID)
 
+	// the unread counts changed, the other apps of the user get the new chatlist
+	if w.ConnectionCount(c.clientID) > 1 {
+		chatlist, err := w.chatlistPayload(c.clientID)
+		if err != nil {
+			return err
+		}
+
+		w.sendToUser(c.clientID, chatlist, c)
+	}
+
 	return nil
 }
+
+// ResumeHandler sends a reconnected client the events it missed since the last one it
+// saw, or tells it to load everything again when they are gone
+func (w *WebsocketServer) ResumeHandler(p Payload, c *Client) error {
+	data := &ResumePayload{}
+	err := json.Unmarshal(p.Data, &data)
+	if err != nil {
+		return err
+	}
+
+	if data.LastSeq < 0 {
+		return ErrorInvalidPayload
+	}
+
+	// a new client is still held, its events are replayed from here on
+	c.holdOnce.Do(func() {})
+
+	w.eventsLock.Lock()
+	liveFrom := c.liveFrom
+	c.resuming = liveFrom == 0
+	w.eventsLock.Unlock()
+
+	w.Logger.Printf("User %v resumes after event %v", c.clientID, data.LastSeq)
+
+	if liveFrom != 0 {
+		// the client already gets new events, only the ones before them are missing
+		_, err = w.replay(c, data.LastSeq, liveFrom)
+		return err
+	}
+
+	return w.catchUp(c, data.LastSeq, 0)
+}
+
+// replay queues the events of the client's user after lastSeq and before until, all of
+// them if until is 0, and returns the sequence number the client is now at
+func (w *WebsocketServer) replay(c *Client, lastSeq int64, until int64) (int64, error) {
+	events, currentSeq, err := w.replayService.Replay(c.clientID, lastSeq)
+	if errors.Is(err, services.ErrReplayGap) {
+		w.Logger.Printf("Cannot replay events after %v to user %v, sending resync", lastSeq, c.clientID)
+
+		dataToSend, err := json.Marshal(&ResyncPayload{Seq: currentSeq})
+		if err != nil {
+			return lastSeq, err
+		}
+
+		c.sendWait(Payload{
+			Type: ResyncFrame,
+			Data: dataToSend,
+		})
+
+		return currentSeq, nil
+	}
+	if err != nil {
+		return lastSeq, err
+	}
+
+	for _, event := range events {
+		if until != 0 && event.Seq >= until {
+			break
+		}
+
+		if !c.sendWait(Payload{Type: event.Type, Data: event.Data, Seq: event.Seq}) {
+			return lastSeq, nil
+		}
+
+		lastSeq = event.Seq
+	}
+
+	return lastSeq, nil
+}
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// setResumeWait shortens how long the events of new clients are held for the test
+func setResumeWait(t *testing.T, wait time.Duration) {
+	previous := resumeWait
+	resumeWait = wait
+	t.Cleanup(func() { resumeWait = previous })
+}
+
+func TestResumeReplaysMissedEvents(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	// kept while the user is offline
+	for i := 1; i <= 5; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	conn := ts.dial(t, 1, 10)
+	sendResume(t, conn, 2, "r1")
+
+	// the ack comes after the replayed events
+	expectEvents(t, conn, 3, 5)
+	expectAck(t, conn, "r1")
+
+	ts.ws.sendToUser(1, event(6), nil)
+	expectEvents(t, conn, 6, 6)
+}
+
+func TestResumeWithUnknownSeqSendsResync(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	for i := 1; i <= 3; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	conn := ts.dial(t, 1, 10)
+	sendResume(t, conn, 99, "r1")
+
+	payload := readPayload(t, conn)
+	resync := ResyncPayload{}
+	if payload.Type != ResyncFrame || json.Unmarshal(payload.Data, &resync) != nil || resync.Seq != 3 {
+		t.Fatalf("got %s frame %s, want a resync to 3", payload.Type, payload.Data)
+	}
+
+	expectAck(t, conn, "r1")
+
+	ts.ws.sendToUser(1, event(4), nil)
+	expectEvents(t, conn, 4, 4)
+}
+
+func TestResumeWithInvalidSeqIsRefused(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	conn := ts.dial(t, 1, 10)
+	sendResume(t, conn, -1, "r1")
+
+	payload := readPayload(t, conn)
+	errorPayload := ErrorPayload{}
+	json.Unmarshal(payload.Data, &errorPayload)
+	if payload.Type != ErrorFrame || payload.RequestID != "r1" || errorPayload.Code != CodeInvalidPayload {
+		t.Fatalf("got %s frame %s for %q, want an invalid_payload error for r1", payload.Type, payload.Data, payload.RequestID)
+	}
+}
+
+func TestHeldEventsAreSentWithoutResume(t *testing.T) {
+	setResumeWait(t, 100*time.Millisecond)
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	// events from before the connection are only sent on resume
+	ts.ws.sendToUser(1, event(1), nil)
+	ts.ws.sendToUser(1, event(2), nil)
+
+	conn := ts.dial(t, 1, 10)
+
+	for i := 3; i <= 5; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	// held until resumeWait is over, then sent in order
+	expectEvents(t, conn, 3, 5)
+
+	ts.ws.sendToUser(1, event(6), nil)
+	expectEvents(t, conn, 6, 6)
+}
+
+func TestResumeWhileEventsArrive(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	ts.ws.queueSize = 128
+
+	for i := 1; i <= 10; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	conn := ts.dial(t, 1, 10)
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 11; i <= 100; i++ {
+			ts.ws.sendToUser(1, event(i), nil)
+		}
+	}()
+
+	sendResume(t, conn, 0, "r1")
+
+	// every event arrives once and in order, however the resume and the new events interleave
+	acked := false
+	for seq := int64(1); seq <= 100; {
+		payload := readPayload(t, conn)
+
+		if payload.Type == AckFrame {
+			if seq <= 10 {
+				t.Fatalf("ack before the replayed events, at event %d", seq)
+			}
+			acked = true
+			continue
+		}
+
+		if payload.Seq != seq {
+			t.Fatalf("got event %d, want %d", payload.Seq, seq)
+		}
+		seq++
+	}
+
+	<-sent
+
+	if !acked {
+		expectAck(t, conn, "r1")
+	}
+}
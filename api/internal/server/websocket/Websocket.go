@@ -1,10 +1,15 @@
 package websocket
 
 import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"SocialNetworkRestApi/api/internal/server/utils"
 	"SocialNetworkRestApi/api/pkg/services"
+	"context"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,6 +21,13 @@ type IWebsocketServer interface {
 }
 
 type WebsocketServer struct {
+	// dropped and evicted count the messages and clients lost to full queues and
+	// lastClientID numbers the clients, they come first to stay 64-bit aligned for
+	// atomic access
+	dropped      uint64
+	evicted      uint64
+	lastClientID uint64
+
 	Logger              *log.Logger
 	upgrader            websocket.Upgrader
 	clients             ClientList
@@ -26,29 +38,89 @@ type WebsocketServer struct {
 	groupService        services.IGroupService
 	groupMemberService  services.IGroupMemberService
 	groupEventService   services.IGroupEventService
+	replayService       services.IReplayService
+	// broker carries events, presence and disconnects to the other instances, node
+	// names this one
+	broker broker.Broker
+	node   string
+	// publishLock orders the events published by this instance, an event is numbered
+	// and published before the next one
+	publishLock sync.Mutex
+	// eventsLock guards the replay state of the clients, it is never held while a
+	// client is waited for
+	eventsLock sync.Mutex
+	// users indexes the clients by user, a user has one client per open app
+	users map[int64]ClientList
+	// remote is the presence of the users on the other instances
+	remote map[string]*nodePresence
+	// done stops announcing the presence of this instance
+	done chan struct{}
+	// queueSize, overflow and writeTimeout apply to the queue of every client
+	queueSize    int
+	overflow     OverflowPolicy
+	writeTimeout time.Duration
+	// writers tracks the write goroutines so shutdown can wait for them to flush
+	writers      sync.WaitGroup
+	shuttingDown bool
 	sync.RWMutex
 }
 
 func (w *WebsocketServer) WShandler(rw http.ResponseWriter, r *http.Request) {
+	principal, err := services.RequestPrincipal(r)
+	if err != nil {
+		w.Logger.Printf("Cannot get user session: %s", err)
+		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// checked again right before the upgrade, a suspension that disconnected the user
+	// after Authenticate ran would otherwise miss this connection
+	suspended, err := w.userService.IsSuspended(principal.UserID)
+	if err != nil {
+		http.Error(rw, "Cannot check account", http.StatusInternalServerError)
+		return
+	}
+
+	if suspended {
+		http.Error(rw, services.ErrAccountSuspended.Error(), http.StatusForbidden)
+		return
+	}
+
+	if w.isShuttingDown() {
+		http.Error(rw, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := w.upgrader.Upgrade(rw, r, nil)
 	if err != nil {
 		w.Logger.Println("Cannot upgrade:", err)
-		rw.Write([]byte(err.Error()))
 		return
 	}
 
 	w.Logger.Println("Successfully upgraded connection")
 
-	userID, err := w.userService.GetUserID(r)
-	if err != nil {
-		w.Logger.Printf("Cannot get user ID: %s", err)
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	client := NewClient(conn, principal.UserID, principal.SessionID, principal.TokenID, w)
+
+	// new events wait until the client resumes or shows it will not, so none of them
+	// can pass the events it missed
+	hold := false
+	if w.replayService.Enabled() {
+		client.heldFrom, err = w.replayService.CurrentSeq(principal.UserID)
+		hold = err == nil
+		client.resuming = hold
+	}
+
+	if !w.addClient(client) {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+		conn.Close()
 		return
 	}
 
-	client := NewClient(conn, userID, w)
+	w.publishPresence(client.clientID)
 
-	w.addClient(client)
+	if hold {
+		time.AfterFunc(resumeWait, func() { w.endHold(client) })
+	}
 
 	go client.monitor()
 	go client.write()
@@ -57,21 +129,36 @@ func (w *WebsocketServer) WShandler(rw http.ResponseWriter, r *http.Request) {
 
 func InitWebsocket(
 	logger *log.Logger,
+	allowedOrigins []string,
+	queueSize int,
+	overflow OverflowPolicy,
+	writeTimeout time.Duration,
+	messageBroker broker.Broker,
+	node string,
 	userService *services.UserService,
 	notificationService *services.NotificationService,
 	chatService *services.ChatService,
 	groupService *services.GroupService,
 	groupMemberService *services.GroupMemberService,
 	groupEventService *services.GroupEventService,
+	replayService *services.ReplayService,
 ) *WebsocketServer {
 	w := &WebsocketServer{
 		Logger: logger,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin:     checkOrigin,
+			CheckOrigin:     checkOrigin(allowedOrigins),
 		},
 		clients:             make(ClientList),
+		users:               make(map[int64]ClientList),
+		remote:              make(map[string]*nodePresence),
+		broker:              messageBroker,
+		node:                node,
+		done:                make(chan struct{}),
+		queueSize:           queueSize,
+		overflow:            overflow,
+		writeTimeout:        writeTimeout,
 		handlers:            make(map[string]PayloadHandler),
 		userService:         userService,
 		notificationService: notificationService,
@@ -79,48 +166,323 @@ func InitWebsocket(
 		groupService:        groupService,
 		groupMemberService:  groupMemberService,
 		groupEventService:   groupEventService,
+		replayService:       replayService,
 	}
 	w.setupHandlers()
+	messageBroker.Subscribe(w.receive)
+	go w.announcePresence()
 	return w
 }
 
-func checkOrigin(r *http.Request) bool {
-	// required for CORS
-	// should return true if origin is allowed
-	origin := r.Header.Get("Origin")
-	switch origin {
-	case "http://localhost:3000":
-		return true
-	default:
-		return false
+// checkOrigin refuses upgrades from pages of other sites, which would otherwise
+// connect with the session cookie of the user
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		return utils.OriginAllowed(allowedOrigins, r.Header.Get("Origin"))
 	}
 }
 
-func (w *WebsocketServer) addClient(client *Client) {
+// addClient registers the client, it returns false once the server is shutting down
+func (w *WebsocketServer) addClient(client *Client) bool {
 	w.Lock()
 	defer w.Unlock()
+	if w.shuttingDown {
+		return false
+	}
 	w.Logger.Printf("Adding client %v", client.clientID)
 	w.clients[client] = true
+	if w.users[client.clientID] == nil {
+		w.users[client.clientID] = make(ClientList)
+	}
+	w.users[client.clientID][client] = true
+	w.writers.Add(1)
+	return true
+}
+
+func (w *WebsocketServer) isShuttingDown() bool {
+	w.RLock()
+	defer w.RUnlock()
+	return w.shuttingDown
+}
+
+// Shutdown stops accepting clients, tells every connected client the server is
+// going away and waits until their pending messages are written or ctx expires.
+// The other instances are told this one has no users anymore.
+func (w *WebsocketServer) Shutdown(ctx context.Context) error {
+	w.Lock()
+	w.shuttingDown = true
+	for client := range w.clients {
+		client.stop(websocket.CloseGoingAway, "server shutting down", true)
+	}
+	w.Unlock()
+
+	defer func() {
+		close(w.done)
+		w.publish(&brokerMessage{Kind: brokerPresence, Node: w.node, Full: true})
+		if err := w.broker.Close(); err != nil {
+			w.Logger.Printf("Cannot close broker: %v", err)
+		}
+	}()
+
+	flushed := make(chan struct{})
+	go func() {
+		w.writers.Wait()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		w.RLock()
+		for client := range w.clients {
+			client.connection.Close()
+		}
+		w.RUnlock()
+		return ctx.Err()
+	}
 }
 
 func (w *WebsocketServer) removeClient(client *Client) {
 	w.Lock()
-	defer w.Unlock()
 	// Check if Client exists, then delete it
-	if _, ok := w.clients[client]; ok {
+	_, ok := w.clients[client]
+	if ok {
 		w.Logger.Printf("Removing client %v", client.clientID)
-		client.connection.Close()
+		// the write goroutine closes the connection, after the close frame if one is due
+		client.stop(0, "", false)
 		delete(w.clients, client)
+		delete(w.users[client.clientID], client)
+		if len(w.users[client.clientID]) == 0 {
+			delete(w.users, client.clientID)
+		}
+	}
+	w.Unlock()
+
+	if ok {
+		w.publishPresence(client.clientID)
 	}
 }
 
-func (w *WebsocketServer) getClientByUserID(TargetID int64) *Client {
+// getClientsByUserID returns every client of the user, none if the user is offline
+func (w *WebsocketServer) getClientsByUserID(userID int64) []*Client {
 	w.RLock()
 	defer w.RUnlock()
+	clients := make([]*Client, 0, len(w.users[userID]))
+	for client := range w.users[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// ConnectionCount returns the number of open connections of the user on all instances
+func (w *WebsocketServer) ConnectionCount(userID int64) int {
+	w.RLock()
+	defer w.RUnlock()
+	count := len(w.users[userID])
+	for _, presence := range w.remote {
+		if presence.alive() {
+			count += presence.connections[userID]
+		}
+	}
+	return count
+}
+
+// ConnectionCounts returns the number of open connections of every connected user on all instances
+func (w *WebsocketServer) ConnectionCounts() map[int64]int {
+	w.RLock()
+	defer w.RUnlock()
+	counts := make(map[int64]int, len(w.users))
+	for userID, clients := range w.users {
+		counts[userID] = len(clients)
+	}
+	for _, presence := range w.remote {
+		if !presence.alive() {
+			continue
+		}
+		for userID, count := range presence.connections {
+			counts[userID] += count
+		}
+	}
+	return counts
+}
+
+// sendToUser records the payload as the next event of the user and queues it for the
+// clients of the user on every instance except skip, which is the client the user
+// acted on. The event is recorded when the user is offline too, to be replayed when
+// they come back.
+func (w *WebsocketServer) sendToUser(userID int64, payload Payload, skip *Client) {
+	w.publishLock.Lock()
+	defer w.publishLock.Unlock()
+
+	seq, err := w.replayService.Record(userID, payload.Type, payload.Data)
+	if err != nil {
+		w.Logger.Printf("Cannot record '%v' event of user %v: %v", payload.Type, userID, err)
+	}
+	payload.Seq = seq
+
+	message := &brokerMessage{
+		Kind:    brokerEvent,
+		Node:    w.node,
+		UserID:  userID,
+		Payload: &payload,
+	}
+	if skip != nil {
+		message.Skip = skip.id
+	}
+
+	w.deliver(message)
+	w.publish(message)
+}
+
+// deliver queues an event for the clients of its user on this instance
+func (w *WebsocketServer) deliver(message *brokerMessage) {
+	w.eventsLock.Lock()
+	defer w.eventsLock.Unlock()
+
+	payload := *message.Payload
+
+	for _, client := range w.getClientsByUserID(message.UserID) {
+		if message.Node == w.node && client.id == message.Skip {
+			continue
+		}
+		// events are kept for replay, so a resuming client only notes what it missed
+		if client.resuming && payload.Seq != 0 {
+			if payload.Seq > client.heldTo {
+				client.heldTo = payload.Seq
+			}
+			continue
+		}
+		// already replayed
+		if payload.Seq != 0 && payload.Seq <= client.replayedTo {
+			continue
+		}
+		if client.liveFrom == 0 {
+			client.liveFrom = payload.Seq
+		}
+		client.send(payload)
+	}
+}
+
+// endHold sends a new client that did not resume the events held back since it
+// connected, and new events from then on
+func (w *WebsocketServer) endHold(c *Client) {
+	c.holdOnce.Do(func() {
+		w.eventsLock.Lock()
+		resuming := c.resuming
+		w.eventsLock.Unlock()
+
+		if !resuming {
+			return
+		}
+
+		// the events after heldFrom are sent now or as they happen
+		if err := w.catchUp(c, c.heldFrom, c.heldFrom+1); err != nil {
+			w.Logger.Printf("Cannot send held events to user %v: %v", c.clientID, err)
+		}
+	})
+}
+
+// catchUp replays the events after lastSeq to a resuming client until no new event was
+// held back meanwhile, then sends new events as they happen, counting liveFrom as the
+// first of them if it is not 0. The events are queued outside eventsLock, so a slow
+// client holds up nobody else.
+func (w *WebsocketServer) catchUp(c *Client, lastSeq int64, liveFrom int64) error {
+	var err error
+
+	for {
+		lastSeq, err = w.replay(c, lastSeq, 0)
+
+		w.eventsLock.Lock()
+		if err != nil || c.heldTo <= lastSeq || c.closed() {
+			c.resuming = false
+			c.replayedTo = lastSeq
+			if liveFrom != 0 {
+				c.liveFrom = liveFrom
+			}
+			w.eventsLock.Unlock()
+			return err
+		}
+		w.eventsLock.Unlock()
+	}
+}
+
+// reachable reports whether an event for the user has to be built, because the user
+// is online or it is kept to be replayed
+func (w *WebsocketServer) reachable(userID int64) bool {
+	return w.replayService.Enabled() || w.ConnectionCount(userID) > 0
+}
+
+// dropMessage counts a message that did not fit in the queue of the client
+func (w *WebsocketServer) dropMessage(client *Client, payload Payload) {
+	atomic.AddUint64(&w.dropped, 1)
+	w.Logger.Printf("Queue of client %v is full, dropped '%v' message", client.clientID, payload.Type)
+}
+
+// QueueStats shows how well the clients of an instance keep up with their messages
+type QueueStats struct {
+	Node            string `json:"node"`
+	Connections     int    `json:"connections"`
+	DroppedMessages uint64 `json:"droppedMessages"`
+	EvictedClients  uint64 `json:"evictedClients"`
+}
+
+// Stats returns the number of open connections and what was lost to full queues since the start
+func (w *WebsocketServer) Stats() QueueStats {
+	w.RLock()
+	connections := len(w.clients)
+	w.RUnlock()
+
+	return QueueStats{
+		Node:            w.node,
+		Connections:     connections,
+		DroppedMessages: atomic.LoadUint64(&w.dropped),
+		EvictedClients:  atomic.LoadUint64(&w.evicted),
+	}
+}
+
+// DisconnectSessions closes every client connected with one of the given sessions, on every instance
+func (w *WebsocketServer) DisconnectSessions(sessionIDs []int64) {
+	w.disconnectSessions(sessionIDs)
+	if len(sessionIDs) > 0 {
+		w.publish(&brokerMessage{Kind: brokerDisconnect, Node: w.node, Sessions: sessionIDs})
+	}
+}
+
+// DisconnectTokens closes every client connected with one of the given access tokens, on every instance
+func (w *WebsocketServer) DisconnectTokens(tokenIDs []int64) {
+	w.disconnectTokens(tokenIDs)
+	if len(tokenIDs) > 0 {
+		w.publish(&brokerMessage{Kind: brokerDisconnect, Node: w.node, Tokens: tokenIDs})
+	}
+}
+
+func (w *WebsocketServer) disconnectSessions(sessionIDs []int64) {
+	w.disconnect(sessionIDs, func(c *Client) int64 { return c.sessionID }, "session revoked")
+}
+
+func (w *WebsocketServer) disconnectTokens(tokenIDs []int64) {
+	w.disconnect(tokenIDs, func(c *Client) int64 { return c.tokenID }, "access token revoked")
+}
+
+func (w *WebsocketServer) disconnect(ids []int64, credential func(c *Client) int64, reason string) {
+	revoked := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		revoked[id] = true
+	}
+
+	w.RLock()
+	clients := []*Client{}
 	for client := range w.clients {
-		if client.clientID == TargetID {
-			return client
+		if id := credential(client); id != 0 && revoked[id] {
+			clients = append(clients, client)
 		}
 	}
-	return nil
+	w.RUnlock()
+
+	for _, client := range clients {
+		w.Logger.Printf("Disconnecting client %v (%s)", client.clientID, reason)
+		client.stop(websocket.ClosePolicyViolation, reason, false)
+		w.removeClient(client)
+	}
 }
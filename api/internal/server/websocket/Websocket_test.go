@@ -0,0 +1,316 @@
+package websocket
+
+import (
+	"SocialNetworkRestApi/api/internal/broker"
+	"SocialNetworkRestApi/api/pkg/models"
+	"SocialNetworkRestApi/api/pkg/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// memoryReplayRepo keeps the replay events of the tests in memory
+type memoryReplayRepo struct {
+	mu     sync.Mutex
+	events []*models.ReplayEvent
+	seqs   map[int64]int64
+}
+
+func newMemoryReplayRepo() *memoryReplayRepo {
+	return &memoryReplayRepo{seqs: make(map[int64]int64)}
+}
+
+func (r *memoryReplayRepo) Append(event *models.ReplayEvent) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seqs[event.UserId]++
+	stored := *event
+	stored.Seq = r.seqs[event.UserId]
+	r.events = append(r.events, &stored)
+	return stored.Seq, nil
+}
+
+func (r *memoryReplayRepo) GetSeq(userId int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seqs[userId], nil
+}
+
+func (r *memoryReplayRepo) GetSince(userId int64, afterSeq int64, limit int) ([]*models.ReplayEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := []*models.ReplayEvent{}
+	for _, event := range r.events {
+		if event.UserId == userId && event.Seq > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryReplayRepo) DeleteBefore(createdBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeUserService lets every user connect
+type fakeUserService struct {
+	services.IUserService
+}
+
+func (fakeUserService) IsSuspended(userID int64) (bool, error) {
+	return false, nil
+}
+
+// testServer is a websocket server reached through an HTTP test server, users connect
+// with ?user=<id>&session=<id> instead of a cookie
+type testServer struct {
+	ws           *WebsocketServer
+	url          string
+	shutdownOnce sync.Once
+}
+
+func newTestWebsocketServer(node string, messageBroker broker.Broker, replayRepo models.IReplayRepository) *WebsocketServer {
+	w := &WebsocketServer{
+		Logger:      log.New(io.Discard, "", 0),
+		clients:     make(ClientList),
+		users:       make(map[int64]ClientList),
+		remote:      make(map[string]*nodePresence),
+		broker:      messageBroker,
+		node:        node,
+		done:        make(chan struct{}),
+		queueSize:   64,
+		overflow:    OverflowDisconnect,
+		handlers:    make(map[string]PayloadHandler),
+		userService: fakeUserService{},
+		replayService: &services.ReplayService{
+			Logger:     log.New(io.Discard, "", 0),
+			ReplayRepo: replayRepo,
+			Retention:  time.Hour,
+			Limit:      100,
+		},
+		writeTimeout: time.Second,
+	}
+	w.setupHandlers()
+	return w
+}
+
+func newTestServer(t *testing.T, node string, messageBroker broker.Broker, replayRepo models.IReplayRepository) *testServer {
+	w := newTestWebsocketServer(node, messageBroker, replayRepo)
+	messageBroker.Subscribe(w.receive)
+	go w.announcePresence()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		userID, _ := strconv.ParseInt(r.URL.Query().Get("user"), 10, 64)
+		sessionID, _ := strconv.ParseInt(r.URL.Query().Get("session"), 10, 64)
+		principal := &services.Principal{UserID: userID, SessionID: sessionID}
+		w.WShandler(rw, r.WithContext(services.WithPrincipal(r.Context(), principal)))
+	}))
+
+	ts := &testServer{ws: w, url: "ws" + strings.TrimPrefix(server.URL, "http")}
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { ts.shutdown(t) })
+
+	return ts
+}
+
+func (ts *testServer) shutdown(t *testing.T) error {
+	var err error
+	ts.shutdownOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = ts.ws.Shutdown(ctx)
+	})
+	return err
+}
+
+func (ts *testServer) dial(t *testing.T, userID int64, sessionID int64) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s/?user=%d&session=%d", ts.url, userID, sessionID), nil)
+	if err != nil {
+		t.Fatalf("Cannot connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func event(n int) Payload {
+	return Payload{Type: "notification", Data: json.RawMessage(fmt.Sprintf(`{"n":%d}`, n))}
+}
+
+func sendResume(t *testing.T, conn *websocket.Conn, lastSeq int64, requestID string) {
+	t.Helper()
+
+	err := conn.WriteJSON(Payload{
+		Type:      Resume,
+		Data:      json.RawMessage(fmt.Sprintf(`{"last_seq":%d}`, lastSeq)),
+		RequestID: requestID,
+	})
+	if err != nil {
+		t.Fatalf("Cannot send resume: %v", err)
+	}
+}
+
+func readPayload(t *testing.T, conn *websocket.Conn) Payload {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var payload Payload
+	if err := conn.ReadJSON(&payload); err != nil {
+		t.Fatalf("Cannot read payload: %v", err)
+	}
+
+	return payload
+}
+
+func expectAck(t *testing.T, conn *websocket.Conn, requestID string) {
+	t.Helper()
+
+	payload := readPayload(t, conn)
+	if payload.Type != AckFrame || payload.RequestID != requestID {
+		t.Fatalf("got %s frame for %q, want an ack for %q", payload.Type, payload.RequestID, requestID)
+	}
+}
+
+func expectEvents(t *testing.T, conn *websocket.Conn, from int64, to int64) {
+	t.Helper()
+
+	for seq := from; seq <= to; seq++ {
+		payload := readPayload(t, conn)
+		if payload.Seq != seq {
+			t.Fatalf("got %s frame with seq %d, want event %d", payload.Type, payload.Seq, seq)
+		}
+	}
+}
+
+func expectClose(t *testing.T, conn *websocket.Conn, code int, reason string) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("connection ended with %v, want close %d", err, code)
+		}
+
+		if closeErr.Code != code || closeErr.Text != reason {
+			t.Fatalf("closed with %d %q, want %d %q", closeErr.Code, closeErr.Text, code, reason)
+		}
+
+		return
+	}
+}
+
+// eventually fails the test if condition does not become true within a few seconds
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventsAreSentInOrder(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+	// room for every event, a full queue would disconnect the client
+	ts.ws.queueSize = 128
+
+	conn := ts.dial(t, 1, 10)
+	sendResume(t, conn, 0, "r1")
+	expectAck(t, conn, "r1")
+
+	for i := 1; i <= 100; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	expectEvents(t, conn, 1, 100)
+}
+
+func TestSecondClientOfUserGetsEvents(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	first := ts.dial(t, 1, 10)
+	sendResume(t, first, 0, "r1")
+	expectAck(t, first, "r1")
+
+	second := ts.dial(t, 1, 11)
+	sendResume(t, second, 0, "r2")
+	expectAck(t, second, "r2")
+
+	if count := ts.ws.ConnectionCount(1); count != 2 {
+		t.Fatalf("ConnectionCount(1) = %d, want 2", count)
+	}
+
+	ts.ws.sendToUser(1, event(1), nil)
+
+	expectEvents(t, first, 1, 1)
+	expectEvents(t, second, 1, 1)
+}
+
+func TestDisconnectSessionsClosesWithReason(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	revoked := ts.dial(t, 1, 10)
+	kept := ts.dial(t, 1, 11)
+	sendResume(t, kept, 0, "r1")
+	expectAck(t, kept, "r1")
+
+	ts.ws.DisconnectSessions([]int64{10})
+
+	expectClose(t, revoked, websocket.ClosePolicyViolation, "session revoked")
+
+	eventually(t, "the revoked client is removed", func() bool { return ts.ws.ConnectionCount(1) == 1 })
+
+	ts.ws.sendToUser(1, event(1), nil)
+	expectEvents(t, kept, 1, 1)
+}
+
+func TestShutdownFlushesQueuedEvents(t *testing.T) {
+	ts := newTestServer(t, "a", broker.NewMemoryBroker(), newMemoryReplayRepo())
+
+	conn := ts.dial(t, 1, 10)
+	sendResume(t, conn, 0, "r1")
+	expectAck(t, conn, "r1")
+
+	for i := 1; i <= 20; i++ {
+		ts.ws.sendToUser(1, event(i), nil)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- ts.shutdown(t) }()
+
+	expectEvents(t, conn, 1, 20)
+	expectClose(t, conn, websocket.CloseGoingAway, "server shutting down")
+
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	if _, _, err := websocket.DefaultDialer.Dial(ts.url+"/?user=1&session=10", nil); err == nil {
+		t.Fatalf("connected after Shutdown")
+	}
+}
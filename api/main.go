@@ -1,28 +1,31 @@
 package main
 
 import (
+	"SocialNetworkRestApi/api/internal/config"
 	"SocialNetworkRestApi/api/internal/server/handlers"
 	"SocialNetworkRestApi/api/internal/server/router"
 	"SocialNetworkRestApi/api/pkg/db/seed"
 	database "SocialNetworkRestApi/api/pkg/db/sqlite"
 	"SocialNetworkRestApi/api/pkg/models"
-	"fmt"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
-type Config struct {
-	port int
-}
-
 func main() {
-	config := &Config{port: 8000}
-
 	logger := log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
 
+	config, err := config.LoadFromEnv()
+	if err != nil {
+		logger.Fatalf("invalid configuration: %s", err)
+	}
+
 	//DATABASE
-	db, err := database.OpenDB()
+	db, err := database.OpenDB(config.DatabasePath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -37,7 +40,7 @@ func main() {
 
 	repos := models.InitRepositories(db)
 
-	app := handlers.InitApp(repos, logger)
+	app := handlers.InitApp(repos, logger, config)
 
 	args := os.Args
 
@@ -51,11 +54,86 @@ func main() {
 
 	}
 
-	r := router.New(app)
-
-	logger.Printf("Starting server on port %d\n", config.port)
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", config.port), r); err != nil {
+	err = app.ModerationService.PromoteAdmins(config.AdminEmails)
+	if err != nil {
 		logger.Fatal(err)
 	}
 
+	sweeperDone := make(chan struct{})
+	sweeperStopped := make(chan struct{})
+	go func() {
+		app.RunSessionSweeper(config.SessionSweepInterval.Duration, sweeperDone)
+		close(sweeperStopped)
+	}()
+
+	deletionSweeperDone := make(chan struct{})
+	deletionSweeperStopped := make(chan struct{})
+	go func() {
+		app.RunAccountDeletionSweeper(config.DeletionSweepInterval.Duration, deletionSweeperDone)
+		close(deletionSweeperStopped)
+	}()
+
+	prunerDone := make(chan struct{})
+	prunerStopped := make(chan struct{})
+	go func() {
+		app.ReplayService.RunPruner(prunerDone)
+		close(prunerStopped)
+	}()
+
+	mailerDone := make(chan struct{})
+	mailerStopped := make(chan struct{})
+	go func() {
+		app.PasswordResetService.RunMailer(mailerDone)
+		close(mailerStopped)
+	}()
+
+	server := &http.Server{
+		Addr:    config.Addr(),
+		Handler: router.New(app, config),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Printf("Starting server on port %d\n", config.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("Server stopped: %s", err)
+		}
+	case <-ctx.Done():
+		logger.Println("Shutting down..")
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout.Duration)
+	defer cancel()
+
+	// stop accepting connections and let in-flight requests finish
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("Cannot shut down HTTP server: %s", err)
+	}
+
+	if err := app.WS.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("Cannot drain websocket clients: %s", err)
+	}
+
+	close(sweeperDone)
+	<-sweeperStopped
+
+	close(deletionSweeperDone)
+	<-deletionSweeperStopped
+
+	close(prunerDone)
+	<-prunerStopped
+
+	close(mailerDone)
+	<-mailerStopped
+
+	logger.Println("Server stopped")
 }
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS user_sessions_user_id;
+
+ALTER TABLE user_sessions DROP COLUMN ip_address;
+
+ALTER TABLE user_sessions DROP COLUMN user_agent;
+
+ALTER TABLE user_sessions DROP COLUMN last_seen_at;
@@ -0,0 +1,12 @@
+ALTER TABLE user_sessions
+ADD COLUMN last_seen_at DATETIME;
+
+ALTER TABLE user_sessions
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE user_sessions
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+
+UPDATE user_sessions SET last_seen_at = created_at;
+
+CREATE INDEX IF NOT EXISTS user_sessions_user_id ON user_sessions (user_id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS reactions_comment_id;
+
+DROP INDEX IF EXISTS reactions_post_id;
+
+DROP INDEX IF EXISTS reactions_user_comment;
+
+DROP INDEX IF EXISTS reactions_user_post;
+
+DROP TABLE IF EXISTS reactions;
+
+DROP TABLE IF EXISTS reaction_types;
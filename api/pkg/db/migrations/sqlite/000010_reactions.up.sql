@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS reaction_types(
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+INSERT INTO reaction_types (id, name)
+VALUES
+(1, "like"),
+(2, "love"),
+(3, "laugh"),
+(4, "sad"),
+(5, "angry");
+
+CREATE TABLE IF NOT EXISTS reactions(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	post_id INTEGER,
+	comment_id INTEGER,
+	reaction_type_id INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	CHECK ((post_id IS NULL) <> (comment_id IS NULL)),
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+	FOREIGN KEY (post_id)
+		REFERENCES posts (id)
+	FOREIGN KEY (comment_id)
+		REFERENCES comments (id)
+	FOREIGN KEY (reaction_type_id)
+		REFERENCES reaction_types (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_user_post ON reactions (user_id, post_id) WHERE post_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_user_comment ON reactions (user_id, comment_id) WHERE comment_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS reactions_post_id ON reactions (post_id);
+
+CREATE INDEX IF NOT EXISTS reactions_comment_id ON reactions (comment_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS comments_parent_comment_id;
+
+DELETE FROM comments WHERE parent_comment_id IS NOT NULL;
+
+ALTER TABLE comments DROP COLUMN depth;
+
+ALTER TABLE comments DROP COLUMN parent_comment_id;
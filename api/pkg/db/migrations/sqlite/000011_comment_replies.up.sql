@@ -0,0 +1,5 @@
+ALTER TABLE comments ADD COLUMN parent_comment_id INTEGER REFERENCES comments (id);
+
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS comments_parent_comment_id ON comments (parent_comment_id);
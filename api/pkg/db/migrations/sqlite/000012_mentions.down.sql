@@ -0,0 +1,10 @@
+DELETE FROM notifications WHERE notification_details_id IN
+	(SELECT id FROM notification_details WHERE notification_type_id = 4);
+
+DELETE FROM notification_details WHERE notification_type_id = 4;
+
+DELETE FROM notification_types WHERE id = 4;
+
+DROP INDEX IF EXISTS mentions_user_entity;
+
+DROP TABLE IF EXISTS mentions;
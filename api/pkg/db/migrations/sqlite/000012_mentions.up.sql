@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS mentions(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	sender_id INTEGER NOT NULL,
+	post_id INTEGER,
+	comment_id INTEGER,
+	message_id INTEGER,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+	FOREIGN KEY (sender_id)
+		REFERENCES users (id)
+	FOREIGN KEY (post_id)
+		REFERENCES posts (id)
+	FOREIGN KEY (comment_id)
+		REFERENCES comments (id)
+	FOREIGN KEY (message_id)
+		REFERENCES messages (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS mentions_user_entity ON mentions (user_id, IFNULL(post_id, 0), IFNULL(comment_id, 0), IFNULL(message_id, 0));
+
+INSERT INTO notification_types (id, name, entity)
+VALUES
+(4, "mention", "mentions");
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS post_tags_tag_post;
+
+DROP INDEX IF EXISTS post_tags_post_tag;
+
+DROP TABLE IF EXISTS post_tags;
+
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS tags(
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags(
+	id INTEGER PRIMARY KEY,
+	post_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	FOREIGN KEY (post_id)
+		REFERENCES posts (id)
+	FOREIGN KEY (tag_id)
+		REFERENCES tags (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS post_tags_post_tag ON post_tags (post_id, tag_id);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_post ON post_tags (tag_id, post_id);
@@ -0,0 +1,87 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+	content,
+	content='posts',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+	content,
+	content='comments',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS groups_fts USING fts5(
+	title,
+	description,
+	content='groups',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+	forname,
+	surname,
+	nickname,
+	content='users',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+	INSERT INTO posts_fts (posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF content ON posts BEGIN
+	INSERT INTO posts_fts (posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	INSERT INTO posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_insert AFTER INSERT ON comments BEGIN
+	INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_delete AFTER DELETE ON comments BEGIN
+	INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+	INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_insert AFTER INSERT ON groups BEGIN
+	INSERT INTO groups_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_delete AFTER DELETE ON groups BEGIN
+	INSERT INTO groups_fts (groups_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS groups_fts_update AFTER UPDATE OF title, description ON groups BEGIN
+	INSERT INTO groups_fts (groups_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	INSERT INTO groups_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users BEGIN
+	INSERT INTO users_fts (rowid, forname, surname, nickname) VALUES (new.id, new.forname, new.surname, new.nickname);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, forname, surname, nickname) VALUES ('delete', old.id, old.forname, old.surname, old.nickname);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF forname, surname, nickname ON users BEGIN
+	INSERT INTO users_fts (users_fts, rowid, forname, surname, nickname) VALUES ('delete', old.id, old.forname, old.surname, old.nickname);
+	INSERT INTO users_fts (rowid, forname, surname, nickname) VALUES (new.id, new.forname, new.surname, new.nickname);
+END;
+
+INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
+INSERT INTO comments_fts (comments_fts) VALUES ('rebuild');
+INSERT INTO groups_fts (groups_fts) VALUES ('rebuild');
+INSERT INTO users_fts (users_fts) VALUES ('rebuild');
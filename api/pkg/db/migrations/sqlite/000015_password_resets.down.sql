@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS password_reset_requests;
+
+DROP INDEX IF EXISTS password_resets_user;
+
+DROP TABLE IF EXISTS password_resets;
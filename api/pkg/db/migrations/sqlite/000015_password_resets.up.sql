@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS password_resets(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user ON password_resets (user_id);
+
+-- reset requests per email and per client IP, scope is "email" or "ip"
+CREATE TABLE IF NOT EXISTS password_reset_requests(
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	requests INTEGER NOT NULL,
+	last_request_at DATETIME NOT NULL,
+	blocked_until DATETIME NOT NULL,
+	PRIMARY KEY (scope, key)
+);
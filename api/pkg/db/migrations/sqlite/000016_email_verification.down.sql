@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS email_verifications_user;
+
+DROP TABLE IF EXISTS email_verifications;
+
+ALTER TABLE users DROP COLUMN email_verified;
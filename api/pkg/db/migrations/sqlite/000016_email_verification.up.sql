@@ -0,0 +1,17 @@
+ALTER TABLE users
+ADD COLUMN email_verified BOOL NOT NULL DEFAULT false;
+
+-- accounts created before verification existed are trusted
+UPDATE users SET email_verified = true;
+
+CREATE TABLE IF NOT EXISTS email_verifications(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS email_verifications_user ON email_verifications (user_id);
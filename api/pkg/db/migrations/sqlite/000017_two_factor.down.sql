@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS login_challenges;
+
+DROP INDEX IF EXISTS recovery_codes_user;
+
+DROP TABLE IF EXISTS recovery_codes;
+
+DROP TABLE IF EXISTS user_totp;
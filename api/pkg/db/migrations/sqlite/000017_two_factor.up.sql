@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+	user_id INTEGER PRIMARY KEY,
+	secret TEXT NOT NULL,
+	enabled BOOL NOT NULL DEFAULT false,
+	last_used_step INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	code_hash TEXT NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user ON recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS login_challenges(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
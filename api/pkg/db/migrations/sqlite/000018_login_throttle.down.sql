@@ -0,0 +1,8 @@
+DELETE FROM notifications WHERE notification_details_id IN
+	(SELECT id FROM notification_details WHERE notification_type_id = 5);
+
+DELETE FROM notification_details WHERE notification_type_id = 5;
+
+DELETE FROM notification_types WHERE id = 5;
+
+DROP TABLE IF EXISTS login_failures;
@@ -0,0 +1,13 @@
+-- failed logins per email and per client IP, scope is "email" or "ip"
+CREATE TABLE IF NOT EXISTS login_failures(
+	scope TEXT NOT NULL,
+	key TEXT NOT NULL,
+	failures INTEGER NOT NULL,
+	last_failure_at DATETIME NOT NULL,
+	blocked_until DATETIME NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+
+INSERT INTO notification_types (id, name, entity)
+VALUES
+(5, "account_locked", "users");
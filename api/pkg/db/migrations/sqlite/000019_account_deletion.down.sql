@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_deletions;
+
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,11 @@
+-- deleted accounts are kept anonymised so that remaining comments and messages still have an author
+ALTER TABLE users
+ADD COLUMN deleted_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS account_deletions(
+	user_id INTEGER PRIMARY KEY,
+	requested_at DATETIME NOT NULL,
+	delete_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS blocks_blocked_id;
+
+DROP TABLE IF EXISTS blocks;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS blocks(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	blocker_id INTEGER NOT NULL,
+	blocked_id INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (blocker_id, blocked_id),
+	FOREIGN KEY (blocker_id)
+		REFERENCES users (id),
+	FOREIGN KEY (blocked_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS blocks_blocked_id ON blocks (blocked_id);
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP INDEX IF EXISTS reports_pending;
+
+DROP INDEX IF EXISTS reports_status;
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE messages DROP COLUMN hidden;
+
+ALTER TABLE comments DROP COLUMN hidden;
+
+ALTER TABLE posts DROP COLUMN hidden;
+
+ALTER TABLE users DROP COLUMN suspended_at;
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,52 @@
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users
+ADD COLUMN suspended_at DATETIME;
+
+-- hidden content stays in the database so that a moderator can restore it
+ALTER TABLE posts
+ADD COLUMN hidden BOOL NOT NULL DEFAULT false;
+
+ALTER TABLE comments
+ADD COLUMN hidden BOOL NOT NULL DEFAULT false;
+
+ALTER TABLE messages
+ADD COLUMN hidden BOOL NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS reports(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	reporter_id INTEGER NOT NULL,
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	-- the author of the reported content, or the user or group creator reported
+	reported_user_id INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	details TEXT NOT NULL DEFAULT '',
+	-- the reported content as it was when the report was made
+	snapshot TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (reporter_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS reports_status ON reports (status, id);
+
+-- a user can only have one unhandled report on the same entity
+CREATE UNIQUE INDEX IF NOT EXISTS reports_pending ON reports (reporter_id, entity_type, entity_id)
+WHERE status IN ('open', 'reviewing');
+
+CREATE TABLE IF NOT EXISTS moderation_actions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	moderator_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	report_id INTEGER NOT NULL DEFAULT 0,
+	note TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (moderator_id)
+		REFERENCES users (id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS access_tokens(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_user ON access_tokens (user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_identities_user;
+
+DROP TABLE IF EXISTS user_identities;
+
+DROP TABLE IF EXISTS oidc_states;
@@ -0,0 +1,29 @@
+-- pending authorization requests, the state is only stored hashed
+CREATE TABLE IF NOT EXISTS oidc_states(
+	id INTEGER PRIMARY KEY,
+	state_hash TEXT NOT NULL UNIQUE,
+	provider TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	nonce TEXT NOT NULL,
+	link_user_id INTEGER NOT NULL DEFAULT 0,
+	reauth BOOLEAN NOT NULL DEFAULT false,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_identities(
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	last_login_at DATETIME,
+	-- a login that confirms a request of an account without a password, it is used once
+	reauthenticated_at DATETIME,
+	UNIQUE(provider, subject),
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user ON user_identities (user_id);
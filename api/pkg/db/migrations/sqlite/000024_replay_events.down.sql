@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS replay_events_created;
+
+DROP TABLE IF EXISTS replay_events;
+
+DROP TABLE IF EXISTS replay_sequences;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS replay_sequences(
+	user_id INTEGER PRIMARY KEY,
+	seq INTEGER NOT NULL,
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS replay_events(
+	user_id INTEGER NOT NULL,
+	seq INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	data TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, seq),
+	FOREIGN KEY (user_id)
+		REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS replay_events_created ON replay_events (created_at);
@@ -83,6 +83,11 @@ func SeedUsers(repo *models.UserRepository) {
 
 		if err != nil {
 			logger.Println(err)
+			continue
+		}
+
+		if err = repo.SetEmailVerified(id); err != nil {
+			logger.Println(err)
 		}
 
 	}
@@ -11,9 +11,9 @@ import (
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 )
 
-// The OpenDB() function returns a sql.DB connection pool.
-func OpenDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "file:database.db")
+// The OpenDB() function returns a sql.DB connection pool for the database file at path.
+func OpenDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path)
 	if err != nil {
 		return nil, err
 	}
@@ -29,8 +29,29 @@ func OpenDB() (*sql.DB, error) {
 	return db, nil
 }
 
+// requireFTS5 fails when the sqlite driver was compiled without full-text search,
+// the search migration cannot run on such a build.
+func requireFTS5(db *sql.DB) error {
+	var enabled bool
+
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return fmt.Errorf("sqlite was built without FTS5, build the server with -tags sqlite_fts5")
+	}
+
+	return nil
+}
+
 // Run migrate scripts to create database if not created before.
 func RunMigrateScripts(db *sql.DB) error {
+	if err := requireFTS5(db); err != nil {
+		return err
+	}
+
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
 		return fmt.Errorf("creating sqlite3 db driver failed %s", err)
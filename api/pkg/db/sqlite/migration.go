@@ -16,6 +16,38 @@
 // api/pkg/db/migrations/sqlite/000007_update_events.up.sql
 // api/pkg/db/migrations/sqlite/000008_add_seed.down.sql
 // api/pkg/db/migrations/sqlite/000008_add_seed.up.sql
+// api/pkg/db/migrations/sqlite/000009_session_lifecycle.down.sql
+// api/pkg/db/migrations/sqlite/000009_session_lifecycle.up.sql
+// api/pkg/db/migrations/sqlite/000010_reactions.down.sql
+// api/pkg/db/migrations/sqlite/000010_reactions.up.sql
+// api/pkg/db/migrations/sqlite/000011_comment_replies.down.sql
+// api/pkg/db/migrations/sqlite/000011_comment_replies.up.sql
+// api/pkg/db/migrations/sqlite/000012_mentions.down.sql
+// api/pkg/db/migrations/sqlite/000012_mentions.up.sql
+// api/pkg/db/migrations/sqlite/000013_tags.down.sql
+// api/pkg/db/migrations/sqlite/000013_tags.up.sql
+// api/pkg/db/migrations/sqlite/000014_search.down.sql
+// api/pkg/db/migrations/sqlite/000014_search.up.sql
+// api/pkg/db/migrations/sqlite/000015_password_resets.down.sql
+// api/pkg/db/migrations/sqlite/000015_password_resets.up.sql
+// api/pkg/db/migrations/sqlite/000016_email_verification.down.sql
+// api/pkg/db/migrations/sqlite/000016_email_verification.up.sql
+// api/pkg/db/migrations/sqlite/000017_two_factor.down.sql
+// api/pkg/db/migrations/sqlite/000017_two_factor.up.sql
+// api/pkg/db/migrations/sqlite/000018_login_throttle.down.sql
+// api/pkg/db/migrations/sqlite/000018_login_throttle.up.sql
+// api/pkg/db/migrations/sqlite/000019_account_deletion.down.sql
+// api/pkg/db/migrations/sqlite/000019_account_deletion.up.sql
+// api/pkg/db/migrations/sqlite/000020_blocks.down.sql
+// api/pkg/db/migrations/sqlite/000020_blocks.up.sql
+// api/pkg/db/migrations/sqlite/000021_moderation.down.sql
+// api/pkg/db/migrations/sqlite/000021_moderation.up.sql
+// api/pkg/db/migrations/sqlite/000022_access_tokens.down.sql
+// api/pkg/db/migrations/sqlite/000022_access_tokens.up.sql
+// api/pkg/db/migrations/sqlite/000023_oidc.down.sql
+// api/pkg/db/migrations/sqlite/000023_oidc.up.sql
+// api/pkg/db/migrations/sqlite/000024_replay_events.down.sql
+// api/pkg/db/migrations/sqlite/000024_replay_events.up.sql
 // DO NOT EDIT!
 
 package database
@@ -403,6 +435,646 @@ func _000008_add_seedUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __000009_session_lifecycleDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x2d\x4e\x2d\x8a\x2f\x4e\x2d\x2e\xce\xcc\xcf\x2b\x8e\x07\xf3\x32\x53\xac\xb9\xb8\x1c\x7d\x42\x5c\x83\x14\x42\x1c\x9d\x7c\x5c\x51\xd5\x28\xb8\x80\x8c\x71\xf6\xf7\x09\xf5\xf5\x53\xc8\x2c\x88\x4f\x4c\x49\x29\x02\x4a\x11\xad\x05\x2c\x93\x98\x9e\x9a\x57\x42\xb4\x96\x9c\xc4\xe2\x12\xa0\x4c\x6a\x5e\x7c\x22\x50\x13\x00\x18\x85\x95\x6c\xc7\x00\x00\x00")
+
+func _000009_session_lifecycleDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000009_session_lifecycleDownSql,
+		"000009_session_lifecycle.down.sql",
+	)
+}
+
+func _000009_session_lifecycleDownSql() (*asset, error) {
+	bytes, err := _000009_session_lifecycleDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000009_session_lifecycle.down.sql", size: 199, mode: os.FileMode(420), modTime: time.Unix(1792316397, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000009_session_lifecycleUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x8f\xb1\x0a\x83\x30\x10\x86\xf7\x3c\xc5\x6d\xb6\xcf\x20\x1d\x52\x73\x42\x20\xc6\xa2\x17\x70\x0b\xa1\x09\x45\x10\x5b\x4c\xfa\xfe\x8d\xd2\xc5\x0e\xc5\xe1\x86\x9f\x8f\xff\xbb\x3b\xae\x08\x3b\x20\x7e\x55\x08\xef\x18\x16\x1b\x43\x8c\xe3\x73\x8e\x8c\x0b\x01\x55\xab\x4c\xa3\x61\x72\x31\x65\x10\x66\xeb\x12\x08\x4e\x48\xb2\xc1\x92\x31\x7e\xa4\xbc\x01\xf7\x08\x73\x02\xc2\x81\x40\xb7\x79\x8c\x52\x20\xb0\xe6\x46\x11\x14\xc5\x51\xd5\xf8\xb2\xce\xfb\x25\x93\x7f\x2a\x73\x5b\x4f\xdc\x5b\xa0\x47\xda\xbf\x71\x81\xfb\x12\x5c\x0a\x3e\x87\x5c\xaa\x3a\x5c\x4b\x52\x0b\x1c\x40\xd6\x9b\x1a\x07\xd9\x53\xbf\x17\xd9\x2d\x8d\x1e\x5a\xfd\xb3\xe1\xf4\x25\xe7\x92\x7d\x00\x72\x2d\x89\xda\x55\x01\x00\x00")
+
+func _000009_session_lifecycleUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000009_session_lifecycleUpSql,
+		"000009_session_lifecycle.up.sql",
+	)
+}
+
+func _000009_session_lifecycleUpSql() (*asset, error) {
+	bytes, err := _000009_session_lifecycleUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000009_session_lifecycle.up.sql", size: 341, mode: os.FileMode(420), modTime: time.Unix(1792316397, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000010_reactionsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4a\x4d\x4c\x2e\xc9\xcc\xcf\x2b\x8e\x4f\xce\xcf\xcd\x4d\xcd\x2b\x89\xcf\x4c\xb1\xe6\xe2\x72\xc1\xaf\xb6\x20\xbf\x98\x38\x85\xa5\xc5\xa9\x45\x30\x93\x89\x54\x0d\x32\x1b\xa6\x34\xc4\xd1\xc9\xc7\x15\x9b\x52\x42\x0a\xe2\x4b\x2a\x0b\x52\x81\xaa\x00\x44\x4d\x3f\xd3\xf4\x00\x00\x00")
+
+func _000010_reactionsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000010_reactionsDownSql,
+		"000010_reactions.down.sql",
+	)
+}
+
+func _000010_reactionsDownSql() (*asset, error) {
+	bytes, err := _000010_reactionsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000010_reactions.down.sql", size: 244, mode: os.FileMode(420), modTime: time.Unix(1792317095, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000010_reactionsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\x52\x49\x6f\x82\x40\x14\x3e\x33\xbf\xe2\xc5\xd3\x90\x70\xe9\x76\xb2\x69\x42\xf1\xa9\x13\x11\xdb\x61\x68\xf5\x44\x88\x10\x4b\x5a\xc1\x08\x36\xf1\xdf\x77\x06\x41\x47\x5c\xda\xf4\x36\x6f\xfd\x96\x37\x0e\x47\x5b\x20\x08\xfb\xd9\x45\x60\x7d\xf0\x26\x02\x70\xca\x7c\xe1\xc3\x3a\x89\xe6\x65\x9a\x67\x61\xb9\x5d\x25\x05\x25\x46\x1a\x03\xf3\x04\x0e\x90\xc3\x0b\x67\x63\x9b\xcf\x60\x84\x33\x8b\x18\x59\xb4\x4c\x40\xe0\x54\x54\xe3\x5e\xe0\xba\x10\x78\xec\x35\x40\x62\x76\x09\x61\x9e\x8f\x5c\xa8\xd1\x49\x6b\x27\xd0\x34\xb6\x40\x4d\x9b\xe4\xcd\x76\x03\xf4\x09\xbd\xb1\xa0\xf3\x95\x7e\x26\x1d\xd3\x22\xf4\x56\x05\xf9\xf7\x2e\xb8\x53\x41\xb4\x59\x7c\x54\xd1\xbd\x8c\x8a\x28\xae\xde\x0f\xf2\x1d\x65\x8b\xf5\xb6\xa3\xf0\x9c\xdf\x25\x5d\x55\xb3\x29\x92\x75\xa8\x55\x1b\x4d\xb2\xb4\xca\x8b\x52\x2b\xc9\xcc\x3c\x5f\x2e\x93\xac\x95\x3c\x52\x79\x61\xd5\x5c\x36\x95\x49\x1c\x46\x25\xf4\x24\x5d\xc1\xc6\xa8\x97\x9d\x21\x3a\x23\xa0\x74\x8f\xe8\x57\x15\x13\x1e\x9f\x80\xea\xa0\x75\x5e\xda\x60\xf4\x27\x1c\xd9\xc0\x53\x32\x80\xd6\x2a\x4c\x62\x18\x1c\xfb\xc8\xd1\x73\xd0\x07\x95\xad\x6c\x37\x5b\xed\x35\x4e\xab\x5d\x65\xcf\xb6\x1f\x18\xb4\x26\xea\xc2\xd9\xa1\xb6\x2d\xad\xd1\xd3\xbf\x61\x12\xed\x9e\xbb\x1f\x25\xad\xec\xe1\xf4\xd2\x59\xc3\x4a\xb5\x62\x0d\x13\xef\x90\xde\xbb\x61\x41\xa3\x13\xde\x87\x12\x17\x74\x7b\x6b\xf3\xff\x83\x58\xab\xbe\x04\xaa\xb9\x55\xe3\xb6\x2e\x78\x02\x7d\x1d\xb3\x61\x7d\x0c\xd7\x48\xfb\xeb\x16\x8d\xc3\xf1\x22\x8d\x6e\x97\xfc\x00\x8d\xb6\x99\xc4\x21\x04\x00\x00")
+
+func _000010_reactionsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000010_reactionsUpSql,
+		"000010_reactions.up.sql",
+	)
+}
+
+func _000010_reactionsUpSql() (*asset, error) {
+	bytes, err := _000010_reactionsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000010_reactions.up.sql", size: 1057, mode: os.FileMode(420), modTime: time.Unix(1792317095, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000011_comment_repliesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xce\xcf\xcd\x4d\xcd\x2b\x29\x8e\x2f\x48\x2c\x02\xd2\xf1\x50\x7e\x7c\x66\x8a\x35\x17\x97\x8b\xab\x8f\x6b\x88\xab\x82\x5b\x90\xbf\x2f\x5c\xa1\x42\xb8\x87\x6b\x90\xab\x02\x86\x72\x05\xcf\x60\x05\x3f\xff\x10\x05\xbf\x50\x1f\x1f\xa0\x56\x47\x9f\x10\xd7\x20\x85\x10\x47\x27\x1f\x57\x84\x56\x17\x90\x0b\x9c\xfd\x7d\x42\x7d\xfd\x14\x52\x52\x0b\x4a\x32\x88\x51\x88\xc5\x61\x00\x6f\x5b\xd5\x1a\xca\x00\x00\x00")
+
+func _000011_comment_repliesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000011_comment_repliesDownSql,
+		"000011_comment_replies.down.sql",
+	)
+}
+
+func _000011_comment_repliesDownSql() (*asset, error) {
+	bytes, err := _000011_comment_repliesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000011_comment_replies.down.sql", size: 202, mode: os.FileMode(420), modTime: time.Unix(1792317273, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000011_comment_repliesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x8e\x31\x0b\xc2\x30\x10\x85\xf7\xfe\x8a\x1b\x75\x73\x77\x3a\x93\x8b\x04\xce\x0b\xa4\x17\xe8\x16\xc4\x14\xec\x50\x2d\xda\xff\x8f\x41\xc4\x0a\x1d\x5c\xef\xbd\xf7\xdd\x87\xac\x14\x41\xf1\xc0\x04\x97\xfb\x38\xf6\xb7\xf9\x09\x68\x2d\x98\xc0\xe9\x24\x30\x9d\x1f\xf5\x94\x3f\x51\x1e\x0a\x78\x51\x3a\xd6\x4d\x24\x47\x91\xc4\x50\xbb\x0c\x37\x43\xd9\xee\x9b\x06\xff\x40\x4b\x3f\xcd\xd7\x2f\x48\x82\x82\x24\x66\xb0\xe4\x30\xb1\xc2\xae\x22\x4c\x24\x54\xaa\x1d\x4b\x1d\x78\xf7\x2e\x51\xe7\x5b\x5d\xbe\xe5\xb5\x5b\x90\x1f\x97\x55\x5c\xd5\x5e\xcd\x93\xf4\xa6\xef\x00\x00\x00")
+
+func _000011_comment_repliesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000011_comment_repliesUpSql,
+		"000011_comment_replies.up.sql",
+	)
+}
+
+func _000011_comment_repliesUpSql() (*asset, error) {
+	bytes, err := _000011_comment_repliesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000011_comment_replies.up.sql", size: 239, mode: os.FileMode(420), modTime: time.Unix(1792317272, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000012_mentionsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x71\xf5\x71\x0d\x71\x55\x70\x0b\xf2\xf7\x55\xc8\xcb\x2f\xc9\x4c\xcb\x4c\x4e\x2c\xc9\xcc\xcf\x2b\x56\x08\xf7\x70\x0d\x72\x45\x11\x8b\x4f\x49\x2d\x49\xcc\xcc\x29\x8e\xcf\x4c\x51\xf0\xf4\xe3\xe2\xd4\x08\x06\x6a\x76\x0e\x51\x00\x72\x31\xf4\xc3\xd4\x62\x33\xa6\xa4\xb2\x20\x15\x64\x86\xad\x82\x89\xa6\x35\x17\x97\x0b\x0e\x27\x10\x69\x04\x3e\x13\x40\xca\x60\xfa\x11\xaa\x83\xfc\x03\x80\xee\x77\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\xc8\x4d\xcd\x03\x7b\x3b\xbe\xb4\x38\xb5\x28\x1e\xc4\x29\xa9\x84\xa9\x0d\x71\x74\xf2\x71\xc5\xa2\xd6\x9a\x0b\x00\x10\xe7\x4f\x7b\x3e\x01\x00\x00")
+
+func _000012_mentionsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000012_mentionsDownSql,
+		"000012_mentions.down.sql",
+	)
+}
+
+func _000012_mentionsDownSql() (*asset, error) {
+	bytes, err := _000012_mentionsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000012_mentions.down.sql", size: 318, mode: os.FileMode(420), modTime: time.Unix(1792317375, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000012_mentionsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x91\x31\x6f\x83\x30\x10\x85\x67\xfc\x2b\x4e\x4c\x20\x79\xe8\xd0\xad\x13\x25\x47\x64\x95\x98\xd6\x98\x2a\x99\x10\x0a\x6e\xc5\x00\x44\xb1\x3b\xe4\xdf\xd7\x0e\x14\x08\x4a\xa4\x6e\xf6\x7b\xf7\xec\xef\xee\x62\x81\x91\x44\x90\xd1\x6b\x8a\xc0\x12\xe0\x99\x04\xdc\xb3\x5c\xe6\xd0\xaa\xce\x34\x7d\xa7\x03\xe2\x35\x35\x30\x2e\x71\x8b\x02\xde\x05\xdb\x45\xe2\x00\x6f\x78\xa0\xc4\xfb\xd1\xea\x5c\x2e\x5c\x17\xe7\x45\x9a\x5a\x4b\xab\xae\x7e\x68\x9e\x7a\x6d\x16\x96\x55\x8e\x7d\xeb\x3e\xbc\x15\x5b\xa5\x75\xf5\xad\x56\x95\x67\x55\x19\x55\x97\x95\x81\x8d\x65\x97\x6c\x87\xcb\xa7\x93\x4c\x20\xdb\x72\x07\x08\xc1\xc8\x17\x12\xcf\x13\x98\xa0\x40\x1e\x63\x0e\x4e\xd5\x10\x5c\xf5\x9b\xf2\x89\xf9\xbf\x81\xb1\x8f\x55\xb9\x53\xef\x96\xcf\x4d\xae\x12\xa3\x71\x37\x34\x0f\x61\x15\x1a\x8d\x31\x14\xbe\x10\x12\x0f\xdb\x2c\x38\xfb\x28\xec\x3a\xf9\x06\xf7\x0f\x96\x5a\x5e\x27\xe3\x2e\xe6\x02\x19\x9f\xf4\x69\x64\xd4\x06\xdd\x44\xff\x5a\xa4\xf0\x14\x4e\xda\xdc\xc7\x8d\x3c\x93\x3a\xd9\x01\x31\x9e\xa3\x90\x6e\x79\x19\x74\xbd\x69\xbe\x9a\x63\xe5\xfe\x29\xcd\xe5\x34\x80\x53\xe8\xaa\x56\x51\x18\x50\x42\xf2\x19\xa5\x05\xe6\x24\x78\xa6\xe0\x8f\x50\xfe\x7c\xd4\xbe\x7d\xf5\x17\x7a\x9b\xdd\x1b\xb4\x02\x00\x00")
+
+func _000012_mentionsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000012_mentionsUpSql,
+		"000012_mentions.up.sql",
+	)
+}
+
+func _000012_mentionsUpSql() (*asset, error) {
+	bytes, err := _000012_mentionsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000012_mentions.up.sql", size: 692, mode: os.FileMode(420), modTime: time.Unix(1792317375, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000013_tagsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\xc8\x2f\x2e\x89\x2f\x49\x4c\x2f\x06\x11\xf1\x20\x9e\x35\x17\x97\x0b\x7e\x95\x30\x16\x4c\x65\x88\xa3\x93\x8f\x2b\x36\x95\xb8\x14\x40\xe4\x00\x71\x43\xdc\x88\x90\x00\x00\x00")
+
+func _000013_tagsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000013_tagsDownSql,
+		"000013_tags.down.sql",
+	)
+}
+
+func _000013_tagsDownSql() (*asset, error) {
+	bytes, err := _000013_tagsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000013_tags.down.sql", size: 144, mode: os.FileMode(420), modTime: time.Unix(1792317849, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000013_tagsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x8f\xcd\x0a\x83\x30\x10\x84\xcf\xc9\x53\xec\x51\xc1\x37\xf0\x64\xed\x28\xa1\x36\xb6\x31\x82\x9e\x44\xa8\x94\x1e\xfa\x03\xfa\xfe\xd4\x18\xad\x56\xb0\xbd\x25\x3b\xb3\xdf\xec\x84\x0a\x81\x06\xe9\x60\x97\x80\x44\x44\x32\xd5\x84\x42\x64\x3a\xa3\xae\xbe\xb6\x0e\x67\xb7\x0b\x09\xa9\x11\x43\xd1\x49\x89\x63\xa0\x4a\x3a\xa0\xf4\x38\x7b\xd4\xf7\x86\x34\x0a\x3d\x2c\xc9\x3c\x49\x28\x97\xe2\x9c\x83\xbb\x3e\xe7\xe1\x36\xf8\xf5\x6c\xbb\xea\x1f\x7d\x30\x2d\xd4\x29\xa3\x97\xfa\xd5\x0d\x25\x4a\x15\x44\x2c\x0d\x82\x9c\x91\xe0\x72\xc6\x14\x22\x28\xc8\x10\x36\xbc\x25\x67\x98\x7f\xd9\x2d\x75\xe5\x36\x57\x5a\xf3\xa2\x93\x6d\xd9\xe7\xef\x51\x6c\x55\xab\xa6\x17\xa5\x72\x9e\x7e\x8e\xf2\x68\x8c\x9b\xa9\xbf\x71\xc6\x6e\x7e\x2b\x9c\xa5\x78\x34\x75\xf5\xf9\x1b\xb9\x64\x2b\xec\xd1\x01\x00\x00")
+
+func _000013_tagsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000013_tagsUpSql,
+		"000013_tags.up.sql",
+	)
+}
+
+func _000013_tagsUpSql() (*asset, error) {
+	bytes, err := _000013_tagsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000013_tags.up.sql", size: 465, mode: os.FileMode(420), modTime: time.Unix(1792317849, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000014_searchDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x09\xf2\x74\x77\x77\x0d\x52\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x2d\x4e\x2d\x2a\x8e\x4f\x2b\x29\x8e\x2f\x2d\x48\x49\x2c\x49\xb5\xe6\x72\x21\xa4\x30\x25\x35\x27\x95\x28\x85\x99\x79\x40\x66\x09\x4e\x85\xe9\x45\xf9\xa5\x05\x44\xd9\x8d\xa4\x92\x80\xe5\x48\x2a\x09\xd8\x9e\x9c\x9f\x9b\x9b\x9a\x57\x42\x94\xfd\x28\x6a\x09\xb8\x00\x45\x2d\x01\x37\x14\xe4\x17\x13\xe7\x00\x84\x42\x02\xb6\x23\x14\xc2\xac\x86\xaa\x74\x74\xf2\x71\xc5\x16\x49\xd6\xd8\xe5\x11\xe1\x88\x43\x01\xb2\x37\x71\x28\x81\xbb\xc5\x9a\x0b\x00\x11\x75\xa4\xcf\x7d\x02\x00\x00")
+
+func _000014_searchDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000014_searchDownSql,
+		"000014_search.down.sql",
+	)
+}
+
+func _000014_searchDownSql() (*asset, error) {
+	bytes, err := _000014_searchDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000014_search.down.sql", size: 637, mode: os.FileMode(420), modTime: time.Unix(1792317975, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000014_searchUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcd\x96\x4d\x6f\xa3\x30\x10\x86\xcf\xe5\x57\xcc\x8d\x54\x8a\x56\xea\x4a\xbb\x97\x2a\x07\xda\x38\x11\x52\x44\x2a\x70\xaa\xde\xa2\x2c\x76\x57\x56\x13\x1c\x81\x69\xa5\xfe\xfa\xda\x80\x0d\xd5\xda\x0b\xfd\xa2\x39\x31\x36\xf3\xe2\x67\x98\x57\xb6\xaf\x63\x14\x60\x04\xb7\x61\x8c\x37\xc1\x0a\x70\x70\xb5\x42\x10\x2e\x20\x5a\x63\x40\x77\x61\x82\x13\x38\xf2\x42\x14\xdb\x7b\x51\xc0\x26\x09\xa3\x25\xc8\xe8\xd7\xc4\x3b\x4b\x79\x26\x68\x26\xa6\x26\x9a\xf9\x55\xa6\xdf\xce\x6c\x73\xfe\xc4\xc8\xcc\x67\x44\x4d\x0a\xfe\x40\x33\xf6\x4c\x67\x7e\x99\xb1\x94\x13\xfa\xfb\x02\x72\x7a\xe0\x8f\x74\x4b\xd8\x2e\xcd\x99\x60\x69\x01\x3f\x7d\xef\xfc\xd2\xf3\xae\xfb\xb9\x52\x7e\x38\xc8\x45\x86\xa1\xe9\xe4\xd1\xe8\xfe\xe6\xbc\x3c\x5a\xd8\x04\x13\x7b\x2a\xd7\x23\xb4\x90\x1f\x3d\x0a\xc6\xb3\x2e\x67\x2d\x1b\x8d\xb2\x2c\x68\x6e\x81\xbc\xe7\x79\xb6\x3b\x28\xcc\xa2\xd4\x91\x5c\xef\xa1\x09\x0d\x6d\x25\xff\x54\x58\x1c\x87\xcb\x25\x8a\x5d\x16\xdc\xb2\x4c\x2e\x29\x20\x58\x60\x95\x14\x25\x28\xc6\xb0\x8e\xea\x04\xb8\x42\xcb\x30\xf2\xce\x9a\xe9\x30\xc2\xeb\x8e\x79\x27\x15\xdb\x14\x1a\xd4\x73\xb8\x0d\x56\x1b\x94\xc0\x24\xa3\x4f\x3f\xd4\x0b\xf5\xd4\x2f\x2f\x3d\x14\xcd\x87\x53\x11\xba\xa7\x82\x36\x54\x73\xb4\x42\x52\x33\x90\xca\x84\x53\x70\x01\xfa\xf5\xe7\xfd\x29\xf0\x3d\xa9\x50\xd5\xf3\x9d\xa8\xe5\x91\xec\x0c\xea\xe6\x66\xae\x34\xeb\x85\x5e\x74\x44\xea\xd1\xda\xd4\xdd\x27\x5c\xfe\xd1\x39\xb6\xb2\x5f\xed\x33\x5f\x8c\xe7\x30\xd2\x70\xbc\xee\xe8\x4b\x1d\xf5\x0a\xbb\xdf\x54\x63\x57\x30\x66\x07\xdb\xad\xde\x65\xaf\x3a\xc3\x56\x7b\xe7\x98\xd0\x60\xf5\x09\x01\x9d\x03\xc2\xce\xd8\xe4\xa9\xb0\x9b\xfb\x56\x64\x87\xe5\x86\x21\xb7\xb1\x69\xd5\xff\xf0\xed\x5d\x6b\x14\x2a\xfc\x48\x21\x0e\x13\xfe\xcb\x73\x2a\xe5\x9d\x82\x11\xcc\x05\xc0\x65\xdd\x2a\xc1\xf6\x9f\xda\xab\x83\xe6\xd5\xb7\x06\xd0\x97\x06\xd0\x77\x06\x3b\xb7\xc9\x57\x83\x56\x23\x07\x46\xf7\xc6\x1a\x1c\x5e\x1e\x52\x83\x09\x4d\xa7\x87\x94\x63\xef\xb8\x51\xaa\x81\x51\xab\xc1\x7b\x0b\x73\x78\xdb\x8d\x78\xb2\x65\x7f\x9f\x87\xfa\xae\x32\x9d\xf2\x72\xfa\xa7\x64\x7b\xe2\x4b\xed\xb0\x83\xaa\x5f\x6b\xdf\x56\xfa\x75\xb6\x5e\xd9\x55\x2f\x65\xc4\x8a\xac\xcb\x0d\x00\x00")
+
+func _000014_searchUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000014_searchUpSql,
+		"000014_search.up.sql",
+	)
+}
+
+func _000014_searchUpSql() (*asset, error) {
+	bytes, err := _000014_searchUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000014_search.up.sql", size: 3531, mode: os.FileMode(420), modTime: time.Unix(1792317975, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000015_password_resetsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x48\x2c\x2e\x2e\xcf\x2f\x4a\x89\x2f\x4a\x2d\x4e\x2d\x01\x92\x85\xa5\xa9\xc5\x25\xc5\xd6\x5c\x5c\x2e\x20\xe5\x9e\x7e\x2e\xae\x11\x38\x95\x17\xc7\x97\x16\xa7\x16\xc1\xd4\xe2\x37\x1a\x68\x24\x00\x73\x8d\x64\x3a\x81\x00\x00\x00")
+
+func _000015_password_resetsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000015_password_resetsDownSql,
+		"000015_password_resets.down.sql",
+	)
+}
+
+func _000015_password_resetsDownSql() (*asset, error) {
+	bytes, err := _000015_password_resetsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000015_password_resets.down.sql", size: 129, mode: os.FileMode(420), modTime: time.Unix(1792326294, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000015_password_resetsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x52\x41\x6e\xc2\x30\x10\x3c\x27\xaf\x18\xe5\x14\x24\x78\x41\x4f\x14\x16\x64\x15\x0c\x35\x46\x82\x53\xe4\x26\x96\x88\x92\x26\x69\x1c\xd4\xf6\xf7\xdd\x84\x42\xa1\x40\x7b\xb1\xe4\x99\xdd\xd9\xf1\xac\x47\x8a\x86\x9a\xa0\x87\x8f\x33\x82\x98\x40\x2e\x34\x68\x23\x56\x7a\x85\xca\x38\xf7\x5e\xd6\x49\x54\x5b\x67\x1b\x17\xfa\x5e\x9a\x40\x48\x4d\x53\x52\x58\x2a\x31\x1f\xaa\x2d\x9e\x68\xdb\xf7\xbd\xbd\xb3\x75\x74\xc6\xb6\x2a\x72\x3d\x9b\x31\xd5\x94\x99\x2d\xa2\x9d\x71\x3b\x68\xda\xe8\x13\x85\xb5\x14\xcf\x6b\xe2\x8a\xb8\xb6\xa6\xb1\x49\x64\x1a\x8c\xd9\x8b\x16\x73\x3a\x17\xb0\x1f\x55\xca\x0e\xee\xd1\x3c\xfa\xa2\x95\xa1\xc9\x42\x91\x98\xca\xd6\x1b\xc2\x6f\x6b\x3d\xdf\xf3\x14\x4d\x48\x91\x1c\xd1\x0a\x2d\xea\x10\xb6\x78\xef\xc1\xf7\x47\x87\x14\x84\x1c\xd3\xe6\xef\x14\xa2\xb6\x13\x0b\xf9\x1b\xff\x19\xc4\x72\x83\x01\x3a\x94\xcf\xb7\xbd\x75\xcc\x56\xdc\x64\x5f\x4d\x9a\xc3\x14\x49\x77\x8b\xf3\xd4\x16\x0d\xc4\xb2\x0f\x17\x97\x95\x45\xea\x10\x74\x25\x01\xca\x1a\x41\x5a\x05\x47\x5b\xff\x2f\x27\x3a\x0e\xe2\x25\x1d\xd4\x2e\xb2\xe6\x4c\x32\xfb\x79\x85\x9d\xdc\xdd\x58\x5b\x6e\xdc\x49\xf5\x5e\xf4\x2f\x79\x19\x67\x9c\xfe\xbe\x68\xf8\x61\xb7\x2a\xce\x7e\x09\xc2\xce\x58\x1f\xec\xa4\x0b\xfd\x0b\x2c\x5f\x00\x4d\x7a\x02\x00\x00")
+
+func _000015_password_resetsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000015_password_resetsUpSql,
+		"000015_password_resets.up.sql",
+	)
+}
+
+func _000015_password_resetsUpSql() (*asset, error) {
+	bytes, err := _000015_password_resetsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000015_password_resets.up.sql", size: 634, mode: os.FileMode(420), modTime: time.Unix(1792326301, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000016_email_verificationDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xcd\x4d\xcc\xcc\x89\x2f\x4b\x2d\xca\x4c\xcb\x4c\x4e\x2c\xc9\xcc\xcf\x2b\x8e\x2f\x2d\x4e\x2d\xb2\xe6\xe2\x72\x01\xa9\x0f\x71\x74\xf2\x71\xc5\xaf\x1e\xa8\xd4\xd1\x27\xc4\x35\x08\xaa\x16\xa4\xbb\x58\x01\xac\xd9\xd9\xdf\x27\xd4\xd7\x0f\x45\x4f\x6a\x8a\x35\x17\x00\xb1\x86\x4f\xc3\x89\x00\x00\x00")
+
+func _000016_email_verificationDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000016_email_verificationDownSql,
+		"000016_email_verification.down.sql",
+	)
+}
+
+func _000016_email_verificationDownSql() (*asset, error) {
+	bytes, err := _000016_email_verificationDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000016_email_verification.down.sql", size: 137, mode: os.FileMode(420), modTime: time.Unix(1792318317, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000016_email_verificationUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x90\xc1\x6e\x83\x30\x10\x44\xcf\xf8\x2b\xf6\x48\xa4\xe6\x0b\xa2\x1e\x08\x2c\x91\x55\x63\x52\x63\x24\x72\x42\x2e\x31\x8a\xd5\x14\x2a\x6c\xaa\x7c\x7e\x71\xa1\x4a\x5a\x35\xea\xcd\xf2\xcc\xee\xbc\xd9\x88\x49\x14\x20\xa3\x2d\x43\x18\xad\x1e\x2c\x89\x92\x04\xe2\x9c\x95\x19\x07\xfd\xa6\xcc\xb9\xfe\xd0\x83\x69\x8d\x3e\xc2\x36\xcf\x19\xf0\x5c\x02\x2f\x19\x83\x04\xd3\xa8\x64\x12\x5a\x75\xb6\x7a\x43\xc8\x7a\x0d\xaa\x69\xfa\xb1\x73\x16\x9a\x41\x2b\x37\x4d\xbc\xe8\xb6\x1f\x34\xcc\x1b\x1a\xe5\x4c\xdf\x81\xbe\x18\xeb\x35\x35\x09\x6e\x18\xfd\x9b\x94\xfb\x24\x92\x0b\x00\x14\x28\x7f\x27\x3f\x7a\xa7\x0f\x89\x05\x7a\xe3\xcc\x4b\xd3\x2f\x1a\xac\x68\x21\x8b\x1f\x23\x73\x94\x0d\x49\x60\x8e\x40\xb9\xc4\xdd\x54\x72\x2f\x68\x16\x89\x03\x3c\xe1\xe1\x81\x04\x3e\xab\xbe\x51\xbf\x7b\x4d\x92\xeb\x5f\x75\x57\x9f\x94\x3d\x81\xc4\x4a\x5e\x2b\x97\x9c\x3e\x97\x38\x39\x96\x7e\xb5\x72\xe0\xc1\x25\xcd\xf0\x76\x81\xbe\xbc\x9b\x41\xdb\x7b\x72\x9a\x0b\xa4\x3b\xee\x41\x20\x5c\x38\x56\x24\x08\x04\xa6\x28\x90\xc7\x58\x2c\x97\x08\xfd\xff\xea\x5a\x9b\xf2\x04\xab\xff\x6b\xd7\x7e\x1a\x72\xfe\x97\x76\x0d\xdc\x90\x4f\x2e\xb3\xac\xc4\xfc\x01\x00\x00")
+
+func _000016_email_verificationUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000016_email_verificationUpSql,
+		"000016_email_verification.up.sql",
+	)
+}
+
+func _000016_email_verificationUpSql() (*asset, error) {
+	bytes, err := _000016_email_verificationUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000016_email_verification.up.sql", size: 508, mode: os.FileMode(420), modTime: time.Unix(1792318317, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000017_two_factorDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\xc8\xc9\x4f\xcf\xcc\x8b\x4f\xce\x48\xcc\xc9\x49\xcd\x4b\x4f\x2d\xb6\xe6\xe2\x72\x01\xa9\xf3\xf4\x73\x71\x8d\x40\x52\x57\x94\x9a\x9c\x5f\x96\x5a\x54\x19\x9f\x9c\x9f\x92\x5a\x1c\x5f\x5a\x9c\x5a\x04\x53\x8a\x6e\x24\xaa\x52\x5c\xaa\x40\x26\xc4\x97\xe4\x97\x14\x58\x73\x01\x00\x60\x11\x8e\xe0\x99\x00\x00\x00")
+
+func _000017_two_factorDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000017_two_factorDownSql,
+		"000017_two_factor.down.sql",
+	)
+}
+
+func _000017_two_factorDownSql() (*asset, error) {
+	bytes, err := _000017_two_factorDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000017_two_factor.down.sql", size: 153, mode: os.FileMode(420), modTime: time.Unix(1792318468, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000017_two_factorUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\x92\x41\x4f\x83\x40\x10\x85\xcf\xf0\x2b\xe6\x48\x13\x0f\xde\x3d\x51\x18\x9a\x8d\x74\xd1\xed\x92\xd0\x13\xd9\xc2\x58\x88\x08\x84\x5d\x8d\xfe\x7b\x17\x34\x95\xb4\xb6\x07\xeb\x6d\xf3\x32\x3b\xef\xbd\x2f\x13\x08\xf4\x25\x82\xf4\x97\x31\x02\x8b\x80\x27\x12\x30\x63\x1b\xb9\x81\x57\x4d\x43\x6e\x3a\xd3\x7b\xae\x33\xbd\xeb\x12\x18\x97\xb8\x42\x01\x0f\x82\xad\x7d\xb1\x85\x7b\xdc\xde\xb8\x8e\xa6\x62\x20\x03\x12\x33\x39\x2d\xe0\x69\x1c\x5b\x99\x5a\xb5\x6b\xa8\x84\x65\x92\xc4\x07\x1d\x42\x8c\xfc\x34\x96\xf0\xa4\x1a\x4d\x76\xaa\x51\xda\xe4\x76\x7f\x99\x6b\x43\xfd\xc1\xe1\x64\xfe\xd6\xce\x5a\x1b\x65\xec\xa4\x32\x10\xda\xd8\x92\xad\x71\x6e\x18\x25\x02\xd9\x8a\x8f\xa9\xc0\xfb\x8e\xbc\x70\x1d\x47\x60\x84\x02\x79\x80\x5f\xa5\x34\x78\xa3\xbe\xb8\x73\xdd\xe0\x7c\xfd\x81\x8a\xee\x8d\x86\x8f\xbc\xe8\x4a\xd2\x96\xc1\xd9\xfa\xc7\x70\x66\x89\xc6\xbf\x79\xa5\x74\x75\x02\x67\x6a\x3c\xeb\x71\x4d\x7c\xc6\x43\xcc\x2e\xc6\x1f\x01\x0f\x90\xf0\x23\xf9\xc7\xe6\x32\x8b\xa6\xdb\xd7\x6d\x5e\x54\xaa\x69\xa8\xdd\xff\x99\x86\xe9\x9e\xa9\xfd\x05\x07\xa4\x9c\x3d\xa6\x23\x02\x65\x0c\xbd\xf4\x46\x5f\x75\x06\xf4\xde\xd7\x83\xed\xfc\x5f\x57\xf2\x09\xf6\xb0\x8d\x25\x24\x03\x00\x00")
+
+func _000017_two_factorUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000017_two_factorUpSql,
+		"000017_two_factor.up.sql",
+	)
+}
+
+func _000017_two_factorUpSql() (*asset, error) {
+	bytes, err := _000017_two_factorUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000017_two_factor.up.sql", size: 804, mode: os.FileMode(420), modTime: time.Unix(1792326475, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000018_login_throttleDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x71\xf5\x71\x0d\x71\x55\x70\x0b\xf2\xf7\x55\xc8\xcb\x2f\xc9\x4c\xcb\x4c\x4e\x2c\xc9\xcc\xcf\x2b\x56\x08\xf7\x70\x0d\x72\x45\x11\x8b\x4f\x49\x2d\x49\xcc\xcc\x29\x8e\xcf\x4c\x51\xf0\xf4\xe3\xe2\xd4\x08\x06\x6a\x76\x0e\x51\x00\x72\x31\xf4\xc3\xd4\x62\x33\xa6\xa4\xb2\x20\x15\x64\x86\xad\x82\xa9\xa6\x35\x17\x97\x0b\x0e\x27\x10\x69\x04\x3e\x13\x40\xca\x60\xfa\x11\xaa\x83\xfc\x03\x14\x42\x1c\x9d\x7c\x5c\x15\x3c\xdd\x14\x5c\x23\x3c\x83\x43\x82\x15\x72\xf2\xd3\x33\xf3\xe2\xd3\x80\xf6\x95\x16\xa5\x16\x5b\x73\x01\x00\x87\x61\x18\x5b\x18\x01\x00\x00")
+
+func _000018_login_throttleDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000018_login_throttleDownSql,
+		"000018_login_throttle.down.sql",
+	)
+}
+
+func _000018_login_throttleDownSql() (*asset, error) {
+	bytes, err := _000018_login_throttleDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000018_login_throttle.down.sql", size: 280, mode: os.FileMode(420), modTime: time.Unix(1792318783, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000018_login_throttleUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6d\x50\x3d\x6f\xc2\x30\x10\x9d\xf1\xaf\x78\xf2\x14\xa4\x30\x76\xea\x94\xb6\x6e\x65\x35\x04\x94\x98\x0a\xa6\xc8\x75\x4c\x65\x11\xec\x28\x36\x03\xff\xbe\x47\x22\x24\xa4\x76\xbc\x77\xef\xeb\x6e\xb5\xc2\x51\xbb\xde\x76\xe8\xc3\x8f\xf3\x11\x83\x1d\x61\xcf\x04\x41\xfb\x6e\x9a\x4c\xef\xac\x4f\x90\xdb\x1c\xd1\x84\xc1\xc2\x45\xf0\x89\xc2\x11\x46\x70\x37\x70\xf6\x5a\x8b\x42\x09\xa8\xe2\xa5\x14\x90\xef\xa8\x36\x0a\x62\x2f\x1b\xd5\xcc\xbe\xed\x2d\xe4\x32\xda\x98\xb1\xc5\x6c\xa2\xc4\x5e\x4d\xb4\x6a\x57\x96\x39\x5b\x9c\xec\xf5\x0f\x76\x17\x41\x56\x4a\x7c\x88\xfa\x71\xd7\xeb\x98\xee\xae\xad\x4e\x78\xa3\x7c\x25\xd7\xe2\x91\xf3\xdd\x07\x73\xb2\x5d\x7b\xf1\x89\xee\xf9\x8f\xb1\xad\xe5\xba\xa8\x0f\xf8\x14\x07\x64\x53\xb1\x1c\xd4\x64\xc9\x96\xcf\x8c\xc9\xaa\x11\xb5\xba\x85\x6f\xe0\x43\x72\x47\x67\x74\x72\xc1\xb7\xe9\x3a\x50\xa9\xcc\x75\x39\xbc\x3e\x93\x84\xfe\xe3\x12\xa9\xbe\x8a\x72\x27\x1a\x96\x3d\xe5\xe0\xda\x98\x40\xc1\xed\xdc\x81\x13\x72\x89\x76\x8c\x9c\x9c\x7f\x01\x16\x5c\x6e\x55\x75\x01\x00\x00")
+
+func _000018_login_throttleUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000018_login_throttleUpSql,
+		"000018_login_throttle.up.sql",
+	)
+}
+
+func _000018_login_throttleUpSql() (*asset, error) {
+	bytes, err := _000018_login_throttleUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000018_login_throttle.up.sql", size: 373, mode: os.FileMode(420), modTime: time.Unix(1792318783, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000019_account_deletionDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\x4c\x4e\xce\x2f\xcd\x2b\x89\x4f\x49\xcd\x49\x2d\xc9\xcc\xcf\x2b\xb6\xe6\xe2\x72\xf4\x09\x71\x0d\x82\xaa\x2c\x2d\x4e\x2d\x2a\x56\x70\x01\x69\x75\xf6\xf7\x09\xf5\xf5\x53\x00\xab\x4c\x4d\x89\x4f\x2c\xb1\xe6\x02\x00\x55\x90\xe1\xfe\x53\x00\x00\x00")
+
+func _000019_account_deletionDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000019_account_deletionDownSql,
+		"000019_account_deletion.down.sql",
+	)
+}
+
+func _000019_account_deletionDownSql() (*asset, error) {
+	bytes, err := _000019_account_deletionDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000019_account_deletion.down.sql", size: 83, mode: os.FileMode(420), modTime: time.Unix(1792319004, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000019_account_deletionUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x75\x4f\xbd\x6e\x83\x30\x10\x9e\xf1\x53\xdc\x48\xa4\xe6\x09\x32\x51\x38\x22\x2b\x60\x2a\xe3\x48\xcd\x84\xac\x70\x0a\x56\xc1\x6e\xb1\xa9\xd4\xb7\xaf\x21\xc9\x52\xa9\xe3\x7d\xff\xb7\xdf\x43\x4f\x23\x05\xea\x41\x5f\xaf\x6e\xb1\xc1\x83\x9e\x09\x3e\xe8\x33\x80\xb6\xce\xfe\x4c\xc6\x47\xd2\x3b\x08\x83\x0e\x30\xd3\xa4\x8d\x35\xf6\x06\x57\x37\x4d\xb4\xc9\x6d\x0f\x13\x79\xaf\x6f\xe4\xc1\x07\x33\x8e\x30\xe8\x6f\x8a\x38\xe8\x25\x0c\x6e\x66\x59\xa5\x50\x82\xca\x5e\x2b\x84\xc5\xd3\xec\x59\x56\x14\x90\x37\xd5\xb9\x16\xcf\xfa\x2e\x86\x17\x99\x42\xc5\x6b\x3c\x30\x96\x4b\x8c\xc7\xc3\xc3\x4b\x10\x8d\x02\x7c\xe7\xad\x6a\x9f\x3b\xbb\xcd\x68\x9c\xf5\x29\x4b\xd6\xd4\xce\xf4\xc0\x85\xc2\x63\xec\x7a\x93\xbc\xce\xe4\x05\x4e\x78\x79\x61\xc9\x4c\x5f\x0b\xf9\x3f\x25\x5b\xa4\x38\x57\x55\x14\xdc\x37\xfc\xc7\x96\x8d\x44\x7e\x14\x6b\x18\xa4\x8f\xa6\x1d\x4b\x12\x89\x25\x4a\x14\x39\xb6\xf7\xaf\x20\x5d\xf1\xdd\x81\xfd\x02\x67\x66\x71\x8e\x55\x01\x00\x00")
+
+func _000019_account_deletionUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000019_account_deletionUpSql,
+		"000019_account_deletion.up.sql",
+	)
+}
+
+func _000019_account_deletionUpSql() (*asset, error) {
+	bytes, err := _000019_account_deletionUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000019_account_deletion.up.sql", size: 341, mode: os.FileMode(420), modTime: time.Unix(1792319004, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000020_blocksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xca\xc9\x4f\xce\x2e\x8e\x07\x53\xa9\x29\xf1\x99\x29\xd6\x5c\x5c\x2e\x20\x85\x21\x8e\x4e\x3e\xae\x18\x0a\xad\xb9\x00\x39\x25\x1a\x13\x46\x00\x00\x00")
+
+func _000020_blocksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000020_blocksDownSql,
+		"000020_blocks.down.sql",
+	)
+}
+
+func _000020_blocksDownSql() (*asset, error) {
+	bytes, err := _000020_blocksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000020_blocks.down.sql", size: 70, mode: os.FileMode(420), modTime: time.Unix(1792319343, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000020_blocksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7d\x90\xc1\x0e\x82\x40\x0c\x44\xcf\xbb\x5f\xd1\x23\x24\xfc\x81\x27\x84\xc1\x34\x42\xd1\x65\x49\xe0\x64\x14\x38\x18\x4d\x4c\x00\xff\x5f\x44\x23\x98\x88\xd7\xbe\x99\x69\x3b\x81\x81\x6f\x41\xd6\x5f\xc7\x20\x8e\x48\x52\x4b\x28\x38\xb3\x19\x9d\xae\xb7\xea\xd2\x39\x5a\x9d\x6b\x62\xb1\xd8\xc0\xd0\xce\x70\xe2\x9b\x92\xb6\x28\xc9\xcf\x6d\xca\x12\x18\x24\x10\xeb\x69\x35\xea\x9b\xf6\x30\x93\x3f\xd3\x24\x8f\xe3\x0f\xad\x17\x68\xd5\x36\xc7\x7e\xa0\xc7\x9e\xc2\xe1\x1e\xcb\x09\xe6\x38\x17\xde\xe7\x20\x67\x5a\xe1\xd1\x14\xe8\x0e\x8a\x28\x35\xe0\x8d\x8c\x87\xcd\x64\xae\x56\xca\x20\x82\x81\x04\xc8\xe8\xde\x35\x6d\x47\xce\xa2\xa5\xfe\x67\xd1\xee\x4a\xeb\xe0\xd5\x17\x4b\x88\xe2\x67\x5f\x87\xd9\xa3\xa9\xbc\x87\x5f\xf9\x2b\xfd\x00\x93\x94\xae\xb4\x75\x01\x00\x00")
+
+func _000020_blocksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000020_blocksUpSql,
+		"000020_blocks.up.sql",
+	)
+}
+
+func _000020_blocksUpSql() (*asset, error) {
+	bytes, err := _000020_blocksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000020_blocks.up.sql", size: 373, mode: os.FileMode(420), modTime: time.Unix(1792319343, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000021_moderationDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\xcf\xc1\x0a\x83\x30\x0c\x06\xe0\xbb\x4f\x91\xf7\xf0\xe4\x66\x07\x85\x4e\x87\x76\xe0\xad\x14\x1b\x9c\xb0\x36\xd2\xd4\xf7\xdf\x1c\x32\x36\x2f\x7a\xca\xe1\xff\xfe\x84\x94\x4d\x7d\x03\x5d\x9c\x94\x00\x79\x01\xd1\xc9\x56\xb7\xe0\xc9\x61\xb4\x69\xa4\x60\x6c\xbf\x0c\xce\xb3\xac\x5c\xa4\xac\x4a\xd1\xfd\xc8\x88\x13\xc5\xc4\x66\xc2\xe0\xc6\x30\xec\x31\x4e\x36\xcd\xdf\x65\xdb\xb3\xab\x7a\xc7\x85\xd2\xa2\x59\x73\x8f\xcc\x76\x40\x86\x4f\xe7\x5c\xab\xfb\xb5\x82\xc7\xe8\x1c\x86\x8d\xec\xc9\x7b\x0c\xe9\x80\x9c\x88\x8f\xb0\x99\x31\xfe\x33\x9e\x79\x79\x15\x9d\xb1\x69\x17\x47\x7a\x62\x9e\xbd\x00\xba\xc5\xf2\x91\x62\x01\x00\x00")
+
+func _000021_moderationDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000021_moderationDownSql,
+		"000021_moderation.down.sql",
+	)
+}
+
+func _000021_moderationDownSql() (*asset, error) {
+	bytes, err := _000021_moderationDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000021_moderation.down.sql", size: 354, mode: os.FileMode(420), modTime: time.Unix(1792319620, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000021_moderationUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x54\x51\x8f\xa2\x30\x10\x7e\x96\x5f\x31\x6f\x6a\xe2\x26\xf7\x7e\x4f\xac\xd6\x3d\x72\x08\x77\x2c\xe4\xdc\x27\xd2\xb5\xb3\x42\x22\x2d\xa1\x65\x8d\xff\xfe\xa6\x80\x8a\xae\x72\x31\xf7\x40\x68\x3b\xd3\x99\xef\x9b\xf9\xa6\xae\x1f\xb3\x08\x62\xf7\xd9\x67\x50\x6b\xac\xb4\xe3\x2e\x16\x30\x0f\xfd\x64\x15\x40\xa5\x76\x08\x31\x5b\xc7\x10\x84\xf4\x25\xbe\x0f\x0b\xb6\x74\x13\x3f\x86\xb1\x75\x1e\x7f\x77\x1c\x77\x28\x80\xae\x75\x89\x52\xa0\x48\xb9\x81\x85\x1b\xb3\xd8\x5b\x31\xba\xf4\xf4\x04\x59\x2e\x04\x4a\xd8\x28\x69\x50\x1a\xd0\x86\x1f\x34\xe4\x12\x4c\x86\x20\xb8\xe1\xef\x5c\x23\x68\x45\x7b\xba\xca\xa1\x50\x02\x2b\x6e\x54\x05\x1b\x2e\xa1\x42\x4d\x4b\x84\xdc\x5c\xe4\x2f\x95\x36\x17\xf9\xbb\x24\xcf\x61\xe8\x7f\xa5\xf0\xc1\x77\x1a\xaf\x18\x6c\x54\x51\x10\x9c\xff\x0b\x52\xa0\xd6\x7c\x8b\x8f\x07\x99\x47\x8c\x6a\xd4\x45\xf1\x96\x8d\x23\x5b\x7b\xaf\xf1\x2b\x31\x2e\x55\x65\xf4\xc4\x19\xe5\x02\xbc\x20\x66\x2f\x94\xee\x57\xe4\xad\xdc\xe8\x0d\x7e\xb2\x37\x70\x93\x38\xf4\x02\x8a\xb0\x62\x41\x3c\x73\x46\xed\x05\xac\xd2\x9e\xff\x31\x31\x99\x89\x64\x6e\x0e\xa9\x39\x94\x57\x1d\x3e\xdb\x6e\x5f\xa4\xd6\xd9\x16\xf1\xda\x64\xd4\x0c\xf5\xd1\xec\xba\x64\xe2\xd8\xce\x19\x90\xcd\x1a\xac\x22\xec\x7a\x5b\xa9\xba\x84\x4d\x85\x4d\x0b\x8f\xee\x27\x94\x22\xb5\x8e\x77\x32\xd2\x25\xad\xe4\x17\x94\x02\x0d\xcf\x77\xfa\x9e\x3e\xc7\x67\xac\xd7\xe8\x80\x93\xd2\x0c\xec\xe9\xb7\xcf\x50\xf6\x7c\x9a\xb3\x82\x0b\x74\x46\x5a\xf2\x52\x67\xca\x0c\xc5\x27\xd1\x9a\xfa\x2e\x02\x45\xd2\xb7\x5e\x0d\xeb\xcb\x11\xe8\xf3\xa8\x4b\x31\x64\x5e\x86\x11\xf3\x5e\x82\xa6\xc7\x93\x5e\x53\xa7\xce\x68\x14\xb1\x25\x8b\x58\x30\x67\xaf\xed\xe8\xc1\xc4\x9e\x4f\xcf\x4a\xf2\x82\x05\x5b\xdf\x56\x52\xda\x81\x0f\x83\xe3\x09\x4c\xda\xa3\x19\x50\x94\x76\x48\x79\xdb\x40\x3b\x72\x4a\xee\x0e\x90\xf1\x4f\xa4\x15\xf5\x55\x66\x5c\x8a\x1d\xd5\xb4\x2b\x9c\x6a\xcb\xa8\x79\x81\xd0\xea\xe7\x88\x21\x09\xbc\xdf\xc9\x30\x14\xfb\x46\xe4\x72\x7b\x81\xa5\x47\x75\x06\x3d\xb5\x9e\x36\x96\xea\x9f\x1f\xc4\x1f\x3a\x26\x5e\x00\x93\xae\xe8\x30\xae\xf0\x33\xc7\x3d\x05\x1d\x4f\x87\x07\xab\x7b\x58\x72\x25\x53\xbe\xb1\xbf\x07\x66\xec\xf4\x28\xdd\x51\x6e\x1b\xf0\xde\x7c\x3d\x3e\x7b\x6d\x4d\x6e\x19\x4f\xaa\xfb\x46\x6e\x52\x19\x1c\x92\xed\x3f\x04\x79\xa1\xb8\x3e\xc5\x41\xc9\xfd\x05\x75\x7e\x7a\x3c\x49\x06\x00\x00")
+
+func _000021_moderationUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000021_moderationUpSql,
+		"000021_moderation.up.sql",
+	)
+}
+
+func _000021_moderationUpSql() (*asset, error) {
+	bytes, err := _000021_moderationUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000021_moderation.up.sql", size: 1609, mode: os.FileMode(420), modTime: time.Unix(1792319620, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000022_access_tokensDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\x4c\x4e\x4e\x2d\x2e\x8e\x2f\xc9\xcf\x4e\xcd\x2b\x8e\x2f\x2d\x4e\x2d\xb2\xe6\xe2\x72\x01\xa9\x0c\x71\x74\xf2\x71\xc5\xa5\xd2\x9a\x0b\x00\xe1\x74\xd9\xc7\x4e\x00\x00\x00")
+
+func _000022_access_tokensDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000022_access_tokensDownSql,
+		"000022_access_tokens.down.sql",
+	)
+}
+
+func _000022_access_tokensDownSql() (*asset, error) {
+	bytes, err := _000022_access_tokensDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000022_access_tokens.down.sql", size: 78, mode: os.FileMode(420), modTime: time.Unix(1792320292, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000022_access_tokensUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7d\x90\xcd\x0e\x82\x30\x10\x84\xcf\xf4\x29\xf6\x08\x89\x6f\xc0\x09\x71\x21\x8d\x58\xb4\x94\x04\x4f\x4d\x03\x4d\x30\xfe\x60\x58\x7c\x7f\x5b\x35\x11\x62\xe2\xf5\x9b\xdd\x99\xc9\xa4\x12\x13\x85\xa0\x92\x75\x81\xc0\x33\x10\xa5\x02\x6c\x78\xa5\x2a\x30\x6d\x6b\x89\xf4\x34\x9c\xed\x8d\x42\x16\x9c\x3a\xe0\x42\x61\x8e\x12\xf6\x92\xef\x12\x79\x84\x2d\x1e\x57\x2c\x78\x90\x1d\xf5\x4c\xf5\x1e\xa2\x2e\x0a\x27\xdd\xcc\xd5\x82\xc2\x46\xcd\xe1\xcb\x51\xf7\x86\xfa\xa5\x04\xb5\xe0\x87\x1a\xdd\x05\xb5\xc3\xdd\xd2\xcf\x63\x3b\x5a\x33\xd9\x4e\x9b\x09\x36\xae\xb5\xe2\x3b\x9c\xcb\x17\x43\x93\x76\x65\x16\x07\x8e\x67\xa5\x44\x9e\x0b\xdf\x16\xc2\x4f\xd9\x88\x05\x81\xc4\x0c\x25\x8a\x14\x2b\xf0\x94\x20\xf4\x3c\x8a\x19\x4b\xdf\xab\x70\xb1\xc1\xe6\xdf\x2a\x3e\x6d\x84\x52\x2c\xe9\x37\x24\x66\x4f\xbe\xa9\xb7\x82\x60\x01\x00\x00")
+
+func _000022_access_tokensUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000022_access_tokensUpSql,
+		"000022_access_tokens.up.sql",
+	)
+}
+
+func _000022_access_tokensUpSql() (*asset, error) {
+	bytes, err := _000022_access_tokensUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000022_access_tokens.up.sql", size: 352, mode: os.FileMode(420), modTime: time.Unix(1792320292, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000023_oidcDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x2d\x4e\x2d\x8a\xcf\x4c\x49\xcd\x2b\xc9\x2c\xc9\x4c\x2d\x8e\x07\xf1\xad\xb9\xb8\x5c\x40\x6a\x43\x1c\x9d\x7c\x5c\x71\xab\xc5\xa5\x2c\x3f\x33\x25\x39\xbe\xb8\x24\xb1\x04\xa4\x04\x00\xeb\x39\x58\x22\x75\x00\x00\x00")
+
+func _000023_oidcDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000023_oidcDownSql,
+		"000023_oidc.down.sql",
+	)
+}
+
+func _000023_oidcDownSql() (*asset, error) {
+	bytes, err := _000023_oidcDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000023_oidc.down.sql", size: 117, mode: os.FileMode(420), modTime: time.Unix(1792320528, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000023_oidcUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x52\x4d\x6f\xe2\x30\x10\x3d\x93\x5f\x31\xb7\x82\x04\xd2\xde\x7b\x4a\xcb\x80\xa2\xa6\x4e\x9b\x1a\x89\x9e\x22\x6f\x3c\x94\xd9\x4d\x6d\xd6\x76\xfa\xf5\xeb\xeb\xa4\xd0\x65\xa1\x4b\x8f\x99\xf7\xe2\x99\xf7\x31\x99\xc0\x86\x8c\x66\xf3\x00\xaa\x0d\x6b\xeb\xf8\x4d\x05\xb6\x06\x1c\xfd\x69\xc9\x07\x3f\x86\xb0\x26\xf0\x41\x05\x02\xf6\x60\x4d\xf3\x1a\xbf\xac\x23\x0d\x6b\xe5\xd7\xa4\x93\xcb\x12\x53\x89\x20\xd3\x8b\x1c\x21\x9b\x81\x28\x24\xe0\x32\xbb\x93\x77\x60\x59\xd7\x55\xff\xaf\x1f\x26\x03\xd6\x90\x09\x89\x73\x2c\xe1\xa6\xcc\xae\xd3\xf2\x1e\xae\xf0\x7e\x9c\x0c\x7a\x46\xd5\x3d\x07\x12\x97\xb2\x7f\x41\x2c\xf2\x1c\x16\x22\xbb\x5d\x60\x64\x6c\x9c\x7d\x62\x4d\xee\x5f\x3c\x02\xb5\xd5\x54\x3d\x91\xe3\x15\x7f\x81\x1a\x6b\x6a\x3a\x9a\x36\x6c\x7e\x57\xad\x27\x57\xed\x5d\xf4\xb9\x73\x8a\xb3\x74\x91\x4b\xf8\x11\x99\x8e\x3a\x53\xe0\xa2\x28\x72\x4c\xc5\x31\x67\xa5\x1a\x4f\xdd\x15\x91\x18\x48\x57\x2a\xc0\x34\x7a\x21\xb3\x6b\xdc\x5f\x48\x2f\x1b\x76\xe4\xbf\x84\x93\xd1\x79\x72\xca\xc2\xed\x9d\x64\x02\x07\x3e\x6d\xe3\xff\x24\x9d\xf2\xcf\xb7\x3f\x7f\x51\x1d\x8e\xe6\xf4\xa8\xb8\x39\x48\x63\xa7\xfa\xec\xec\x7b\xc9\x8d\xf2\xa1\x6a\xec\x03\x9b\x7d\x46\x04\x26\x13\x50\xd0\x03\xb1\x58\x11\xaa\xad\x59\xb1\x7b\xf4\x71\xba\xad\x1c\xd8\x15\x28\x03\xaa\xae\x6d\x6b\x02\x3c\x73\xac\x65\x1b\x22\xbe\x51\xde\x3f\x5b\xa7\xc7\xc0\xa1\xeb\x62\xd4\xab\xa1\x4b\x78\x17\x54\x67\x52\x7d\x78\x55\xdc\xf9\x51\xa3\xe1\xce\x84\x31\x6c\x55\x8f\x22\x36\x2b\x4a\xcc\xe6\xa2\xb3\x10\x86\x5b\x07\x47\xc9\x60\x50\xe2\x0c\x4b\x14\x97\xf8\x11\x81\x87\x61\x37\xdf\x0b\x2b\x13\x53\x5c\x9e\x0e\xab\x2f\x19\x14\xe2\x70\xfe\x77\xd1\x79\xf2\x0e\x05\xdd\x39\xad\x81\x03\x00\x00")
+
+func _000023_oidcUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000023_oidcUpSql,
+		"000023_oidc.up.sql",
+	)
+}
+
+func _000023_oidcUpSql() (*asset, error) {
+	bytes, err := _000023_oidcUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000023_oidc.up.sql", size: 897, mode: os.FileMode(420), modTime: time.Unix(1792327625, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000024_replay_eventsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4a\x2d\xc8\x49\xac\x8c\x4f\x2d\x4b\xcd\x2b\x29\x8e\x4f\x2e\x4a\x4d\x2c\x49\x4d\xb1\xe6\xe2\x72\x01\x29\x0e\x71\x74\xf2\x71\xc5\xa5\x98\x80\xa2\xe2\xd4\xc2\xd2\xd4\xbc\xe4\x54\xa0\x3a\x00\x81\x5e\xdc\xb9\x79\x00\x00\x00")
+
+func _000024_replay_eventsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000024_replay_eventsDownSql,
+		"000024_replay_events.down.sql",
+	)
+}
+
+func _000024_replay_eventsDownSql() (*asset, error) {
+	bytes, err := _000024_replay_eventsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000024_replay_events.down.sql", size: 121, mode: os.FileMode(420), modTime: time.Unix(1792321950, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __000024_replay_eventsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x8f\xcd\x0a\x82\x40\x14\x85\xd7\xce\x53\xdc\xa5\x82\x6f\xd0\xca\xf4\x2a\x43\x36\xc6\x38\x81\xae\x64\xd0\xbb\x08\x22\x4a\xa7\xc0\xb7\x6f\x04\xb1\x89\xfe\x16\x6d\xbf\x73\x39\xf7\x3b\xb1\xc4\x48\x21\xa8\x68\x9d\x23\xf0\x14\x44\xa1\x00\x2b\x5e\xaa\x12\x7a\x3a\x1f\xf5\xd8\x0c\x74\xb9\xd2\xa9\xa5\xc1\x67\xde\x75\xa0\xbe\x39\x74\xc0\x85\xc2\x0c\x25\xec\x24\xdf\x46\xb2\x86\x0d\xd6\x21\xf3\xec\xe5\x92\x4c\x3d\x62\x9f\xe7\x16\xa7\x85\x44\x9e\x89\xe9\x08\xfc\xb9\x21\x60\x9e\x27\x31\x45\x89\x22\xc6\x12\x26\x3a\x80\x3f\xf1\x60\xc5\x58\xfc\x53\x8a\x6e\x74\x32\xef\x8c\x9c\xbf\x1f\x74\xcc\x78\x26\x50\x58\x29\x17\x76\xda\xe8\x17\xd8\xf6\xa4\x0d\x75\x8d\x36\x90\x58\x1f\xc5\xb7\xe8\xc6\xce\xf8\x65\x57\x08\xf6\x6b\xf0\xcf\x68\x2e\x12\xac\xbe\x8d\x6e\x66\x2d\x28\xc4\x73\x00\xfe\x43\xd8\x16\xde\x01\x8c\xb4\xa7\x23\xda\x01\x00\x00")
+
+func _000024_replay_eventsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__000024_replay_eventsUpSql,
+		"000024_replay_events.up.sql",
+	)
+}
+
+func _000024_replay_eventsUpSql() (*asset, error) {
+	bytes, err := _000024_replay_eventsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "000024_replay_events.up.sql", size: 474, mode: os.FileMode(420), modTime: time.Unix(1792321950, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -471,6 +1143,38 @@ var _bindata = map[string]func() (*asset, error){
 	"000007_update_events.up.sql": _000007_update_eventsUpSql,
 	"000008_add_seed.down.sql": _000008_add_seedDownSql,
 	"000008_add_seed.up.sql": _000008_add_seedUpSql,
+	"000009_session_lifecycle.down.sql": _000009_session_lifecycleDownSql,
+	"000009_session_lifecycle.up.sql": _000009_session_lifecycleUpSql,
+	"000010_reactions.down.sql": _000010_reactionsDownSql,
+	"000010_reactions.up.sql": _000010_reactionsUpSql,
+	"000011_comment_replies.down.sql": _000011_comment_repliesDownSql,
+	"000011_comment_replies.up.sql": _000011_comment_repliesUpSql,
+	"000012_mentions.down.sql": _000012_mentionsDownSql,
+	"000012_mentions.up.sql": _000012_mentionsUpSql,
+	"000013_tags.down.sql": _000013_tagsDownSql,
+	"000013_tags.up.sql": _000013_tagsUpSql,
+	"000014_search.down.sql": _000014_searchDownSql,
+	"000014_search.up.sql": _000014_searchUpSql,
+	"000015_password_resets.down.sql": _000015_password_resetsDownSql,
+	"000015_password_resets.up.sql": _000015_password_resetsUpSql,
+	"000016_email_verification.down.sql": _000016_email_verificationDownSql,
+	"000016_email_verification.up.sql": _000016_email_verificationUpSql,
+	"000017_two_factor.down.sql": _000017_two_factorDownSql,
+	"000017_two_factor.up.sql": _000017_two_factorUpSql,
+	"000018_login_throttle.down.sql": _000018_login_throttleDownSql,
+	"000018_login_throttle.up.sql": _000018_login_throttleUpSql,
+	"000019_account_deletion.down.sql": _000019_account_deletionDownSql,
+	"000019_account_deletion.up.sql": _000019_account_deletionUpSql,
+	"000020_blocks.down.sql": _000020_blocksDownSql,
+	"000020_blocks.up.sql": _000020_blocksUpSql,
+	"000021_moderation.down.sql": _000021_moderationDownSql,
+	"000021_moderation.up.sql": _000021_moderationUpSql,
+	"000022_access_tokens.down.sql": _000022_access_tokensDownSql,
+	"000022_access_tokens.up.sql": _000022_access_tokensUpSql,
+	"000023_oidc.down.sql": _000023_oidcDownSql,
+	"000023_oidc.up.sql": _000023_oidcUpSql,
+	"000024_replay_events.down.sql": _000024_replay_eventsDownSql,
+	"000024_replay_events.up.sql": _000024_replay_eventsUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -529,6 +1233,38 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"000007_update_events.up.sql": &bintree{_000007_update_eventsUpSql, map[string]*bintree{}},
 	"000008_add_seed.down.sql": &bintree{_000008_add_seedDownSql, map[string]*bintree{}},
 	"000008_add_seed.up.sql": &bintree{_000008_add_seedUpSql, map[string]*bintree{}},
+	"000009_session_lifecycle.down.sql": &bintree{_000009_session_lifecycleDownSql, map[string]*bintree{}},
+	"000009_session_lifecycle.up.sql": &bintree{_000009_session_lifecycleUpSql, map[string]*bintree{}},
+	"000010_reactions.down.sql": &bintree{_000010_reactionsDownSql, map[string]*bintree{}},
+	"000010_reactions.up.sql": &bintree{_000010_reactionsUpSql, map[string]*bintree{}},
+	"000011_comment_replies.down.sql": &bintree{_000011_comment_repliesDownSql, map[string]*bintree{}},
+	"000011_comment_replies.up.sql": &bintree{_000011_comment_repliesUpSql, map[string]*bintree{}},
+	"000012_mentions.down.sql": &bintree{_000012_mentionsDownSql, map[string]*bintree{}},
+	"000012_mentions.up.sql": &bintree{_000012_mentionsUpSql, map[string]*bintree{}},
+	"000013_tags.down.sql": &bintree{_000013_tagsDownSql, map[string]*bintree{}},
+	"000013_tags.up.sql": &bintree{_000013_tagsUpSql, map[string]*bintree{}},
+	"000014_search.down.sql": &bintree{_000014_searchDownSql, map[string]*bintree{}},
+	"000014_search.up.sql": &bintree{_000014_searchUpSql, map[string]*bintree{}},
+	"000015_password_resets.down.sql": &bintree{_000015_password_resetsDownSql, map[string]*bintree{}},
+	"000015_password_resets.up.sql": &bintree{_000015_password_resetsUpSql, map[string]*bintree{}},
+	"000016_email_verification.down.sql": &bintree{_000016_email_verificationDownSql, map[string]*bintree{}},
+	"000016_email_verification.up.sql": &bintree{_000016_email_verificationUpSql, map[string]*bintree{}},
+	"000017_two_factor.down.sql": &bintree{_000017_two_factorDownSql, map[string]*bintree{}},
+	"000017_two_factor.up.sql": &bintree{_000017_two_factorUpSql, map[string]*bintree{}},
+	"000018_login_throttle.down.sql": &bintree{_000018_login_throttleDownSql, map[string]*bintree{}},
+	"000018_login_throttle.up.sql": &bintree{_000018_login_throttleUpSql, map[string]*bintree{}},
+	"000019_account_deletion.down.sql": &bintree{_000019_account_deletionDownSql, map[string]*bintree{}},
+	"000019_account_deletion.up.sql": &bintree{_000019_account_deletionUpSql, map[string]*bintree{}},
+	"000020_blocks.down.sql": &bintree{_000020_blocksDownSql, map[string]*bintree{}},
+	"000020_blocks.up.sql": &bintree{_000020_blocksUpSql, map[string]*bintree{}},
+	"000021_moderation.down.sql": &bintree{_000021_moderationDownSql, map[string]*bintree{}},
+	"000021_moderation.up.sql": &bintree{_000021_moderationUpSql, map[string]*bintree{}},
+	"000022_access_tokens.down.sql": &bintree{_000022_access_tokensDownSql, map[string]*bintree{}},
+	"000022_access_tokens.up.sql": &bintree{_000022_access_tokensUpSql, map[string]*bintree{}},
+	"000023_oidc.down.sql": &bintree{_000023_oidcDownSql, map[string]*bintree{}},
+	"000023_oidc.up.sql": &bintree{_000023_oidcUpSql, map[string]*bintree{}},
+	"000024_replay_events.down.sql": &bintree{_000024_replay_eventsDownSql, map[string]*bintree{}},
+	"000024_replay_events.up.sql": &bintree{_000024_replay_eventsUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,33 @@
+package enums
+
+type ReactionType int64
+
+const (
+	Like ReactionType = iota + 1
+	Love
+	Laugh
+	Sad
+	Angry
+)
+
+var reactionTypeNames = map[ReactionType]string{
+	Like:  "like",
+	Love:  "love",
+	Laugh: "laugh",
+	Sad:   "sad",
+	Angry: "angry",
+}
+
+func (t ReactionType) String() string {
+	return reactionTypeNames[t]
+}
+
+// ParseReactionType returns the reaction type with the given name
+func ParseReactionType(name string) (ReactionType, bool) {
+	for reactionType, reactionName := range reactionTypeNames {
+		if reactionName == name {
+			return reactionType, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scopes of a personal access token, a session is allowed everything
+const (
+	ScopeRead = "read"
+	ScopePost = "post"
+	ScopeChat = "chat"
+)
+
+var AccessTokenScopes = []string{ScopeRead, ScopePost, ScopeChat}
+
+// AccessToken is a named personal access token of a user, only the hash of the token is stored
+type AccessToken struct {
+	Id         int64
+	UserId     int64
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+}
+
+type IAccessTokenRepository interface {
+	Insert(token *AccessToken) (int64, error)
+	GetByHash(tokenHash string) (*AccessToken, error)
+	GetAllByUserId(userId int64) ([]*AccessToken, error)
+	CountByUserId(userId int64) (int, error)
+	UpdateLastUsed(id int64, lastUsedAt time.Time) error
+	Delete(id int64, userId int64) (bool, error)
+	DeleteByUserId(userId int64) ([]int64, error)
+}
+
+type AccessTokenRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewAccessTokenRepo(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo AccessTokenRepository) Insert(token *AccessToken) (int64, error) {
+	query := `INSERT INTO access_tokens (user_id, name, token_hash, scopes, created_at)
+	VALUES(?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		token.UserId,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, ","),
+		token.CreatedAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Inserted access token for user %d (last insert ID: %d)", token.UserId, lastId)
+
+	return lastId, nil
+}
+
+const accessTokenColumns = `id, user_id, name, token_hash, scopes, created_at, last_used_at`
+
+func scanAccessToken(row interface{ Scan(...interface{}) error }) (*AccessToken, error) {
+	token := &AccessToken{}
+	var scopes string
+
+	err := row.Scan(&token.Id, &token.UserId, &token.Name, &token.TokenHash, &scopes, &token.CreatedAt, &token.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+
+	return token, nil
+}
+
+// Returns the token with the hash, sql.ErrNoRows when it is unknown or revoked
+func (repo AccessTokenRepository) GetByHash(tokenHash string) (*AccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE token_hash = ?`
+
+	return scanAccessToken(repo.DB.QueryRow(query, tokenHash))
+}
+
+func (repo AccessTokenRepository) GetAllByUserId(userId int64) ([]*AccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE user_id = ? ORDER BY id`
+
+	rows, err := repo.DB.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := []*AccessToken{}
+
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (repo AccessTokenRepository) CountByUserId(userId int64) (int, error) {
+	query := `SELECT COUNT(*) FROM access_tokens WHERE user_id = ?`
+
+	var count int
+	err := repo.DB.QueryRow(query, userId).Scan(&count)
+
+	return count, err
+}
+
+func (repo AccessTokenRepository) UpdateLastUsed(id int64, lastUsedAt time.Time) error {
+	query := `UPDATE access_tokens SET last_used_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, lastUsedAt, id)
+
+	return err
+}
+
+// Revokes a token of the user, reports whether the user had it
+func (repo AccessTokenRepository) Delete(id int64, userId int64) (bool, error) {
+	query := `DELETE FROM access_tokens WHERE id = ? AND user_id = ?`
+
+	result, err := repo.DB.Exec(query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+
+	return deleted > 0, err
+}
+
+// Revokes all tokens of the user, returns the ids of the removed tokens
+func (repo AccessTokenRepository) DeleteByUserId(userId int64) ([]int64, error) {
+	rows, err := repo.DB.Query(`DELETE FROM access_tokens WHERE user_id = ? RETURNING id`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
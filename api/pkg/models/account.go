@@ -0,0 +1,468 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AccountDeletion struct {
+	UserId      int64
+	RequestedAt time.Time
+	DeleteAt    time.Time
+}
+
+// DeletedAccount lists what has to be cleaned up outside the database after an account is deleted
+type DeletedAccount struct {
+	UserId     int64
+	SessionIds []int64
+	ImagePaths []string
+}
+
+// ExportSection is one file of the personal data export, rows map column names to values
+type ExportSection struct {
+	Name string
+	Rows []map[string]interface{}
+}
+
+type IAccountRepository interface {
+	Export(userId int64) ([]*ExportSection, error)
+	GetUploadedImages(userId int64) ([]string, error)
+	GetDeletion(userId int64) (*AccountDeletion, error)
+	ScheduleDeletion(deletion *AccountDeletion) error
+	CancelDeletion(userId int64) (bool, error)
+	GetDueDeletions(now time.Time) ([]*AccountDeletion, error)
+	Delete(userId int64) (*DeletedAccount, error)
+}
+
+type AccountRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewAccountRepo(db *sql.DB) *AccountRepository {
+	return &AccountRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// exportQueries select the data of a user for the export, every ? is the user id
+var exportQueries = []struct {
+	name  string
+	query string
+}{
+	{"profile", `SELECT id, forname AS first_name, surname AS last_name, email, birthday, nickname, about, image_path, created_at, is_public, email_verified
+	FROM users WHERE id = ?`},
+	{"followers", `SELECT u.id, u.forname AS first_name, u.surname AS last_name, u.nickname, f.accepted FROM followers f
+	INNER JOIN users u ON
+	f.follower_id = u.id
+	WHERE f.following_id = ?`},
+	{"following", `SELECT u.id, u.forname AS first_name, u.surname AS last_name, u.nickname, f.accepted FROM followers f
+	INNER JOIN users u ON
+	f.following_id = u.id
+	WHERE f.follower_id = ?`},
+	{"posts", `SELECT p.id, p.group_id, pt.name AS privacy, p.content, p.image_path, p.created_at FROM posts p
+	LEFT JOIN privacy_type pt ON
+	p.privacy_type_id = pt.id
+	WHERE p.user_id = ?
+	ORDER BY p.id`},
+	{"comments", `SELECT id, post_id, parent_comment_id, content, image_path, created_at FROM comments
+	WHERE user_id = ?
+	ORDER BY id`},
+	{"reactions", `SELECT r.id, r.post_id, r.comment_id, rt.name AS reaction, r.created_at FROM reactions r
+	INNER JOIN reaction_types rt ON
+	r.reaction_type_id = rt.id
+	WHERE r.user_id = ?
+	ORDER BY r.id`},
+	{"messages", `SELECT id, sender_id, recipient_id, group_id, content, image_path, sent_at, read_at FROM messages
+	WHERE sender_id = ?1 OR recipient_id = ?1
+	ORDER BY id`},
+	{"groups_created", `SELECT id, title, description, image_path, created_at FROM groups
+	WHERE creator_id = ?
+	ORDER BY id`},
+	{"group_memberships", `SELECT g.id AS group_id, g.title, ug.joined_at, ug.accepted FROM user_groups ug
+	INNER JOIN groups g ON
+	ug.group_id = g.id
+	WHERE ug.user_id = ?
+	ORDER BY ug.id`},
+	{"event_rsvps", `SELECT e.id AS event_id, e.group_id, e.title, e.event_time, e.event_end_time, a.is_attending FROM group_event_attendance a
+	INNER JOIN group_events e ON
+	a.event_id = e.id
+	WHERE a.user_id = ?
+	ORDER BY a.id`},
+	{"notifications", `SELECT n.id, nt.name AS type, nd.sender_id, nd.entity_id, nd.created_at, n.seen_at FROM notifications n
+	INNER JOIN notification_details nd ON
+	n.notification_details_id = nd.id
+	INNER JOIN notification_types nt ON
+	nd.notification_type_id = nt.id
+	WHERE n.receiver_id = ?
+	ORDER BY n.id`},
+	{"blocked_users", `SELECT b.blocked_id AS user_id, u.nickname, b.created_at FROM blocks b
+	INNER JOIN users u ON
+	b.blocked_id = u.id
+	WHERE b.blocker_id = ?
+	ORDER BY b.id`},
+	{"access_tokens", `SELECT id, name, scopes, created_at, last_used_at FROM access_tokens
+	WHERE user_id = ?
+	ORDER BY id`},
+	{"linked_logins", `SELECT id, provider, subject, email, created_at, last_login_at FROM user_identities
+	WHERE user_id = ?
+	ORDER BY id`},
+}
+
+// Returns all personal data of the user, one section per export file
+func (repo AccountRepository) Export(userId int64) ([]*ExportSection, error) {
+	sections := []*ExportSection{}
+
+	for _, export := range exportQueries {
+		rows, err := repo.DB.Query(export.query, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		section := &ExportSection{Name: export.name}
+		section.Rows, err = scanExportRows(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
+
+func scanExportRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			switch value := values[i].(type) {
+			case []byte:
+				values[i] = string(value)
+			case int64:
+				// SQLite stores booleans as integers
+				if strings.EqualFold(column.DatabaseTypeName(), "BOOL") {
+					values[i] = value != 0
+				}
+			}
+			row[column.Name()] = values[i]
+		}
+
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// Returns the file names of the images the user uploaded
+func (repo AccountRepository) GetUploadedImages(userId int64) ([]string, error) {
+	query := `SELECT image_path FROM users WHERE id = ?1
+	UNION SELECT image_path FROM posts WHERE user_id = ?1
+	UNION SELECT image_path FROM comments WHERE user_id = ?1
+	UNION SELECT image_path FROM messages WHERE sender_id = ?1`
+
+	return queryImagePaths(repo.DB, query, userId)
+}
+
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryImagePaths returns the non-empty image paths selected by the query
+func queryImagePaths(db querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	paths := []string{}
+
+	for rows.Next() {
+		var path sql.NullString
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+
+		if path.String != "" {
+			paths = append(paths, path.String)
+		}
+	}
+
+	return paths, rows.Err()
+}
+
+func (repo AccountRepository) GetDeletion(userId int64) (*AccountDeletion, error) {
+	query := `SELECT user_id, requested_at, delete_at FROM account_deletions WHERE user_id = ?`
+
+	deletion := &AccountDeletion{}
+
+	err := repo.DB.QueryRow(query, userId).Scan(&deletion.UserId, &deletion.RequestedAt, &deletion.DeleteAt)
+
+	return deletion, err
+}
+
+func (repo AccountRepository) ScheduleDeletion(deletion *AccountDeletion) error {
+	query := `INSERT INTO account_deletions (user_id, requested_at, delete_at) VALUES(?, ?, ?)`
+
+	_, err := repo.DB.Exec(query, deletion.UserId, deletion.RequestedAt, deletion.DeleteAt)
+	if err != nil {
+		return err
+	}
+
+	repo.Logger.Printf("Scheduled deletion of user %d at %s", deletion.UserId, deletion.DeleteAt)
+
+	return nil
+}
+
+// Removes a scheduled deletion, reports whether there was one
+func (repo AccountRepository) CancelDeletion(userId int64) (bool, error) {
+	query := `DELETE FROM account_deletions WHERE user_id = ?`
+
+	result, err := repo.DB.Exec(query, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (repo AccountRepository) GetDueDeletions(now time.Time) ([]*AccountDeletion, error) {
+	query := `SELECT user_id, requested_at, delete_at FROM account_deletions WHERE delete_at <= ? ORDER BY delete_at`
+
+	rows, err := repo.DB.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deletions := []*AccountDeletion{}
+
+	for rows.Next() {
+		deletion := &AccountDeletion{}
+
+		err := rows.Scan(&deletion.UserId, &deletion.RequestedAt, &deletion.DeleteAt)
+		if err != nil {
+			return nil, err
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deletions, nil
+}
+
+// idList is a comma separated list of ids for IN clauses, the ids come from the database
+func idList(ids []int64) string {
+	if len(ids) == 0 {
+		return "NULL"
+	}
+
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, strconv.FormatInt(id, 10))
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// Deletes the account in one transaction. Posts are removed with everything attached to them,
+// comments and sent messages stay in threads and chats with their content removed, and the
+// users row is kept anonymised. Groups are handed to their longest standing member or
+// removed when the user was the only one.
+func (repo AccountRepository) Delete(userId int64) (*DeletedAccount, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	deleted := &DeletedAccount{UserId: userId}
+
+	emptyGroups, err := transferGroups(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := idList(emptyGroups)
+
+	// posts of the user and of the groups that are removed
+	postIds, err := queryIds(tx, `SELECT id FROM posts WHERE user_id = ? OR group_id IN (`+groups+`)`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := idList(postIds)
+
+	deleted.ImagePaths, err = queryImagePaths(tx, `SELECT image_path FROM users WHERE id = ?1
+	UNION SELECT image_path FROM posts WHERE id IN (`+posts+`)
+	UNION SELECT image_path FROM comments WHERE user_id = ?1 OR post_id IN (`+posts+`)
+	UNION SELECT image_path FROM messages WHERE sender_id = ?1 OR group_id IN (`+groups+`)
+	UNION SELECT image_path FROM groups WHERE id IN (`+groups+`)`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted.SessionIds, err = queryIds(tx, `SELECT id FROM user_sessions WHERE user_id = ?`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := `SELECT id FROM comments WHERE post_id IN (` + posts + `)`
+	events := `SELECT id FROM group_events WHERE group_id IN (` + groups + `)`
+	mentions := `SELECT id FROM mentions WHERE user_id = ?1 OR sender_id = ?1 OR post_id IN (` + posts + `) OR comment_id IN (` + comments + `)`
+
+	// notifications that point at removed rows, the entity of each type is listed in notification_types
+	notificationDetails := `SELECT id FROM notification_details WHERE sender_id = ?1
+	OR (notification_type_id = 1 AND entity_id IN (SELECT id FROM user_groups WHERE user_id = ?1 OR group_id IN (` + groups + `)))
+	OR (notification_type_id = 2 AND entity_id IN (` + groups + `))
+	OR (notification_type_id = 3 AND entity_id IN (` + events + `))
+	OR (notification_type_id = 4 AND entity_id IN (` + mentions + `))`
+
+	statements := []string{
+		`DELETE FROM notifications WHERE receiver_id = ?1 OR notification_details_id IN (` + notificationDetails + `)`,
+		`DELETE FROM notification_details WHERE id IN (` + notificationDetails + `)`,
+		`DELETE FROM mentions WHERE id IN (` + mentions + `)`,
+		`DELETE FROM reactions WHERE user_id = ?1 OR post_id IN (` + posts + `) OR comment_id IN (` + comments + `)`,
+		`DELETE FROM post_tags WHERE post_id IN (` + posts + `)`,
+		`DELETE FROM allowed_private_posts WHERE user_id = ?1 OR post_id IN (` + posts + `)`,
+		`DELETE FROM comments WHERE post_id IN (` + posts + `)`,
+		`DELETE FROM posts WHERE id IN (` + posts + `)`,
+		`UPDATE comments SET content = '[deleted]', image_path = '' WHERE user_id = ?1`,
+		`DELETE FROM messages WHERE group_id IN (` + groups + `)`,
+		`UPDATE messages SET content = '[deleted]', image_path = '' WHERE sender_id = ?1`,
+		`DELETE FROM group_event_attendance WHERE user_id = ?1 OR event_id IN (` + events + `)`,
+		`DELETE FROM group_events WHERE group_id IN (` + groups + `)`,
+		`DELETE FROM user_groups WHERE user_id = ?1 OR group_id IN (` + groups + `)`,
+		`DELETE FROM groups WHERE id IN (` + groups + `)`,
+		`DELETE FROM followers WHERE follower_id = ?1 OR following_id = ?1`,
+		`DELETE FROM user_sessions WHERE user_id = ?1`,
+		`DELETE FROM password_resets WHERE user_id = ?1`,
+		`DELETE FROM email_verifications WHERE user_id = ?1`,
+		`DELETE FROM recovery_codes WHERE user_id = ?1`,
+		`DELETE FROM login_challenges WHERE user_id = ?1`,
+		`DELETE FROM user_totp WHERE user_id = ?1`,
+		`DELETE FROM login_failures WHERE scope = 'email' AND key = (SELECT lower(trim(email)) FROM users WHERE id = ?1)`,
+		`DELETE FROM account_deletions WHERE user_id = ?1`,
+		`DELETE FROM blocks WHERE blocker_id = ?1 OR blocked_id = ?1`,
+		`DELETE FROM access_tokens WHERE user_id = ?1`,
+		`DELETE FROM user_identities WHERE user_id = ?1`,
+		`DELETE FROM oidc_states WHERE link_user_id = ?1`,
+		`DELETE FROM replay_events WHERE user_id = ?1`,
+		`DELETE FROM replay_sequences WHERE user_id = ?1`,
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `UPDATE users SET forname = 'Deleted', surname = 'user', email = 'deleted-' || id || '@invalid', password = '',
+	birthday = ?, nickname = '', about = '', image_path = '', is_public = false, email_verified = false, deleted_at = ?
+	WHERE id = ?`
+
+	_, err = tx.Exec(query, time.Time{}, time.Now(), userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	repo.Logger.Printf("Deleted user %d with %d post(s), removed %d group(s)", userId, len(postIds), len(emptyGroups))
+
+	return deleted, nil
+}
+
+// transferGroups makes the longest standing member the creator of each group of the user
+// and returns the groups that have no other member
+func transferGroups(tx *sql.Tx, userId int64) ([]int64, error) {
+	groupIds, err := queryIds(tx, `SELECT id FROM groups WHERE creator_id = ?`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	emptyGroups := []int64{}
+
+	for _, groupId := range groupIds {
+		query := `SELECT user_id FROM user_groups
+		WHERE group_id = ? AND user_id != ? AND accepted = true
+		ORDER BY joined_at, id
+		LIMIT 1`
+
+		var newCreatorId int64
+		err := tx.QueryRow(query, groupId, userId).Scan(&newCreatorId)
+
+		if err == sql.ErrNoRows {
+			emptyGroups = append(emptyGroups, groupId)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(`UPDATE groups SET creator_id = ? WHERE id = ?`, newCreatorId, groupId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return emptyGroups, nil
+}
+
+func queryIds(db querier, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
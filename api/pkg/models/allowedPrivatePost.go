@@ -13,6 +13,7 @@ type AllowedPost struct {
 
 type IAllowedPostRepository interface {
 	Insert(allowedPost *AllowedPost) (int64, error)
+	DeleteByPostId(postId int64) error
 }
 
 type AllowedPostRepository struct {
@@ -50,3 +51,23 @@ func (repo AllowedPostRepository) Insert(allowedPost *AllowedPost) (int64, error
 
 	return lastId, nil
 }
+
+func (repo AllowedPostRepository) DeleteByPostId(postId int64) error {
+	query := `DELETE FROM allowed_private_posts WHERE post_id = ?`
+
+	result, err := repo.DB.Exec(query, postId)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	repo.Logger.Printf("Deleted %d allowed user(s) for post %d", rowsAffected, postId)
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+type Block struct {
+	Id        int64
+	BlockerId int64
+	BlockedId int64
+	CreatedAt time.Time
+}
+
+type IBlockRepository interface {
+	Insert(block *Block) (bool, error)
+	Delete(blockerId int64, blockedId int64) (bool, error)
+	IsBlockedEitherWay(userId int64, otherId int64) (bool, error)
+	GetBlockedUserIds(userId int64) (map[int64]bool, error)
+	GetBlockedUsers(blockerId int64) ([]*User, error)
+}
+
+type BlockRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewBlockRepo(db *sql.DB) *BlockRepository {
+	return &BlockRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// blockedUserIds selects the users that a user has blocked or was blocked by,
+// it takes the id of the user twice
+const blockedUserIds = `SELECT blocked_id FROM blocks WHERE blocker_id = ?
+	UNION SELECT blocker_id FROM blocks WHERE blocked_id = ?`
+
+// Inserts the block and severs the follow relations between the two users in both directions,
+// pending follow requests are removed with their notifications. Reports whether the block is new.
+func (repo BlockRepository) Insert(block *Block) (bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	query := `INSERT OR IGNORE INTO blocks (blocker_id, blocked_id, created_at) VALUES(?, ?, ?)`
+
+	result, err := tx.Exec(query, block.BlockerId, block.BlockedId, block.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	followers := `SELECT id FROM followers WHERE (follower_id = ?1 AND following_id = ?2) OR (follower_id = ?2 AND following_id = ?1)`
+	notificationDetails := `SELECT id FROM notification_details WHERE notification_type_id = 0 AND entity_id IN (` + followers + `)`
+
+	statements := []string{
+		`DELETE FROM notifications WHERE notification_details_id IN (` + notificationDetails + `)`,
+		`DELETE FROM notification_details WHERE id IN (` + notificationDetails + `)`,
+		`DELETE FROM followers WHERE id IN (` + followers + `)`,
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement, block.BlockerId, block.BlockedId); err != nil {
+			return false, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	if inserted > 0 {
+		repo.Logger.Printf("User %d blocked user %d", block.BlockerId, block.BlockedId)
+	}
+
+	return inserted > 0, nil
+}
+
+// Removes the block, reports whether there was one
+func (repo BlockRepository) Delete(blockerId int64, blockedId int64) (bool, error) {
+	query := `DELETE FROM blocks WHERE blocker_id = ? AND blocked_id = ?`
+
+	result, err := repo.DB.Exec(query, blockerId, blockedId)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+
+	return deleted > 0, err
+}
+
+// Reports whether either of the users has blocked the other
+func (repo BlockRepository) IsBlockedEitherWay(userId int64, otherId int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM blocks
+	WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))`
+
+	var blocked bool
+	err := repo.DB.QueryRow(query, userId, otherId, otherId, userId).Scan(&blocked)
+
+	return blocked, err
+}
+
+// Returns the ids of the users that the user has blocked or was blocked by
+func (repo BlockRepository) GetBlockedUserIds(userId int64) (map[int64]bool, error) {
+	rows, err := repo.DB.Query(blockedUserIds, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := map[int64]bool{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Returns the users blocked by the user, most recently blocked first
+func (repo BlockRepository) GetBlockedUsers(blockerId int64) ([]*User, error) {
+	query := `SELECT u.id, u.forname, u.surname, u.nickname, u.image_path FROM blocks b
+	INNER JOIN users u ON
+	b.blocked_id = u.id
+	WHERE b.blocker_id = ?
+	ORDER BY b.id DESC`
+
+	rows, err := repo.DB.Query(query, blockerId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		user := &User{}
+
+		err := rows.Scan(&user.Id, &user.FirstName, &user.LastName, &user.Nickname, &user.ImagePath)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
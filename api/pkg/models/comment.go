@@ -9,31 +9,39 @@ import (
 
 const CommentLimit = 5
 
+// Comment is a comment on a post, replies have a parent comment and a depth above 0
 type Comment struct {
-	Id        int64
-	PostId    int64
-	UserId    int64
-	Content   string
-	ImagePath string
-	CreatedAt time.Time
+	Id              int64
+	PostId          int64
+	UserId          int64
+	ParentCommentId int64
+	Depth           int
+	Content         string
+	ImagePath       string
+	CreatedAt       time.Time
 }
 
 type PostComment struct {
-	Id           int64
-	UserId       int64
-	UserName     string
-	Content      string
-	ImagePath    string
-	CreatedAt    time.Time
-	CommentCount int
+	Id              int64
+	UserId          int64
+	UserName        string
+	ParentCommentId int64
+	Depth           int
+	Content         string
+	ImagePath       string
+	CreatedAt       time.Time
+	CommentCount    int
+	ReplyCount      int
 }
 
 type ICommentRepository interface {
-	GetAllByPostId(postId int64, offset int64) ([]*PostComment, error)
+	GetAllByPostId(postId int64, currentUserId int64, offset int64) ([]*PostComment, error)
+	GetReplies(parentCommentId int64, currentUserId int64, offset int64) ([]*PostComment, error)
 	GetAllByUserId(userId int64) ([]*Comment, error)
 	GetById(id int64) (*Comment, error)
 	Insert(comment *Comment) (int64, error)
 	Update(comment *Comment) error
+	GetImagePathsByPostId(postId int64) ([]string, error)
 	InsertSeedComment(comment *Comment) (int64, error)
 }
 
@@ -50,12 +58,14 @@ func NewCommentRepo(db *sql.DB) *CommentRepository {
 }
 
 func (repo CommentRepository) Insert(comment *Comment) (int64, error) {
-	query := `INSERT INTO comments (post_id, user_id, content, image_path, created_at)
-	VALUES(?, ?, ?, ?, ?)`
+	query := `INSERT INTO comments (post_id, user_id, parent_comment_id, depth, content, image_path, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?)`
 
 	args := []interface{}{
 		comment.PostId,
 		comment.UserId,
+		nullableId(comment.ParentCommentId),
+		comment.Depth,
 		comment.Content,
 		comment.ImagePath,
 		time.Now(),
@@ -86,30 +96,69 @@ func (repo CommentRepository) Update(comment *Comment) error {
 }
 
 func (repo CommentRepository) GetById(id int64) (*Comment, error) {
-	query := `SELECT id, post_id, user_id, content,  image_path, created_at FROM comments WHERE id = ?`
+	query := `SELECT id, post_id, user_id, COALESCE(parent_comment_id, 0), depth, content,  image_path, created_at FROM comments WHERE id = ?`
 	row := repo.DB.QueryRow(query, id)
 	comment := &Comment{}
 
-	err := row.Scan(&comment.Id, &comment.PostId, &comment.UserId, &comment.Content, &comment.ImagePath, &comment.CreatedAt)
+	err := row.Scan(&comment.Id, &comment.PostId, &comment.UserId, &comment.ParentCommentId, &comment.Depth, &comment.Content, &comment.ImagePath, &comment.CreatedAt)
 
 	return comment, err
 }
 
-func (repo CommentRepository) GetAllByPostId(postId int64, offset int64) ([]*PostComment, error) {
-	query := `SELECT c.id, c.user_id, u.nickname, c.content, c.image_path, c.created_at, cc.comment_count FROM comments c
+// Hidden comments stay in their thread with the content removed
+const (
+	commentContent   = `CASE WHEN c.hidden THEN '[hidden]' ELSE c.content END`
+	commentImagePath = `CASE WHEN c.hidden THEN '' ELSE c.image_path END`
+)
+
+// Returns the top level comments of a post, newest first, without those of users blocked by or blocking
+// the current user. CommentCount holds all comments of the post including replies
+func (repo CommentRepository) GetAllByPostId(postId int64, currentUserId int64, offset int64) ([]*PostComment, error) {
+	query := `SELECT c.id, c.user_id, u.nickname, COALESCE(c.parent_comment_id, 0), c.depth, ` + commentContent + `, ` + commentImagePath + `, c.created_at, cc.comment_count,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id) FROM comments c
 	LEFT JOIN users u ON c.user_id = u.id
 	LEFT JOIN (SELECT COUNT(*) AS comment_count, post_id FROM comments GROUP BY post_id) AS cc ON c.post_id = cc.post_id
-	WHERE c.post_id = ? 
+	WHERE c.post_id = ? AND c.parent_comment_id IS NULL
+	AND c.user_id NOT IN (` + blockedUserIds + `)
 	GROUP BY c.id
 	ORDER BY c.created_at DESC
 	LIMIT ? OFFSET ?`
 
 	args := []interface{}{
 		postId,
+		currentUserId,
+		currentUserId,
 		CommentLimit,
 		(offset * CommentLimit),
 	}
 
+	return repo.queryPostComments(query, args...)
+}
+
+// Returns the direct replies of a comment, oldest first, without those of users blocked by or blocking
+// the current user. CommentCount holds the number of replies of the parent
+func (repo CommentRepository) GetReplies(parentCommentId int64, currentUserId int64, offset int64) ([]*PostComment, error) {
+	query := `SELECT c.id, c.user_id, u.nickname, COALESCE(c.parent_comment_id, 0), c.depth, ` + commentContent + `, ` + commentImagePath + `, c.created_at,
+	(SELECT COUNT(*) FROM comments s WHERE s.parent_comment_id = c.parent_comment_id),
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_comment_id = c.id) FROM comments c
+	LEFT JOIN users u ON c.user_id = u.id
+	WHERE c.parent_comment_id = ?
+	AND c.user_id NOT IN (` + blockedUserIds + `)
+	ORDER BY c.created_at ASC, c.id ASC
+	LIMIT ? OFFSET ?`
+
+	args := []interface{}{
+		parentCommentId,
+		currentUserId,
+		currentUserId,
+		CommentLimit,
+		(offset * CommentLimit),
+	}
+
+	return repo.queryPostComments(query, args...)
+}
+
+func (repo CommentRepository) queryPostComments(query string, args ...interface{}) ([]*PostComment, error) {
 	rows, err := repo.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -121,7 +170,7 @@ func (repo CommentRepository) GetAllByPostId(postId int64, offset int64) ([]*Pos
 	for rows.Next() {
 		comment := &PostComment{}
 
-		err := rows.Scan(&comment.Id, &comment.UserId, &comment.UserName, &comment.Content, &comment.ImagePath, &comment.CreatedAt, &comment.CommentCount)
+		err := rows.Scan(&comment.Id, &comment.UserId, &comment.UserName, &comment.ParentCommentId, &comment.Depth, &comment.Content, &comment.ImagePath, &comment.CreatedAt, &comment.CommentCount, &comment.ReplyCount)
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +185,7 @@ func (repo CommentRepository) GetAllByPostId(postId int64, offset int64) ([]*Pos
 }
 
 func (repo CommentRepository) GetAllByUserId(userId int64) ([]*Comment, error) {
-	query := `SELECT id, post_id, user_id, content,  image_path, created_at FROM comments WHERE user_id = ? ORDER BY created_at DESC`
+	query := `SELECT id, post_id, user_id, COALESCE(parent_comment_id, 0), depth, content,  image_path, created_at FROM comments WHERE user_id = ? ORDER BY created_at DESC`
 	rows, err := repo.DB.Query(query, userId)
 	if err != nil {
 		return nil, err
@@ -148,7 +197,7 @@ func (repo CommentRepository) GetAllByUserId(userId int64) ([]*Comment, error) {
 	for rows.Next() {
 		comment := &Comment{}
 
-		err := rows.Scan(&comment.Id, &comment.PostId, &comment.UserId, &comment.Content, &comment.ImagePath, &comment.CreatedAt)
+		err := rows.Scan(&comment.Id, &comment.PostId, &comment.UserId, &comment.ParentCommentId, &comment.Depth, &comment.Content, &comment.ImagePath, &comment.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -162,6 +211,36 @@ func (repo CommentRepository) GetAllByUserId(userId int64) ([]*Comment, error) {
 	return comments, nil
 }
 
+// Returns the stored image names of all comments of a post
+func (repo CommentRepository) GetImagePathsByPostId(postId int64) ([]string, error) {
+	query := `SELECT image_path FROM comments WHERE post_id = ? AND image_path IS NOT NULL AND image_path != ''`
+
+	rows, err := repo.DB.Query(query, postId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	imagePaths := []string{}
+
+	for rows.Next() {
+		var imagePath string
+
+		err := rows.Scan(&imagePath)
+		if err != nil {
+			return nil, err
+		}
+		imagePaths = append(imagePaths, imagePath)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return imagePaths, nil
+}
+
 func (repo CommentRepository) InsertSeedComment(comment *Comment) (int64, error) {
 	query := `INSERT INTO comments (post_id, user_id, content, image_path, created_at)
 	VALUES(?, ?, ?, ?, ?)`
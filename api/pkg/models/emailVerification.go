@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// EmailVerification is a pending email confirmation, only the hash of its token is stored
+type EmailVerification struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type IEmailVerificationRepository interface {
+	Insert(verification *EmailVerification) (int64, error)
+	GetLastCreatedAt(userId int64) (time.Time, error)
+	Consume(tokenHash string) (int64, error)
+	DeleteByUserId(userId int64) error
+}
+
+type EmailVerificationRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewEmailVerificationRepo(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo EmailVerificationRepository) Insert(verification *EmailVerification) (int64, error) {
+	query := `INSERT INTO email_verifications (user_id, token_hash, created_at, expires_at)
+	VALUES(?, ?, ?, ?)`
+
+	args := []interface{}{
+		verification.UserId,
+		verification.TokenHash,
+		time.Now(),
+		verification.ExpiresAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Inserted email verification for user %d (last insert ID: %d)", verification.UserId, lastId)
+
+	return lastId, nil
+}
+
+// Returns when the last verification email of the user was issued, sql.ErrNoRows if never
+func (repo EmailVerificationRepository) GetLastCreatedAt(userId int64) (time.Time, error) {
+	query := `SELECT created_at FROM email_verifications WHERE user_id = ? ORDER BY id DESC LIMIT 1`
+
+	var createdAt time.Time
+	err := repo.DB.QueryRow(query, userId).Scan(&createdAt)
+
+	return createdAt, err
+}
+
+// Removes an unexpired verification and returns its user id,
+// sql.ErrNoRows means the token is unknown, used or expired
+func (repo EmailVerificationRepository) Consume(tokenHash string) (int64, error) {
+	query := `DELETE FROM email_verifications
+	WHERE token_hash = ? AND expires_at > ?
+	RETURNING user_id`
+
+	var userId int64
+	err := repo.DB.QueryRow(query, tokenHash, time.Now()).Scan(&userId)
+
+	return userId, err
+}
+
+func (repo EmailVerificationRepository) DeleteByUserId(userId int64) error {
+	query := `DELETE FROM email_verifications WHERE user_id = ?`
+
+	_, err := repo.DB.Exec(query, userId)
+
+	return err
+}
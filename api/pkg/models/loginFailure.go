@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// Scopes of the failed login counters
+const (
+	LoginScopeEmail = "email"
+	LoginScopeIP    = "ip"
+)
+
+type LoginFailure struct {
+	Scope         string
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	// BlockedUntil is the earliest time of the next login attempt
+	BlockedUntil time.Time
+}
+
+type ILoginFailureRepository interface {
+	Get(scope string, key string) (*LoginFailure, error)
+	Save(failure *LoginFailure) error
+	Delete(scope string, key string) error
+}
+
+type LoginFailureRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewLoginFailureRepo(db *sql.DB) *LoginFailureRepository {
+	return &LoginFailureRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo LoginFailureRepository) Get(scope string, key string) (*LoginFailure, error) {
+	query := `SELECT scope, key, failures, last_failure_at, blocked_until FROM login_failures WHERE scope = ? AND key = ?`
+
+	failure := &LoginFailure{}
+
+	err := repo.DB.QueryRow(query, scope, key).Scan(&failure.Scope, &failure.Key, &failure.Failures, &failure.LastFailureAt, &failure.BlockedUntil)
+
+	return failure, err
+}
+
+func (repo LoginFailureRepository) Save(failure *LoginFailure) error {
+	query := `INSERT INTO login_failures (scope, key, failures, last_failure_at, blocked_until)
+	VALUES(?, ?, ?, ?, ?)
+	ON CONFLICT (scope, key) DO UPDATE SET
+	failures = excluded.failures,
+	last_failure_at = excluded.last_failure_at,
+	blocked_until = excluded.blocked_until`
+
+	args := []interface{}{
+		failure.Scope,
+		failure.Key,
+		failure.Failures,
+		failure.LastFailureAt,
+		failure.BlockedUntil,
+	}
+
+	_, err := repo.DB.Exec(query, args...)
+
+	return err
+}
+
+func (repo LoginFailureRepository) Delete(scope string, key string) error {
+	query := `DELETE FROM login_failures WHERE scope = ? AND key = ?`
+
+	_, err := repo.DB.Exec(query, scope, key)
+
+	return err
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// Mention of a user in a post, a comment or a chat message, only one of the entity ids is set
+type Mention struct {
+	Id        int64
+	UserId    int64
+	SenderId  int64
+	PostId    int64
+	CommentId int64
+	MessageId int64
+	CreatedAt time.Time
+}
+
+type IMentionRepository interface {
+	Insert(mention *Mention) (int64, error)
+	GetById(id int64) (*Mention, error)
+}
+
+type MentionRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewMentionRepo(db *sql.DB) *MentionRepository {
+	return &MentionRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Inserts a mention, returns 0 if the user was already mentioned in the same entity
+func (repo MentionRepository) Insert(mention *Mention) (int64, error) {
+	query := `INSERT OR IGNORE INTO mentions (user_id, sender_id, post_id, comment_id, message_id, created_at)
+	VALUES(?, ?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		mention.UserId,
+		mention.SenderId,
+		nullableId(mention.PostId),
+		nullableId(mention.CommentId),
+		nullableId(mention.MessageId),
+		time.Now(),
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil || rowsAffected == 0 {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Inserted mention of user %d (last insert ID: %d)", mention.UserId, lastId)
+
+	return lastId, nil
+}
+
+func (repo MentionRepository) GetById(id int64) (*Mention, error) {
+	query := `SELECT id, user_id, sender_id, IFNULL(post_id, 0), IFNULL(comment_id, 0), IFNULL(message_id, 0), created_at FROM mentions WHERE id = ?`
+	row := repo.DB.QueryRow(query, id)
+	mention := &Mention{}
+
+	err := row.Scan(&mention.Id, &mention.UserId, &mention.SenderId, &mention.PostId, &mention.CommentId, &mention.MessageId, &mention.CreatedAt)
+
+	return mention, err
+}
@@ -76,8 +76,11 @@ func (repo MessageRepository) Update(message *Message) error {
 	return nil
 }
 
+// messageContent removes the content of hidden messages from chat history
+const messageContent = `CASE WHEN hidden THEN '[hidden]' ELSE content END`
+
 func (repo MessageRepository) GetMessagesByGroupId(groupId int64, lastMessage int64) ([]*Message, error) {
-	query := `SELECT id, sender_id, group_id, content, sent_at FROM messages m
+	query := `SELECT id, sender_id, group_id, ` + messageContent + `, sent_at FROM messages m
 	WHERE group_id = ? AND id < ?
 	ORDER BY sent_at DESC LIMIT 10`
 
@@ -113,7 +116,7 @@ func (repo MessageRepository) GetMessagesByGroupId(groupId int64, lastMessage in
 }
 
 func (repo MessageRepository) GetMessagesByUserIds(userId int64, secondUserId int64, lastMessage int64) ([]*Message, error) {
-	query := `SELECT id, sender_id, recipient_id, group_id, content, sent_at FROM messages m
+	query := `SELECT id, sender_id, recipient_id, group_id, ` + messageContent + `, sent_at FROM messages m
 	WHERE (sender_id = ? AND recipient_id = ? AND id < ?) OR (sender_id = ? AND recipient_id = ? AND id < ?) 
     ORDER BY sent_at DESC LIMIT 10`
 
@@ -231,12 +234,12 @@ func (repo MessageRepository) GetLastMessage(userId int64, otherId int64, isGrou
 	var args []interface{}
 
 	if isGroup {
-		query = `SELECT id, sender_id, recipient_id, group_id, content, sent_at FROM messages WHERE group_id = ? ORDER BY sent_at DESC LIMIT 1`
+		query = `SELECT id, sender_id, recipient_id, group_id, ` + messageContent + `, sent_at FROM messages WHERE group_id = ? ORDER BY sent_at DESC LIMIT 1`
 		args = []interface{}{
 			otherId,
 		}
 	} else {
-		query = `SELECT id, sender_id, recipient_id, group_id, content, sent_at FROM messages WHERE (sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?) ORDER BY sent_at DESC LIMIT 1`
+		query = `SELECT id, sender_id, recipient_id, group_id, ` + messageContent + `, sent_at FROM messages WHERE (sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?) ORDER BY sent_at DESC LIMIT 1`
 		args = []interface{}{
 			userId,
 			otherId,
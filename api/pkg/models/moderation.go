@@ -0,0 +1,215 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// Actions a moderator can take, every action is kept in the audit trail
+const (
+	ModerationHide        = "hide"
+	ModerationUnhide      = "unhide"
+	ModerationSuspend     = "suspend"
+	ModerationUnsuspend   = "unsuspend"
+	ModerationDeleteGroup = "delete_group"
+	ModerationGrantAdmin  = "grant_admin"
+	ModerationRevokeAdmin = "revoke_admin"
+)
+
+// Audit trail actions of report triage, keyed by the status the report is moved to
+var ModerationReportActions = map[string]string{
+	ReportStatusOpen:      "reopen",
+	ReportStatusReviewing: "review",
+	ReportStatusResolved:  "resolve",
+	ReportStatusDismissed: "dismiss",
+}
+
+const ModerationActionLimit = 50
+
+type ModerationAction struct {
+	Id          int64
+	ModeratorId int64
+	Action      string
+	EntityType  string
+	EntityId    int64
+	ReportId    int64
+	Note        string
+	CreatedAt   time.Time
+}
+
+type IModerationRepository interface {
+	InsertAction(action *ModerationAction) (int64, error)
+	GetActions(offset int64) ([]*ModerationAction, error)
+	SetHidden(entityType string, entityId int64, hidden bool) (bool, error)
+	DeleteGroup(groupId int64) ([]string, error)
+}
+
+type ModerationRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewModerationRepo(db *sql.DB) *ModerationRepository {
+	return &ModerationRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Tables holding the content that can be hidden
+var hideableTables = map[string]string{
+	ReportEntityPost:    "posts",
+	ReportEntityComment: "comments",
+	ReportEntityMessage: "messages",
+}
+
+// IsHideable reports whether entities of the type can be hidden
+func IsHideable(entityType string) bool {
+	_, ok := hideableTables[entityType]
+	return ok
+}
+
+func (repo ModerationRepository) InsertAction(action *ModerationAction) (int64, error) {
+	query := `INSERT INTO moderation_actions (moderator_id, action, entity_type, entity_id, report_id, note, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		action.ModeratorId,
+		action.Action,
+		action.EntityType,
+		action.EntityId,
+		action.ReportId,
+		action.Note,
+		action.CreatedAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Moderator %d: %s %s %d (last insert ID: %d)", action.ModeratorId, action.Action, action.EntityType, action.EntityId, lastId)
+
+	return lastId, nil
+}
+
+// Returns the audit trail, newest first
+func (repo ModerationRepository) GetActions(offset int64) ([]*ModerationAction, error) {
+	query := `SELECT id, moderator_id, action, entity_type, entity_id, report_id, note, created_at FROM moderation_actions
+	ORDER BY id DESC
+	LIMIT ? OFFSET ?`
+
+	rows, err := repo.DB.Query(query, ModerationActionLimit, offset*ModerationActionLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	actions := []*ModerationAction{}
+
+	for rows.Next() {
+		action := &ModerationAction{}
+
+		err := rows.Scan(&action.Id, &action.ModeratorId, &action.Action, &action.EntityType, &action.EntityId, &action.ReportId, &action.Note, &action.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// Hides or restores a post, comment or message, reports whether the entity exists
+func (repo ModerationRepository) SetHidden(entityType string, entityId int64, hidden bool) (bool, error) {
+	query := `UPDATE ` + hideableTables[entityType] + ` SET hidden = ? WHERE id = ?`
+
+	result, err := repo.DB.Exec(query, hidden, entityId)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+
+	return updated > 0, err
+}
+
+// Deletes the group with its posts, chat, events, memberships and the notifications
+// pointing at them in one transaction. Returns the images of the removed rows,
+// sql.ErrNoRows when the group does not exist.
+func (repo ModerationRepository) DeleteGroup(groupId int64) ([]string, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var id int64
+	if err = tx.QueryRow(`SELECT id FROM groups WHERE id = ?`, groupId).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	posts := `SELECT id FROM posts WHERE group_id = ?1`
+	comments := `SELECT id FROM comments WHERE post_id IN (` + posts + `)`
+	events := `SELECT id FROM group_events WHERE group_id = ?1`
+	mentions := `SELECT id FROM mentions WHERE post_id IN (` + posts + `) OR comment_id IN (` + comments + `)
+	OR message_id IN (SELECT id FROM messages WHERE group_id = ?1)`
+
+	imagePaths, err := queryImagePaths(tx, `SELECT image_path FROM groups WHERE id = ?1
+	UNION SELECT image_path FROM posts WHERE group_id = ?1
+	UNION SELECT image_path FROM comments WHERE post_id IN (`+posts+`)
+	UNION SELECT image_path FROM messages WHERE group_id = ?1`, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the entity of each notification type is listed in notification_types
+	notificationDetails := `SELECT id FROM notification_details
+	WHERE (notification_type_id = 1 AND entity_id IN (SELECT id FROM user_groups WHERE group_id = ?1))
+	OR (notification_type_id = 2 AND entity_id = ?1)
+	OR (notification_type_id = 3 AND entity_id IN (` + events + `))
+	OR (notification_type_id = 4 AND entity_id IN (` + mentions + `))`
+
+	statements := []string{
+		`DELETE FROM notifications WHERE notification_details_id IN (` + notificationDetails + `)`,
+		`DELETE FROM notification_details WHERE id IN (` + notificationDetails + `)`,
+		`DELETE FROM mentions WHERE id IN (` + mentions + `)`,
+		`DELETE FROM reactions WHERE post_id IN (` + posts + `) OR comment_id IN (` + comments + `)`,
+		`DELETE FROM post_tags WHERE post_id IN (` + posts + `)`,
+		`DELETE FROM allowed_private_posts WHERE post_id IN (` + posts + `)`,
+		`DELETE FROM comments WHERE post_id IN (` + posts + `)`,
+		`DELETE FROM posts WHERE group_id = ?1`,
+		`DELETE FROM messages WHERE group_id = ?1`,
+		`DELETE FROM group_event_attendance WHERE event_id IN (` + events + `)`,
+		`DELETE FROM group_events WHERE group_id = ?1`,
+		`DELETE FROM user_groups WHERE group_id = ?1`,
+		`DELETE FROM groups WHERE id = ?1`,
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement, groupId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	repo.Logger.Printf("Deleted group %d", groupId)
+
+	return imagePaths, nil
+}
@@ -34,6 +34,9 @@ type NotificationJSON struct {
 	EventId          int64     `json:"event_id"`
 	EventName        string    `json:"event_name"`
 	EventDate        time.Time `json:"event_datetime"`
+	PostId           int64     `json:"post_id"`
+	CommentId        int64     `json:"comment_id"`
+	MessageId        int64     `json:"message_id"`
 }
 
 type INotificationRepository interface {
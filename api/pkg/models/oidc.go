@@ -0,0 +1,222 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// OIDCState is a pending authorization request at an OpenID Connect provider,
+// LinkUserId is set when a logged in user links the provider to the account, or
+// confirms a request by logging in there again when Reauth is set
+type OIDCState struct {
+	Id           int64
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	LinkUserId   int64
+	Reauth       bool
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// UserIdentity links an account to the subject of an OpenID Connect provider
+type UserIdentity struct {
+	Id          int64
+	UserId      int64
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt sql.NullTime
+}
+
+type IOIDCRepository interface {
+	InsertState(state *OIDCState) (int64, error)
+	ConsumeState(stateHash string) (*OIDCState, error)
+	InsertIdentity(identity *UserIdentity) (int64, error)
+	GetIdentity(provider string, subject string) (*UserIdentity, error)
+	GetIdentitiesByUserId(userId int64) ([]*UserIdentity, error)
+	UpdateIdentityLogin(id int64, lastLoginAt time.Time) error
+	DeleteIdentity(id int64, userId int64) (bool, error)
+	SetIdentityReauthenticated(id int64, reauthenticatedAt time.Time) error
+	ConsumeReauthentication(userId int64, since time.Time) (bool, error)
+}
+
+type OIDCRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewOIDCRepo(db *sql.DB) *OIDCRepository {
+	return &OIDCRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Inserts a pending authorization request, abandoned requests are removed on the way
+func (repo OIDCRepository) InsertState(state *OIDCState) (int64, error) {
+	_, err := repo.DB.Exec(`DELETE FROM oidc_states WHERE expires_at <= ?`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO oidc_states (state_hash, provider, code_verifier, nonce, link_user_id, reauth, created_at, expires_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		state.StateHash,
+		state.Provider,
+		state.CodeVerifier,
+		state.Nonce,
+		state.LinkUserId,
+		state.Reauth,
+		state.CreatedAt,
+		state.ExpiresAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Removes and returns an unexpired authorization request,
+// sql.ErrNoRows means the state is unknown, used or expired
+func (repo OIDCRepository) ConsumeState(stateHash string) (*OIDCState, error) {
+	query := `DELETE FROM oidc_states WHERE state_hash = ? AND expires_at > ?
+	RETURNING id, state_hash, provider, code_verifier, nonce, link_user_id, reauth, created_at, expires_at`
+
+	state := &OIDCState{}
+
+	err := repo.DB.QueryRow(query, stateHash, time.Now()).Scan(&state.Id, &state.StateHash, &state.Provider,
+		&state.CodeVerifier, &state.Nonce, &state.LinkUserId, &state.Reauth, &state.CreatedAt, &state.ExpiresAt)
+
+	return state, err
+}
+
+// Inserts an identity, returns 0 if the subject is already linked to an account
+func (repo OIDCRepository) InsertIdentity(identity *UserIdentity) (int64, error) {
+	query := `INSERT OR IGNORE INTO user_identities (user_id, provider, subject, email, created_at)
+	VALUES(?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		identity.UserId,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Linked %s identity to user %d (last insert ID: %d)", identity.Provider, identity.UserId, lastId)
+
+	return lastId, nil
+}
+
+const identityColumns = `id, user_id, provider, subject, email, created_at, last_login_at`
+
+func scanIdentity(row interface{ Scan(...interface{}) error }) (*UserIdentity, error) {
+	identity := &UserIdentity{}
+
+	err := row.Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject, &identity.Email,
+		&identity.CreatedAt, &identity.LastLoginAt)
+
+	return identity, err
+}
+
+func (repo OIDCRepository) GetIdentity(provider string, subject string) (*UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = ? AND subject = ?`
+
+	return scanIdentity(repo.DB.QueryRow(query, provider, subject))
+}
+
+func (repo OIDCRepository) GetIdentitiesByUserId(userId int64) ([]*UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = ? ORDER BY id`
+
+	rows, err := repo.DB.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	identities := []*UserIdentity{}
+
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (repo OIDCRepository) UpdateIdentityLogin(id int64, lastLoginAt time.Time) error {
+	query := `UPDATE user_identities SET last_login_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, lastLoginAt, id)
+
+	return err
+}
+
+// Unlinks an identity of the user, reports whether the user had it
+func (repo OIDCRepository) DeleteIdentity(id int64, userId int64) (bool, error) {
+	query := `DELETE FROM user_identities WHERE id = ? AND user_id = ?`
+
+	result, err := repo.DB.Exec(query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+
+	return deleted > 0, err
+}
+
+func (repo OIDCRepository) SetIdentityReauthenticated(id int64, reauthenticatedAt time.Time) error {
+	query := `UPDATE user_identities SET reauthenticated_at = ?, last_login_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, reauthenticatedAt, reauthenticatedAt, id)
+
+	return err
+}
+
+// Uses up a login of the user at a linked provider since the time, reports whether there was one
+func (repo OIDCRepository) ConsumeReauthentication(userId int64, since time.Time) (bool, error) {
+	query := `UPDATE user_identities SET reauthenticated_at = NULL WHERE user_id = ? AND reauthenticated_at > ?`
+
+	result, err := repo.DB.Exec(query, userId, since)
+	if err != nil {
+		return false, err
+	}
+
+	consumed, err := result.RowsAffected()
+
+	return consumed > 0, err
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// PasswordReset is a single-use password reset request, only the hash of its token is stored
+type PasswordReset struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Scopes of the reset request counters
+const (
+	ResetScopeEmail = "email"
+	ResetScopeIP    = "ip"
+)
+
+// ResetRequests counts the reset requests for an email or from a client IP
+type ResetRequests struct {
+	Scope         string
+	Key           string
+	Requests      int
+	LastRequestAt time.Time
+	// BlockedUntil is the earliest time of the next request
+	BlockedUntil time.Time
+}
+
+type IPasswordResetRepository interface {
+	Insert(reset *PasswordReset) (int64, error)
+	Consume(tokenHash string) (int64, error)
+	DeleteByUserId(userId int64) error
+	GetRequests(scope string, key string) (*ResetRequests, error)
+	SaveRequests(requests *ResetRequests) error
+}
+
+type PasswordResetRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewPasswordResetRepo(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo PasswordResetRepository) Insert(reset *PasswordReset) (int64, error) {
+	query := `INSERT INTO password_resets (user_id, token_hash, created_at, expires_at)
+	VALUES(?, ?, ?, ?)`
+
+	args := []interface{}{
+		reset.UserId,
+		reset.TokenHash,
+		time.Now(),
+		reset.ExpiresAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Inserted password reset for user %d (last insert ID: %d)", reset.UserId, lastId)
+
+	return lastId, nil
+}
+
+// Marks an unused and unexpired reset as used and returns its user id,
+// sql.ErrNoRows means the token is unknown, used or expired
+func (repo PasswordResetRepository) Consume(tokenHash string) (int64, error) {
+	query := `UPDATE password_resets SET used_at = ?
+	WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+	RETURNING user_id`
+
+	now := time.Now()
+
+	var userId int64
+	err := repo.DB.QueryRow(query, now, tokenHash, now).Scan(&userId)
+
+	return userId, err
+}
+
+// Removes all reset requests of the user, used or not
+func (repo PasswordResetRepository) DeleteByUserId(userId int64) error {
+	query := `DELETE FROM password_resets WHERE user_id = ?`
+
+	_, err := repo.DB.Exec(query, userId)
+
+	return err
+}
+
+func (repo PasswordResetRepository) GetRequests(scope string, key string) (*ResetRequests, error) {
+	query := `SELECT scope, key, requests, last_request_at, blocked_until FROM password_reset_requests WHERE scope = ? AND key = ?`
+
+	requests := &ResetRequests{}
+
+	err := repo.DB.QueryRow(query, scope, key).Scan(&requests.Scope, &requests.Key, &requests.Requests, &requests.LastRequestAt, &requests.BlockedUntil)
+
+	return requests, err
+}
+
+func (repo PasswordResetRepository) SaveRequests(requests *ResetRequests) error {
+	query := `INSERT INTO password_reset_requests (scope, key, requests, last_request_at, blocked_until)
+	VALUES(?, ?, ?, ?, ?)
+	ON CONFLICT (scope, key) DO UPDATE SET
+	requests = excluded.requests,
+	last_request_at = excluded.last_request_at,
+	blocked_until = excluded.blocked_until`
+
+	args := []interface{}{
+		requests.Scope,
+		requests.Key,
+		requests.Requests,
+		requests.LastRequestAt,
+		requests.BlockedUntil,
+	}
+
+	_, err := repo.DB.Exec(query, args...)
+
+	return err
+}
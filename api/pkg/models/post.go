@@ -37,9 +37,12 @@ type IPostRepository interface {
 	GetAllByUserId(id int64, offset int64) ([]*FeedPost, error)
 	GetAllByGroupId(id int64, offset int64) ([]*FeedPost, error)
 	GetAllFeedPosts(currentUserId int64, offset int64) ([]*FeedPost, error)
+	GetAllByTag(tag string, currentUserId int64, offset int64) ([]*FeedPost, error)
 	GetById(id int64) (*Post, error)
+	IsVisibleToUser(postId int64, userId int64) (bool, error)
 	Insert(post *Post) (int64, error)
 	Update(post *Post) error
+	Delete(id int64) error
 	GetCommentCount(postId int64) (int, error)
 	GetLastPostId() (int64, error)
 	GetAllByUserAndRequestingUserIds(userId int64, offset int64, requestingUserId int64) ([]*FeedPost, error)
@@ -105,6 +108,42 @@ func (repo PostRepository) Update(post *Post) error {
 
 }
 
+// Deletes the post together with its mentions, tags, reactions, comments and allowed
+// users in one transaction
+func (repo PostRepository) Delete(id int64) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	comments := `SELECT id FROM comments WHERE post_id = ?1`
+
+	statements := []string{
+		`DELETE FROM mentions WHERE post_id = ?1 OR comment_id IN (` + comments + `)`,
+		`DELETE FROM post_tags WHERE post_id = ?1`,
+		`DELETE FROM reactions WHERE post_id = ?1 OR comment_id IN (` + comments + `)`,
+		`DELETE FROM comments WHERE post_id = ?1`,
+		`DELETE FROM allowed_private_posts WHERE post_id = ?1`,
+		`DELETE FROM posts WHERE id = ?1`,
+	}
+
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	repo.Logger.Printf("Deleted post %d", id)
+
+	return nil
+}
+
 func (repo PostRepository) GetById(id int64) (*Post, error) {
 	query := `SELECT id, user_id, content, created_at, image_path, privacy_type_id, group_id FROM posts WHERE id = ?`
 	row := repo.DB.QueryRow(query, id)
@@ -122,7 +161,7 @@ func (repo PostRepository) GetAllByUserId(id int64, offset int64) ([]*FeedPost,
 	p.user_id = u.id
 	LEFT JOIN comments c ON
 	p.id = c.post_id
-	WHERE p.user_id = ? AND p.id < ?
+	WHERE p.user_id = ? AND p.id < ? AND p.hidden = false
 	GROUP BY p.id
     ORDER BY p.id DESC
 	LIMIT ?`
@@ -167,7 +206,7 @@ func (repo PostRepository) GetAllByGroupId(groupId int64, offset int64) ([]*Feed
 	p.user_id = u.id
 	LEFT JOIN comments c ON
 	p.id = c.post_id
-	WHERE p.group_id = ? AND p.id < ?
+	WHERE p.group_id = ? AND p.id < ? AND p.hidden = false
 	GROUP BY p.id
 	ORDER BY p.id DESC
 	LIMIT ?`
@@ -205,6 +244,47 @@ func (repo PostRepository) GetAllByGroupId(groupId int64, offset int64) ([]*Feed
 	return posts, nil
 }
 
+// feedVisibilityJoins and feedVisibilityCondition select the posts p a user may see in the feed,
+// the condition takes the arguments returned by feedVisibilityArgs
+const feedVisibilityJoins = `LEFT JOIN  followers f ON  
+	p.user_id = f.following_id
+	LEFT JOIN allowed_private_posts app ON
+	p.id = app.post_id`
+
+const feedVisibilityCondition = `(privacy_type_id = 1
+	OR p.user_id = ?
+	OR (privacy_type_id = 2 AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1)
+	OR (privacy_type_id = 3 AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1 AND app.id IS NOT NULL AND app.user_id = ?)
+	OR p.group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?))
+	AND p.user_id NOT IN (` + blockedUserIds + `)
+	AND p.hidden = false`
+
+func feedVisibilityArgs(currentUserId int64) []interface{} {
+	return []interface{}{
+		currentUserId,
+		currentUserId,
+		currentUserId,
+		currentUserId,
+		currentUserId,
+		currentUserId,
+		currentUserId,
+	}
+}
+
+// Reports whether the post is shown to the user by the feed
+func (repo PostRepository) IsVisibleToUser(postId int64, userId int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM posts p
+	` + feedVisibilityJoins + `
+	WHERE p.id = ? AND ` + feedVisibilityCondition + `)`
+
+	args := append([]interface{}{postId}, feedVisibilityArgs(userId)...)
+
+	var visible bool
+	err := repo.DB.QueryRow(query, args...).Scan(&visible)
+
+	return visible, err
+}
+
 // Return all posts to the current user by offset
 func (m PostRepository) GetAllFeedPosts(currentUserId int64, offset int64) ([]*FeedPost, error) {
 
@@ -215,32 +295,66 @@ func (m PostRepository) GetAllFeedPosts(currentUserId int64, offset int64) ([]*F
 	stmt := `SELECT p.id, p.user_id, u.forname, u.surname, u.nickname, p.content, p.created_at, p.image_path, privacy_type_id, p.group_id, COUNT(DISTINCT c.id) FROM posts p 
 	LEFT JOIN users u on
 	p.user_id = u.id
-	LEFT JOIN  followers f ON  
-	p.user_id = f.following_id
-	LEFT JOIN allowed_private_posts app ON
-	p.id = app.post_id
+	` + feedVisibilityJoins + `
 	LEFT JOIN comments c ON
 	p.id = c.post_id
-	WHERE (privacy_type_id = 1
-	OR p.user_id = ?
-	OR (privacy_type_id = 2 AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1)
-	OR (privacy_type_id = 3 AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1 AND app.id IS NOT NULL AND app.user_id = ?)
-	OR p.group_id IN (SELECT group_id FROM user_groups WHERE user_id = ?))
+	WHERE ` + feedVisibilityCondition + `
 	AND p.id < ?
 	GROUP BY p.id
 	ORDER BY p.id DESC
 	LIMIT ?`
 
-	args := []interface{}{
-		currentUserId,
-		currentUserId,
-		currentUserId,
-		currentUserId,
-		currentUserId,
-		offset,
-		FeedLimit,
+	args := append(feedVisibilityArgs(currentUserId), offset, FeedLimit)
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
 	}
 
+	defer rows.Close()
+
+	posts := []*FeedPost{}
+
+	for rows.Next() {
+		post := &FeedPost{}
+
+		err := rows.Scan(&post.Id, &post.UserId, &post.FirstName, &post.LastName, &post.Nickname, &post.Content, &post.CreatedAt, &post.ImagePath, &post.PrivacyType, &post.GroupId, &post.CommentCount)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Return the posts with the tag visible to the current user, older than the post id given as offset
+func (m PostRepository) GetAllByTag(tag string, currentUserId int64, offset int64) ([]*FeedPost, error) {
+
+	stmt := `SELECT p.id, p.user_id, u.forname, u.surname, u.nickname, p.content, p.created_at, p.image_path, privacy_type_id, p.group_id, COUNT(DISTINCT c.id) FROM posts p
+	INNER JOIN post_tags pt ON
+	p.id = pt.post_id
+	INNER JOIN tags t ON
+	pt.tag_id = t.id
+	LEFT JOIN users u on
+	p.user_id = u.id
+	` + feedVisibilityJoins + `
+	LEFT JOIN comments c ON
+	p.id = c.post_id
+	WHERE t.name = ?
+	AND ` + feedVisibilityCondition + `
+	AND p.id < ?
+	GROUP BY p.id
+	ORDER BY p.id DESC
+	LIMIT ?`
+
+	args := append([]interface{}{tag}, feedVisibilityArgs(currentUserId)...)
+	args = append(args, offset, FeedLimit)
+
 	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
@@ -335,6 +449,8 @@ func (repo PostRepository) GetAllByUserAndRequestingUserIds(userId int64, offset
 	WHERE (p.privacy_type_id = 1 AND p.user_id = ?
 	OR (p.privacy_type_id = 2 AND p.user_id = ? AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1)
 	OR (p.privacy_type_id = 3 AND p.user_id = ? AND f.id IS NOT NULL AND f.follower_id = ? AND f.accepted = 1 AND app.id IS NOT NULL AND app.user_id = ?))
+	AND p.user_id NOT IN (` + blockedUserIds + `)
+	AND p.hidden = false
 	AND p.id < ?
 	GROUP BY p.id
 	ORDER BY p.id DESC
@@ -347,6 +463,8 @@ func (repo PostRepository) GetAllByUserAndRequestingUserIds(userId int64, offset
 		userId,
 		requestingUserId,
 		requestingUserId,
+		requestingUserId,
+		requestingUserId,
 		offset,
 		FeedLimit,
 	}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reaction belongs either to a post or to a comment, the other id is 0
+type Reaction struct {
+	Id           int64
+	UserId       int64
+	PostId       int64
+	CommentId    int64
+	ReactionType enums.ReactionType
+	CreatedAt    time.Time
+}
+
+// ReactionSummary aggregates the reactions of a single post or comment
+type ReactionSummary struct {
+	Counts     map[string]int
+	MyReaction enums.ReactionType
+}
+
+type IReactionRepository interface {
+	GetPostReaction(userId int64, postId int64) (*Reaction, error)
+	GetCommentReaction(userId int64, commentId int64) (*Reaction, error)
+	Insert(reaction *Reaction) (int64, error)
+	UpdateType(id int64, reactionType enums.ReactionType) error
+	Delete(id int64) error
+	GetPostSummaries(postIds []int64, userId int64) (map[int64]*ReactionSummary, error)
+	GetCommentSummaries(commentIds []int64, userId int64) (map[int64]*ReactionSummary, error)
+}
+
+type ReactionRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewReactionRepo(db *sql.DB) *ReactionRepository {
+	return &ReactionRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo ReactionRepository) Insert(reaction *Reaction) (int64, error) {
+	query := `INSERT INTO reactions (user_id, post_id, comment_id, reaction_type_id, created_at)
+	VALUES(?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		reaction.UserId,
+		nullableId(reaction.PostId),
+		nullableId(reaction.CommentId),
+		reaction.ReactionType,
+		time.Now(),
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("Inserted reaction by user %d (last insert ID: %d)", reaction.UserId, lastId)
+
+	return lastId, nil
+}
+
+// Returns the reaction of a user to a post
+func (repo ReactionRepository) GetPostReaction(userId int64, postId int64) (*Reaction, error) {
+	query := `SELECT id, user_id, post_id, reaction_type_id, created_at FROM reactions WHERE user_id = ? AND post_id = ?`
+	row := repo.DB.QueryRow(query, userId, postId)
+	reaction := &Reaction{}
+
+	err := row.Scan(&reaction.Id, &reaction.UserId, &reaction.PostId, &reaction.ReactionType, &reaction.CreatedAt)
+
+	return reaction, err
+}
+
+// Returns the reaction of a user to a comment
+func (repo ReactionRepository) GetCommentReaction(userId int64, commentId int64) (*Reaction, error) {
+	query := `SELECT id, user_id, comment_id, reaction_type_id, created_at FROM reactions WHERE user_id = ? AND comment_id = ?`
+	row := repo.DB.QueryRow(query, userId, commentId)
+	reaction := &Reaction{}
+
+	err := row.Scan(&reaction.Id, &reaction.UserId, &reaction.CommentId, &reaction.ReactionType, &reaction.CreatedAt)
+
+	return reaction, err
+}
+
+func (repo ReactionRepository) UpdateType(id int64, reactionType enums.ReactionType) error {
+	query := `UPDATE reactions SET reaction_type_id = ?, created_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, reactionType, time.Now(), id)
+
+	return err
+}
+
+func (repo ReactionRepository) Delete(id int64) error {
+	query := `DELETE FROM reactions WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, id)
+
+	return err
+}
+
+// Returns reaction counts of the given posts, keyed by post id, together with the reaction of the user
+func (repo ReactionRepository) GetPostSummaries(postIds []int64, userId int64) (map[int64]*ReactionSummary, error) {
+	return repo.getSummaries("post_id", postIds, userId)
+}
+
+// Returns reaction counts of the given comments, keyed by comment id, together with the reaction of the user
+func (repo ReactionRepository) GetCommentSummaries(commentIds []int64, userId int64) (map[int64]*ReactionSummary, error) {
+	return repo.getSummaries("comment_id", commentIds, userId)
+}
+
+func (repo ReactionRepository) getSummaries(column string, ids []int64, userId int64) (map[int64]*ReactionSummary, error) {
+	summaries := make(map[int64]*ReactionSummary, len(ids))
+
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	query := `SELECT ` + column + `, reaction_type_id, COUNT(*), MAX(user_id = ?) FROM reactions
+	WHERE ` + column + ` IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+	GROUP BY ` + column + `, reaction_type_id`
+
+	args := []interface{}{userId}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id           int64
+			reactionType enums.ReactionType
+			count        int
+			mine         bool
+		)
+
+		if err := rows.Scan(&id, &reactionType, &count, &mine); err != nil {
+			return nil, err
+		}
+
+		summary, ok := summaries[id]
+		if !ok {
+			summary = &ReactionSummary{Counts: map[string]int{}}
+			summaries[id] = summary
+		}
+
+		summary.Counts[reactionType.String()] = count
+		if mine {
+			summary.MyReaction = reactionType
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// nullableId stores 0 as NULL
+func nullableId(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
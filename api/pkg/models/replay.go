@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// ReplayEvent is a websocket event kept so a user who missed it can be sent it again
+type ReplayEvent struct {
+	UserId    int64
+	Seq       int64
+	Type      string
+	Data      []byte
+	CreatedAt time.Time
+}
+
+type IReplayRepository interface {
+	Append(event *ReplayEvent) (int64, error)
+	GetSeq(userId int64) (int64, error)
+	GetSince(userId int64, afterSeq int64, limit int) ([]*ReplayEvent, error)
+	DeleteBefore(createdBefore time.Time) (int64, error)
+}
+
+type ReplayRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewReplayRepo(db *sql.DB) *ReplayRepository {
+	return &ReplayRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Append stores the event under the next sequence number of its user and returns it.
+// The sequence is kept apart from the events, pruning them does not restart it.
+func (repo ReplayRepository) Append(event *ReplayEvent) (int64, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	query := `INSERT INTO replay_sequences (user_id, seq) VALUES(?, 1)
+	ON CONFLICT (user_id) DO UPDATE SET seq = seq + 1
+	RETURNING seq`
+
+	var seq int64
+	if err = tx.QueryRow(query, event.UserId).Scan(&seq); err != nil {
+		return 0, err
+	}
+
+	query = `INSERT INTO replay_events (user_id, seq, type, data, created_at) VALUES(?, ?, ?, ?, ?)`
+
+	_, err = tx.Exec(query, event.UserId, seq, event.Type, string(event.Data), event.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	event.Seq = seq
+
+	return seq, nil
+}
+
+// GetSeq returns the sequence number of the last event of the user, 0 if there was none
+func (repo ReplayRepository) GetSeq(userId int64) (int64, error) {
+	query := `SELECT seq FROM replay_sequences WHERE user_id = ?`
+
+	var seq int64
+	err := repo.DB.QueryRow(query, userId).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return seq, err
+}
+
+// GetSince returns at most limit events of the user that came after afterSeq, oldest first
+func (repo ReplayRepository) GetSince(userId int64, afterSeq int64, limit int) ([]*ReplayEvent, error) {
+	query := `SELECT user_id, seq, type, data, created_at FROM replay_events
+	WHERE user_id = ? AND seq > ?
+	ORDER BY seq ASC
+	LIMIT ?`
+
+	rows, err := repo.DB.Query(query, userId, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []*ReplayEvent{}
+
+	for rows.Next() {
+		event := &ReplayEvent{}
+		var data string
+
+		err := rows.Scan(&event.UserId, &event.Seq, &event.Type, &data, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Data = []byte(data)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteBefore removes the events created before the given time and returns how many there were
+func (repo ReplayRepository) DeleteBefore(createdBefore time.Time) (int64, error) {
+	query := `DELETE FROM replay_events WHERE created_at < ?`
+
+	result, err := repo.DB.Exec(query, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
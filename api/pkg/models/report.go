@@ -0,0 +1,194 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// Kinds of entities that can be reported
+const (
+	ReportEntityPost    = "post"
+	ReportEntityComment = "comment"
+	ReportEntityMessage = "message"
+	ReportEntityGroup   = "group"
+	ReportEntityUser    = "user"
+)
+
+// Statuses of a report, open and reviewing reports are still waiting for a moderator
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusReviewing = "reviewing"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+var ReportStatuses = []string{ReportStatusOpen, ReportStatusReviewing, ReportStatusResolved, ReportStatusDismissed}
+
+var ReportReasons = []string{"spam", "harassment", "hate_speech", "violence", "nudity", "misinformation", "other"}
+
+const ReportLimit = 20
+
+type Report struct {
+	Id             int64
+	ReporterId     int64
+	EntityType     string
+	EntityId       int64
+	ReportedUserId int64
+	Reason         string
+	Details        string
+	Snapshot       string
+	Status         string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReportTarget is the reported entity as it is stored now
+type ReportTarget struct {
+	OwnerId  int64
+	Snapshot string
+}
+
+type IReportRepository interface {
+	Insert(report *Report) (int64, error)
+	GetById(id int64) (*Report, error)
+	GetAll(status string, offset int64) ([]*Report, error)
+	UpdateStatus(id int64, status string) error
+	GetTarget(entityType string, entityId int64) (*ReportTarget, error)
+}
+
+type ReportRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) *ReportRepository {
+	return &ReportRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Selects the owner and the text of each kind of reported entity
+var reportTargetQueries = map[string]string{
+	ReportEntityPost:    `SELECT user_id, content FROM posts WHERE id = ?`,
+	ReportEntityComment: `SELECT user_id, content FROM comments WHERE id = ?`,
+	ReportEntityMessage: `SELECT sender_id, content FROM messages WHERE id = ?`,
+	ReportEntityGroup:   `SELECT creator_id, title || char(10) || description FROM groups WHERE id = ?`,
+	ReportEntityUser: `SELECT id, forname || ' ' || surname || ' (' || nickname || ')' || char(10) || about FROM users
+	WHERE id = ? AND deleted_at IS NULL`,
+}
+
+// IsReportEntity reports whether entities of the type can be reported
+func IsReportEntity(entityType string) bool {
+	_, ok := reportTargetQueries[entityType]
+	return ok
+}
+
+// Inserts a report, returns 0 if the reporter already has a pending report on the entity
+func (repo ReportRepository) Insert(report *Report) (int64, error) {
+	query := `INSERT OR IGNORE INTO reports (reporter_id, entity_type, entity_id, reported_user_id, reason, details, snapshot, status, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	args := []interface{}{
+		report.ReporterId,
+		report.EntityType,
+		report.EntityId,
+		report.ReportedUserId,
+		report.Reason,
+		report.Details,
+		report.Snapshot,
+		report.Status,
+		report.CreatedAt,
+		report.UpdatedAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return 0, err
+	}
+
+	lastId, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	repo.Logger.Printf("User %d reported %s %d (last insert ID: %d)", report.ReporterId, report.EntityType, report.EntityId, lastId)
+
+	return lastId, nil
+}
+
+const reportColumns = `id, reporter_id, entity_type, entity_id, reported_user_id, reason, details, snapshot, status, created_at, updated_at`
+
+func scanReport(row interface{ Scan(...interface{}) error }) (*Report, error) {
+	report := &Report{}
+
+	err := row.Scan(&report.Id, &report.ReporterId, &report.EntityType, &report.EntityId, &report.ReportedUserId, &report.Reason,
+		&report.Details, &report.Snapshot, &report.Status, &report.CreatedAt, &report.UpdatedAt)
+
+	return report, err
+}
+
+func (repo ReportRepository) GetById(id int64) (*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = ?`
+
+	return scanReport(repo.DB.QueryRow(query, id))
+}
+
+// Returns the reports with the status, oldest first so that the queue is handled in order.
+// An empty status returns the reports still waiting for a moderator.
+func (repo ReportRepository) GetAll(status string, offset int64) ([]*Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE status = ? ORDER BY id LIMIT ? OFFSET ?`
+	args := []interface{}{status, ReportLimit, offset * ReportLimit}
+
+	if status == "" {
+		query = `SELECT ` + reportColumns + ` FROM reports WHERE status IN (?, ?) ORDER BY id LIMIT ? OFFSET ?`
+		args = []interface{}{ReportStatusOpen, ReportStatusReviewing, ReportLimit, offset * ReportLimit}
+	}
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	reports := []*Report{}
+
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+func (repo ReportRepository) UpdateStatus(id int64, status string) error {
+	query := `UPDATE reports SET status = ?, updated_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, status, time.Now(), id)
+
+	return err
+}
+
+// Returns the owner and text of the entity, sql.ErrNoRows when it does not exist
+func (repo ReportRepository) GetTarget(entityType string, entityId int64) (*ReportTarget, error) {
+	target := &ReportTarget{}
+
+	err := repo.DB.QueryRow(reportTargetQueries[entityType], entityId).Scan(&target.OwnerId, &target.Snapshot)
+
+	return target, err
+}
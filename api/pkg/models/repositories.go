@@ -6,18 +6,33 @@ import (
 
 // Repositories contains all the repo structs
 type Repositories struct {
-	UserRepo            *UserRepository
-	SessionRepo         *SessionRepository
-	FollowerRepo        *FollowerRepository
-	PostRepo            *PostRepository
-	CommentRepo         *CommentRepository
-	GroupRepo           *GroupRepository
-	EventRepo           *EventRepository
-	MessageRepo         *MessageRepository
-	NotificationRepo    *NotificationRepository
-	GroupMemberRepo     *GroupMemberRepository
-	AllowedPostRepo     *AllowedPostRepository
-	EventAttendanceRepo *EventAttendanceRepository
+	UserRepo              *UserRepository
+	SessionRepo           *SessionRepository
+	FollowerRepo          *FollowerRepository
+	PostRepo              *PostRepository
+	CommentRepo           *CommentRepository
+	GroupRepo             *GroupRepository
+	EventRepo             *EventRepository
+	MessageRepo           *MessageRepository
+	NotificationRepo      *NotificationRepository
+	GroupMemberRepo       *GroupMemberRepository
+	AllowedPostRepo       *AllowedPostRepository
+	EventAttendanceRepo   *EventAttendanceRepository
+	ReactionRepo          *ReactionRepository
+	MentionRepo           *MentionRepository
+	TagRepo               *TagRepository
+	SearchRepo            *SearchRepository
+	PasswordResetRepo     *PasswordResetRepository
+	EmailVerificationRepo *EmailVerificationRepository
+	TwoFactorRepo         *TwoFactorRepository
+	LoginFailureRepo      *LoginFailureRepository
+	AccountRepo           *AccountRepository
+	BlockRepo             *BlockRepository
+	ReportRepo            *ReportRepository
+	ModerationRepo        *ModerationRepository
+	AccessTokenRepo       *AccessTokenRepository
+	OIDCRepo              *OIDCRepository
+	ReplayRepo            *ReplayRepository
 }
 
 // InitRepositories should be called in main.go
@@ -34,19 +49,49 @@ func InitRepositories(db *sql.DB) *Repositories {
 	groupMemberRepo := NewGroupMemberRepo(db)
 	allowedPostRepo := NewAllowedPostRepo(db)
 	eventAttendanceRepo := NewEventAttendanceRepo(db)
+	reactionRepo := NewReactionRepo(db)
+	mentionRepo := NewMentionRepo(db)
+	tagRepo := NewTagRepo(db)
+	searchRepo := NewSearchRepo(db)
+	passwordResetRepo := NewPasswordResetRepo(db)
+	emailVerificationRepo := NewEmailVerificationRepo(db)
+	twoFactorRepo := NewTwoFactorRepo(db)
+	loginFailureRepo := NewLoginFailureRepo(db)
+	accountRepo := NewAccountRepo(db)
+	blockRepo := NewBlockRepo(db)
+	reportRepo := NewReportRepo(db)
+	moderationRepo := NewModerationRepo(db)
+	accessTokenRepo := NewAccessTokenRepo(db)
+	oidcRepo := NewOIDCRepo(db)
+	replayRepo := NewReplayRepo(db)
 
 	return &Repositories{
-		UserRepo:            userRepo,
-		SessionRepo:         sessionRepo,
-		FollowerRepo:        followerRepo,
-		PostRepo:            postRepo,
-		CommentRepo:         commentRepo,
-		GroupRepo:           groupRepo,
-		EventRepo:           eventRepo,
-		MessageRepo:         messageRepo,
-		NotificationRepo:    notificationRepo,
-		GroupMemberRepo:     groupMemberRepo,
-		AllowedPostRepo:     allowedPostRepo,
-		EventAttendanceRepo: eventAttendanceRepo,
+		UserRepo:              userRepo,
+		SessionRepo:           sessionRepo,
+		FollowerRepo:          followerRepo,
+		PostRepo:              postRepo,
+		CommentRepo:           commentRepo,
+		GroupRepo:             groupRepo,
+		EventRepo:             eventRepo,
+		MessageRepo:           messageRepo,
+		NotificationRepo:      notificationRepo,
+		GroupMemberRepo:       groupMemberRepo,
+		AllowedPostRepo:       allowedPostRepo,
+		EventAttendanceRepo:   eventAttendanceRepo,
+		ReactionRepo:          reactionRepo,
+		MentionRepo:           mentionRepo,
+		TagRepo:               tagRepo,
+		SearchRepo:            searchRepo,
+		PasswordResetRepo:     passwordResetRepo,
+		EmailVerificationRepo: emailVerificationRepo,
+		TwoFactorRepo:         twoFactorRepo,
+		LoginFailureRepo:      loginFailureRepo,
+		AccountRepo:           accountRepo,
+		BlockRepo:             blockRepo,
+		ReportRepo:            reportRepo,
+		ModerationRepo:        moderationRepo,
+		AccessTokenRepo:       accessTokenRepo,
+		OIDCRepo:              oidcRepo,
+		ReplayRepo:            replayRepo,
 	}
 }
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+)
+
+const SearchLimit = 20
+
+// Markers wrapped around the matched terms of a snippet
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
+// SearchHit is a ranked full-text search result, a lower rank is a better match
+type SearchHit struct {
+	Type      string
+	Id        int64
+	PostId    int64
+	GroupId   int64
+	UserId    int64
+	Title     string
+	Snippet   string
+	ImagePath string
+	Rank      float64
+}
+
+type ISearchRepository interface {
+	Search(currentUserId int64, match string, types []string, offset int, limit int) ([]*SearchHit, error)
+}
+
+type SearchRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewSearchRepo(db *sql.DB) *SearchRepository {
+	return &SearchRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// displayName is the nickname of user u, or the full name when there is none
+const displayName = `CASE WHEN IFNULL(u.nickname, '') = '' THEN u.forname || ' ' || u.surname ELSE u.nickname END`
+
+// visiblePostIds selects the ids of the posts the user may see in the feed
+const visiblePostIds = `SELECT p.id FROM posts p
+	` + feedVisibilityJoins + `
+	WHERE ` + feedVisibilityCondition
+
+// Each search source selects type, id, post_id, group_id, user_id, title, snippet, image_path and search_rank
+// for an FTS5 match expression, sources reading posts also take feedVisibilityArgs and sources
+// hiding blocked users take the id of the current user twice after that.
+var searchSources = map[string]struct {
+	query         string
+	useVisibility bool
+	useBlocks     bool
+}{
+	"posts": {
+		query: `SELECT 'post' AS type, p.id AS id, p.id AS post_id, IFNULL(p.group_id, 0) AS group_id, p.user_id AS user_id, ` + displayName + ` AS title,
+		snippet(posts_fts, -1, char(2), char(3), '…', 16) AS snippet, IFNULL(p.image_path, '') AS image_path, bm25(posts_fts) AS search_rank
+		FROM posts_fts
+		INNER JOIN posts p ON
+		p.id = posts_fts.rowid
+		LEFT JOIN users u ON
+		p.user_id = u.id
+		WHERE posts_fts MATCH ?
+		AND p.id IN (` + visiblePostIds + `)`,
+		useVisibility: true,
+	},
+	"comments": {
+		query: `SELECT 'comment' AS type, c.id AS id, c.post_id AS post_id, IFNULL(p.group_id, 0) AS group_id, c.user_id AS user_id, ` + displayName + ` AS title,
+		snippet(comments_fts, -1, char(2), char(3), '…', 16) AS snippet, IFNULL(c.image_path, '') AS image_path, bm25(comments_fts) AS search_rank
+		FROM comments_fts
+		INNER JOIN comments c ON
+		c.id = comments_fts.rowid
+		INNER JOIN posts p ON
+		c.post_id = p.id
+		LEFT JOIN users u ON
+		c.user_id = u.id
+		WHERE comments_fts MATCH ?
+		AND c.post_id IN (` + visiblePostIds + `)
+		AND c.hidden = false
+		AND c.user_id NOT IN (` + blockedUserIds + `)`,
+		useVisibility: true,
+		useBlocks:     true,
+	},
+	"groups": {
+		query: `SELECT 'group' AS type, g.id AS id, 0 AS post_id, g.id AS group_id, g.creator_id AS user_id, g.title AS title,
+		snippet(groups_fts, -1, char(2), char(3), '…', 16) AS snippet, IFNULL(g.image_path, '') AS image_path, bm25(groups_fts, 2.0, 1.0) AS search_rank
+		FROM groups_fts
+		INNER JOIN groups g ON
+		g.id = groups_fts.rowid
+		WHERE groups_fts MATCH ?`,
+	},
+	"users": {
+		query: `SELECT 'user' AS type, u.id AS id, 0 AS post_id, 0 AS group_id, u.id AS user_id, ` + displayName + ` AS title,
+		snippet(users_fts, -1, char(2), char(3), '…', 8) AS snippet, IFNULL(u.image_path, '') AS image_path, bm25(users_fts) AS search_rank
+		FROM users_fts
+		INNER JOIN users u ON
+		u.id = users_fts.rowid
+		WHERE users_fts MATCH ?
+		AND u.deleted_at IS NULL
+		AND u.id NOT IN (` + blockedUserIds + `)`,
+		useBlocks: true,
+	},
+}
+
+// SearchTypes lists the sources that can be searched
+var SearchTypes = []string{"users", "groups", "posts", "comments"}
+
+// Search runs the match expression against the given sources and returns the results ordered by rank
+func (repo SearchRepository) Search(currentUserId int64, match string, types []string, offset int, limit int) ([]*SearchHit, error) {
+	queries := []string{}
+	args := []interface{}{}
+
+	for _, searchType := range types {
+		source := searchSources[searchType]
+
+		queries = append(queries, source.query)
+		args = append(args, match)
+
+		if source.useVisibility {
+			args = append(args, feedVisibilityArgs(currentUserId)...)
+		}
+
+		if source.useBlocks {
+			args = append(args, currentUserId, currentUserId)
+		}
+	}
+
+	query := `SELECT type, id, post_id, group_id, user_id, title, snippet, image_path, search_rank FROM (
+	` + strings.Join(queries, "\n\tUNION ALL\n\t") + `)
+	ORDER BY search_rank, type, id
+	LIMIT ? OFFSET ?`
+
+	args = append(args, limit, offset)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hits := []*SearchHit{}
+
+	for rows.Next() {
+		hit := &SearchHit{}
+
+		err := rows.Scan(&hit.Type, &hit.Id, &hit.PostId, &hit.GroupId, &hit.UserId, &hit.Title, &hit.Snippet, &hit.ImagePath, &hit.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		hits = append(hits, hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
@@ -8,17 +8,25 @@ import (
 )
 
 type Session struct {
-	Id        int64
-	UserId    int64
-	Token     string
-	CreatedAt time.Time
+	Id         int64
+	UserId     int64
+	Token      string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string
+	IpAddress  string
 }
 
 type ISessionRepository interface {
 	DeleteByToken(token string) error
+	DeleteById(id int64) error
+	DeleteByUserId(userId int64) ([]int64, error)
+	DeleteExpired(lastSeenBefore time.Time) ([]int64, error)
 	GetByToken(token string) (*Session, error)
-	GetUserSessions(id int) ([]*Session, error)
+	GetById(id int64) (*Session, error)
+	GetUserSessions(userId int64) ([]*Session, error)
 	Insert(session *Session) (int64, error)
+	UpdateLastSeen(id int64, lastSeenAt time.Time) error
 }
 
 type SessionRepository struct {
@@ -35,13 +43,18 @@ func NewSessionRepo(db *sql.DB) *SessionRepository {
 
 // Inserts a new user session to database
 func (repo SessionRepository) Insert(session *Session) (int64, error) {
-	query := `INSERT INTO user_sessions (user_id, token, created_at)
-	VALUES(?, ?, ?)`
+	query := `INSERT INTO user_sessions (user_id, token, created_at, last_seen_at, user_agent, ip_address)
+	VALUES(?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
 
 	args := []interface{}{
 		session.UserId,
 		session.Token,
-		time.Now(),
+		now,
+		now,
+		session.UserAgent,
+		session.IpAddress,
 	}
 
 	result, err := repo.DB.Exec(query, args...)
@@ -64,21 +77,67 @@ func (repo SessionRepository) Insert(session *Session) (int64, error) {
 // Returns a session by token
 func (repo SessionRepository) GetByToken(token string) (*Session, error) {
 
-	query := `SELECT id, user_id, token, created_at FROM user_sessions WHERE token = ?`
+	query := `SELECT id, user_id, token, created_at, last_seen_at, user_agent, ip_address FROM user_sessions WHERE token = ?`
 	row := repo.DB.QueryRow(query, token)
 	session := &Session{}
 
-	err := row.Scan(&session.Id, &session.UserId, &session.Token, &session.CreatedAt)
+	err := row.Scan(&session.Id, &session.UserId, &session.Token, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.IpAddress)
 
 	return session, err
 }
 
-// Returns all user sessions
-func (repo SessionRepository) GetUserSessions(id int) ([]*Session, error) {
+// Returns a session by id
+func (repo SessionRepository) GetById(id int64) (*Session, error) {
+
+	query := `SELECT id, user_id, token, created_at, last_seen_at, user_agent, ip_address FROM user_sessions WHERE id = ?`
+	row := repo.DB.QueryRow(query, id)
+	session := &Session{}
+
+	err := row.Scan(&session.Id, &session.UserId, &session.Token, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.IpAddress)
 
-	//TODO
-	//Not sure if needed
-	return nil, nil
+	return session, err
+}
+
+// Returns all user sessions, most recently used first
+func (repo SessionRepository) GetUserSessions(userId int64) ([]*Session, error) {
+
+	query := `SELECT id, user_id, token, created_at, last_seen_at, user_agent, ip_address FROM user_sessions
+	WHERE user_id = ?
+	ORDER BY last_seen_at DESC`
+
+	rows, err := repo.DB.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		session := &Session{}
+
+		err := rows.Scan(&session.Id, &session.UserId, &session.Token, &session.CreatedAt, &session.LastSeenAt, &session.UserAgent, &session.IpAddress)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Marks the session as used at the given time
+func (repo SessionRepository) UpdateLastSeen(id int64, lastSeenAt time.Time) error {
+	query := `UPDATE user_sessions SET last_seen_at = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, lastSeenAt, id)
+
+	return err
 }
 
 // remove a session by token
@@ -99,3 +158,73 @@ func (repo SessionRepository) DeleteByToken(token string) error {
 
 	return err
 }
+
+// remove a session by id
+func (repo SessionRepository) DeleteById(id int64) error {
+	query := `DELETE FROM user_sessions WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	repo.Logger.Printf("Deleted session %d", id)
+
+	return nil
+}
+
+// remove all sessions of a user, returns the ids of the removed sessions
+func (repo SessionRepository) DeleteByUserId(userId int64) ([]int64, error) {
+	return repo.deleteWhere(`user_id = ?`, userId)
+}
+
+// remove all sessions not used since the given time, returns the ids of the removed sessions
+func (repo SessionRepository) DeleteExpired(lastSeenBefore time.Time) ([]int64, error) {
+	return repo.deleteWhere(`last_seen_at < ?`, lastSeenBefore)
+}
+
+func (repo SessionRepository) deleteWhere(condition string, args ...interface{}) ([]int64, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM user_sessions WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_sessions WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		repo.Logger.Printf("Deleted %d session(s)", len(ids))
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+type TrendingTag struct {
+	Name      string
+	PostCount int
+}
+
+type ITagRepository interface {
+	SetPostTags(postId int64, tags []string) error
+	GetTrending(currentUserId int64, since time.Time, limit int) ([]*TrendingTag, error)
+}
+
+type TagRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewTagRepo(db *sql.DB) *TagRepository {
+	return &TagRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+// Replaces the tags of a post, tags that are no longer used by any post are kept
+func (repo TagRepository) SetPostTags(postId int64, tags []string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM post_tags WHERE post_id = ?`, postId)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES(?)`, tag)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT OR IGNORE INTO post_tags (post_id, tag_id)
+		SELECT ?, id FROM tags WHERE name = ?`
+
+		_, err = tx.Exec(query, postId, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		repo.Logger.Printf("Tagged post %d with %s", postId, strings.Join(tags, ", "))
+	}
+
+	return nil
+}
+
+// Returns the most used tags in the posts created after since that the user may see
+func (repo TagRepository) GetTrending(currentUserId int64, since time.Time, limit int) ([]*TrendingTag, error) {
+	query := `SELECT t.name, COUNT(DISTINCT p.id) AS post_count FROM tags t
+	INNER JOIN post_tags pt ON
+	t.id = pt.tag_id
+	INNER JOIN posts p ON
+	pt.post_id = p.id
+	` + feedVisibilityJoins + `
+	WHERE ` + feedVisibilityCondition + `
+	AND p.created_at > ?
+	GROUP BY t.id
+	ORDER BY post_count DESC, MAX(p.id) DESC
+	LIMIT ?`
+
+	args := append(feedVisibilityArgs(currentUserId), since, limit)
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []*TrendingTag{}
+
+	for rows.Next() {
+		tag := &TrendingTag{}
+
+		err := rows.Scan(&tag.Name, &tag.PostCount)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+)
+
+// TOTP is the authenticator app secret of a user, it protects logins once Enabled
+type TOTP struct {
+	UserId       int64
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// RecoveryCode is an unused recovery code, only its bcrypt hash is stored
+type RecoveryCode struct {
+	Id       int64
+	CodeHash string
+}
+
+// LoginChallenge is a login waiting for its second factor, only the hash of its token is stored
+type LoginChallenge struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ITwoFactorRepository interface {
+	GetTOTP(userId int64) (*TOTP, error)
+	SavePendingTOTP(userId int64, secret string) error
+	EnableTOTP(userId int64) error
+	UseTOTPStep(userId int64, step int64) (bool, error)
+	DeleteTOTP(userId int64) error
+	ReplaceRecoveryCodes(userId int64, codeHashes []string) error
+	GetRecoveryCodes(userId int64) ([]*RecoveryCode, error)
+	UseRecoveryCode(userId int64, id int64) (bool, error)
+	CountRecoveryCodes(userId int64) (int, error)
+	InsertChallenge(challenge *LoginChallenge) (int64, error)
+	GetChallenge(tokenHash string) (*LoginChallenge, error)
+	AddChallengeAttempt(id int64) error
+	DeleteChallenge(id int64) error
+}
+
+type TwoFactorRepository struct {
+	Logger *log.Logger
+	DB     *sql.DB
+}
+
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		Logger: log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile),
+		DB:     db,
+	}
+}
+
+func (repo TwoFactorRepository) GetTOTP(userId int64) (*TOTP, error) {
+	query := `SELECT user_id, secret, enabled, last_used_step, created_at FROM user_totp WHERE user_id = ?`
+	row := repo.DB.QueryRow(query, userId)
+	totp := &TOTP{}
+
+	err := row.Scan(&totp.UserId, &totp.Secret, &totp.Enabled, &totp.LastUsedStep, &totp.CreatedAt)
+
+	return totp, err
+}
+
+// Stores a new secret waiting for confirmation, replacing an earlier unconfirmed one
+func (repo TwoFactorRepository) SavePendingTOTP(userId int64, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret, enabled, last_used_step, created_at)
+	VALUES(?, ?, false, 0, ?)
+	ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at
+	WHERE enabled = false`
+
+	_, err := repo.DB.Exec(query, userId, secret, time.Now())
+
+	return err
+}
+
+func (repo TwoFactorRepository) EnableTOTP(userId int64) error {
+	query := `UPDATE user_totp SET enabled = true WHERE user_id = ?`
+
+	_, err := repo.DB.Exec(query, userId)
+
+	if err == nil {
+		repo.Logger.Printf("Enabled two-factor authentication for user %d", userId)
+	}
+
+	return err
+}
+
+// Records the time step of an accepted code, false means the step or a later one was already used
+func (repo TwoFactorRepository) UseTOTPStep(userId int64, step int64) (bool, error) {
+	query := `UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`
+
+	result, err := repo.DB.Exec(query, step, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	return rowsAffected == 1, err
+}
+
+// Removes the secret and the recovery codes of the user
+func (repo TwoFactorRepository) DeleteTOTP(userId int64) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userId); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userId); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	repo.Logger.Printf("Disabled two-factor authentication for user %d", userId)
+
+	return nil
+}
+
+func (repo TwoFactorRepository) ReplaceRecoveryCodes(userId int64, codeHashes []string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userId); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES(?, ?)`, userId, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Returns the unused recovery codes of the user
+func (repo TwoFactorRepository) GetRecoveryCodes(userId int64) ([]*RecoveryCode, error) {
+	query := `SELECT id, code_hash FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`
+
+	rows, err := repo.DB.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	codes := []*RecoveryCode{}
+
+	for rows.Next() {
+		code := &RecoveryCode{}
+
+		if err := rows.Scan(&code.Id, &code.CodeHash); err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// Marks a recovery code as used, false means it was used in the meantime
+func (repo TwoFactorRepository) UseRecoveryCode(userId int64, id int64) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = ? WHERE id = ? AND user_id = ? AND used_at IS NULL`
+
+	result, err := repo.DB.Exec(query, time.Now(), id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	return rowsAffected == 1, err
+}
+
+// Returns the number of unused recovery codes
+func (repo TwoFactorRepository) CountRecoveryCodes(userId int64) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`
+
+	var count int
+	err := repo.DB.QueryRow(query, userId).Scan(&count)
+
+	return count, err
+}
+
+func (repo TwoFactorRepository) InsertChallenge(challenge *LoginChallenge) (int64, error) {
+	query := `INSERT INTO login_challenges (user_id, token_hash, attempts, created_at, expires_at)
+	VALUES(?, ?, 0, ?, ?)`
+
+	args := []interface{}{
+		challenge.UserId,
+		challenge.TokenHash,
+		time.Now(),
+		challenge.ExpiresAt,
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (repo TwoFactorRepository) GetChallenge(tokenHash string) (*LoginChallenge, error) {
+	query := `SELECT id, user_id, token_hash, attempts, created_at, expires_at FROM login_challenges WHERE token_hash = ?`
+	row := repo.DB.QueryRow(query, tokenHash)
+	challenge := &LoginChallenge{}
+
+	err := row.Scan(&challenge.Id, &challenge.UserId, &challenge.TokenHash, &challenge.Attempts, &challenge.CreatedAt, &challenge.ExpiresAt)
+
+	return challenge, err
+}
+
+func (repo TwoFactorRepository) AddChallengeAttempt(id int64) error {
+	query := `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, id)
+
+	return err
+}
+
+// Removes the challenge together with any expired ones
+func (repo TwoFactorRepository) DeleteChallenge(id int64) error {
+	query := `DELETE FROM login_challenges WHERE id = ? OR expires_at < ?`
+
+	_, err := repo.DB.Exec(query, id, time.Now())
+
+	return err
+}
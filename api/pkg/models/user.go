@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -21,6 +22,18 @@ type User struct {
 	IsPublic  bool
 }
 
+// Roles of the users
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// AccountStatus holds what Authenticate needs to know about the user of a session
+type AccountStatus struct {
+	Role      string
+	Suspended bool
+}
+
 type SignupJSON struct {
 	Email           string `json:"email"`
 	Password        string `json:"password"`
@@ -47,10 +60,18 @@ type IUserRepository interface {
 	GetByEmail(email string) (*User, error)
 	GetByUserName(userName string) (*User, error)
 	CheckIfNicknameExists(nickname string, id int64) error
+	GetIdsByNicknames(nicknames []string) (map[string]int64, error)
 	GetAllUserFollowers(id int64) ([]*User, error)
 	GetAllFollowedBy(id int64) ([]*User, error)
 	GetAllUsers(id int64) ([]*User, error)
 	UpdateImage(id int64, imagePath string) error
+	UpdatePassword(id int64, passwordHash string) error
+	IsEmailVerified(id int64) (bool, error)
+	SetEmailVerified(id int64) error
+	GetAccountStatus(id int64) (*AccountStatus, error)
+	SetRole(id int64, role string) error
+	SetRoleByEmails(emails []string, role string) (int64, error)
+	SetSuspended(id int64, suspended bool) error
 }
 
 type UserRepository struct {
@@ -161,6 +182,47 @@ func (repo UserRepository) CheckIfNicknameExists(nickname string, id int64) erro
 	return err
 }
 
+// Returns the ids of the users with the given nicknames, keyed by nickname
+func (repo UserRepository) GetIdsByNicknames(nicknames []string) (map[string]int64, error) {
+	ids := make(map[string]int64, len(nicknames))
+
+	if len(nicknames) == 0 {
+		return ids, nil
+	}
+
+	query := `SELECT id, nickname FROM users WHERE nickname IN (?` + strings.Repeat(", ?", len(nicknames)-1) + `)`
+
+	args := []interface{}{}
+	for _, nickname := range nicknames {
+		args = append(args, nickname)
+	}
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id       int64
+			nickname string
+		)
+
+		if err := rows.Scan(&id, &nickname); err != nil {
+			return nil, err
+		}
+		ids[nickname] = id
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 // Return all user followers, who follow user with given id
 func (repo UserRepository) GetAllUserFollowers(id int64) ([]*User, error) {
 	stmt := `SELECT users.id, users.forname, users.surname, users.email, users.password, birthday, nickname, about, image_path, created_at, is_public FROM users
@@ -226,7 +288,7 @@ func (repo UserRepository) GetAllFollowedBy(id int64) ([]*User, error) {
 
 func (repo UserRepository) GetAllUsers(id int64) ([]*User, error) {
 	stmt := `SELECT id, forname, surname, email, birthday, nickname, about, image_path, created_at, is_public FROM users 
-	WHERE id != ?`
+	WHERE id != ? AND deleted_at IS NULL`
 
 	rows, err := repo.DB.Query(stmt, id)
 	if err != nil {
@@ -261,3 +323,81 @@ func (repo UserRepository) UpdateImage(id int64, imagePath string) error {
 
 	return err
 }
+
+func (repo UserRepository) UpdatePassword(id int64, passwordHash string) error {
+	query := `UPDATE users SET password = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, passwordHash, id)
+
+	return err
+}
+
+func (repo UserRepository) IsEmailVerified(id int64) (bool, error) {
+	query := `SELECT email_verified FROM users WHERE id = ?`
+
+	var verified bool
+	err := repo.DB.QueryRow(query, id).Scan(&verified)
+
+	return verified, err
+}
+
+func (repo UserRepository) SetEmailVerified(id int64) error {
+	query := `UPDATE users SET email_verified = true WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, id)
+
+	return err
+}
+
+func (repo UserRepository) GetAccountStatus(id int64) (*AccountStatus, error) {
+	query := `SELECT role, suspended_at IS NOT NULL FROM users WHERE id = ?`
+
+	status := &AccountStatus{}
+	err := repo.DB.QueryRow(query, id).Scan(&status.Role, &status.Suspended)
+
+	return status, err
+}
+
+func (repo UserRepository) SetRole(id int64, role string) error {
+	query := `UPDATE users SET role = ? WHERE id = ?`
+
+	_, err := repo.DB.Exec(query, role, id)
+
+	return err
+}
+
+// Gives the role to the users with the given emails, returns the number of users changed
+func (repo UserRepository) SetRoleByEmails(emails []string, role string) (int64, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	query := `UPDATE users SET role = ? WHERE role != ? AND lower(email) IN (?` + strings.Repeat(", ?", len(emails)-1) + `)`
+
+	args := []interface{}{role, role}
+	for _, email := range emails {
+		args = append(args, strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	result, err := repo.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Suspends or reinstates the user, suspended users cannot log in or use their sessions
+func (repo UserRepository) SetSuspended(id int64, suspended bool) error {
+	query := `UPDATE users SET suspended_at = NULL WHERE id = ?`
+	args := []interface{}{id}
+
+	if suspended {
+		query = `UPDATE users SET suspended_at = ? WHERE id = ? AND suspended_at IS NULL`
+		args = []interface{}{time.Now(), id}
+	}
+
+	_, err := repo.DB.Exec(query, args...)
+
+	return err
+}
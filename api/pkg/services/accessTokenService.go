@@ -0,0 +1,201 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type IAccessTokenService interface {
+	CreateToken(userId int64, request AccessTokenRequestJSON) (*CreatedAccessTokenJSON, error)
+	GetTokens(userId int64) ([]*AccessTokenJSON, error)
+	RevokeToken(userId int64, tokenId int64) error
+	RevokeAllTokens(userId int64) ([]int64, error)
+}
+
+type AccessTokenService struct {
+	Logger          *log.Logger
+	AccessTokenRepo models.IAccessTokenRepository
+}
+
+func InitAccessTokenService(
+	logger *log.Logger,
+	accessTokenRepo *models.AccessTokenRepository,
+) *AccessTokenService {
+	return &AccessTokenService{
+		Logger:          logger,
+		AccessTokenRepo: accessTokenRepo,
+	}
+}
+
+// accessTokenPrefix makes leaked tokens easy to recognise in logs and code
+const accessTokenPrefix = "snt_"
+
+const (
+	maxAccessTokens       = 20
+	maxAccessTokenNameLen = 100
+)
+
+var (
+	ErrInvalidTokenName   = errors.New("token name must be 1-100 characters")
+	ErrInvalidTokenScope  = errors.New("token scopes must be some of read, post and chat")
+	ErrTooManyTokens      = errors.New("too many access tokens")
+	ErrAccessTokenMissing = errors.New("access token not found")
+)
+
+type AccessTokenRequestJSON struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type AccessTokenJSON struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// CreatedAccessTokenJSON carries the token itself, it is only shown once
+type CreatedAccessTokenJSON struct {
+	AccessTokenJSON
+	Token string `json:"token"`
+}
+
+func toAccessTokenJSON(token *models.AccessToken) *AccessTokenJSON {
+	tokenJSON := &AccessTokenJSON{
+		Id:        token.Id,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+
+	if token.LastUsedAt.Valid {
+		tokenJSON.LastUsedAt = &token.LastUsedAt.Time
+	}
+
+	return tokenJSON
+}
+
+// validScopes returns the requested scopes in their canonical order without duplicates
+func validScopes(requested []string) ([]string, error) {
+	wanted := map[string]bool{}
+	for _, scope := range requested {
+		if !contains(models.AccessTokenScopes, scope) {
+			return nil, ErrInvalidTokenScope
+		}
+		wanted[scope] = true
+	}
+
+	scopes := []string{}
+	for _, scope := range models.AccessTokenScopes {
+		if wanted[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, ErrInvalidTokenScope
+	}
+
+	return scopes, nil
+}
+
+// Creates a named access token with the scopes, only its hash is stored
+func (s *AccessTokenService) CreateToken(userId int64, request AccessTokenRequestJSON) (*CreatedAccessTokenJSON, error) {
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenNameLen {
+		return nil, ErrInvalidTokenName
+	}
+
+	scopes, err := validScopes(request.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.AccessTokenRepo.CountByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot count access tokens: %s", err)
+		return nil, err
+	}
+
+	if count >= maxAccessTokens {
+		return nil, ErrTooManyTokens
+	}
+
+	secret, _, err := newSecretToken()
+	if err != nil {
+		s.Logger.Printf("Cannot create access token: %s", err)
+		return nil, err
+	}
+
+	secret = accessTokenPrefix + secret
+
+	token := &models.AccessToken{
+		UserId:    userId,
+		Name:      name,
+		TokenHash: hashSecretToken(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	token.Id, err = s.AccessTokenRepo.Insert(token)
+	if err != nil {
+		s.Logger.Printf("Cannot insert access token: %s", err)
+		return nil, err
+	}
+
+	return &CreatedAccessTokenJSON{
+		AccessTokenJSON: *toAccessTokenJSON(token),
+		Token:           secret,
+	}, nil
+}
+
+func (s *AccessTokenService) GetTokens(userId int64) ([]*AccessTokenJSON, error) {
+
+	tokens, err := s.AccessTokenRepo.GetAllByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get access tokens: %s", err)
+		return nil, err
+	}
+
+	tokensJSON := []*AccessTokenJSON{}
+	for _, token := range tokens {
+		tokensJSON = append(tokensJSON, toAccessTokenJSON(token))
+	}
+
+	return tokensJSON, nil
+}
+
+func (s *AccessTokenService) RevokeToken(userId int64, tokenId int64) error {
+
+	revoked, err := s.AccessTokenRepo.Delete(tokenId, userId)
+	if err != nil {
+		s.Logger.Printf("Cannot revoke access token: %s", err)
+		return err
+	}
+
+	if !revoked {
+		return ErrAccessTokenMissing
+	}
+
+	s.Logger.Printf("User %d revoked access token %d", userId, tokenId)
+
+	return nil
+}
+
+// Revokes every access token of the user, returns the ids of the revoked tokens
+func (s *AccessTokenService) RevokeAllTokens(userId int64) ([]int64, error) {
+
+	tokenIds, err := s.AccessTokenRepo.DeleteByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot revoke access tokens: %s", err)
+		return nil, err
+	}
+
+	return tokenIds, nil
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireAdminRefusesAccessTokens(t *testing.T) {
+	s := newTestServices(t)
+	admin := s.newAdmin(t, "admin@example.com")
+	s.newUser(t, "anna@example.com")
+
+	session := s.login(t, "admin@example.com")
+	userSession := s.login(t, "anna@example.com")
+	token := s.newAccessToken(t, admin, models.ScopeRead, models.ScopePost, models.ScopeChat)
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		want    int
+	}{
+		{"admin session", withSession(httptest.NewRequest("GET", "/admin/reports", nil), session), http.StatusOK},
+		{"user session", withSession(httptest.NewRequest("GET", "/admin/reports", nil), userSession), http.StatusForbidden},
+		{"access token of the admin", withToken(httptest.NewRequest("GET", "/admin/reports", nil), token.Token), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+
+			w := httptest.NewRecorder()
+			s.users.Authenticate(s.users.RequireAdmin(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))(w, tt.request)
+
+			if w.Code != tt.want || called != (tt.want == http.StatusOK) {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	s := newTestServices(t)
+	tokens := InitAccessTokenService(s.logger, s.repos.AccessTokenRepo)
+	anna := s.newUser(t, "anna@example.com")
+
+	created, err := tokens.CreateToken(anna, AccessTokenRequestJSON{Name: " bot ", Scopes: []string{models.ScopeChat, models.ScopeRead, models.ScopeChat}})
+	if err != nil {
+		t.Fatalf("CreateToken() = %v", err)
+	}
+
+	if created.Name != "bot" || len(created.Scopes) != 2 || created.Scopes[0] != models.ScopeRead || created.Scopes[1] != models.ScopeChat {
+		t.Fatalf("CreateToken() = %+v, want bot with the read and chat scopes", created.AccessTokenJSON)
+	}
+
+	if len(created.Token) <= len(accessTokenPrefix) || created.Token[:len(accessTokenPrefix)] != accessTokenPrefix {
+		t.Fatalf("token %q has no %s prefix", created.Token, accessTokenPrefix)
+	}
+
+	if stored, err := s.repos.AccessTokenRepo.GetByHash(hashSecretToken(created.Token)); err != nil || stored.Id != created.Id {
+		t.Fatalf("GetByHash() = %+v, %v, want the token stored by its hash", stored, err)
+	}
+
+	tests := []struct {
+		name    string
+		request AccessTokenRequestJSON
+		want    error
+	}{
+		{"empty name", AccessTokenRequestJSON{Name: " ", Scopes: []string{models.ScopeRead}}, ErrInvalidTokenName},
+		{"long name", AccessTokenRequestJSON{Name: strings.Repeat("ä", maxAccessTokenNameLen+1), Scopes: []string{models.ScopeRead}}, ErrInvalidTokenName},
+		{"no scopes", AccessTokenRequestJSON{Name: "bot"}, ErrInvalidTokenScope},
+		{"unknown scope", AccessTokenRequestJSON{Name: "bot", Scopes: []string{models.ScopeRead, "admin"}}, ErrInvalidTokenScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.CreateToken(anna, tt.request); err != tt.want {
+				t.Fatalf("CreateToken() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	for i := 1; i < maxAccessTokens; i++ {
+		s.newAccessToken(t, anna, models.ScopeRead)
+	}
+
+	if _, err = tokens.CreateToken(anna, AccessTokenRequestJSON{Name: "one too many", Scopes: []string{models.ScopeRead}}); err != ErrTooManyTokens {
+		t.Fatalf("CreateToken() = %v, want %v", err, ErrTooManyTokens)
+	}
+
+	if _, err = tokens.CreateToken(s.newUser(t, "bob@example.com"), AccessTokenRequestJSON{Name: "bot", Scopes: []string{models.ScopeRead}}); err != nil {
+		t.Fatalf("token of another user: CreateToken() = %v", err)
+	}
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	read := s.newAccessToken(t, anna, models.ScopeRead).Token
+	post := s.newAccessToken(t, anna, models.ScopePost).Token
+	chat := s.newAccessToken(t, anna, models.ScopeChat).Token
+	all := s.newAccessToken(t, anna, models.ScopeRead, models.ScopePost, models.ScopeChat).Token
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		scope  string
+		want   int
+	}{
+		{"read token reads", read, "GET", "", http.StatusOK},
+		{"read token heads", read, "HEAD", "", http.StatusOK},
+		{"read token writes", read, "POST", "", http.StatusForbidden},
+		{"read token deletes", read, "DELETE", "", http.StatusForbidden},
+		{"read token posts", read, "POST", models.ScopePost, http.StatusForbidden},
+		{"post token reads", post, "GET", "", http.StatusForbidden},
+		{"post token posts", post, "POST", models.ScopePost, http.StatusOK},
+		{"post token chats", post, "GET", models.ScopeChat, http.StatusForbidden},
+		{"chat token chats", chat, "GET", models.ScopeChat, http.StatusOK},
+		{"chat token posts", chat, "POST", models.ScopePost, http.StatusForbidden},
+		{"token with every scope writes", all, "PUT", "", http.StatusForbidden},
+		{"token with every scope posts", all, "POST", models.ScopePost, http.StatusOK},
+		{"token with every scope chats", all, "GET", models.ScopeChat, http.StatusOK},
+		{"unknown token", "snt_unknown", "GET", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+
+			w := httptest.NewRecorder()
+			s.users.AuthenticateScope(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})(w, withToken(httptest.NewRequest(tt.method, "/posts", nil), tt.token))
+
+			if w.Code != tt.want || called != (tt.want == http.StatusOK) {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	s := newTestServices(t)
+	tokens := InitAccessTokenService(s.logger, s.repos.AccessTokenRepo)
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	revoked := s.newAccessToken(t, anna, models.ScopeRead)
+	kept := s.newAccessToken(t, anna, models.ScopeRead)
+	bobToken := s.newAccessToken(t, bob, models.ScopeRead)
+
+	if err := tokens.RevokeToken(bob, revoked.Id); err != ErrAccessTokenMissing {
+		t.Fatalf("RevokeToken() of another user = %v, want %v", err, ErrAccessTokenMissing)
+	}
+
+	if err := tokens.RevokeToken(anna, revoked.Id); err != nil {
+		t.Fatalf("RevokeToken() = %v", err)
+	}
+
+	if err := tokens.RevokeToken(anna, revoked.Id); err != ErrAccessTokenMissing {
+		t.Fatalf("second RevokeToken() = %v, want %v", err, ErrAccessTokenMissing)
+	}
+
+	if w, _ := s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), revoked.Token)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w, principal := s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), kept.Token)); principal == nil {
+		t.Fatalf("other token: got %d, want the handler to be called", w.Code)
+	}
+
+	tokenIds, err := tokens.RevokeAllTokens(anna)
+	if err != nil || len(tokenIds) != 1 || tokenIds[0] != kept.Id {
+		t.Fatalf("RevokeAllTokens() = %v, %v, want [%d]", tokenIds, err, kept.Id)
+	}
+
+	if remaining, err := tokens.GetTokens(anna); err != nil || len(remaining) != 0 {
+		t.Fatalf("GetTokens() = %d tokens, %v, want none", len(remaining), err)
+	}
+
+	if remaining, err := tokens.GetTokens(bob); err != nil || len(remaining) != 1 || remaining[0].Id != bobToken.Id {
+		t.Fatalf("GetTokens() of another user = %v, %v, want the token kept", remaining, err)
+	}
+}
+
+func TestAccessTokenLastUsed(t *testing.T) {
+	s := newTestServices(t)
+	tokens := InitAccessTokenService(s.logger, s.repos.AccessTokenRepo)
+	anna := s.newUser(t, "anna@example.com")
+
+	created := s.newAccessToken(t, anna, models.ScopeRead)
+	if created.LastUsedAt != nil {
+		t.Fatalf("new token was used at %v", created.LastUsedAt)
+	}
+
+	s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), created.Token))
+
+	listed, err := tokens.GetTokens(anna)
+	if err != nil || len(listed) != 1 || listed[0].LastUsedAt == nil {
+		t.Fatalf("GetTokens() = %v, %v, want the token with its last use", listed, err)
+	}
+
+	// uses within the renew interval are not written again
+	lastUsedAt := time.Now().Add(-sessionRenewInterval / 2)
+	if err = s.repos.AccessTokenRepo.UpdateLastUsed(created.Id, lastUsedAt); err != nil {
+		t.Fatalf("UpdateLastUsed() = %v", err)
+	}
+
+	s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), created.Token))
+
+	if listed, _ = tokens.GetTokens(anna); !listed[0].LastUsedAt.Equal(lastUsedAt) {
+		t.Fatalf("last used at %v, want %v", listed[0].LastUsedAt, lastUsedAt)
+	}
+
+	lastUsedAt = time.Now().Add(-2 * sessionRenewInterval)
+	if err = s.repos.AccessTokenRepo.UpdateLastUsed(created.Id, lastUsedAt); err != nil {
+		t.Fatalf("UpdateLastUsed() = %v", err)
+	}
+
+	s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), created.Token))
+
+	if listed, _ = tokens.GetTokens(anna); !listed[0].LastUsedAt.After(lastUsedAt) {
+		t.Fatalf("last used at %v, want it renewed", listed[0].LastUsedAt)
+	}
+}
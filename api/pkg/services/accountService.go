@@ -0,0 +1,239 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/models"
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+type IAccountService interface {
+	ExportAccount(userId int64, w io.Writer) error
+	GetDeletion(userId int64) (*AccountDeletionJSON, error)
+	ScheduleDeletion(userId int64, password string, code string, ip string) (*AccountDeletionJSON, error)
+	CancelDeletion(userId int64) error
+	DeleteDueAccounts() ([]int64, error)
+}
+
+type AccountService struct {
+	Logger       *log.Logger
+	UserRepo     models.IUserRepository
+	AccountRepo  models.IAccountRepository
+	ImageService utils.IImageService
+	// TwoFactor confirms the deletion of accounts without a password
+	TwoFactor ITwoFactorService
+	// DeletionGracePeriod is how long a deletion can be cancelled
+	DeletionGracePeriod time.Duration
+}
+
+func InitAccountService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	accountRepo *models.AccountRepository,
+	imageService *utils.ImageService,
+	twoFactor *TwoFactorService,
+	deletionGracePeriod time.Duration,
+) *AccountService {
+	return &AccountService{
+		Logger:              logger,
+		UserRepo:            userRepo,
+		AccountRepo:         accountRepo,
+		ImageService:        imageService,
+		TwoFactor:           twoFactor,
+		DeletionGracePeriod: deletionGracePeriod,
+	}
+}
+
+var (
+	ErrDeletionScheduled   = errors.New("account deletion is already scheduled")
+	ErrNoDeletionScheduled = errors.New("account deletion is not scheduled")
+)
+
+type AccountDeletionJSON struct {
+	RequestedAt time.Time `json:"requestedAt"`
+	DeleteAt    time.Time `json:"deleteAt"`
+}
+
+// Writes a zip archive with one JSON file per kind of personal data and the uploaded images
+func (s *AccountService) ExportAccount(userId int64, w io.Writer) error {
+
+	sections, err := s.AccountRepo.Export(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot export account: %s", err)
+		return err
+	}
+
+	images, err := s.AccountRepo.GetUploadedImages(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get uploaded images: %s", err)
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	exportedAt := time.Now()
+
+	for _, section := range sections {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     section.Name + ".json",
+			Method:   zip.Deflate,
+			Modified: exportedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+
+		if err = encoder.Encode(section.Rows); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range images {
+		if err = s.addImage(archive, image, exportedAt); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// addImage copies an uploaded image into the archive, images missing on disk are skipped
+func (s *AccountService) addImage(archive *zip.Writer, image string, exportedAt time.Time) error {
+	source, err := s.ImageService.OpenImage(image)
+	if err != nil {
+		s.Logger.Printf("Cannot export image %s: %s", image, err)
+		return nil
+	}
+
+	defer source.Close()
+
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     "images/" + filepath.Base(image),
+		Method:   zip.Store,
+		Modified: exportedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, source)
+
+	return err
+}
+
+func (s *AccountService) GetDeletion(userId int64) (*AccountDeletionJSON, error) {
+
+	deletion, err := s.AccountRepo.GetDeletion(userId)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoDeletionScheduled
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get account deletion: %s", err)
+		return nil, err
+	}
+
+	return &AccountDeletionJSON{
+		RequestedAt: deletion.RequestedAt,
+		DeleteAt:    deletion.DeleteAt,
+	}, nil
+}
+
+// Schedules the account for deletion after the grace period, the password confirms the request.
+// Users without a password confirm with a two-factor code or a login at a linked provider.
+func (s *AccountService) ScheduleDeletion(userId int64, password string, code string, ip string) (*AccountDeletionJSON, error) {
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return nil, err
+	}
+
+	if user.Password == "" {
+		if err = s.TwoFactor.ConfirmWithoutPassword(userId, code, ip); err != nil {
+			return nil, err
+		}
+	} else if !CheckPasswordHash(password, user.Password) {
+		return nil, ErrIncorrectPassword
+	}
+
+	if _, err = s.GetDeletion(userId); err == nil {
+		return nil, ErrDeletionScheduled
+	}
+
+	if err != ErrNoDeletionScheduled {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	deletion := &models.AccountDeletion{
+		UserId:      userId,
+		RequestedAt: now,
+		DeleteAt:    now.Add(s.DeletionGracePeriod),
+	}
+
+	err = s.AccountRepo.ScheduleDeletion(deletion)
+	if err != nil {
+		s.Logger.Printf("Cannot schedule account deletion: %s", err)
+		return nil, err
+	}
+
+	return &AccountDeletionJSON{
+		RequestedAt: deletion.RequestedAt,
+		DeleteAt:    deletion.DeleteAt,
+	}, nil
+}
+
+func (s *AccountService) CancelDeletion(userId int64) error {
+
+	cancelled, err := s.AccountRepo.CancelDeletion(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot cancel account deletion: %s", err)
+		return err
+	}
+
+	if !cancelled {
+		return ErrNoDeletionScheduled
+	}
+
+	s.Logger.Printf("User %d cancelled the account deletion", userId)
+
+	return nil
+}
+
+// Deletes the accounts whose grace period is over and returns the ids of their removed sessions
+func (s *AccountService) DeleteDueAccounts() ([]int64, error) {
+
+	deletions, err := s.AccountRepo.GetDueDeletions(time.Now())
+	if err != nil {
+		s.Logger.Printf("Cannot get due account deletions: %s", err)
+		return nil, err
+	}
+
+	sessionIDs := []int64{}
+
+	for _, deletion := range deletions {
+		deleted, err := s.AccountRepo.Delete(deletion.UserId)
+		if err != nil {
+			s.Logger.Printf("Cannot delete user %d: %s", deletion.UserId, err)
+			return sessionIDs, err
+		}
+
+		for _, image := range deleted.ImagePaths {
+			s.ImageService.DeleteImage(image)
+		}
+
+		sessionIDs = append(sessionIDs, deleted.SessionIds...)
+	}
+
+	return sessionIDs, nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func newTestAccountService(s *testServices) *AccountService {
+	return InitAccountService(s.logger, s.repos.UserRepo, s.repos.AccountRepo, s.images, s.twoFactor, time.Hour)
+}
+
+// countRows returns the number of rows of the count query
+func countRows(t *testing.T, s *testServices, query string, args ...interface{}) int {
+	t.Helper()
+
+	var count int
+	if err := s.db.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("Cannot count rows: %v", err)
+	}
+
+	return count
+}
+
+// setImage sets the image path of the row in the table to a new image file
+func setImage(t *testing.T, s *testServices, table string, id int64, name string) string {
+	t.Helper()
+
+	if _, err := s.db.Exec(`UPDATE `+table+` SET image_path = ? WHERE id = ?`, newImage(t, s, name), id); err != nil {
+		t.Fatalf("Cannot set image: %v", err)
+	}
+
+	return name
+}
+
+func newGroup(t *testing.T, s *testServices, creatorId int64, title string, members ...int64) int64 {
+	t.Helper()
+
+	groupId, err := s.repos.GroupRepo.Insert(&models.Group{CreatorId: creatorId, Title: title, Description: title})
+	if err != nil {
+		t.Fatalf("Cannot insert group: %v", err)
+	}
+
+	joinedAt := time.Now()
+	for _, userId := range append([]int64{creatorId}, members...) {
+		_, err = s.repos.GroupMemberRepo.Insert(&models.GroupMember{UserId: userId, GroupId: groupId, JoinedAt: joinedAt, Accepted: true})
+		if err != nil {
+			t.Fatalf("Cannot insert group member: %v", err)
+		}
+		joinedAt = joinedAt.Add(time.Second)
+	}
+
+	return groupId
+}
+
+// newPersonalData gives the user a token, a linked login, a replay event and a message to the recipient
+func newPersonalData(t *testing.T, s *testServices, userId int64, recipientId int64, name string) {
+	t.Helper()
+
+	now := time.Now()
+
+	if _, err := s.repos.AccessTokenRepo.Insert(&models.AccessToken{UserId: userId, Name: name, TokenHash: name, Scopes: []string{"read"}, CreatedAt: now}); err != nil {
+		t.Fatalf("Cannot insert access token: %v", err)
+	}
+
+	if _, err := s.repos.OIDCRepo.InsertIdentity(&models.UserIdentity{UserId: userId, Provider: "test", Subject: name, Email: name + "@example.com", CreatedAt: now}); err != nil {
+		t.Fatalf("Cannot insert identity: %v", err)
+	}
+
+	if _, err := s.repos.ReplayRepo.Append(&models.ReplayEvent{UserId: userId, Type: "test", Data: []byte(`{}`), CreatedAt: now}); err != nil {
+		t.Fatalf("Cannot append replay event: %v", err)
+	}
+
+	if _, err := s.repos.MessageRepo.Insert(&models.Message{SenderId: userId, RecipientId: recipientId, Content: "hello from " + name, SentAt: now}); err != nil {
+		t.Fatalf("Cannot insert message: %v", err)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	accounts := newTestAccountService(s)
+
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+	carl := s.newUser(t, "carl@example.com")
+
+	s.follow(t, bob, anna)
+	s.follow(t, anna, carl)
+	s.follow(t, carl, bob)
+
+	annaPost := newPost(t, posts, anna, "anna's post", enums.Public)
+	bobPost := newPost(t, posts, bob, "bob's post", enums.Public)
+	commentUnderAnna := newComment(t, comments, bob, annaPost, 0, "bob under anna")
+	annaComment := newComment(t, comments, anna, bobPost, 0, "anna under bob")
+	carlComment := newComment(t, comments, carl, bobPost, 0, "carl under bob")
+
+	images := []string{
+		setImage(t, s, "users", anna, "anna-profile.png"),
+		setImage(t, s, "posts", annaPost, "anna-post.png"),
+		setImage(t, s, "comments", annaComment, "anna-comment.png"),
+	}
+	bobImage := setImage(t, s, "posts", bobPost, "bob-post.png")
+
+	sharedGroup := newGroup(t, s, anna, "shared", carl, bob)
+	annaGroup := newGroup(t, s, anna, "alone")
+	bobGroup := newGroup(t, s, bob, "bob's", anna)
+
+	newPersonalData(t, s, anna, bob, "anna")
+	newPersonalData(t, s, bob, anna, "bob")
+
+	s.login(t, "anna@example.com")
+	s.login(t, "bob@example.com")
+
+	err := s.repos.AccountRepo.ScheduleDeletion(&models.AccountDeletion{UserId: anna, RequestedAt: time.Now().Add(-2 * time.Hour), DeleteAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("ScheduleDeletion() = %v", err)
+	}
+
+	sessionIds, err := accounts.DeleteDueAccounts()
+	if err != nil {
+		t.Fatalf("DeleteDueAccounts() = %v", err)
+	}
+
+	if len(sessionIds) != 1 {
+		t.Fatalf("DeleteDueAccounts() removed sessions %v, want 1", sessionIds)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		want  int
+	}{
+		{"anonymised user", `SELECT COUNT(*) FROM users WHERE id = ? AND forname = 'Deleted' AND email LIKE 'deleted-%@invalid' AND password = '' AND image_path = '' AND deleted_at IS NOT NULL`, []interface{}{anna}, 1},
+		{"other users", `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND id IN (?, ?)`, []interface{}{bob, carl}, 2},
+		{"posts of the user", `SELECT COUNT(*) FROM posts WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"comments under the posts", `SELECT COUNT(*) FROM comments WHERE id = ?`, []interface{}{commentUnderAnna}, 0},
+		{"posts of others", `SELECT COUNT(*) FROM posts WHERE id = ? AND content = 'bob''s post'`, []interface{}{bobPost}, 1},
+		{"comments of the user", `SELECT COUNT(*) FROM comments WHERE id = ? AND content = '[deleted]' AND image_path = ''`, []interface{}{annaComment}, 1},
+		{"comments of others", `SELECT COUNT(*) FROM comments WHERE id = ? AND content = 'carl under bob'`, []interface{}{carlComment}, 1},
+		{"messages of the user", `SELECT COUNT(*) FROM messages WHERE sender_id = ? AND content = '[deleted]'`, []interface{}{anna}, 1},
+		{"messages of others", `SELECT COUNT(*) FROM messages WHERE sender_id = ? AND content = 'hello from bob'`, []interface{}{bob}, 1},
+		{"transferred group", `SELECT COUNT(*) FROM groups WHERE id = ? AND creator_id = ?`, []interface{}{sharedGroup, carl}, 1},
+		{"group without members", `SELECT COUNT(*) FROM groups WHERE id = ?`, []interface{}{annaGroup}, 0},
+		{"groups of others", `SELECT COUNT(*) FROM groups WHERE id = ? AND creator_id = ?`, []interface{}{bobGroup, bob}, 1},
+		{"memberships of the user", `SELECT COUNT(*) FROM user_groups WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"memberships of others", `SELECT COUNT(*) FROM user_groups WHERE user_id IN (?, ?)`, []interface{}{bob, carl}, 3},
+		{"follows of the user", `SELECT COUNT(*) FROM followers WHERE follower_id = ?1 OR following_id = ?1`, []interface{}{anna}, 0},
+		{"follows of others", `SELECT COUNT(*) FROM followers WHERE follower_id = ? AND following_id = ?`, []interface{}{carl, bob}, 1},
+		{"sessions of the user", `SELECT COUNT(*) FROM user_sessions WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"sessions of others", `SELECT COUNT(*) FROM user_sessions WHERE user_id = ?`, []interface{}{bob}, 1},
+		{"tokens of the user", `SELECT COUNT(*) FROM access_tokens WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"tokens of others", `SELECT COUNT(*) FROM access_tokens WHERE user_id = ?`, []interface{}{bob}, 1},
+		{"identities of the user", `SELECT COUNT(*) FROM user_identities WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"identities of others", `SELECT COUNT(*) FROM user_identities WHERE user_id = ?`, []interface{}{bob}, 1},
+		{"replay events of the user", `SELECT COUNT(*) FROM replay_events WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"replay sequence of the user", `SELECT COUNT(*) FROM replay_sequences WHERE user_id = ?`, []interface{}{anna}, 0},
+		{"replay events of others", `SELECT COUNT(*) FROM replay_events WHERE user_id = ?`, []interface{}{bob}, 1},
+		{"scheduled deletion", `SELECT COUNT(*) FROM account_deletions WHERE user_id = ?`, []interface{}{anna}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countRows(t, s, tt.query, tt.args...); got != tt.want {
+				t.Fatalf("count = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	for _, image := range images {
+		if imageExists(s, image) {
+			t.Errorf("image %s of the deleted user is kept", image)
+		}
+	}
+
+	if !imageExists(s, bobImage) {
+		t.Errorf("image %s of another user is deleted", bobImage)
+	}
+
+	if sessionIds, err = accounts.DeleteDueAccounts(); err != nil || len(sessionIds) != 0 {
+		t.Fatalf("second DeleteDueAccounts() = %v, %v, want nothing to delete", sessionIds, err)
+	}
+}
+
+func TestExportAccount(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	accounts := newTestAccountService(s)
+
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	postId := newPost(t, posts, anna, "exported", enums.Public)
+	image := setImage(t, s, "posts", postId, "exported.png")
+	newPersonalData(t, s, anna, bob, "anna")
+
+	var buffer bytes.Buffer
+	if err := accounts.ExportAccount(anna, &buffer); err != nil {
+		t.Fatalf("ExportAccount() = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("Cannot read export: %v", err)
+	}
+
+	files := map[string]bool{}
+	for _, file := range archive.File {
+		files[file.Name] = true
+	}
+
+	sections := []string{
+		"profile",
+		"followers",
+		"following",
+		"posts",
+		"comments",
+		"reactions",
+		"messages",
+		"groups_created",
+		"group_memberships",
+		"event_rsvps",
+		"notifications",
+		"blocked_users",
+		"access_tokens",
+		"linked_logins",
+	}
+
+	for _, section := range sections {
+		if !files[section+".json"] {
+			t.Errorf("export has no %s.json", section)
+		}
+	}
+
+	if !files["images/"+image] {
+		t.Errorf("export has no images/%s", image)
+	}
+
+	if len(files) != len(sections)+1 {
+		t.Errorf("export has %d files, want %d", len(files), len(sections)+1)
+	}
+}
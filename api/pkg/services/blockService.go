@@ -0,0 +1,122 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+type IBlockService interface {
+	BlockUser(userId int64, blockedId int64) error
+	UnblockUser(userId int64, blockedId int64) error
+	GetBlockedUsers(userId int64) ([]*models.SimpleUserJSON, error)
+	IsBlocked(userId int64, otherId int64) (bool, error)
+}
+
+type BlockService struct {
+	Logger    *log.Logger
+	UserRepo  models.IUserRepository
+	BlockRepo models.IBlockRepository
+}
+
+func InitBlockService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	blockRepo *models.BlockRepository,
+) *BlockService {
+	return &BlockService{
+		Logger:    logger,
+		UserRepo:  userRepo,
+		BlockRepo: blockRepo,
+	}
+}
+
+var (
+	ErrUserBlocked      = errors.New("user is blocked")
+	ErrCannotBlockSelf  = errors.New("cannot block yourself")
+	ErrUserNotBlocked   = errors.New("user is not blocked")
+	ErrBlockUserMissing = errors.New("user does not exist")
+)
+
+// Blocks the user, the follow relations between the two users are severed in both directions
+func (s *BlockService) BlockUser(userId int64, blockedId int64) error {
+
+	if userId == blockedId {
+		return ErrCannotBlockSelf
+	}
+
+	_, err := s.UserRepo.GetById(blockedId)
+	if err == sql.ErrNoRows {
+		return ErrBlockUserMissing
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return err
+	}
+
+	_, err = s.BlockRepo.Insert(&models.Block{
+		BlockerId: userId,
+		BlockedId: blockedId,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot block user: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *BlockService) UnblockUser(userId int64, blockedId int64) error {
+
+	unblocked, err := s.BlockRepo.Delete(userId, blockedId)
+	if err != nil {
+		s.Logger.Printf("Cannot unblock user: %s", err)
+		return err
+	}
+
+	if !unblocked {
+		return ErrUserNotBlocked
+	}
+
+	s.Logger.Printf("User %d unblocked user %d", userId, blockedId)
+
+	return nil
+}
+
+func (s *BlockService) GetBlockedUsers(userId int64) ([]*models.SimpleUserJSON, error) {
+
+	users, err := s.BlockRepo.GetBlockedUsers(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get blocked users: %s", err)
+		return nil, err
+	}
+
+	usersJSON := []*models.SimpleUserJSON{}
+
+	for _, user := range users {
+		usersJSON = append(usersJSON, &models.SimpleUserJSON{
+			Id:        int(user.Id),
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Nickname:  user.Nickname,
+			ImagePath: user.ImagePath,
+		})
+	}
+
+	return usersJSON, nil
+}
+
+// Reports whether either of the users has blocked the other
+func (s *BlockService) IsBlocked(userId int64, otherId int64) (bool, error) {
+
+	blocked, err := s.BlockRepo.IsBlockedEitherWay(userId, otherId)
+	if err != nil {
+		s.Logger.Printf("Cannot check block: %s", err)
+	}
+
+	return blocked, err
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"database/sql"
+	"testing"
+)
+
+func newTestBlockService(s *testServices) *BlockService {
+	return InitBlockService(s.logger, s.repos.UserRepo, s.repos.BlockRepo)
+}
+
+func TestBlockUser(t *testing.T) {
+	s := newTestServices(t)
+	blocks := newTestBlockService(s)
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	if err := blocks.BlockUser(anna, anna); err != ErrCannotBlockSelf {
+		t.Fatalf("blocking yourself: got %v, want %v", err, ErrCannotBlockSelf)
+	}
+
+	if err := blocks.BlockUser(anna, bob+100); err != ErrBlockUserMissing {
+		t.Fatalf("blocking a missing user: got %v, want %v", err, ErrBlockUserMissing)
+	}
+
+	if err := blocks.UnblockUser(anna, bob); err != ErrUserNotBlocked {
+		t.Fatalf("unblocking a user not blocked: got %v, want %v", err, ErrUserNotBlocked)
+	}
+
+	s.follow(t, anna, bob)
+	s.follow(t, bob, anna)
+
+	if err := blocks.BlockUser(anna, bob); err != nil {
+		t.Fatalf("BlockUser() = %v", err)
+	}
+
+	for _, pair := range [][2]int64{{anna, bob}, {bob, anna}} {
+		if _, err := s.repos.FollowerRepo.GetByFollowerAndFollowing(pair[0], pair[1]); err != sql.ErrNoRows {
+			t.Fatalf("user %d still follows user %d: %v", pair[0], pair[1], err)
+		}
+	}
+
+	for _, pair := range [][2]int64{{anna, bob}, {bob, anna}} {
+		if blocked, err := blocks.IsBlocked(pair[0], pair[1]); err != nil || !blocked {
+			t.Fatalf("IsBlocked(%d, %d) = %v, %v, want true", pair[0], pair[1], blocked, err)
+		}
+	}
+
+	blocked, err := blocks.GetBlockedUsers(anna)
+	if err != nil || len(blocked) != 1 || blocked[0].Id != int(bob) {
+		t.Fatalf("GetBlockedUsers() = %+v, %v, want bob", blocked, err)
+	}
+
+	if blocked, _ = blocks.GetBlockedUsers(bob); len(blocked) != 0 {
+		t.Fatalf("blocked user lists %+v as blocked", blocked)
+	}
+
+	if err = blocks.UnblockUser(anna, bob); err != nil {
+		t.Fatalf("UnblockUser() = %v", err)
+	}
+
+	if blocked, err := blocks.IsBlocked(bob, anna); err != nil || blocked {
+		t.Fatalf("IsBlocked() = %v, %v after unblocking", blocked, err)
+	}
+}
+
+func TestBlockHidesContent(t *testing.T) {
+	s := newTestServices(t)
+	blocks := newTestBlockService(s)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	a := newPostAudience(t, s)
+
+	annaPost := newPost(t, posts, a.anna, "anna", enums.Public)
+	davePost := newPost(t, posts, a.dave, "dave", enums.Public)
+	newComment(t, comments, a.dave, annaPost, 0, "dave")
+
+	if err := blocks.BlockUser(a.anna, a.dave); err != nil {
+		t.Fatalf("BlockUser() = %v", err)
+	}
+
+	// the block hides the posts of both users from each other
+	if got := feedContents(t, posts, a.anna); got != "anna" {
+		t.Fatalf("blocker feed shows %q, want %q", got, "anna")
+	}
+
+	if got := feedContents(t, posts, a.dave); got != "dave" {
+		t.Fatalf("blocked feed shows %q, want %q", got, "dave")
+	}
+
+	if _, err := comments.GetPostComments(davePost, 0, a.anna); err != sql.ErrNoRows {
+		t.Fatalf("comments of the blocked user's post: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if _, err := comments.GetPostComments(annaPost, 0, a.dave); err != sql.ErrNoRows {
+		t.Fatalf("comments of the blocker's post: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	// comments of the blocked user are left out for the blocker
+	got, err := comments.GetPostComments(annaPost, 0, a.anna)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("GetPostComments() = %d comments, %v, want none", len(got), err)
+	}
+
+	// others see both users
+	if got := feedContents(t, posts, a.bob); got != "anna,dave" {
+		t.Fatalf("feed of another user shows %q, want %q", got, "anna,dave")
+	}
+
+	if got, err = comments.GetPostComments(annaPost, 0, a.bob); err != nil || len(got) != 1 {
+		t.Fatalf("GetPostComments() = %d comments, %v, want 1", len(got), err)
+	}
+}
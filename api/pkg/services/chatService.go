@@ -16,10 +16,12 @@ type IChatService interface {
 }
 
 type ChatService struct {
-	Logger    *log.Logger
-	UserRepo  models.IUserRepository
-	ChatRepo  models.IMessageRepository
-	GroupRepo models.IGroupRepository
+	Logger             *log.Logger
+	UserRepo           models.IUserRepository
+	ChatRepo           models.IMessageRepository
+	GroupRepo          models.IGroupRepository
+	VerificationPolicy IVerificationPolicy
+	BlockRepo          models.IBlockRepository
 }
 
 func InitChatService(
@@ -27,15 +29,25 @@ func InitChatService(
 	userRepo *models.UserRepository,
 	chatRepo *models.MessageRepository,
 	groupRepo *models.GroupRepository,
+	verificationPolicy IVerificationPolicy,
+	blockRepo *models.BlockRepository,
 ) *ChatService {
 	return &ChatService{
-		Logger:    logger,
-		UserRepo:  userRepo,
-		ChatRepo:  chatRepo,
-		GroupRepo: groupRepo,
+		Logger:             logger,
+		UserRepo:           userRepo,
+		ChatRepo:           chatRepo,
+		GroupRepo:          groupRepo,
+		VerificationPolicy: verificationPolicy,
+		BlockRepo:          blockRepo,
 	}
 }
 
+var (
+	ErrNoMessageTarget = errors.New("neither recipient nor group id is specified")
+	ErrMessageNotFound = errors.New("message does not exist")
+	ErrNotRecipient    = errors.New("not recipient")
+)
+
 type UserChatList struct {
 	UserID      int       `json:"user_id"`
 	Name        string    `json:"name"`
@@ -142,6 +154,10 @@ func (s *ChatService) GetChatlist(userID int64) ([]UserChatList, []GroupChatList
 
 func (s *ChatService) CreateMessage(message *models.Message) (int64, error) {
 
+	if err := s.VerificationPolicy.CheckAllowed(message.SenderId, ActionMessage); err != nil {
+		return -1, err
+	}
+
 	// check if users exist
 	_, err := s.UserRepo.GetById(message.SenderId)
 	if err != nil {
@@ -155,6 +171,17 @@ func (s *ChatService) CreateMessage(message *models.Message) (int64, error) {
 			s.Logger.Printf("User with id %d does not exist", message.RecipientId)
 			return -1, err
 		}
+
+		blocked, err := s.BlockRepo.IsBlockedEitherWay(message.SenderId, message.RecipientId)
+		if err != nil {
+			s.Logger.Printf("Cannot check block: %s", err)
+			return -1, err
+		}
+
+		if blocked {
+			s.Logger.Printf("User %d cannot message user %d, one has blocked the other", message.SenderId, message.RecipientId)
+			return -1, ErrUserBlocked
+		}
 	} else if message.GroupId != 0 {
 		_, err = s.GroupRepo.GetById(message.GroupId)
 		if err != nil {
@@ -163,7 +190,7 @@ func (s *ChatService) CreateMessage(message *models.Message) (int64, error) {
 		}
 	} else {
 		s.Logger.Printf("Neither recipient nor group id is specified")
-		return -1, errors.New("neither recipient nor group id is specified")
+		return -1, ErrNoMessageTarget
 	}
 
 	lastID, err := s.ChatRepo.Insert(message)
@@ -221,7 +248,7 @@ func (s *ChatService) GetMessageHistory(userId int64, otherId int64, groupId int
 
 	} else {
 		s.Logger.Printf("Neither recipient nor group id is specified")
-		return nil, errors.New("neither recipient nor group id is specified")
+		return nil, ErrNoMessageTarget
 	}
 
 	messagesJSON := []*MessageJSON{}
@@ -315,12 +342,12 @@ func (s *ChatService) HandleMessagesRead(userId int64, messageId int64) error {
 
 	if message == nil {
 		s.Logger.Printf("Message with id %d does not exist", messageId)
-		return errors.New("message does not exist")
+		return ErrMessageNotFound
 	}
 
 	if message.RecipientId != userId {
 		// mark read only messages that were sent to the user
-		return errors.New("not recipient")
+		return ErrNotRecipient
 	}
 
 	err = s.ChatRepo.MarkMessagesRead(message.SenderId, userId, messageId)
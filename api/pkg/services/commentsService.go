@@ -1,48 +1,142 @@
 package services
 
 import (
+	"SocialNetworkRestApi/api/internal/server/utils"
 	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
 	"errors"
 	"log"
 	"time"
 )
 
 type ICommentService interface {
-	GetPostComments(userId int64, offset int64) ([]*CommentJSON, error)
+	GetPostComments(postId int64, offset int64, requestingUserId int64) ([]*CommentJSON, error)
+	GetCommentReplies(commentId int64, offset int64, requestingUserId int64) ([]*CommentJSON, error)
+	GetById(commentId int64) (*models.Comment, error)
 	CreateComment(comment *models.Comment) error
 }
 
+var (
+	ErrMaxCommentDepth       = errors.New("comment thread is too deep")
+	ErrParentCommentMismatch = errors.New("parent comment belongs to another post")
+)
+
 // Controller contains the service, which contains database-related logic, as an injectable dependency, allowing us to decouple business logic from db logic.
 type CommentService struct {
-	Logger            *log.Logger
-	CommentRepository models.ICommentRepository
-	UserRepository    models.IUserRepository
+	Logger             *log.Logger
+	CommentRepository  models.ICommentRepository
+	PostRepository     models.IPostRepository
+	UserRepository     models.IUserRepository
+	ReactionRepository models.IReactionRepository
+	ImageService       utils.IImageService
+	VerificationPolicy IVerificationPolicy
+	// MaxDepth is the deepest reply level, 0 allows top level comments only
+	MaxDepth int
 }
 
-func InitCommentService(logger *log.Logger, commentRepo *models.CommentRepository, userRepo *models.UserRepository) *CommentService {
+func InitCommentService(logger *log.Logger, commentRepo *models.CommentRepository, postRepo *models.PostRepository, userRepo *models.UserRepository, reactionRepo *models.ReactionRepository, imageService *utils.ImageService, verificationPolicy IVerificationPolicy, maxDepth int) *CommentService {
 	return &CommentService{
-		Logger:            logger,
-		CommentRepository: commentRepo,
-		UserRepository:    userRepo,
+		Logger:             logger,
+		CommentRepository:  commentRepo,
+		PostRepository:     postRepo,
+		UserRepository:     userRepo,
+		ReactionRepository: reactionRepo,
+		ImageService:       imageService,
+		VerificationPolicy: verificationPolicy,
+		MaxDepth:           maxDepth,
 	}
 }
 
 type CommentJSON struct {
-	Id           int       `json:"id"`
-	UserId       int       `json:"userId"`
-	UserName     string    `json:"userName"`
-	Content      string    `json:"content"`
-	ImagePath    string    `json:"imagePath"`
-	CreatedAt    time.Time `json:"createdAt"`
-	CommentCount int       `json:"commentCount"`
+	Id              int       `json:"id"`
+	UserId          int       `json:"userId"`
+	UserName        string    `json:"userName"`
+	Content         string    `json:"content"`
+	ImagePath       string    `json:"imagePath"`
+	CreatedAt       time.Time `json:"createdAt"`
+	CommentCount    int       `json:"commentCount"`
+	ParentCommentId int       `json:"parentCommentId"`
+	Depth           int       `json:"depth"`
+	ReplyCount      int       `json:"replyCount"`
+	ReactionsJSON
 }
 
-func (s *CommentService) GetPostComments(postId int64, offset int64) ([]*CommentJSON, error) {
+// checkPostVisible reports a post the feed does not show to the user as missing
+func (s *CommentService) checkPostVisible(postId int64, userId int64) error {
+
+	visible, err := s.PostRepository.IsVisibleToUser(postId, userId)
+	if err != nil {
+		s.Logger.Printf("Cannot check post visibility: %s", err)
+		return err
+	}
 
-	result, err := s.CommentRepository.GetAllByPostId(postId, offset)
+	if !visible {
+		s.Logger.Printf("Post %d is not visible to user %d", postId, userId)
+		return sql.ErrNoRows
+	}
 
+	return nil
+}
+
+func (s *CommentService) GetPostComments(postId int64, offset int64, requestingUserId int64) ([]*CommentJSON, error) {
+
+	if err := s.checkPostVisible(postId, requestingUserId); err != nil {
+		return nil, err
+	}
+
+	result, err := s.CommentRepository.GetAllByPostId(postId, requestingUserId, offset)
 	if err != nil {
 		s.Logger.Printf("Failed fetching comments: %s", err)
+		return nil, err
+	}
+
+	return s.toCommentJSON(result, requestingUserId), nil
+}
+
+// Returns a page of direct replies to a comment, comments under posts hidden from
+// the user are reported as missing
+func (s *CommentService) GetCommentReplies(commentId int64, offset int64, requestingUserId int64) ([]*CommentJSON, error) {
+
+	comment, err := s.CommentRepository.GetById(commentId)
+	if err != nil {
+		s.Logger.Printf("Failed fetching comment: %s", err)
+		return nil, err
+	}
+
+	if err := s.checkPostVisible(comment.PostId, requestingUserId); err != nil {
+		return nil, err
+	}
+
+	result, err := s.CommentRepository.GetReplies(commentId, requestingUserId, offset)
+	if err != nil {
+		s.Logger.Printf("Failed fetching replies: %s", err)
+		return nil, err
+	}
+
+	return s.toCommentJSON(result, requestingUserId), nil
+}
+
+func (s *CommentService) GetById(commentId int64) (*models.Comment, error) {
+
+	comment, err := s.CommentRepository.GetById(commentId)
+	if err != nil {
+		s.Logger.Printf("Failed fetching comment: %s", err)
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *CommentService) toCommentJSON(result []*models.PostComment, requestingUserId int64) []*CommentJSON {
+
+	commentIds := []int64{}
+	for _, p := range result {
+		commentIds = append(commentIds, p.Id)
+	}
+
+	summaries, err := s.ReactionRepository.GetCommentSummaries(commentIds, requestingUserId)
+	if err != nil {
+		s.Logger.Printf("Failed fetching comment reactions: %s", err)
 	}
 
 	comments := []*CommentJSON{}
@@ -59,20 +153,40 @@ func (s *CommentService) GetPostComments(postId int64, offset int64) ([]*Comment
 		}
 
 		comments = append(comments, &CommentJSON{
-			int(p.Id),
-			int(p.UserId),
-			user.Nickname,
-			p.Content,
-			p.ImagePath,
-			p.CreatedAt,
-			p.CommentCount,
+			Id:              int(p.Id),
+			UserId:          int(p.UserId),
+			UserName:        user.Nickname,
+			Content:         p.Content,
+			ImagePath:       p.ImagePath,
+			CreatedAt:       p.CreatedAt,
+			CommentCount:    p.CommentCount,
+			ParentCommentId: int(p.ParentCommentId),
+			Depth:           p.Depth,
+			ReplyCount:      p.ReplyCount,
+			ReactionsJSON:   newReactionsJSON(summaries[p.Id]),
 		})
 	}
 
-	return comments, nil
+	return comments
 }
 
-func (s *CommentService) CreateComment(comment *models.Comment) error {
+// Stores a comment or reply, a post hidden from the user is reported as missing.
+// The image of the comment is saved before and deleted again when it is refused.
+func (s *CommentService) CreateComment(comment *models.Comment) (err error) {
+
+	defer func() {
+		if err != nil && comment.ImagePath != "" {
+			s.ImageService.DeleteImage(comment.ImagePath)
+		}
+	}()
+
+	if err := s.VerificationPolicy.CheckAllowed(comment.UserId, ActionComment); err != nil {
+		return err
+	}
+
+	if err := s.checkPostVisible(comment.PostId, comment.UserId); err != nil {
+		return err
+	}
 
 	if len(comment.Content) == 0 && len(comment.ImagePath) == 0 {
 		err := errors.New("comment content too short")
@@ -80,11 +194,35 @@ func (s *CommentService) CreateComment(comment *models.Comment) error {
 		return err
 	}
 
-	_, err := s.CommentRepository.Insert(comment)
+	if comment.ParentCommentId > 0 {
+		parent, err := s.CommentRepository.GetById(comment.ParentCommentId)
+		if err == sql.ErrNoRows {
+			return ErrParentCommentMismatch
+		}
+		if err != nil {
+			s.Logger.Printf("CreateComment error: %s", err)
+			return err
+		}
+
+		if parent.PostId != comment.PostId {
+			return ErrParentCommentMismatch
+		}
+
+		if parent.Depth+1 > s.MaxDepth {
+			return ErrMaxCommentDepth
+		}
+
+		comment.Depth = parent.Depth + 1
+	}
+
+	id, err := s.CommentRepository.Insert(comment)
 
 	if err != nil {
 		log.Printf("CreateComment error: %s", err)
+		return err
 	}
 
-	return err
+	comment.Id = id
+
+	return nil
 }
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"testing"
+)
+
+func newTestCommentService(s *testServices) *CommentService {
+	return InitCommentService(
+		s.logger,
+		s.repos.CommentRepo,
+		s.repos.PostRepo,
+		s.repos.UserRepo,
+		s.repos.ReactionRepo,
+		s.images,
+		s.emailVerification,
+		2,
+	)
+}
+
+// newComment creates a comment or reply of the user and returns its id
+func newComment(t *testing.T, comments *CommentService, userId int64, postId int64, parentId int64, content string) int64 {
+	t.Helper()
+
+	comment := &models.Comment{PostId: postId, UserId: userId, ParentCommentId: parentId, Content: content}
+	if err := comments.CreateComment(comment); err != nil {
+		t.Fatalf("CreateComment() = %v", err)
+	}
+
+	return comment.Id
+}
+
+func TestCommentsOfHiddenPosts(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "private", enums.Private)
+	commentId := newComment(t, comments, a.bob, postId, 0, "comment")
+
+	if got, err := comments.GetPostComments(postId, 0, a.bob); err != nil || len(got) != 1 {
+		t.Fatalf("follower: got %d comments, %v, want 1", len(got), err)
+	}
+
+	// the post is not visible to dave, so it is reported as missing
+	if _, err := comments.GetPostComments(postId, 0, a.dave); err != sql.ErrNoRows {
+		t.Fatalf("GetPostComments() = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if _, err := comments.GetCommentReplies(commentId, 0, a.dave); err != sql.ErrNoRows {
+		t.Fatalf("GetCommentReplies() = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	err := comments.CreateComment(&models.Comment{PostId: postId, UserId: a.dave, Content: "comment"})
+	if err != sql.ErrNoRows {
+		t.Fatalf("CreateComment() = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestCommentReplies(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "public", enums.Public)
+	otherPostId := newPost(t, posts, a.anna, "other", enums.Public)
+
+	commentId := newComment(t, comments, a.bob, postId, 0, "comment")
+	replyId := newComment(t, comments, a.carl, postId, commentId, "reply")
+	deepestId := newComment(t, comments, a.dave, postId, replyId, "deepest")
+
+	replies, err := comments.GetCommentReplies(commentId, 0, a.dave)
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("GetCommentReplies() = %d replies, %v, want 1", len(replies), err)
+	}
+
+	if reply := replies[0]; reply.Id != int(replyId) || reply.ParentCommentId != int(commentId) || reply.Depth != 1 {
+		t.Fatalf("got reply %+v, want reply %d at depth 1", reply, replyId)
+	}
+
+	tests := []struct {
+		name    string
+		comment *models.Comment
+		want    error
+	}{
+		{"too deep", &models.Comment{PostId: postId, UserId: a.bob, ParentCommentId: deepestId, Content: "reply"}, ErrMaxCommentDepth},
+		{"parent under another post", &models.Comment{PostId: otherPostId, UserId: a.bob, ParentCommentId: commentId, Content: "reply"}, ErrParentCommentMismatch},
+		{"missing parent", &models.Comment{PostId: postId, UserId: a.bob, ParentCommentId: deepestId + 100, Content: "reply"}, ErrParentCommentMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := comments.CreateComment(tt.comment); err != tt.want {
+				t.Fatalf("CreateComment() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefusedCommentDeletesImage(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "public", enums.Public)
+	otherPostId := newPost(t, posts, a.anna, "other", enums.Public)
+	privatePostId := newPost(t, posts, a.anna, "private", enums.Private)
+
+	commentId := newComment(t, comments, a.bob, postId, 0, "comment")
+	replyId := newComment(t, comments, a.carl, postId, commentId, "reply")
+	deepestId := newComment(t, comments, a.dave, postId, replyId, "deepest")
+
+	tests := []struct {
+		name       string
+		comment    *models.Comment
+		unverified bool
+		want       error
+	}{
+		{"too deep", &models.Comment{PostId: postId, UserId: a.bob, ParentCommentId: deepestId}, false, ErrMaxCommentDepth},
+		{"parent under another post", &models.Comment{PostId: otherPostId, UserId: a.bob, ParentCommentId: commentId}, false, ErrParentCommentMismatch},
+		{"post not visible", &models.Comment{PostId: privatePostId, UserId: a.dave}, false, sql.ErrNoRows},
+		{"email not verified", &models.Comment{PostId: postId, UserId: a.bob}, true, ErrEmailNotVerified},
+		{"stored", &models.Comment{PostId: postId, UserId: a.bob}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.emailVerification.Restricted = map[string]bool{ActionComment: tt.unverified}
+
+			// the handler saves the uploaded image before the comment
+			tt.comment.ImagePath = newImage(t, s, "upload.png")
+
+			if err := comments.CreateComment(tt.comment); err != tt.want {
+				t.Fatalf("CreateComment() = %v, want %v", err, tt.want)
+			}
+
+			if kept := imageExists(s, tt.comment.ImagePath); kept != (tt.want == nil) {
+				t.Fatalf("image kept = %v, want %v", kept, tt.want == nil)
+			}
+		})
+	}
+}
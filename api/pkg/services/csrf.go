@@ -0,0 +1,63 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+// CSRFHeader carries the CSRF token on requests made with the session cookie
+const CSRFHeader = "X-CSRF-Token"
+
+var ErrCSRFToken = errors.New("missing or invalid CSRF token")
+
+// csrfToken derives the CSRF token of a session. It cannot be computed without the
+// HttpOnly session cookie and changes with every login, so nothing has to be stored.
+func csrfToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// safeMethod reports whether requests with the method do not change anything
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// validCSRFToken reports whether the request carries the CSRF token of the principal
+func validCSRFToken(r *http.Request, principal *Principal) bool {
+	token := r.Header.Get(CSRFHeader)
+	if token == "" || principal.CSRFToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(principal.CSRFToken)) == 1
+}
+
+// CookieOptions are the attributes of the cookies set by the server
+type CookieOptions struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// NewCookie returns an HttpOnly cookie with the configured attributes, a negative
+// maxAge deletes the cookie
+func (s *UserService) NewCookie(name string, value string, path string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.Cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: s.Cookie.SameSite,
+	}
+}
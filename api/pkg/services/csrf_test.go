@@ -0,0 +1,110 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFTokenIsRequiredWithSessionCookie(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+	token := s.login(t, "anna@example.com")
+	otherToken := s.login(t, "anna@example.com")
+
+	_, principal := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/csrf", nil), token))
+	if principal == nil || principal.CSRFToken == "" {
+		t.Fatalf("got principal %+v, want a CSRF token", principal)
+	}
+
+	if principal.CSRFToken != csrfToken(token) || csrfToken(token) == csrfToken(otherToken) {
+		t.Fatalf("CSRF token is not derived from the session")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		csrf   string
+		want   int
+	}{
+		{"GET without token", "GET", "", http.StatusOK},
+		{"HEAD without token", "HEAD", "", http.StatusOK},
+		{"OPTIONS without token", "OPTIONS", "", http.StatusOK},
+		{"POST without token", "POST", "", http.StatusForbidden},
+		{"PUT without token", "PUT", "", http.StatusForbidden},
+		{"DELETE without token", "DELETE", "", http.StatusForbidden},
+		{"POST with a wrong token", "POST", "wrong", http.StatusForbidden},
+		{"POST with the token of another session", "POST", csrfToken(otherToken), http.StatusForbidden},
+		{"POST with the token", "POST", csrfToken(token), http.StatusOK},
+		{"DELETE with the token", "DELETE", csrfToken(token), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := withSession(httptest.NewRequest(tt.method, "/posts", nil), token)
+			if tt.csrf != "" {
+				r.Header.Set(CSRFHeader, tt.csrf)
+			}
+
+			w, principal := s.serveAuthenticated(r)
+			if w.Code != tt.want || (principal != nil) != (tt.want == http.StatusOK) {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessTokensDoNotNeedCSRFToken(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	tokens := InitAccessTokenService(s.logger, s.repos.AccessTokenRepo)
+
+	created, err := tokens.CreateToken(anna, AccessTokenRequestJSON{Name: "bot", Scopes: []string{models.ScopePost}})
+	if err != nil {
+		t.Fatalf("CreateToken() = %v", err)
+	}
+
+	var principal *Principal
+
+	r := httptest.NewRequest("POST", "/post", nil)
+	r.Header.Set("Authorization", "Bearer "+created.Token)
+
+	w := httptest.NewRecorder()
+	s.users.AuthenticateScope(models.ScopePost, func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromContext(r.Context())
+	})(w, r)
+
+	if w.Code != http.StatusOK || principal == nil || principal.UserID != anna || principal.TokenID != created.Id {
+		t.Fatalf("got %d with principal %+v, want access token %d of user %d", w.Code, principal, created.Id, anna)
+	}
+}
+
+func TestCookieAttributes(t *testing.T) {
+	s := newTestServices(t)
+	s.users.Cookie = CookieOptions{Secure: true, SameSite: http.SameSiteStrictMode, Domain: "example.com"}
+
+	w := httptest.NewRecorder()
+	s.users.SetCookie(w, "token")
+
+	cookie := sessionCookie(w)
+	if cookie == nil {
+		t.Fatalf("no session cookie set")
+	}
+
+	if cookie.Value != "token" || cookie.Path != "/" || cookie.Domain != "example.com" || !cookie.Secure || !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("got cookie %+v, want a secure HttpOnly strict cookie for example.com", cookie)
+	}
+
+	if cookie.MaxAge != int(s.users.SessionTTL.Seconds()) {
+		t.Fatalf("got max age %d, want the session TTL", cookie.MaxAge)
+	}
+
+	w = httptest.NewRecorder()
+	s.users.ClearCookie(w)
+
+	if cookie = sessionCookie(w); cookie == nil || cookie.MaxAge >= 0 || !cookie.Secure || cookie.Domain != "example.com" {
+		t.Fatalf("got cookie %+v, want the session cookie deleted with the same attributes", cookie)
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/internal/mailer"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Actions that the verification policy can deny to unverified accounts
+const (
+	ActionPost    = "post"
+	ActionComment = "comment"
+	ActionMessage = "message"
+	ActionGroup   = "group"
+)
+
+// IVerificationPolicy decides whether a user may act before confirming the email
+type IVerificationPolicy interface {
+	CheckAllowed(userId int64, action string) error
+}
+
+type IEmailVerificationService interface {
+	IVerificationPolicy
+	SendVerification(userId int64) error
+	ResendVerification(userId int64) error
+	ConfirmEmail(token string) error
+	IsVerified(userId int64) (bool, error)
+}
+
+type EmailVerificationService struct {
+	Logger                *log.Logger
+	UserRepo              models.IUserRepository
+	EmailVerificationRepo models.IEmailVerificationRepository
+	Mailer                mailer.Mailer
+	// TokenTTL is how long a confirmation link stays valid
+	TokenTTL time.Duration
+	// ResendInterval is the minimum time between two confirmation emails of a user
+	ResendInterval time.Duration
+	// FrontendURL is the base of the confirmation link sent to the user
+	FrontendURL string
+	// Restricted holds the actions denied to unverified users
+	Restricted map[string]bool
+}
+
+func InitEmailVerificationService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	emailVerificationRepo *models.EmailVerificationRepository,
+	mailSender mailer.Mailer,
+	tokenTTL time.Duration,
+	resendInterval time.Duration,
+	frontendURL string,
+	restrictions []string,
+) *EmailVerificationService {
+	restricted := map[string]bool{}
+	for _, action := range restrictions {
+		restricted[action] = true
+	}
+
+	return &EmailVerificationService{
+		Logger:                logger,
+		UserRepo:              userRepo,
+		EmailVerificationRepo: emailVerificationRepo,
+		Mailer:                mailSender,
+		TokenTTL:              tokenTTL,
+		ResendInterval:        resendInterval,
+		FrontendURL:           frontendURL,
+		Restricted:            restricted,
+	}
+}
+
+var (
+	ErrEmailNotVerified        = errors.New("email is not verified")
+	ErrEmailAlreadyVerified    = errors.New("email is already verified")
+	ErrVerificationThrottled   = errors.New("verification email was sent recently, try again later")
+	ErrInvalidVerificationLink = errors.New("invalid or expired verification token")
+)
+
+// Returns ErrEmailNotVerified when the action is restricted and the user has not confirmed the email
+func (s *EmailVerificationService) CheckAllowed(userId int64, action string) error {
+	if !s.Restricted[action] {
+		return nil
+	}
+
+	verified, err := s.UserRepo.IsEmailVerified(userId)
+	if err != nil {
+		s.Logger.Printf("CheckAllowed error: %s", err)
+		return err
+	}
+
+	if !verified {
+		s.Logger.Printf("Unverified user %d cannot %s", userId, action)
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+func (s *EmailVerificationService) IsVerified(userId int64) (bool, error) {
+	return s.UserRepo.IsEmailVerified(userId)
+}
+
+// Emails a new confirmation link to the user, earlier links stop working
+func (s *EmailVerificationService) SendVerification(userId int64) error {
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("SendVerification error: %s", err)
+		return err
+	}
+
+	token, tokenHash, err := newSecretToken()
+	if err != nil {
+		s.Logger.Printf("Cannot create verification token: %s", err)
+		return err
+	}
+
+	err = s.EmailVerificationRepo.DeleteByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("SendVerification error: %s", err)
+		return err
+	}
+
+	_, err = s.EmailVerificationRepo.Insert(&models.EmailVerification{
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.TokenTTL),
+	})
+	if err != nil {
+		s.Logger.Printf("SendVerification error: %s", err)
+		return err
+	}
+
+	link := strings.TrimRight(s.FrontendURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+
+	err = s.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nwelcome! Open the link below to confirm your email address, it is valid for %s.\n\n%s\n\n"+
+			"If you did not sign up, you can ignore this email.\n", user.FirstName, s.TokenTTL, link),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot send verification email: %s", err)
+		return err
+	}
+
+	s.Logger.Printf("Verification email sent to user %d", userId)
+
+	return nil
+}
+
+// Sends the confirmation link again, at most once per ResendInterval
+func (s *EmailVerificationService) ResendVerification(userId int64) error {
+
+	verified, err := s.UserRepo.IsEmailVerified(userId)
+	if err != nil {
+		s.Logger.Printf("ResendVerification error: %s", err)
+		return err
+	}
+
+	if verified {
+		return ErrEmailAlreadyVerified
+	}
+
+	lastSent, err := s.EmailVerificationRepo.GetLastCreatedAt(userId)
+	if err != nil && err != sql.ErrNoRows {
+		s.Logger.Printf("ResendVerification error: %s", err)
+		return err
+	}
+
+	if err == nil && time.Since(lastSent) < s.ResendInterval {
+		return ErrVerificationThrottled
+	}
+
+	return s.SendVerification(userId)
+}
+
+// Marks the email of the token owner as verified, a token works once
+func (s *EmailVerificationService) ConfirmEmail(token string) error {
+
+	userId, err := s.EmailVerificationRepo.Consume(hashSecretToken(token))
+	if err == sql.ErrNoRows {
+		return ErrInvalidVerificationLink
+	}
+
+	if err != nil {
+		s.Logger.Printf("ConfirmEmail error: %s", err)
+		return err
+	}
+
+	err = s.UserRepo.SetEmailVerified(userId)
+	if err != nil {
+		s.Logger.Printf("ConfirmEmail error: %s", err)
+		return err
+	}
+
+	s.Logger.Printf("User %d verified the email", userId)
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"testing"
+	"time"
+)
+
+// verificationToken returns the token of the last confirmation email
+func verificationToken(t *testing.T, s *testServices) string {
+	t.Helper()
+
+	mails := s.mails.sent()
+	if len(mails) == 0 {
+		t.Fatalf("no verification email sent")
+	}
+
+	return linkToken(t, mails[len(mails)-1], "/verify-email")
+}
+
+func TestRegistrationSendsVerification(t *testing.T) {
+	s := newTestServices(t)
+
+	_, err := s.users.UserRegister(&models.User{FirstName: "Anna", Email: "anna@example.com", Password: testPassword}, newRequest("192.0.2.1"))
+	if err != nil {
+		t.Fatalf("UserRegister() = %v", err)
+	}
+
+	user, err := s.repos.UserRepo.GetByEmail("anna@example.com")
+	if err != nil {
+		t.Fatalf("Cannot get user: %v", err)
+	}
+
+	mails := s.mails.sent()
+	if len(mails) != 1 || mails[0].To != "anna@example.com" {
+		t.Fatalf("sent %+v, want a verification email to anna@example.com", mails)
+	}
+
+	if verified, _ := s.emailVerification.IsVerified(user.Id); verified {
+		t.Fatalf("verified before the link was opened")
+	}
+
+	token := verificationToken(t, s)
+
+	if err = s.emailVerification.ConfirmEmail(token); err != nil {
+		t.Fatalf("ConfirmEmail() = %v", err)
+	}
+
+	if verified, _ := s.emailVerification.IsVerified(user.Id); !verified {
+		t.Fatalf("not verified after the link was opened")
+	}
+
+	if err = s.emailVerification.ConfirmEmail(token); err != ErrInvalidVerificationLink {
+		t.Fatalf("used link: got %v, want %v", err, ErrInvalidVerificationLink)
+	}
+
+	if err = s.emailVerification.ResendVerification(user.Id); err != ErrEmailAlreadyVerified {
+		t.Fatalf("resending when verified: got %v, want %v", err, ErrEmailAlreadyVerified)
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	if err := s.emailVerification.SendVerification(anna); err != nil {
+		t.Fatalf("SendVerification() = %v", err)
+	}
+
+	earlier := verificationToken(t, s)
+
+	if err := s.emailVerification.ResendVerification(anna); err != ErrVerificationThrottled {
+		t.Fatalf("resending right away: got %v, want %v", err, ErrVerificationThrottled)
+	}
+
+	s.emailVerification.ResendInterval = 0
+
+	if err := s.emailVerification.ResendVerification(anna); err != nil {
+		t.Fatalf("ResendVerification() = %v", err)
+	}
+
+	if len(s.mails.sent()) != 2 {
+		t.Fatalf("sent %d emails, want 2", len(s.mails.sent()))
+	}
+
+	if err := s.emailVerification.ConfirmEmail(earlier); err != ErrInvalidVerificationLink {
+		t.Fatalf("earlier link: got %v, want %v", err, ErrInvalidVerificationLink)
+	}
+
+	if err := s.emailVerification.ConfirmEmail(verificationToken(t, s)); err != nil {
+		t.Fatalf("ConfirmEmail() = %v", err)
+	}
+}
+
+func TestExpiredVerificationLink(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	s.emailVerification.TokenTTL = -time.Minute
+
+	if err := s.emailVerification.SendVerification(anna); err != nil {
+		t.Fatalf("SendVerification() = %v", err)
+	}
+
+	if err := s.emailVerification.ConfirmEmail(verificationToken(t, s)); err != ErrInvalidVerificationLink {
+		t.Fatalf("got %v, want %v", err, ErrInvalidVerificationLink)
+	}
+
+	if verified, _ := s.emailVerification.IsVerified(anna); verified {
+		t.Fatalf("verified with an expired link")
+	}
+}
+
+func TestUnverifiedUsersAreRestricted(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	s.emailVerification.Restricted = map[string]bool{ActionPost: true}
+
+	if err := s.emailVerification.CheckAllowed(anna, ActionPost); err != ErrEmailNotVerified {
+		t.Fatalf("restricted action: got %v, want %v", err, ErrEmailNotVerified)
+	}
+
+	if err := s.emailVerification.CheckAllowed(anna, ActionComment); err != nil {
+		t.Fatalf("unrestricted action: got %v", err)
+	}
+
+	if err := s.repos.UserRepo.SetEmailVerified(anna); err != nil {
+		t.Fatalf("Cannot verify email: %v", err)
+	}
+
+	if err := s.emailVerification.CheckAllowed(anna, ActionPost); err != nil {
+		t.Fatalf("verified user: got %v", err)
+	}
+}
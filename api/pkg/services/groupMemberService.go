@@ -21,6 +21,7 @@ type GroupMemberService struct {
 	NotificationRepository models.INotificationRepository
 	GroupRepository        models.IGroupRepository
 	GroupMemberRepository  models.IGroupMemberRepository
+	BlockRepository        models.IBlockRepository
 }
 
 func InitGroupMemberService(
@@ -28,13 +29,15 @@ func InitGroupMemberService(
 	userRepo *models.UserRepository,
 	notificationsRepo *models.NotificationRepository,
 	groupRepository *models.GroupRepository,
-	groupMemberRepo *models.GroupMemberRepository) *GroupMemberService {
+	groupMemberRepo *models.GroupMemberRepository,
+	blockRepo *models.BlockRepository) *GroupMemberService {
 	return &GroupMemberService{
 		Logger:                 logger,
 		UserRepository:         userRepo,
 		NotificationRepository: notificationsRepo,
 		GroupRepository:        groupRepository,
 		GroupMemberRepository:  groupMemberRepo,
+		BlockRepository:        blockRepo,
 	}
 }
 
@@ -106,6 +109,19 @@ func (s *GroupMemberService) AddMembers(userId int64, members models.GroupMember
 		return nil, errors.New("not a member of this group")
 	}
 
+	blockedIds, err := s.BlockRepository.GetBlockedUserIds(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get blocked users: %s", err)
+		return nil, err
+	}
+
+	for _, userIdToAdd := range members.UserIds {
+		if blockedIds[int64(userIdToAdd)] {
+			s.Logger.Printf("User %d cannot invite blocked user %d", userId, userIdToAdd)
+			return nil, ErrUserBlocked
+		}
+	}
+
 	notificationDetails := &models.NotificationDetails{
 		SenderId:         userId,
 		NotificationType: "group_invite",
@@ -310,9 +326,18 @@ func (s *GroupMemberService) GetMembersToAdd(groupId int64, userId int64) ([]*mo
 		simpleMembers[followed.Id] = simpleMember
 	}
 
+	blockedIds, err := s.BlockRepository.GetBlockedUserIds(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get blocked users: %s", err)
+		return nil, err
+	}
+
 	simpleMembersArray := make([]*models.SimpleUserJSON, 0, len(simpleMembers))
 
-	for _, simpleMember := range simpleMembers {
+	for id, simpleMember := range simpleMembers {
+		if blockedIds[id] {
+			continue
+		}
 		simpleMembersArray = append(simpleMembersArray, simpleMember)
 	}
 
@@ -21,10 +21,12 @@ type IGroupService interface {
 }
 
 type GroupService struct {
-	Logger          *log.Logger
-	GroupRepository models.IGroupRepository
-	GroupMemberRepo models.IGroupMemberRepository
-	UserRepository  models.IUserRepository
+	Logger             *log.Logger
+	GroupRepository    models.IGroupRepository
+	GroupMemberRepo    models.IGroupMemberRepository
+	UserRepository     models.IUserRepository
+	ImageService       utils.IImageService
+	VerificationPolicy IVerificationPolicy
 }
 
 func InitGroupService(
@@ -32,12 +34,16 @@ func InitGroupService(
 	groupRepo *models.GroupRepository,
 	groupMemberRepo *models.GroupMemberRepository,
 	userRepo *models.UserRepository,
+	imageService *utils.ImageService,
+	verificationPolicy IVerificationPolicy,
 ) *GroupService {
 	return &GroupService{
-		Logger:          logger,
-		GroupRepository: groupRepo,
-		GroupMemberRepo: groupMemberRepo,
-		UserRepository:  userRepo,
+		Logger:             logger,
+		GroupRepository:    groupRepo,
+		GroupMemberRepo:    groupMemberRepo,
+		UserRepository:     userRepo,
+		ImageService:       imageService,
+		VerificationPolicy: verificationPolicy,
 	}
 }
 
@@ -109,6 +115,11 @@ func (s *GroupService) SearchGroupsAndUsers(userId int64, searchString string) (
 }
 
 func (s *GroupService) CreateGroup(groupFormData *models.GroupJSON, userId int64) (int64, error) {
+
+	if err := s.VerificationPolicy.CheckAllowed(userId, ActionGroup); err != nil {
+		return -1, err
+	}
+
 	group := &models.Group{
 		CreatorId:   userId,
 		ImagePath:   groupFormData.ImagePath,
@@ -167,7 +178,7 @@ func (s *GroupService) UpdateGroupImage(userId int64, groupId int64, imageFile m
 	}
 
 	// save image
-	imagePath, err := utils.SaveImage(imageFile, header)
+	imagePath, err := s.ImageService.SaveImage(imageFile, header)
 	if err != nil {
 		s.Logger.Printf("Cannot save image: %s", err)
 		return err
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+type ILoginThrottleService interface {
+	Check(email string, ip string) (time.Duration, error)
+	RecordFailure(email string, ip string) (bool, error)
+	RecordSuccess(email string) error
+}
+
+// LoginThrottleService counts failed logins per email and per client IP. Failures for an email
+// make the next attempt wait exponentially longer and lock it after MaxFailures, too many failures
+// from one IP block that IP. Counters are forgotten LockoutDuration after the last failure.
+type LoginThrottleService struct {
+	Logger           *log.Logger
+	LoginFailureRepo models.ILoginFailureRepository
+	MaxFailures      int
+	MaxIPFailures    int
+	LockoutDuration  time.Duration
+}
+
+func InitLoginThrottleService(
+	logger *log.Logger,
+	loginFailureRepo *models.LoginFailureRepository,
+	maxFailures int,
+	maxIPFailures int,
+	lockoutDuration time.Duration,
+) *LoginThrottleService {
+	return &LoginThrottleService{
+		Logger:           logger,
+		LoginFailureRepo: loginFailureRepo,
+		MaxFailures:      maxFailures,
+		MaxIPFailures:    maxIPFailures,
+		LockoutDuration:  lockoutDuration,
+	}
+}
+
+const (
+	// loginFreeAttempts is the number of failures for an email before the backoff starts
+	loginFreeAttempts = 3
+	loginBackoffBase  = time.Second
+)
+
+var (
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrTooManyLoginAttempts = errors.New("too many login attempts, try again later")
+)
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Returns ErrTooManyLoginAttempts and the time to wait if the email or IP may not log in yet
+func (s *LoginThrottleService) Check(email string, ip string) (time.Duration, error) {
+	wait := time.Duration(0)
+
+	for _, counter := range [][2]string{{models.LoginScopeEmail, normalizeLoginEmail(email)}, {models.LoginScopeIP, ip}} {
+		failure, err := s.LoginFailureRepo.Get(counter[0], counter[1])
+		if err == sql.ErrNoRows {
+			continue
+		}
+
+		if err != nil {
+			s.Logger.Printf("Cannot get login failures: %s", err)
+			return 0, err
+		}
+
+		if blocked := time.Until(failure.BlockedUntil); blocked > wait {
+			wait = blocked
+		}
+	}
+
+	if wait > 0 {
+		return wait, ErrTooManyLoginAttempts
+	}
+
+	return 0, nil
+}
+
+// Counts a failed login, reports whether the email got locked by this failure
+func (s *LoginThrottleService) RecordFailure(email string, ip string) (bool, error) {
+	now := time.Now()
+
+	emailFailure, err := s.increment(models.LoginScopeEmail, normalizeLoginEmail(email), now)
+	if err != nil {
+		return false, err
+	}
+
+	locked := false
+
+	switch {
+	case emailFailure.Failures == s.MaxFailures:
+		emailFailure.BlockedUntil = now.Add(s.LockoutDuration)
+		locked = true
+		s.Logger.Printf("Login for %s locked after %d failures", emailFailure.Key, emailFailure.Failures)
+	case emailFailure.Failures > loginFreeAttempts:
+		backoff := loginBackoffBase << (emailFailure.Failures - loginFreeAttempts - 1)
+		if backoff > s.LockoutDuration || backoff <= 0 {
+			backoff = s.LockoutDuration
+		}
+		emailFailure.BlockedUntil = now.Add(backoff)
+	}
+
+	ipFailure, err := s.increment(models.LoginScopeIP, ip, now)
+	if err != nil {
+		return false, err
+	}
+
+	if ipFailure.Failures >= s.MaxIPFailures {
+		ipFailure.BlockedUntil = now.Add(s.LockoutDuration)
+		s.Logger.Printf("Login from %s blocked after %d failures", ip, ipFailure.Failures)
+	}
+
+	for _, failure := range []*models.LoginFailure{emailFailure, ipFailure} {
+		if err = s.LoginFailureRepo.Save(failure); err != nil {
+			s.Logger.Printf("Cannot save login failures: %s", err)
+			return false, err
+		}
+	}
+
+	return locked, nil
+}
+
+// Forgets the failures of the email, failures from the IP are kept so that
+// logging in to one account does not reset guessing on others
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	err := s.LoginFailureRepo.Delete(models.LoginScopeEmail, normalizeLoginEmail(email))
+	if err != nil {
+		s.Logger.Printf("Cannot reset login failures: %s", err)
+	}
+
+	return err
+}
+
+// increment returns the counter with one more failure, counters idle for longer than the lockout start over
+func (s *LoginThrottleService) increment(scope string, key string, now time.Time) (*models.LoginFailure, error) {
+	failure, err := s.LoginFailureRepo.Get(scope, key)
+	if err != nil && err != sql.ErrNoRows {
+		s.Logger.Printf("Cannot get login failures: %s", err)
+		return nil, err
+	}
+
+	if err == sql.ErrNoRows || now.Sub(failure.LastFailureAt) > s.LockoutDuration {
+		failure = &models.LoginFailure{Scope: scope, Key: key}
+	}
+
+	failure.Failures++
+	failure.LastFailureAt = now
+
+	return failure, nil
+}
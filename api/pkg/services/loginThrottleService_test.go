@@ -0,0 +1,153 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// recordFailures counts n failed logins and returns whether the last one locked the email
+func recordFailures(t *testing.T, throttle *LoginThrottleService, email string, ip string, n int) bool {
+	t.Helper()
+
+	locked := false
+	for i := 0; i < n; i++ {
+		var err error
+		if locked, err = throttle.RecordFailure(email, ip); err != nil {
+			t.Fatalf("RecordFailure() = %v", err)
+		}
+	}
+
+	return locked
+}
+
+// expectWait fails unless Check blocks for about want
+func expectWait(t *testing.T, throttle *LoginThrottleService, email string, ip string, want time.Duration) {
+	t.Helper()
+
+	wait, err := throttle.Check(email, ip)
+
+	if want == 0 {
+		if err != nil {
+			t.Fatalf("Check(%s, %s) = %v, %v, want no wait", email, ip, wait, err)
+		}
+		return
+	}
+
+	if err != ErrTooManyLoginAttempts || wait > want || wait < want-5*time.Second {
+		t.Fatalf("Check(%s, %s) = %v, %v, want a wait of %v", email, ip, wait, err, want)
+	}
+}
+
+func TestLoginBackoffAndLockout(t *testing.T) {
+	s := newTestServices(t)
+	throttle := s.loginThrottle
+
+	if recordFailures(t, throttle, "anna@example.com", "192.0.2.1", loginFreeAttempts) {
+		t.Fatalf("locked after the free attempts")
+	}
+	expectWait(t, throttle, "anna@example.com", "192.0.2.1", 0)
+
+	recordFailures(t, throttle, "anna@example.com", "192.0.2.1", 1)
+	expectWait(t, throttle, "anna@example.com", "192.0.2.2", loginBackoffBase)
+
+	if !recordFailures(t, throttle, "anna@example.com", "192.0.2.1", 1) {
+		t.Fatalf("failure %d did not lock the email", throttle.MaxFailures)
+	}
+
+	// the email is locked from everywhere, other emails are not
+	expectWait(t, throttle, " ANNA@example.com", "192.0.2.2", throttle.LockoutDuration)
+	expectWait(t, throttle, "bob@example.com", "192.0.2.1", 0)
+
+	if recordFailures(t, throttle, "anna@example.com", "192.0.2.1", 1) {
+		t.Fatalf("a locked email was reported locked again")
+	}
+}
+
+func TestLoginFailuresFromOneIP(t *testing.T) {
+	s := newTestServices(t)
+	throttle := s.loginThrottle
+
+	for i := 0; i < throttle.MaxIPFailures; i++ {
+		recordFailures(t, throttle, fmt.Sprintf("user%d@example.com", i), "192.0.2.1", 1)
+	}
+
+	expectWait(t, throttle, "anna@example.com", "192.0.2.1", throttle.LockoutDuration)
+	expectWait(t, throttle, "anna@example.com", "192.0.2.2", 0)
+}
+
+func TestLoginSuccessForgetsEmailFailures(t *testing.T) {
+	s := newTestServices(t)
+	throttle := s.loginThrottle
+
+	recordFailures(t, throttle, "anna@example.com", "192.0.2.1", loginFreeAttempts+1)
+
+	if err := throttle.RecordSuccess("Anna@Example.com"); err != nil {
+		t.Fatalf("RecordSuccess() = %v", err)
+	}
+
+	expectWait(t, throttle, "anna@example.com", "192.0.2.2", 0)
+
+	ipFailure, err := s.repos.LoginFailureRepo.Get(models.LoginScopeIP, "192.0.2.1")
+	if err != nil || ipFailure.Failures != loginFreeAttempts+1 {
+		t.Fatalf("IP failures = %+v, %v, want them kept", ipFailure, err)
+	}
+}
+
+func TestIdleLoginFailuresStartOver(t *testing.T) {
+	s := newTestServices(t)
+	throttle := s.loginThrottle
+
+	idle := time.Now().Add(-throttle.LockoutDuration - time.Minute)
+	err := s.repos.LoginFailureRepo.Save(&models.LoginFailure{
+		Scope:         models.LoginScopeEmail,
+		Key:           "anna@example.com",
+		Failures:      throttle.MaxFailures - 1,
+		LastFailureAt: idle,
+		BlockedUntil:  idle,
+	})
+	if err != nil {
+		t.Fatalf("Cannot save failures: %v", err)
+	}
+
+	if recordFailures(t, throttle, "anna@example.com", "192.0.2.1", 1) {
+		t.Fatalf("locked by failures older than the lockout")
+	}
+
+	expectWait(t, throttle, "anna@example.com", "192.0.2.1", 0)
+}
+
+func TestUserLoginIsThrottled(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	s.loginThrottle.MaxFailures = loginFreeAttempts + 1
+
+	wrong := &models.User{Email: "anna@example.com", Password: "wrong"}
+
+	for i := 1; i <= loginFreeAttempts+1; i++ {
+		result, err := s.users.UserLogin(wrong, newRequest("192.0.2.1"))
+		if err != ErrInvalidCredentials {
+			t.Fatalf("failure %d: got %v, want %v", i, err, ErrInvalidCredentials)
+		}
+
+		if locked := i == s.loginThrottle.MaxFailures; (result.LockedUserId == anna) != locked {
+			t.Fatalf("failure %d: got locked user %d", i, result.LockedUserId)
+		}
+	}
+
+	// the correct password does not help while the account is locked
+	result, err := s.users.UserLogin(&models.User{Email: "anna@example.com", Password: testPassword}, newRequest("192.0.2.2"))
+	if err != ErrTooManyLoginAttempts || result.RetryAfter <= s.loginThrottle.LockoutDuration-time.Minute {
+		t.Fatalf("got %+v, %v, want %v with the lockout to wait", result, err, ErrTooManyLoginAttempts)
+	}
+
+	// unknown emails are throttled the same way
+	for i := 0; i < loginFreeAttempts+1; i++ {
+		s.users.UserLogin(&models.User{Email: "nobody@example.com", Password: "wrong"}, newRequest("192.0.2.3"))
+	}
+
+	if _, err = s.users.UserLogin(&models.User{Email: "nobody@example.com", Password: "wrong"}, newRequest("192.0.2.3")); err != ErrTooManyLoginAttempts {
+		t.Fatalf("unknown email: got %v, want %v", err, ErrTooManyLoginAttempts)
+	}
+}
@@ -0,0 +1,591 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/internal/server/utils"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+type IModerationService interface {
+	CreateReport(reporterId int64, request ReportRequestJSON) (*ReportJSON, error)
+	GetReports(status string, offset int64) ([]*ReportJSON, error)
+	GetReport(reportId int64) (*ReportJSON, error)
+	UpdateReportStatus(moderatorId int64, reportId int64, request ReportStatusJSON) (*ReportJSON, error)
+	TakeAction(moderatorId int64, request ModerationActionRequestJSON) (*ModerationResult, error)
+	GetAuditTrail(offset int64) ([]*ModerationActionJSON, error)
+	SetRole(moderatorId int64, userId int64, role string) error
+	PromoteAdmins(emails []string) error
+}
+
+type ModerationService struct {
+	Logger          *log.Logger
+	UserRepo        models.IUserRepository
+	SessionRepo     models.ISessionRepository
+	AccessTokenRepo models.IAccessTokenRepository
+	PostRepo        models.IPostRepository
+	CommentRepo     models.ICommentRepository
+	MessageRepo     models.IMessageRepository
+	GroupMemberRepo models.IGroupMemberRepository
+	ReportRepo      models.IReportRepository
+	ModerationRepo  models.IModerationRepository
+	ImageService    utils.IImageService
+}
+
+func InitModerationService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	sessionRepo *models.SessionRepository,
+	accessTokenRepo *models.AccessTokenRepository,
+	postRepo *models.PostRepository,
+	commentRepo *models.CommentRepository,
+	messageRepo *models.MessageRepository,
+	groupMemberRepo *models.GroupMemberRepository,
+	reportRepo *models.ReportRepository,
+	moderationRepo *models.ModerationRepository,
+	imageService *utils.ImageService,
+) *ModerationService {
+	return &ModerationService{
+		Logger:          logger,
+		UserRepo:        userRepo,
+		SessionRepo:     sessionRepo,
+		AccessTokenRepo: accessTokenRepo,
+		PostRepo:        postRepo,
+		CommentRepo:     commentRepo,
+		MessageRepo:     messageRepo,
+		GroupMemberRepo: groupMemberRepo,
+		ReportRepo:      reportRepo,
+		ModerationRepo:  moderationRepo,
+		ImageService:    imageService,
+	}
+}
+
+var (
+	ErrInvalidReportEntity   = errors.New("invalid report entity type")
+	ErrInvalidReportReason   = errors.New("invalid report reason")
+	ErrInvalidReportStatus   = errors.New("invalid report status")
+	ErrReportTargetNotFound  = errors.New("reported entity does not exist")
+	ErrCannotReportOwn       = errors.New("cannot report yourself or your own content")
+	ErrReportAlreadyPending  = errors.New("you have already reported this")
+	ErrReportNotFound        = errors.New("report not found")
+	ErrInvalidModeration     = errors.New("action cannot be taken on this entity type")
+	ErrModerationNotFound    = errors.New("entity does not exist")
+	ErrCannotModerateSelf    = errors.New("cannot take this action on your own account")
+	ErrCannotSuspendAdmin    = errors.New("administrators cannot be suspended")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrReportDetailsTooLong  = errors.New("report details are too long")
+	ErrModerationNoteTooLong = errors.New("moderation note is too long")
+)
+
+// maxReportText limits the details of a report and the note of a moderator
+const maxReportText = 1000
+
+type ReportRequestJSON struct {
+	EntityType string `json:"entityType"`
+	EntityId   int64  `json:"entityId"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+}
+
+type ReportJSON struct {
+	Id             int64     `json:"id"`
+	ReporterId     int64     `json:"reporterId"`
+	EntityType     string    `json:"entityType"`
+	EntityId       int64     `json:"entityId"`
+	ReportedUserId int64     `json:"reportedUserId"`
+	Reason         string    `json:"reason"`
+	Details        string    `json:"details"`
+	Snapshot       string    `json:"snapshot"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type ReportStatusJSON struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+type ModerationActionRequestJSON struct {
+	Action     string `json:"action"`
+	EntityType string `json:"entityType"`
+	EntityId   int64  `json:"entityId"`
+	// ReportId is resolved by the action when set
+	ReportId int64  `json:"reportId"`
+	Note     string `json:"note"`
+}
+
+type ModerationActionJSON struct {
+	Id          int64     `json:"id"`
+	ModeratorId int64     `json:"moderatorId"`
+	Action      string    `json:"action"`
+	EntityType  string    `json:"entityType"`
+	EntityId    int64     `json:"entityId"`
+	ReportId    int64     `json:"reportId"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ModerationResult lists the sessions ended and access tokens locked out by an action,
+// their websocket clients have to be disconnected
+type ModerationResult struct {
+	SessionIds []int64
+	TokenIds   []int64
+}
+
+func newReportJSON(report *models.Report) *ReportJSON {
+	return &ReportJSON{
+		Id:             report.Id,
+		ReporterId:     report.ReporterId,
+		EntityType:     report.EntityType,
+		EntityId:       report.EntityId,
+		ReportedUserId: report.ReportedUserId,
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Snapshot:       report.Snapshot,
+		Status:         report.Status,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Files a report on content or a profile the reporter can see
+func (s *ModerationService) CreateReport(reporterId int64, request ReportRequestJSON) (*ReportJSON, error) {
+
+	if !models.IsReportEntity(request.EntityType) {
+		return nil, ErrInvalidReportEntity
+	}
+
+	if !contains(models.ReportReasons, request.Reason) {
+		return nil, ErrInvalidReportReason
+	}
+
+	request.Details = strings.TrimSpace(request.Details)
+	if len(request.Details) > maxReportText {
+		return nil, ErrReportDetailsTooLong
+	}
+
+	visible, err := s.canSee(reporterId, request.EntityType, request.EntityId)
+	if err != nil {
+		s.Logger.Printf("Cannot check reported entity: %s", err)
+		return nil, err
+	}
+
+	if !visible {
+		return nil, ErrReportTargetNotFound
+	}
+
+	target, err := s.ReportRepo.GetTarget(request.EntityType, request.EntityId)
+	if err == sql.ErrNoRows {
+		return nil, ErrReportTargetNotFound
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get reported entity: %s", err)
+		return nil, err
+	}
+
+	if target.OwnerId == reporterId {
+		return nil, ErrCannotReportOwn
+	}
+
+	now := time.Now()
+
+	report := &models.Report{
+		ReporterId:     reporterId,
+		EntityType:     request.EntityType,
+		EntityId:       request.EntityId,
+		ReportedUserId: target.OwnerId,
+		Reason:         request.Reason,
+		Details:        request.Details,
+		Snapshot:       target.Snapshot,
+		Status:         models.ReportStatusOpen,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	report.Id, err = s.ReportRepo.Insert(report)
+	if err != nil {
+		s.Logger.Printf("Cannot insert report: %s", err)
+		return nil, err
+	}
+
+	if report.Id == 0 {
+		return nil, ErrReportAlreadyPending
+	}
+
+	return newReportJSON(report), nil
+}
+
+// canSee reports whether the user can see the entity, groups and profiles can always be reported
+func (s *ModerationService) canSee(userId int64, entityType string, entityId int64) (bool, error) {
+	switch entityType {
+	case models.ReportEntityPost:
+		return s.PostRepo.IsVisibleToUser(entityId, userId)
+
+	case models.ReportEntityComment:
+		comment, err := s.CommentRepo.GetById(entityId)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return s.PostRepo.IsVisibleToUser(comment.PostId, userId)
+
+	case models.ReportEntityMessage:
+		message, err := s.MessageRepo.GetById(entityId)
+		if err != nil || message.Id == 0 {
+			return false, err
+		}
+
+		if message.GroupId == 0 {
+			return message.SenderId == userId || message.RecipientId == userId, nil
+		}
+
+		member, err := s.GroupMemberRepo.GetMemberByGroupId(message.GroupId, userId)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return member.Accepted, nil
+	}
+
+	return true, nil
+}
+
+// Returns a page of the reports with the status, or of the unhandled reports if the status is empty
+func (s *ModerationService) GetReports(status string, offset int64) ([]*ReportJSON, error) {
+
+	if status != "" && !contains(models.ReportStatuses, status) {
+		return nil, ErrInvalidReportStatus
+	}
+
+	reports, err := s.ReportRepo.GetAll(status, offset)
+	if err != nil {
+		s.Logger.Printf("Cannot get reports: %s", err)
+		return nil, err
+	}
+
+	reportsJSON := []*ReportJSON{}
+
+	for _, report := range reports {
+		reportsJSON = append(reportsJSON, newReportJSON(report))
+	}
+
+	return reportsJSON, nil
+}
+
+func (s *ModerationService) GetReport(reportId int64) (*ReportJSON, error) {
+
+	report, err := s.ReportRepo.GetById(reportId)
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get report: %s", err)
+		return nil, err
+	}
+
+	return newReportJSON(report), nil
+}
+
+// Moves the report to another status, e.g. reviewing while it is looked into or dismissed when nothing is wrong
+func (s *ModerationService) UpdateReportStatus(moderatorId int64, reportId int64, request ReportStatusJSON) (*ReportJSON, error) {
+
+	action, ok := models.ModerationReportActions[request.Status]
+	if !ok {
+		return nil, ErrInvalidReportStatus
+	}
+
+	if len(request.Note) > maxReportText {
+		return nil, ErrModerationNoteTooLong
+	}
+
+	report, err := s.GetReport(reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ReportRepo.UpdateStatus(reportId, request.Status)
+	if err != nil {
+		s.Logger.Printf("Cannot update report status: %s", err)
+		return nil, err
+	}
+
+	err = s.audit(&models.ModerationAction{
+		ModeratorId: moderatorId,
+		Action:      action,
+		EntityType:  report.EntityType,
+		EntityId:    report.EntityId,
+		ReportId:    reportId,
+		Note:        request.Note,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetReport(reportId)
+}
+
+// Hides or restores content, suspends or reinstates a user or deletes a group. The report
+// in the request, if any, is resolved by the action.
+func (s *ModerationService) TakeAction(moderatorId int64, request ModerationActionRequestJSON) (*ModerationResult, error) {
+
+	if len(request.Note) > maxReportText {
+		return nil, ErrModerationNoteTooLong
+	}
+
+	if request.ReportId != 0 {
+		if _, err := s.GetReport(request.ReportId); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ModerationResult{}
+	var err error
+
+	switch request.Action {
+	case models.ModerationHide, models.ModerationUnhide:
+		err = s.setHidden(request.EntityType, request.EntityId, request.Action == models.ModerationHide)
+
+	case models.ModerationSuspend, models.ModerationUnsuspend:
+		result.SessionIds, result.TokenIds, err = s.setSuspended(moderatorId, request.EntityType, request.EntityId, request.Action == models.ModerationSuspend)
+
+	case models.ModerationDeleteGroup:
+		err = s.deleteGroup(request.EntityType, request.EntityId)
+
+	default:
+		err = ErrInvalidModeration
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ReportId != 0 {
+		err = s.ReportRepo.UpdateStatus(request.ReportId, models.ReportStatusResolved)
+		if err != nil {
+			s.Logger.Printf("Cannot resolve report: %s", err)
+			return nil, err
+		}
+	}
+
+	err = s.audit(&models.ModerationAction{
+		ModeratorId: moderatorId,
+		Action:      request.Action,
+		EntityType:  request.EntityType,
+		EntityId:    request.EntityId,
+		ReportId:    request.ReportId,
+		Note:        request.Note,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *ModerationService) setHidden(entityType string, entityId int64, hidden bool) error {
+	if !models.IsHideable(entityType) {
+		return ErrInvalidModeration
+	}
+
+	found, err := s.ModerationRepo.SetHidden(entityType, entityId, hidden)
+	if err != nil {
+		s.Logger.Printf("Cannot hide %s: %s", entityType, err)
+		return err
+	}
+
+	if !found {
+		return ErrModerationNotFound
+	}
+
+	return nil
+}
+
+// setSuspended suspends or reinstates the user, a suspension also ends all sessions of the user
+func (s *ModerationService) setSuspended(moderatorId int64, entityType string, userId int64, suspended bool) ([]int64, []int64, error) {
+	if entityType != models.ReportEntityUser {
+		return nil, nil, ErrInvalidModeration
+	}
+
+	if userId == moderatorId {
+		return nil, nil, ErrCannotModerateSelf
+	}
+
+	status, err := s.UserRepo.GetAccountStatus(userId)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrModerationNotFound
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get account status: %s", err)
+		return nil, nil, err
+	}
+
+	if suspended && status.Role == models.RoleAdmin {
+		return nil, nil, ErrCannotSuspendAdmin
+	}
+
+	err = s.UserRepo.SetSuspended(userId, suspended)
+	if err != nil {
+		s.Logger.Printf("Cannot suspend user: %s", err)
+		return nil, nil, err
+	}
+
+	if !suspended {
+		return nil, nil, nil
+	}
+
+	sessionIds, err := s.SessionRepo.DeleteByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot delete user sessions: %s", err)
+		return nil, nil, err
+	}
+
+	// the tokens are kept, Authenticate refuses them until the user is unsuspended
+	tokens, err := s.AccessTokenRepo.GetAllByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get access tokens: %s", err)
+		return nil, nil, err
+	}
+
+	tokenIds := []int64{}
+	for _, token := range tokens {
+		tokenIds = append(tokenIds, token.Id)
+	}
+
+	return sessionIds, tokenIds, nil
+}
+
+func (s *ModerationService) deleteGroup(entityType string, groupId int64) error {
+	if entityType != models.ReportEntityGroup {
+		return ErrInvalidModeration
+	}
+
+	imagePaths, err := s.ModerationRepo.DeleteGroup(groupId)
+	if err == sql.ErrNoRows {
+		return ErrModerationNotFound
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot delete group: %s", err)
+		return err
+	}
+
+	for _, image := range imagePaths {
+		s.ImageService.DeleteImage(image)
+	}
+
+	return nil
+}
+
+func (s *ModerationService) audit(action *models.ModerationAction) error {
+	action.CreatedAt = time.Now()
+
+	_, err := s.ModerationRepo.InsertAction(action)
+	if err != nil {
+		s.Logger.Printf("Cannot insert moderation action: %s", err)
+	}
+
+	return err
+}
+
+// Returns a page of the moderation actions, newest first
+func (s *ModerationService) GetAuditTrail(offset int64) ([]*ModerationActionJSON, error) {
+
+	actions, err := s.ModerationRepo.GetActions(offset)
+	if err != nil {
+		s.Logger.Printf("Cannot get moderation actions: %s", err)
+		return nil, err
+	}
+
+	actionsJSON := []*ModerationActionJSON{}
+
+	for _, action := range actions {
+		actionsJSON = append(actionsJSON, &ModerationActionJSON{
+			Id:          action.Id,
+			ModeratorId: action.ModeratorId,
+			Action:      action.Action,
+			EntityType:  action.EntityType,
+			EntityId:    action.EntityId,
+			ReportId:    action.ReportId,
+			Note:        action.Note,
+			CreatedAt:   action.CreatedAt,
+		})
+	}
+
+	return actionsJSON, nil
+}
+
+// Makes the user an administrator or a regular user, administrators cannot demote themselves
+func (s *ModerationService) SetRole(moderatorId int64, userId int64, role string) error {
+
+	action := models.ModerationGrantAdmin
+
+	switch role {
+	case models.RoleAdmin:
+	case models.RoleUser:
+		action = models.ModerationRevokeAdmin
+	default:
+		return ErrInvalidRole
+	}
+
+	if userId == moderatorId {
+		return ErrCannotModerateSelf
+	}
+
+	if _, err := s.UserRepo.GetAccountStatus(userId); err == sql.ErrNoRows {
+		return ErrModerationNotFound
+	} else if err != nil {
+		s.Logger.Printf("Cannot get account status: %s", err)
+		return err
+	}
+
+	err := s.UserRepo.SetRole(userId, role)
+	if err != nil {
+		s.Logger.Printf("Cannot set role: %s", err)
+		return err
+	}
+
+	return s.audit(&models.ModerationAction{
+		ModeratorId: moderatorId,
+		Action:      action,
+		EntityType:  models.ReportEntityUser,
+		EntityId:    userId,
+	})
+}
+
+// Makes the users with the configured emails administrators, called on startup
+func (s *ModerationService) PromoteAdmins(emails []string) error {
+
+	promoted, err := s.UserRepo.SetRoleByEmails(emails, models.RoleAdmin)
+	if err != nil {
+		s.Logger.Printf("Cannot promote administrators: %s", err)
+		return err
+	}
+
+	if promoted > 0 {
+		s.Logger.Printf("Promoted %d user(s) to administrator", promoted)
+	}
+
+	return nil
+}
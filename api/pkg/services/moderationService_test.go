@@ -0,0 +1,355 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestModerationService(s *testServices) *ModerationService {
+	return InitModerationService(
+		s.logger,
+		s.repos.UserRepo,
+		s.repos.SessionRepo,
+		s.repos.AccessTokenRepo,
+		s.repos.PostRepo,
+		s.repos.CommentRepo,
+		s.repos.MessageRepo,
+		s.repos.GroupMemberRepo,
+		s.repos.ReportRepo,
+		s.repos.ModerationRepo,
+		s.images,
+	)
+}
+
+// newAdmin inserts a user with the admin role
+func (s *testServices) newAdmin(t *testing.T, email string) int64 {
+	t.Helper()
+
+	id := s.newUser(t, email)
+	if err := s.repos.UserRepo.SetRole(id, models.RoleAdmin); err != nil {
+		t.Fatalf("SetRole() = %v", err)
+	}
+
+	return id
+}
+
+func TestSuspensionLocksUserOut(t *testing.T) {
+	s := newTestServices(t)
+	moderation := newTestModerationService(s)
+	admin := s.newAdmin(t, "admin@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	session := s.login(t, "bob@example.com")
+	token := s.newAccessToken(t, bob, models.ScopeRead)
+
+	suspend := ModerationActionRequestJSON{Action: models.ModerationSuspend, EntityType: models.ReportEntityUser, EntityId: bob}
+
+	result, err := moderation.TakeAction(admin, suspend)
+	if err != nil {
+		t.Fatalf("TakeAction() = %v", err)
+	}
+
+	if len(result.SessionIds) != 1 || len(result.TokenIds) != 1 || result.TokenIds[0] != token.Id {
+		t.Fatalf("TakeAction() = %+v, want the session and token %d of the user", result, token.Id)
+	}
+
+	if w, _ := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), session)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("session of a suspended user: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w, _ := s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), token.Token)); w.Code != http.StatusForbidden {
+		t.Fatalf("token of a suspended user: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if _, err = s.users.UserLogin(&models.User{Email: "bob@example.com", Password: testPassword}, newRequest("192.0.2.1")); err != ErrAccountSuspended {
+		t.Fatalf("UserLogin() = %v, want %v", err, ErrAccountSuspended)
+	}
+
+	suspend.Action = models.ModerationUnsuspend
+	if _, err = moderation.TakeAction(admin, suspend); err != nil {
+		t.Fatalf("TakeAction() = %v", err)
+	}
+
+	if w, principal := s.serveAuthenticated(withToken(httptest.NewRequest("GET", "/posts", nil), token.Token)); principal == nil {
+		t.Fatalf("token of a reinstated user: got %d, want the handler to be called", w.Code)
+	}
+
+	s.login(t, "bob@example.com")
+
+	tests := []struct {
+		name    string
+		request ModerationActionRequestJSON
+		want    error
+	}{
+		{"self", ModerationActionRequestJSON{Action: models.ModerationSuspend, EntityType: models.ReportEntityUser, EntityId: admin}, ErrCannotModerateSelf},
+		{"admin", ModerationActionRequestJSON{Action: models.ModerationSuspend, EntityType: models.ReportEntityUser, EntityId: s.newAdmin(t, "other@example.com")}, ErrCannotSuspendAdmin},
+		{"unknown user", ModerationActionRequestJSON{Action: models.ModerationSuspend, EntityType: models.ReportEntityUser, EntityId: 1000}, ErrModerationNotFound},
+		{"not a user", ModerationActionRequestJSON{Action: models.ModerationSuspend, EntityType: models.ReportEntityPost, EntityId: bob}, ErrInvalidModeration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := moderation.TakeAction(admin, tt.request); err != tt.want {
+				t.Fatalf("TakeAction() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHiddenContent(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	moderation := newTestModerationService(s)
+	admin := s.newAdmin(t, "admin@example.com")
+	a := newPostAudience(t, s)
+
+	newPost(t, posts, a.anna, "kept", enums.Public)
+	hiddenPost := newPost(t, posts, a.anna, "hidden", enums.Public)
+	commentId := newComment(t, comments, a.bob, hiddenPost, 0, "rude")
+
+	hide := func(entityType string, entityId int64, action string) {
+		t.Helper()
+
+		_, err := moderation.TakeAction(admin, ModerationActionRequestJSON{Action: action, EntityType: entityType, EntityId: entityId})
+		if err != nil {
+			t.Fatalf("TakeAction() = %v", err)
+		}
+	}
+
+	commentContent := func() string {
+		t.Helper()
+
+		result, err := comments.GetPostComments(hiddenPost, 0, a.bob)
+		if err != nil || len(result) != 1 {
+			t.Fatalf("GetPostComments() = %v, %v, want the comment", result, err)
+		}
+
+		return result[0].Content
+	}
+
+	hide(models.ReportEntityComment, commentId, models.ModerationHide)
+
+	if got := commentContent(); got != "[hidden]" {
+		t.Fatalf("hidden comment content = %q, want %q", got, "[hidden]")
+	}
+
+	hide(models.ReportEntityPost, hiddenPost, models.ModerationHide)
+
+	for _, userId := range []int64{a.anna, a.bob, a.dave} {
+		if got := feedContents(t, posts, userId); got != "kept" {
+			t.Fatalf("feed of user %d = %q, want %q", userId, got, "kept")
+		}
+	}
+
+	hide(models.ReportEntityPost, hiddenPost, models.ModerationUnhide)
+	hide(models.ReportEntityComment, commentId, models.ModerationUnhide)
+
+	if got := feedContents(t, posts, a.bob); got != "hidden,kept" {
+		t.Fatalf("feed after unhide = %q, want %q", got, "hidden,kept")
+	}
+
+	if got := commentContent(); got != "rude" {
+		t.Fatalf("restored comment content = %q, want %q", got, "rude")
+	}
+
+	tests := []struct {
+		name    string
+		request ModerationActionRequestJSON
+		want    error
+	}{
+		{"user", ModerationActionRequestJSON{Action: models.ModerationHide, EntityType: models.ReportEntityUser, EntityId: a.bob}, ErrInvalidModeration},
+		{"missing post", ModerationActionRequestJSON{Action: models.ModerationHide, EntityType: models.ReportEntityPost, EntityId: 1000}, ErrModerationNotFound},
+		{"unknown action", ModerationActionRequestJSON{Action: "ban", EntityType: models.ReportEntityPost, EntityId: hiddenPost}, ErrInvalidModeration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := moderation.TakeAction(admin, tt.request); err != tt.want {
+				t.Fatalf("TakeAction() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateReport(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	moderation := newTestModerationService(s)
+	a := newPostAudience(t, s)
+
+	public := newPost(t, posts, a.anna, "public", enums.Public)
+	private := newPost(t, posts, a.anna, "private", enums.Private)
+
+	report, err := moderation.CreateReport(a.bob, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "spam", Details: " buy now "})
+	if err != nil {
+		t.Fatalf("CreateReport() = %v", err)
+	}
+
+	if report.ReportedUserId != a.anna || report.Snapshot != "public" || report.Details != "buy now" || report.Status != models.ReportStatusOpen {
+		t.Fatalf("CreateReport() = %+v, want an open report on the post of anna", report)
+	}
+
+	tests := []struct {
+		name    string
+		userId  int64
+		request ReportRequestJSON
+		want    error
+	}{
+		{"pending report", a.bob, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "other"}, ErrReportAlreadyPending},
+		{"invalid entity", a.bob, ReportRequestJSON{EntityType: "event", EntityId: public, Reason: "spam"}, ErrInvalidReportEntity},
+		{"invalid reason", a.bob, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "boring"}, ErrInvalidReportReason},
+		{"details too long", a.bob, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "spam", Details: strings.Repeat("a", maxReportText+1)}, ErrReportDetailsTooLong},
+		{"own post", a.anna, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "spam"}, ErrCannotReportOwn},
+		{"own profile", a.bob, ReportRequestJSON{EntityType: models.ReportEntityUser, EntityId: a.bob, Reason: "spam"}, ErrCannotReportOwn},
+		{"post not visible", a.dave, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: private, Reason: "spam"}, ErrReportTargetNotFound},
+		{"missing user", a.bob, ReportRequestJSON{EntityType: models.ReportEntityUser, EntityId: 1000, Reason: "spam"}, ErrReportTargetNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := moderation.CreateReport(tt.userId, tt.request); err != tt.want {
+				t.Fatalf("CreateReport() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err = moderation.CreateReport(a.carl, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: public, Reason: "spam"}); err != nil {
+		t.Fatalf("report of another user: CreateReport() = %v", err)
+	}
+}
+
+func TestReportTriageIsAudited(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	moderation := newTestModerationService(s)
+	admin := s.newAdmin(t, "admin@example.com")
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "spam", enums.Public)
+
+	spam, err := moderation.CreateReport(a.bob, ReportRequestJSON{EntityType: models.ReportEntityPost, EntityId: postId, Reason: "spam"})
+	if err != nil {
+		t.Fatalf("CreateReport() = %v", err)
+	}
+
+	other, err := moderation.CreateReport(a.carl, ReportRequestJSON{EntityType: models.ReportEntityUser, EntityId: a.anna, Reason: "other"})
+	if err != nil {
+		t.Fatalf("CreateReport() = %v", err)
+	}
+
+	reviewing, err := moderation.UpdateReportStatus(admin, spam.Id, ReportStatusJSON{Status: models.ReportStatusReviewing, Note: "looking"})
+	if err != nil || reviewing.Status != models.ReportStatusReviewing {
+		t.Fatalf("UpdateReportStatus() = %+v, %v, want a report under review", reviewing, err)
+	}
+
+	if _, err = moderation.TakeAction(admin, ModerationActionRequestJSON{Action: models.ModerationHide, EntityType: models.ReportEntityPost, EntityId: postId, ReportId: spam.Id}); err != nil {
+		t.Fatalf("TakeAction() = %v", err)
+	}
+
+	if _, err = moderation.UpdateReportStatus(admin, other.Id, ReportStatusJSON{Status: models.ReportStatusDismissed}); err != nil {
+		t.Fatalf("UpdateReportStatus() = %v", err)
+	}
+
+	if report, err := moderation.GetReport(spam.Id); err != nil || report.Status != models.ReportStatusResolved {
+		t.Fatalf("GetReport() = %+v, %v, want the report resolved by the action", report, err)
+	}
+
+	for status, want := range map[string]int{"": 0, models.ReportStatusResolved: 1, models.ReportStatusDismissed: 1} {
+		if reports, err := moderation.GetReports(status, 0); err != nil || len(reports) != want {
+			t.Fatalf("GetReports(%q) = %d reports, %v, want %d", status, len(reports), err, want)
+		}
+	}
+
+	if _, err = moderation.GetReports("closed", 0); err != ErrInvalidReportStatus {
+		t.Fatalf("GetReports() = %v, want %v", err, ErrInvalidReportStatus)
+	}
+
+	if _, err = moderation.UpdateReportStatus(admin, 1000, ReportStatusJSON{Status: models.ReportStatusDismissed}); err != ErrReportNotFound {
+		t.Fatalf("UpdateReportStatus() = %v, want %v", err, ErrReportNotFound)
+	}
+
+	trail, err := moderation.GetAuditTrail(0)
+	if err != nil {
+		t.Fatalf("GetAuditTrail() = %v", err)
+	}
+
+	actions := []string{}
+	for _, action := range trail {
+		if action.ModeratorId != admin {
+			t.Fatalf("action %+v is not by the moderator", action)
+		}
+		actions = append(actions, action.Action)
+	}
+
+	if got, want := strings.Join(actions, ","), "dismiss,hide,review"; got != want {
+		t.Fatalf("audit trail = %q, want %q", got, want)
+	}
+
+	if trail[1].ReportId != spam.Id || trail[2].Note != "looking" {
+		t.Fatalf("audit trail = %+v, want the report and note recorded", trail)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	s := newTestServices(t)
+	moderation := newTestModerationService(s)
+	admin := s.newAdmin(t, "admin@example.com")
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "Bob@Example.com")
+
+	role := func(userId int64) string {
+		t.Helper()
+
+		status, err := s.repos.UserRepo.GetAccountStatus(userId)
+		if err != nil {
+			t.Fatalf("GetAccountStatus() = %v", err)
+		}
+
+		return status.Role
+	}
+
+	if err := moderation.PromoteAdmins([]string{"bob@example.com", "nobody@example.com"}); err != nil {
+		t.Fatalf("PromoteAdmins() = %v", err)
+	}
+
+	if role(bob) != models.RoleAdmin || role(anna) != models.RoleUser {
+		t.Fatalf("PromoteAdmins() gave roles %s and %s, want only bob promoted", role(anna), role(bob))
+	}
+
+	if err := moderation.SetRole(admin, anna, models.RoleAdmin); err != nil || role(anna) != models.RoleAdmin {
+		t.Fatalf("SetRole() = %v with role %s, want admin", err, role(anna))
+	}
+
+	if err := moderation.SetRole(admin, anna, models.RoleUser); err != nil || role(anna) != models.RoleUser {
+		t.Fatalf("SetRole() = %v with role %s, want user", err, role(anna))
+	}
+
+	tests := []struct {
+		name   string
+		userId int64
+		role   string
+		want   error
+	}{
+		{"self", admin, models.RoleUser, ErrCannotModerateSelf},
+		{"invalid role", anna, "owner", ErrInvalidRole},
+		{"unknown user", 1000, models.RoleAdmin, ErrModerationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := moderation.SetRole(admin, tt.userId, tt.role); err != tt.want {
+				t.Fatalf("SetRole() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	trail, err := moderation.GetAuditTrail(0)
+	if err != nil || len(trail) != 2 || trail[0].Action != models.ModerationRevokeAdmin || trail[1].Action != models.ModerationGrantAdmin {
+		t.Fatalf("GetAuditTrail() = %+v, %v, want the grant and revoke", trail, err)
+	}
+}
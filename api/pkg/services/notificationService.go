@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -20,6 +22,11 @@ type INotificationService interface {
 	HandleEventInvite(notificationID int64, accepted bool) error
 	CreateGroupInvite(creatorId int64, groupId int64, membersToAdd []int64) ([]*models.NotificationJSON, error)
 	HandleGroupInvite(notificationID int64, accepted bool) error
+	CreatePostMentions(senderId int64, postId int64) ([]*models.NotificationJSON, error)
+	CreateCommentMentions(senderId int64, commentId int64) ([]*models.NotificationJSON, error)
+	CreateMessageMentions(message *models.Message) ([]*models.NotificationJSON, error)
+	CreateAccountLocked(userId int64) (*models.NotificationJSON, error)
+	DismissNotification(notificationID int64) error
 }
 
 type NotificationService struct {
@@ -31,6 +38,10 @@ type NotificationService struct {
 	GroupMemberRepo        models.IGroupMemberRepository
 	EventRepo              models.IEventRepository
 	EventAttendanceRepo    models.IEventAttendanceRepository
+	PostRepo               models.IPostRepository
+	CommentRepo            models.ICommentRepository
+	MentionRepo            models.IMentionRepository
+	BlockRepo              models.IBlockRepository
 }
 
 func InitNotificationService(
@@ -42,6 +53,10 @@ func InitNotificationService(
 	groupMemberRepo *models.GroupMemberRepository,
 	eventRepo *models.EventRepository,
 	eventAttendanceRepo *models.EventAttendanceRepository,
+	postRepo *models.PostRepository,
+	commentRepo *models.CommentRepository,
+	mentionRepo *models.MentionRepository,
+	blockRepo *models.BlockRepository,
 ) *NotificationService {
 	return &NotificationService{
 		Logger:                 logger,
@@ -52,9 +67,28 @@ func InitNotificationService(
 		GroupMemberRepo:        groupMemberRepo,
 		EventRepo:              eventRepo,
 		EventAttendanceRepo:    eventAttendanceRepo,
+		PostRepo:               postRepo,
+		CommentRepo:            commentRepo,
+		MentionRepo:            mentionRepo,
+		BlockRepo:              blockRepo,
 	}
 }
 
+var (
+	ErrFollowRequestExists   = errors.New("follow request already exists")
+	ErrFollowRequestHandled  = errors.New("follow request already handled")
+	ErrFollowRequestAccepted = errors.New("follow request already accepted")
+	ErrAlreadyGroupMember    = errors.New("already a member of this group")
+	ErrGroupRequestPending   = errors.New("already has a pending request for this group")
+	ErrGroupRequestHandled   = errors.New("group request already handled")
+	ErrGroupRequestAccepted  = errors.New("group request already accepted")
+	ErrNotGroupCreator       = errors.New("user is not creator of group")
+	ErrEventInviteHandled    = errors.New("event invite already handled")
+	ErrEventInviteProcessed  = errors.New("event invite already processed")
+	ErrGroupInviteHandled    = errors.New("group invite already handled")
+	ErrGroupInviteAccepted   = errors.New("group invite already accepted")
+)
+
 func (s *NotificationService) GetById(notificationId int64) (*models.Notification, error) {
 
 	notification, err := s.NotificationRepository.GetById(notificationId)
@@ -179,6 +213,27 @@ func (s *NotificationService) GetUserNotifications(userId int64) ([]*models.Noti
 			singleNotification.EventDate = event.EventTime
 		}
 
+		if notificationDetails.NotificationType == "mention" {
+			mention, err := s.MentionRepo.GetById(notificationDetails.EntityId)
+			if err == sql.ErrNoRows {
+				// the post or comment has been deleted since
+				continue
+			}
+			if err != nil {
+				s.Logger.Printf("Cannot get mention: %s", err)
+				return nil, err
+			}
+			singleNotification.PostId = mention.PostId
+			singleNotification.CommentId = mention.CommentId
+			singleNotification.MessageId = mention.MessageId
+			if mention.CommentId > 0 {
+				comment, err := s.CommentRepo.GetById(mention.CommentId)
+				if err == nil {
+					singleNotification.PostId = comment.PostId
+				}
+			}
+		}
+
 		NotificationJSON = append(NotificationJSON, singleNotification)
 	}
 
@@ -201,10 +256,20 @@ func (s *NotificationService) CreateFollowRequest(followerId int64, followingId
 		return -1, err
 	}
 
+	blocked, err := s.BlockRepo.IsBlockedEitherWay(followerId, followingId)
+	if err != nil {
+		s.Logger.Printf("Cannot check block: %s", err)
+		return -1, err
+	}
+
+	if blocked {
+		return -1, ErrUserBlocked
+	}
+
 	// check if follow request already exists
 	_, err = s.FollowerRepo.GetByFollowerAndFollowing(followerId, followingId)
 	if err == nil {
-		return -1, errors.New("follow request already exists")
+		return -1, ErrFollowRequestExists
 	}
 
 	accepted := sql.NullBool{Bool: false, Valid: false}
@@ -274,7 +339,7 @@ func (s *NotificationService) HandleFollowRequest(notificationId int64, accepted
 
 	// check if follow request already handled
 	if notification.Reaction.Valid {
-		return errors.New("follow request already handled")
+		return ErrFollowRequestHandled
 	}
 
 	// check if follow request exists
@@ -286,7 +351,7 @@ func (s *NotificationService) HandleFollowRequest(notificationId int64, accepted
 
 	// check if follow request is accepted
 	if follower.Accepted.Valid {
-		return errors.New("follow request already accepted")
+		return ErrFollowRequestAccepted
 	}
 
 	// update follow request
@@ -342,10 +407,10 @@ func (s *NotificationService) CreateGroupRequest(senderId int64, groupId int64)
 			return -1, errors.New("error in checking if user is already member of group")
 		} else if member.Accepted {
 			s.Logger.Printf("User %d is already a member of this group", senderId)
-			return -1, errors.New("already a member of this group")
+			return -1, ErrAlreadyGroupMember
 		} else if !member.Accepted {
 			s.Logger.Printf("User %d already has a pending request for this group", senderId)
-			return -1, errors.New("already has a pending request for this group")
+			return -1, ErrGroupRequestPending
 		}
 	}
 
@@ -401,7 +466,7 @@ func (s *NotificationService) HandleGroupRequest(creatorID int64, notificationID
 
 	// check if group request already handled
 	if notification.Reaction.Valid {
-		return errors.New("group request already handled")
+		return ErrGroupRequestHandled
 	}
 
 	notificationDetails, err := s.NotificationRepository.GetDetailsById(notification.NotificationDetailsId)
@@ -424,7 +489,7 @@ func (s *NotificationService) HandleGroupRequest(creatorID int64, notificationID
 
 	if groupMember.Accepted {
 		s.Logger.Printf("Group request already accepted: %d", notificationDetails.EntityId)
-		return errors.New("group request already accepted")
+		return ErrGroupRequestAccepted
 	}
 
 	// check if user is creator of group
@@ -435,7 +500,7 @@ func (s *NotificationService) HandleGroupRequest(creatorID int64, notificationID
 	}
 
 	if group.CreatorId != creatorID {
-		return errors.New("user is not creator of group")
+		return ErrNotGroupCreator
 	}
 
 	// update group request
@@ -481,7 +546,7 @@ func (s *NotificationService) HandleEventInvite(notificationID int64, accepted b
 
 	// check if event invite already handled
 	if notification.Reaction.Valid {
-		return errors.New("event invite already handled")
+		return ErrEventInviteHandled
 	}
 
 	notificationDetails, err := s.NotificationRepository.GetDetailsById(notification.NotificationDetailsId)
@@ -506,7 +571,7 @@ func (s *NotificationService) HandleEventInvite(notificationID int64, accepted b
 
 	for _, attendee := range attendees {
 		if attendee.UserId == notification.ReceiverId {
-			return errors.New("event invite already processed")
+			return ErrEventInviteProcessed
 		}
 	}
 
@@ -546,7 +611,19 @@ func (s *NotificationService) CreateGroupInvite(creatorId int64, groupId int64,
 	}
 
 	if group.CreatorId != creatorId {
-		return nil, errors.New("user is not creator of group")
+		return nil, ErrNotGroupCreator
+	}
+
+	blockedIds, err := s.BlockRepo.GetBlockedUserIds(creatorId)
+	if err != nil {
+		s.Logger.Printf("Cannot get blocked users: %s", err)
+		return nil, err
+	}
+
+	for _, memberToAdd := range membersToAdd {
+		if blockedIds[memberToAdd] {
+			return nil, ErrUserBlocked
+		}
 	}
 
 	// create notification
@@ -617,7 +694,7 @@ func (s *NotificationService) HandleGroupInvite(notificationID int64, accepted b
 
 	// check if group invite already handled
 	if notification.Reaction.Valid {
-		return errors.New("group invite already handled")
+		return ErrGroupInviteHandled
 	}
 
 	notificationDetails, err := s.NotificationRepository.GetDetailsById(notification.NotificationDetailsId)
@@ -634,7 +711,7 @@ func (s *NotificationService) HandleGroupInvite(notificationID int64, accepted b
 			return err
 		} else if groupMember.Accepted {
 			s.Logger.Printf("Group invite already accepted: %d", notificationDetails.EntityId)
-			return errors.New("group invite already accepted")
+			return ErrGroupInviteAccepted
 		}
 	}
 
@@ -669,3 +746,255 @@ func (s *NotificationService) HandleGroupInvite(notificationID int64, accepted b
 
 	return nil
 }
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_.\-]+)`)
+
+// ParseMentions returns the distinct nicknames mentioned as @nickname in content
+func ParseMentions(content string) []string {
+	seen := map[string]bool{}
+	nicknames := []string{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		// allow punctuation right after a mention
+		nickname := strings.TrimRight(match[1], ".-")
+
+		if nickname == "" || seen[nickname] {
+			continue
+		}
+
+		seen[nickname] = true
+		nicknames = append(nicknames, nickname)
+	}
+
+	return nicknames
+}
+
+// Notifies the users mentioned in a post who are allowed to see it
+func (s *NotificationService) CreatePostMentions(senderId int64, postId int64) ([]*models.NotificationJSON, error) {
+
+	post, err := s.PostRepo.GetById(postId)
+	if err != nil {
+		s.Logger.Printf("Cannot get post: %s", err)
+		return nil, err
+	}
+
+	canSee := func(userId int64) (bool, error) {
+		return s.PostRepo.IsVisibleToUser(post.Id, userId)
+	}
+
+	return s.createMentions(senderId, post.Content, &models.Mention{PostId: post.Id}, post.Id, post.GroupId, canSee)
+}
+
+// Notifies the users mentioned in a comment who are allowed to see its post
+func (s *NotificationService) CreateCommentMentions(senderId int64, commentId int64) ([]*models.NotificationJSON, error) {
+
+	comment, err := s.CommentRepo.GetById(commentId)
+	if err != nil {
+		s.Logger.Printf("Cannot get comment: %s", err)
+		return nil, err
+	}
+
+	post, err := s.PostRepo.GetById(comment.PostId)
+	if err != nil {
+		s.Logger.Printf("Cannot get post: %s", err)
+		return nil, err
+	}
+
+	canSee := func(userId int64) (bool, error) {
+		return s.PostRepo.IsVisibleToUser(post.Id, userId)
+	}
+
+	return s.createMentions(senderId, comment.Content, &models.Mention{CommentId: comment.Id}, post.Id, post.GroupId, canSee)
+}
+
+// Notifies the users mentioned in a chat message who take part in the chat
+func (s *NotificationService) CreateMessageMentions(message *models.Message) ([]*models.NotificationJSON, error) {
+
+	canSee := func(userId int64) (bool, error) {
+		if message.GroupId == 0 {
+			return userId == message.RecipientId, nil
+		}
+
+		member, err := s.GroupMemberRepo.GetMemberByGroupId(message.GroupId, userId)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return member.Accepted, nil
+	}
+
+	return s.createMentions(message.SenderId, message.Content, &models.Mention{MessageId: message.Id}, 0, message.GroupId, canSee)
+}
+
+func (s *NotificationService) createMentions(
+	senderId int64,
+	content string,
+	mention *models.Mention,
+	postId int64,
+	groupId int64,
+	canSee func(userId int64) (bool, error),
+) ([]*models.NotificationJSON, error) {
+
+	notificationsToBroadcast := []*models.NotificationJSON{}
+
+	nicknames := ParseMentions(content)
+	if len(nicknames) == 0 {
+		return notificationsToBroadcast, nil
+	}
+
+	userIds, err := s.UserRepo.GetIdsByNicknames(nicknames)
+	if err != nil {
+		s.Logger.Printf("Cannot resolve mentions: %s", err)
+		return nil, err
+	}
+
+	sender, err := s.UserRepo.GetById(senderId)
+	if err != nil {
+		s.Logger.Printf("Cannot get sender: %s", err)
+		return nil, err
+	}
+
+	if sender.Nickname == "" {
+		sender.Nickname = sender.FirstName + " " + sender.LastName
+	}
+
+	blockedIds, err := s.BlockRepo.GetBlockedUserIds(senderId)
+	if err != nil {
+		s.Logger.Printf("Cannot get blocked users: %s", err)
+		return nil, err
+	}
+
+	for _, nickname := range nicknames {
+		userId, ok := userIds[nickname]
+		if !ok || userId == senderId || blockedIds[userId] {
+			continue
+		}
+
+		visible, err := canSee(userId)
+		if err != nil {
+			s.Logger.Printf("Cannot check visibility for user %d: %s", userId, err)
+			return nil, err
+		}
+
+		if !visible {
+			s.Logger.Printf("User %d cannot see the mention, not notifying", userId)
+			continue
+		}
+
+		mention.UserId = userId
+		mention.SenderId = senderId
+
+		mentionId, err := s.MentionRepo.Insert(mention)
+		if err != nil {
+			s.Logger.Printf("Cannot insert mention: %s", err)
+			return nil, err
+		}
+
+		// already mentioned, e.g. when a post is edited
+		if mentionId == 0 {
+			continue
+		}
+
+		notificationDetails := &models.NotificationDetails{
+			SenderId:         senderId,
+			NotificationType: "mention",
+			EntityId:         mentionId,
+			CreatedAt:        time.Now(),
+		}
+
+		detailsId, err := s.NotificationRepository.InsertDetails(notificationDetails)
+		if err != nil {
+			s.Logger.Printf("Cannot insert notification details: %s", err)
+			return nil, err
+		}
+
+		notificationId, err := s.NotificationRepository.InsertNotification(&models.Notification{
+			ReceiverId:            userId,
+			NotificationDetailsId: detailsId,
+		})
+		if err != nil {
+			s.Logger.Printf("Cannot insert notification: %s", err)
+			return nil, err
+		}
+
+		notificationsToBroadcast = append(notificationsToBroadcast, &models.NotificationJSON{
+			ReceiverId:       userId,
+			NotificationType: notificationDetails.NotificationType,
+			NotificationId:   notificationId,
+			SenderId:         senderId,
+			SenderName:       sender.Nickname,
+			GroupId:          groupId,
+			PostId:           postId,
+			CommentId:        mention.CommentId,
+			MessageId:        mention.MessageId,
+		})
+	}
+
+	return notificationsToBroadcast, nil
+}
+
+// Notifies the user that their account was locked after too many failed logins
+func (s *NotificationService) CreateAccountLocked(userId int64) (*models.NotificationJSON, error) {
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return nil, err
+	}
+
+	if user.Nickname == "" {
+		user.Nickname = user.FirstName + " " + user.LastName
+	}
+
+	notificationDetails := &models.NotificationDetails{
+		SenderId:         userId,
+		NotificationType: "account_locked",
+		EntityId:         userId,
+		CreatedAt:        time.Now(),
+	}
+
+	detailsId, err := s.NotificationRepository.InsertDetails(notificationDetails)
+	if err != nil {
+		s.Logger.Printf("Cannot insert notification details: %s", err)
+		return nil, err
+	}
+
+	notificationId, err := s.NotificationRepository.InsertNotification(&models.Notification{
+		ReceiverId:            userId,
+		NotificationDetailsId: detailsId,
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot insert notification: %s", err)
+		return nil, err
+	}
+
+	return &models.NotificationJSON{
+		ReceiverId:       userId,
+		NotificationType: notificationDetails.NotificationType,
+		NotificationId:   notificationId,
+		SenderId:         userId,
+		SenderName:       user.Nickname,
+	}, nil
+}
+
+// Marks a notification that needs no answer, such as a mention, as handled so it is not listed again
+func (s *NotificationService) DismissNotification(notificationID int64) error {
+	notification, err := s.NotificationRepository.GetById(notificationID)
+	if err != nil {
+		s.Logger.Printf("Cannot get notification: %s", err)
+		return err
+	}
+
+	notification.SeenAt = time.Now()
+	notification.Reaction = sql.NullBool{Bool: true, Valid: true}
+
+	err = s.NotificationRepository.Update(notification)
+	if err != nil {
+		s.Logger.Printf("Cannot update notification: %s", err)
+		return err
+	}
+
+	return nil
+}
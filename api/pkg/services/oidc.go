@@ -0,0 +1,495 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCProviderConfig is an OpenID Connect provider users can log in with
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested besides openid
+	Scopes []string
+}
+
+const (
+	// oidcClockSkew is the difference between our clock and the provider's that is tolerated
+	oidcClockSkew = time.Minute
+	// oidcKeyRefetchInterval limits how often the keys are fetched again for an unknown key id
+	oidcKeyRefetchInterval = time.Minute
+	oidcMaxResponseSize    = 1 << 20
+)
+
+var errOIDCUnknownKey = errors.New("ID token is signed with an unknown key")
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims are the ID token claims used to find or create the account
+type oidcClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	AuthorizedBy  string       `json:"azp"`
+	Expiry        float64      `json:"exp"`
+	IssuedAt      float64      `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified oidcBool     `json:"email_verified"`
+	Name          string       `json:"name"`
+	GivenName     string       `json:"given_name"`
+	FamilyName    string       `json:"family_name"`
+	// AuthTime is when the user last logged in at the provider
+	AuthTime float64 `json:"auth_time"`
+}
+
+// oidcAudience is the aud claim, a single string or a list of them
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// oidcBool is a boolean claim, some providers send it as a string
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = oidcBool(v)
+	case string:
+		*b = oidcBool(v == "true")
+	}
+
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// oidcClient talks to one provider. The discovery document and the signing keys are
+// fetched on first use, the keys again when a token is signed with an unknown key.
+type oidcClient struct {
+	config      OIDCProviderConfig
+	redirectURL string
+	httpClient  *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func newOIDCClient(config OIDCProviderConfig, redirectURL string, httpClient *http.Client) *oidcClient {
+	return &oidcClient{
+		config:      config,
+		redirectURL: redirectURL,
+		httpClient:  httpClient,
+	}
+}
+
+// getJSON decodes the JSON document at endpoint into data
+func (c *oidcClient) getJSON(endpoint string, data interface{}) error {
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(data)
+}
+
+func (c *oidcClient) discover() (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	discovery := &oidcDiscovery{}
+
+	err := c.getJSON(strings.TrimRight(c.config.Issuer, "/")+"/.well-known/openid-configuration", discovery)
+	if err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+
+	if discovery.Issuer != c.config.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", discovery.Issuer, c.config.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery: endpoints are missing")
+	}
+
+	c.discovery = discovery
+
+	return discovery, nil
+}
+
+// authorizationURL is where the user is sent to log in at the provider
+func (c *oidcClient) authorizationURL(state string, nonce string, codeChallenge string, reauth bool) (string, error) {
+	discovery, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.config.ClientID)
+	query.Set("redirect_uri", c.redirectURL)
+	query.Set("scope", strings.Join(append([]string{"openid"}, c.config.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	// a login the provider remembers does not confirm anything
+	if reauth {
+		query.Set("prompt", "login")
+		query.Set("max_age", "0")
+	}
+	endpoint.RawQuery = query.Encode()
+
+	return endpoint.String(), nil
+}
+
+// exchange redeems the authorization code and returns the raw ID token
+func (c *oidcClient) exchange(code string, codeVerifier string) (string, error) {
+	discovery, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.redirectURL},
+		"client_id":     {c.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	token := struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token response %s: %s %s", resp.Status, token.Error, token.ErrorDescription)
+	}
+
+	if token.IDToken == "" {
+		return "", errors.New("token response has no ID token")
+	}
+
+	return token.IDToken, nil
+}
+
+// verifyIDToken checks the signature and the claims of an ID token issued for our client
+func (c *oidcClient) verifyIDToken(rawToken string, nonce string) (*oidcClaims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID token is not a signed JWT")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("ID token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("ID token signature: %w", err)
+	}
+
+	key, err := c.signingKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := &oidcClaims{}
+	if err = decodeJWTPart(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("ID token claims: %w", err)
+	}
+
+	now := time.Now()
+
+	switch {
+	case claims.Issuer != c.config.Issuer:
+		return nil, fmt.Errorf("ID token issuer %q does not match", claims.Issuer)
+	case !contains(claims.Audience, c.config.ClientID):
+		return nil, errors.New("ID token is not issued for this client")
+	case len(claims.Audience) > 1 && claims.AuthorizedBy != c.config.ClientID:
+		return nil, errors.New("ID token is authorized for another client")
+	case now.After(time.Unix(int64(claims.Expiry), 0).Add(oidcClockSkew)):
+		return nil, errors.New("ID token has expired")
+	case now.Add(oidcClockSkew).Before(time.Unix(int64(claims.IssuedAt), 0)):
+		return nil, errors.New("ID token is issued in the future")
+	case claims.Nonce != nonce:
+		return nil, errors.New("ID token nonce does not match")
+	case claims.Subject == "":
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return claims, nil
+}
+
+func decodeJWTPart(part string, data interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, data)
+}
+
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("ID token algorithm does not match its key")
+		}
+
+		if rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return errors.New("ID token signature is invalid")
+		}
+
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("ID token algorithm does not match its key")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.New("ID token signature is invalid")
+		}
+
+	default:
+		return fmt.Errorf("ID token algorithm %q is not supported", alg)
+	}
+
+	return nil
+}
+
+// signingKey returns the provider key with the id, a token without key id needs a provider with a single key
+func (c *oidcClient) signingKey(kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.lookupKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(c.keysFetchedAt) < oidcKeyRefetchInterval {
+		return nil, errOIDCUnknownKey
+	}
+
+	if err := c.fetchKeys(); err != nil {
+		return nil, err
+	}
+
+	key, ok = c.lookupKey(kid)
+	if !ok {
+		return nil, errOIDCUnknownKey
+	}
+
+	return key, nil
+}
+
+func (c *oidcClient) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// fetchKeys replaces the cached keys, the caller holds the lock
+func (c *oidcClient) fetchKeys() error {
+	if c.discovery == nil {
+		return errors.New("provider is not discovered yet")
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+
+	c.keysFetchedAt = time.Now()
+
+	if err := c.getJSON(c.discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("keys: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	c.keys = keys
+
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("curve %q is not supported", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("key is not on the curve")
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key type %q is not supported", jwk.Kty)
+}
+
+// pkceChallenge is the S256 code challenge of the verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
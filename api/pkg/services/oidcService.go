@@ -0,0 +1,478 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type IOIDCService interface {
+	GetProviders() []*OIDCProviderJSON
+	BeginAuthorization(provider string, linkUserId int64) (*OIDCAuthorization, error)
+	BeginReauthentication(provider string, userId int64) (*OIDCAuthorization, error)
+	CompleteAuthorization(provider string, state string, code string, r *http.Request) (*OIDCResult, error)
+	GetIdentities(userId int64) ([]*IdentityJSON, error)
+	UnlinkIdentity(userId int64, identityId int64) error
+}
+
+type OIDCService struct {
+	Logger      *log.Logger
+	UserRepo    models.IUserRepository
+	OIDCRepo    models.IOIDCRepository
+	UserService IUserService
+	// EmailVerification confirms the email of new users whose provider did not verify it
+	EmailVerification IEmailVerificationService
+	// providers keeps the configured order, clients are looked up by name
+	providers []OIDCProviderConfig
+	clients   map[string]*oidcClient
+}
+
+// InitOIDCService sets up a client for every provider, the providers send the user
+// back to callbackBaseURL/oidc/{name}/callback
+func InitOIDCService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	oidcRepo *models.OIDCRepository,
+	userService *UserService,
+	emailVerification *EmailVerificationService,
+	providers []OIDCProviderConfig,
+	callbackBaseURL string,
+) *OIDCService {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	clients := map[string]*oidcClient{}
+	for i, provider := range providers {
+		if provider.DisplayName == "" {
+			providers[i].DisplayName = provider.Name
+		}
+		if provider.Scopes == nil {
+			providers[i].Scopes = []string{"email", "profile"}
+		}
+
+		redirectURL := strings.TrimRight(callbackBaseURL, "/") + "/oidc/" + provider.Name + "/callback"
+		clients[provider.Name] = newOIDCClient(providers[i], redirectURL, httpClient)
+	}
+
+	return &OIDCService{
+		Logger:            logger,
+		UserRepo:          userRepo,
+		OIDCRepo:          oidcRepo,
+		UserService:       userService,
+		EmailVerification: emailVerification,
+		providers:         providers,
+		clients:           clients,
+	}
+}
+
+// oidcStateTTL is how long the user has to log in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCProviderUnknown = errors.New("unknown login provider")
+	ErrOIDCStateInvalid    = errors.New("login request is invalid or has expired")
+	ErrOIDCLoginFailed     = errors.New("login with the provider failed")
+	ErrOIDCEmailMissing    = errors.New("the provider did not share an email address")
+	ErrOIDCEmailTaken      = errors.New("an account with this email exists, log in with your password and link the provider")
+	ErrIdentityTaken       = errors.New("this login is already linked to another account")
+	ErrIdentityNotFound    = errors.New("linked login not found")
+	ErrLastLoginMethod     = errors.New("cannot unlink the only way to log in, set a password first")
+	ErrOIDCReauthFailed    = errors.New("log in again with a provider linked to your account")
+)
+
+type OIDCProviderJSON struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// OIDCAuthorization is the provider URL to send the user to, State has to be
+// bound to the browser so that the callback cannot be replayed in another one
+type OIDCAuthorization struct {
+	URL   string
+	State string
+}
+
+// OIDCResult is the login started at the provider, the user the provider was linked to
+// or the user who logged in there again to confirm a request
+type OIDCResult struct {
+	Login                 *LoginResult
+	LinkedUserId          int64
+	ReauthenticatedUserId int64
+}
+
+type IdentityJSON struct {
+	Id          int64      `json:"id"`
+	Provider    string     `json:"provider"`
+	DisplayName string     `json:"displayName"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+}
+
+func (s *OIDCService) GetProviders() []*OIDCProviderJSON {
+	providers := []*OIDCProviderJSON{}
+	for _, provider := range s.providers {
+		providers = append(providers, &OIDCProviderJSON{
+			Name:        provider.Name,
+			DisplayName: provider.DisplayName,
+		})
+	}
+
+	return providers
+}
+
+// randomURLToken returns n random bytes encoded for use in URLs
+func randomURLToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Starts a login, or linking the provider to the account when linkUserId is set
+func (s *OIDCService) BeginAuthorization(provider string, linkUserId int64) (*OIDCAuthorization, error) {
+	return s.beginAuthorization(provider, linkUserId, false)
+}
+
+// Starts a login at a provider linked to the account, it confirms the next request of a
+// user without a password
+func (s *OIDCService) BeginReauthentication(provider string, userId int64) (*OIDCAuthorization, error) {
+	return s.beginAuthorization(provider, userId, true)
+}
+
+func (s *OIDCService) beginAuthorization(provider string, linkUserId int64, reauth bool) (*OIDCAuthorization, error) {
+
+	client, ok := s.clients[provider]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	state, stateHash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomURLToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := client.authorizationURL(state, nonce, pkceChallenge(verifier), reauth)
+	if err != nil {
+		s.Logger.Printf("OIDC provider %s: %s", provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	now := time.Now()
+
+	_, err = s.OIDCRepo.InsertState(&models.OIDCState{
+		StateHash:    stateHash,
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserId:   linkUserId,
+		Reauth:       reauth,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(oidcStateTTL),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot store OIDC state: %s", err)
+		return nil, err
+	}
+
+	return &OIDCAuthorization{URL: authorizationURL, State: state}, nil
+}
+
+// Finishes the authorization the provider sent the user back from
+func (s *OIDCService) CompleteAuthorization(provider string, state string, code string, r *http.Request) (*OIDCResult, error) {
+
+	client, ok := s.clients[provider]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	pending, err := s.OIDCRepo.ConsumeState(hashSecretToken(state))
+	if err == sql.ErrNoRows || (err == nil && pending.Provider != provider) {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get OIDC state: %s", err)
+		return nil, err
+	}
+
+	if code == "" {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	rawToken, err := client.exchange(code, pending.CodeVerifier)
+	if err != nil {
+		s.Logger.Printf("OIDC provider %s: %s", provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	claims, err := client.verifyIDToken(rawToken, pending.Nonce)
+	if err != nil {
+		s.Logger.Printf("OIDC provider %s: %s", provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	if pending.Reauth {
+		err = s.reauthenticate(pending, provider, claims)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OIDCResult{ReauthenticatedUserId: pending.LinkUserId}, nil
+	}
+
+	if pending.LinkUserId != 0 {
+		err = s.linkIdentity(pending.LinkUserId, provider, claims)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OIDCResult{LinkedUserId: pending.LinkUserId}, nil
+	}
+
+	userId, err := s.findOrCreateUser(provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	login, err := s.UserService.CompleteExternalLogin(userId, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCResult{Login: login}, nil
+}
+
+// linkIdentity links the subject to the user, linking it again to the same user is not an error
+func (s *OIDCService) linkIdentity(userId int64, provider string, claims *oidcClaims) error {
+
+	id, err := s.OIDCRepo.InsertIdentity(&models.UserIdentity{
+		UserId:    userId,
+		Provider:  provider,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot link identity: %s", err)
+		return err
+	}
+
+	if id != 0 {
+		return nil
+	}
+
+	existing, err := s.OIDCRepo.GetIdentity(provider, claims.Subject)
+	if err != nil {
+		s.Logger.Printf("Cannot get identity: %s", err)
+		return err
+	}
+
+	if existing.UserId != userId {
+		return ErrIdentityTaken
+	}
+
+	return nil
+}
+
+// reauthenticate records that the user of the request logged in at the provider again. The
+// subject has to be linked to the user and the login has to be newer than the request.
+func (s *OIDCService) reauthenticate(pending *models.OIDCState, provider string, claims *oidcClaims) error {
+
+	identity, err := s.OIDCRepo.GetIdentity(provider, claims.Subject)
+	if err == sql.ErrNoRows || (err == nil && identity.UserId != pending.LinkUserId) {
+		return ErrOIDCReauthFailed
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get identity: %s", err)
+		return err
+	}
+
+	if time.Unix(int64(claims.AuthTime), 0).Add(oidcClockSkew).Before(pending.CreatedAt) {
+		s.Logger.Printf("OIDC provider %s did not log user %d in again", provider, pending.LinkUserId)
+		return ErrOIDCReauthFailed
+	}
+
+	err = s.OIDCRepo.SetIdentityReauthenticated(identity.Id, time.Now())
+	if err != nil {
+		s.Logger.Printf("Cannot update identity: %s", err)
+	}
+
+	return err
+}
+
+// findOrCreateUser returns the account linked to the subject. An unknown subject is
+// linked to the account with the same email if the provider verified it, otherwise a
+// new account is created.
+func (s *OIDCService) findOrCreateUser(provider string, claims *oidcClaims) (int64, error) {
+
+	identity, err := s.OIDCRepo.GetIdentity(provider, claims.Subject)
+	if err == nil {
+		if err = s.OIDCRepo.UpdateIdentityLogin(identity.Id, time.Now()); err != nil {
+			s.Logger.Printf("Cannot update identity: %s", err)
+		}
+		return identity.UserId, nil
+	}
+
+	if err != sql.ErrNoRows {
+		s.Logger.Printf("Cannot get identity: %s", err)
+		return 0, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" {
+		return 0, ErrOIDCEmailMissing
+	}
+
+	user, err := s.UserRepo.GetByEmail(email)
+
+	switch {
+	case err == nil && !bool(claims.EmailVerified):
+		return 0, ErrOIDCEmailTaken
+	case err == nil:
+		s.Logger.Printf("Linking %s login to user %d by verified email", provider, user.Id)
+	case err == sql.ErrNoRows:
+		user, err = s.createUser(email, claims)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		s.Logger.Printf("Cannot get user: %s", err)
+		return 0, err
+	}
+
+	if err = s.linkIdentity(user.Id, provider, claims); err != nil {
+		return 0, err
+	}
+
+	return user.Id, nil
+}
+
+// createUser registers an account without a password, the user can set one with a password reset
+func (s *OIDCService) createUser(email string, claims *oidcClaims) (*models.User, error) {
+
+	user := &models.User{
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		Email:     email,
+	}
+
+	if user.FirstName == "" {
+		user.FirstName = claims.Name
+	}
+
+	if user.FirstName == "" {
+		user.FirstName = strings.Split(email, "@")[0]
+	}
+
+	id, err := s.UserRepo.Insert(user)
+	if err != nil {
+		s.Logger.Printf("Cannot create user: %s", err)
+		return nil, err
+	}
+
+	user.Id = id
+
+	if bool(claims.EmailVerified) {
+		err = s.UserRepo.SetEmailVerified(id)
+	} else {
+		err = s.EmailVerification.SendVerification(id)
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot verify email of new user: %s", err)
+	}
+
+	return user, nil
+}
+
+func (s *OIDCService) GetIdentities(userId int64) ([]*IdentityJSON, error) {
+
+	identities, err := s.OIDCRepo.GetIdentitiesByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get identities: %s", err)
+		return nil, err
+	}
+
+	identitiesJSON := []*IdentityJSON{}
+
+	for _, identity := range identities {
+		identityJSON := &IdentityJSON{
+			Id:          identity.Id,
+			Provider:    identity.Provider,
+			DisplayName: identity.Provider,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+		}
+
+		for _, provider := range s.providers {
+			if provider.Name == identity.Provider {
+				identityJSON.DisplayName = provider.DisplayName
+			}
+		}
+
+		if identity.LastLoginAt.Valid {
+			identityJSON.LastLoginAt = &identity.LastLoginAt.Time
+		}
+
+		identitiesJSON = append(identitiesJSON, identityJSON)
+	}
+
+	return identitiesJSON, nil
+}
+
+// Unlinks a provider from the account, a user without a password keeps at least one
+func (s *OIDCService) UnlinkIdentity(userId int64, identityId int64) error {
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return err
+	}
+
+	identities, err := s.OIDCRepo.GetIdentitiesByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get identities: %s", err)
+		return err
+	}
+
+	if user.Password == "" && len(identities) <= 1 {
+		for _, identity := range identities {
+			if identity.Id == identityId {
+				return ErrLastLoginMethod
+			}
+		}
+	}
+
+	unlinked, err := s.OIDCRepo.DeleteIdentity(identityId, userId)
+	if err != nil {
+		s.Logger.Printf("Cannot unlink identity: %s", err)
+		return err
+	}
+
+	if !unlinked {
+		return ErrIdentityNotFound
+	}
+
+	s.Logger.Printf("User %d unlinked identity %d", userId, identityId)
+
+	return nil
+}
@@ -0,0 +1,591 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testClientID = "social-network"
+
+// mockIssuer is an OpenID Connect provider serving discovery, its keys and a token
+// endpoint. authorize stands in for the user logging in at the provider.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*mockAuthorization
+}
+
+// mockAuthorization is an issued authorization code and the ID token it is redeemed for
+type mockAuthorization struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Cannot generate key: %v", err)
+	}
+
+	m := &mockIssuer{key: key, codes: make(map[string]*mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 m.server.URL,
+		"authorization_endpoint": m.server.URL + "/authorize",
+		"token_endpoint":         m.server.URL + "/token",
+		"jwks_uri":               m.server.URL + "/jwks",
+	})
+}
+
+func (m *mockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	authorization, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	if !ok || pkceChallenge(r.PostFormValue("code_verifier")) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(authorization.claims)})
+}
+
+// sign returns the claims as an RS256 JWT
+func (m *mockIssuer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize logs the subject in at the provider and returns the code the user is sent
+// back with. The claims replace the ones of a valid ID token, a nil value removes one.
+func (m *mockIssuer) authorize(t *testing.T, authorizationURL string, subject string, claims map[string]interface{}) string {
+	t.Helper()
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("Invalid authorization URL: %v", err)
+	}
+
+	query := parsed.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %s", parsed.RawQuery)
+	}
+
+	now := time.Now()
+	idClaims := map[string]interface{}{
+		"iss":   m.server.URL,
+		"sub":   subject,
+		"aud":   testClientID,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": query.Get("nonce"),
+	}
+
+	for name, value := range claims {
+		if value == nil {
+			delete(idClaims, name)
+		} else {
+			idClaims[name] = value
+		}
+	}
+
+	code, err := randomURLToken(16)
+	if err != nil {
+		t.Fatalf("Cannot create code: %v", err)
+	}
+
+	m.mu.Lock()
+	m.codes[code] = &mockAuthorization{challenge: query.Get("code_challenge"), claims: idClaims}
+	m.mu.Unlock()
+
+	return code
+}
+
+func newTestOIDCService(t *testing.T) (*testServices, *OIDCService, *mockIssuer) {
+	t.Helper()
+
+	s := newTestServices(t)
+	issuer := newMockIssuer(t)
+
+	providers := []OIDCProviderConfig{{Name: "mock", Issuer: issuer.server.URL, ClientID: testClientID, ClientSecret: "secret"}}
+	oidc := InitOIDCService(s.logger, s.repos.UserRepo, s.repos.OIDCRepo, s.users, s.emailVerification, providers, "http://api.test")
+
+	return s, oidc, issuer
+}
+
+// loginWithProvider runs a whole authorization, linkUserId is 0 for a login
+func loginWithProvider(t *testing.T, oidc *OIDCService, issuer *mockIssuer, linkUserId int64, subject string, claims map[string]interface{}) (*OIDCResult, error) {
+	t.Helper()
+
+	authorization, err := oidc.BeginAuthorization("mock", linkUserId)
+	if err != nil {
+		t.Fatalf("BeginAuthorization() = %v", err)
+	}
+
+	code := issuer.authorize(t, authorization.URL, subject, claims)
+
+	return oidc.CompleteAuthorization("mock", authorization.State, code, newRequest("192.0.2.1"))
+}
+
+// sessionUser returns the user the session token of the login belongs to
+func sessionUser(t *testing.T, s *testServices, result *OIDCResult) int64 {
+	t.Helper()
+
+	if result == nil || result.Login == nil || result.Login.SessionToken == "" {
+		t.Fatalf("got %+v, want a session", result)
+	}
+
+	session, err := s.repos.SessionRepo.GetByToken(result.Login.SessionToken)
+	if err != nil {
+		t.Fatalf("Cannot get session: %v", err)
+	}
+
+	return session.UserId
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+
+	claims := map[string]interface{}{"email": "new@example.com", "email_verified": true, "given_name": "Mari", "family_name": "Maasikas"}
+
+	result, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", claims)
+	if err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	userId := sessionUser(t, s, result)
+
+	user, err := s.repos.UserRepo.GetByEmail("new@example.com")
+	if err != nil || user.Id != userId {
+		t.Fatalf("logged in as %d, created user %+v (%v)", userId, user, err)
+	}
+
+	if user.FirstName != "Mari" || user.LastName != "Maasikas" || user.Password != "" {
+		t.Fatalf("created %+v, want Mari Maasikas without a password", user)
+	}
+
+	if verified, _ := s.repos.UserRepo.IsEmailVerified(userId); !verified {
+		t.Fatalf("email verified by the provider is not verified")
+	}
+
+	// the next login finds the account by the subject, even with another email
+	claims["email"] = "changed@example.com"
+
+	result, err = loginWithProvider(t, oidc, issuer, 0, "subject-1", claims)
+	if err != nil {
+		t.Fatalf("second CompleteAuthorization() = %v", err)
+	}
+
+	if again := sessionUser(t, s, result); again != userId {
+		t.Fatalf("second login as user %d, want %d", again, userId)
+	}
+
+	identities, err := oidc.GetIdentities(userId)
+	if err != nil || len(identities) != 1 || identities[0].LastLoginAt == nil {
+		t.Fatalf("GetIdentities() = %+v, %v, want one identity with a last login", identities, err)
+	}
+}
+
+func TestOIDCLoginSendsVerificationForUnverifiedEmail(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+
+	result, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", map[string]interface{}{"email": "new@example.com", "email_verified": "false"})
+	if err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	userId := sessionUser(t, s, result)
+
+	if verified, _ := s.repos.UserRepo.IsEmailVerified(userId); verified {
+		t.Fatalf("email not verified by the provider is verified")
+	}
+
+	if mails := s.mails.sent(); len(mails) != 1 || mails[0].To != "new@example.com" {
+		t.Fatalf("sent %+v, want a verification email", mails)
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	_, oidc, issuer := newTestOIDCService(t)
+
+	authorization, err := oidc.BeginAuthorization("mock", 0)
+	if err != nil {
+		t.Fatalf("BeginAuthorization() = %v", err)
+	}
+
+	claims := map[string]interface{}{"email": "new@example.com", "email_verified": true}
+
+	if _, err = oidc.CompleteAuthorization("mock", "unknown-state", issuer.authorize(t, authorization.URL, "subject-1", claims), newRequest("192.0.2.1")); err != ErrOIDCStateInvalid {
+		t.Fatalf("unknown state: got %v, want %v", err, ErrOIDCStateInvalid)
+	}
+
+	if _, err = oidc.CompleteAuthorization("mock", authorization.State, issuer.authorize(t, authorization.URL, "subject-1", claims), newRequest("192.0.2.1")); err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	if _, err = oidc.CompleteAuthorization("mock", authorization.State, issuer.authorize(t, authorization.URL, "subject-1", claims), newRequest("192.0.2.1")); err != ErrOIDCStateInvalid {
+		t.Fatalf("reused state: got %v, want %v", err, ErrOIDCStateInvalid)
+	}
+
+	if _, err = oidc.CompleteAuthorization("other", authorization.State, "code", newRequest("192.0.2.1")); err != ErrOIDCProviderUnknown {
+		t.Fatalf("unknown provider: got %v, want %v", err, ErrOIDCProviderUnknown)
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"nonce mismatch", map[string]interface{}{"nonce": "another-nonce"}},
+		{"nonce missing", map[string]interface{}{"nonce": nil}},
+		{"audience mismatch", map[string]interface{}{"aud": "another-client"}},
+		{"audience without authorized party", map[string]interface{}{"aud": []string{testClientID, "another-client"}}},
+		{"authorized for another client", map[string]interface{}{"aud": []string{testClientID, "another-client"}, "azp": "another-client"}},
+		{"expired", map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}},
+		{"issued in the future", map[string]interface{}{"iat": now.Add(2 * time.Minute).Unix()}},
+		{"issuer mismatch", map[string]interface{}{"iss": "https://issuer.example.com"}},
+		{"subject missing", map[string]interface{}{"sub": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, oidc, issuer := newTestOIDCService(t)
+
+			tt.claims["email"] = "new@example.com"
+			tt.claims["email_verified"] = true
+
+			if _, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", tt.claims); err != ErrOIDCLoginFailed {
+				t.Fatalf("got %v, want %v", err, ErrOIDCLoginFailed)
+			}
+
+			if _, err := s.repos.UserRepo.GetByEmail("new@example.com"); err == nil {
+				t.Fatalf("user was created from an invalid ID token")
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsWrongCodeVerifier(t *testing.T) {
+	_, oidc, issuer := newTestOIDCService(t)
+
+	authorization, err := oidc.BeginAuthorization("mock", 0)
+	if err != nil {
+		t.Fatalf("BeginAuthorization() = %v", err)
+	}
+
+	code := issuer.authorize(t, authorization.URL, "subject-1", map[string]interface{}{"email": "new@example.com"})
+
+	// a code issued for another authorization request
+	issuer.mu.Lock()
+	issuer.codes[code].challenge = pkceChallenge("another-verifier")
+	issuer.mu.Unlock()
+
+	if _, err = oidc.CompleteAuthorization("mock", authorization.State, code, newRequest("192.0.2.1")); err != ErrOIDCLoginFailed {
+		t.Fatalf("got %v, want %v", err, ErrOIDCLoginFailed)
+	}
+}
+
+func TestOIDCLinksAccountByVerifiedEmail(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+
+	anna := s.newUser(t, "anna@example.com")
+
+	_, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", map[string]interface{}{"email": "anna@example.com", "email_verified": false})
+	if err != ErrOIDCEmailTaken {
+		t.Fatalf("unverified email: got %v, want %v", err, ErrOIDCEmailTaken)
+	}
+
+	if identities, _ := oidc.GetIdentities(anna); len(identities) != 0 {
+		t.Fatalf("unverified email linked %+v", identities)
+	}
+
+	result, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", map[string]interface{}{"email": "anna@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("verified email: CompleteAuthorization() = %v", err)
+	}
+
+	if userId := sessionUser(t, s, result); userId != anna {
+		t.Fatalf("logged in as user %d, want %d", userId, anna)
+	}
+
+	if identities, _ := oidc.GetIdentities(anna); len(identities) != 1 || identities[0].Provider != "mock" {
+		t.Fatalf("GetIdentities() = %+v, want the mock provider", identities)
+	}
+}
+
+func TestOIDCLinkToAccount(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	claims := map[string]interface{}{"email": "someone@example.com"}
+
+	result, err := loginWithProvider(t, oidc, issuer, anna, "subject-1", claims)
+	if err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	if result.LinkedUserId != anna || result.Login != nil {
+		t.Fatalf("got %+v, want the provider linked to user %d without a login", result, anna)
+	}
+
+	// linking the same login again is not an error
+	if _, err = loginWithProvider(t, oidc, issuer, anna, "subject-1", claims); err != nil {
+		t.Fatalf("linking again: got %v", err)
+	}
+
+	if _, err = loginWithProvider(t, oidc, issuer, bob, "subject-1", claims); err != ErrIdentityTaken {
+		t.Fatalf("linking to another user: got %v, want %v", err, ErrIdentityTaken)
+	}
+
+	result, err = loginWithProvider(t, oidc, issuer, 0, "subject-1", claims)
+	if err != nil {
+		t.Fatalf("login with the linked provider: %v", err)
+	}
+
+	if userId := sessionUser(t, s, result); userId != anna {
+		t.Fatalf("logged in as user %d, want %d", userId, anna)
+	}
+}
+
+func TestOIDCUnlinkKeepsALoginMethod(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+
+	result, err := loginWithProvider(t, oidc, issuer, 0, "subject-1", map[string]interface{}{"email": "new@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	withoutPassword := sessionUser(t, s, result)
+
+	identities, _ := oidc.GetIdentities(withoutPassword)
+	if len(identities) != 1 {
+		t.Fatalf("GetIdentities() = %+v, want one identity", identities)
+	}
+
+	if err = oidc.UnlinkIdentity(withoutPassword, identities[0].Id); err != ErrLastLoginMethod {
+		t.Fatalf("unlinking the only login: got %v, want %v", err, ErrLastLoginMethod)
+	}
+
+	anna := s.newUser(t, "anna@example.com")
+
+	if _, err = loginWithProvider(t, oidc, issuer, anna, "subject-2", nil); err != nil {
+		t.Fatalf("linking: %v", err)
+	}
+
+	identities, _ = oidc.GetIdentities(anna)
+	if len(identities) != 1 {
+		t.Fatalf("GetIdentities() = %+v, want one identity", identities)
+	}
+
+	if err = oidc.UnlinkIdentity(withoutPassword, identities[0].Id); err != ErrIdentityNotFound {
+		t.Fatalf("unlinking another user's login: got %v, want %v", err, ErrIdentityNotFound)
+	}
+
+	if err = oidc.UnlinkIdentity(anna, identities[0].Id); err != nil {
+		t.Fatalf("unlinking with a password set: got %v", err)
+	}
+
+	if identities, _ = oidc.GetIdentities(anna); len(identities) != 0 {
+		t.Fatalf("GetIdentities() = %+v after unlinking", identities)
+	}
+}
+
+// newOIDCUser logs in with the provider for the first time and returns the new user without a password
+func newOIDCUser(t *testing.T, s *testServices, oidc *OIDCService, issuer *mockIssuer, subject string) int64 {
+	t.Helper()
+
+	result, err := loginWithProvider(t, oidc, issuer, 0, subject, map[string]interface{}{"email": subject + "@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	return sessionUser(t, s, result)
+}
+
+// reauthenticate logs the user in at the provider again as the subject, authenticated at authTime
+func reauthenticate(t *testing.T, oidc *OIDCService, issuer *mockIssuer, userId int64, subject string, authTime time.Time) (*OIDCResult, error) {
+	t.Helper()
+
+	authorization, err := oidc.BeginReauthentication("mock", userId)
+	if err != nil {
+		t.Fatalf("BeginReauthentication() = %v", err)
+	}
+
+	parsed, _ := url.Parse(authorization.URL)
+	if parsed.Query().Get("prompt") != "login" || parsed.Query().Get("max_age") != "0" {
+		t.Fatalf("authorization URL %s does not ask the provider for a new login", authorization.URL)
+	}
+
+	code := issuer.authorize(t, authorization.URL, subject, map[string]interface{}{"auth_time": authTime.Unix()})
+
+	return oidc.CompleteAuthorization("mock", authorization.State, code, newRequest("192.0.2.1"))
+}
+
+func TestPasswordlessUserConfirmsWithProvider(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+	accounts := newTestAccountService(s)
+
+	userId := newOIDCUser(t, s, oidc, issuer, "subject-1")
+	newOIDCUser(t, s, oidc, issuer, "subject-2")
+
+	if _, err := accounts.ScheduleDeletion(userId, "", "", "192.0.2.1"); err != ErrConfirmationRequired {
+		t.Fatalf("ScheduleDeletion() without a login = %v, want %v", err, ErrConfirmationRequired)
+	}
+
+	tests := []struct {
+		name     string
+		subject  string
+		authTime time.Time
+	}{
+		{"remembered login", "subject-1", time.Now().Add(-time.Hour)},
+		{"subject of another user", "subject-2", time.Now()},
+		{"unknown subject", "subject-3", time.Now()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := reauthenticate(t, oidc, issuer, userId, tt.subject, tt.authTime); err != ErrOIDCReauthFailed {
+				t.Fatalf("CompleteAuthorization() = %v, want %v", err, ErrOIDCReauthFailed)
+			}
+
+			if _, err := accounts.ScheduleDeletion(userId, "", "", "192.0.2.1"); err != ErrConfirmationRequired {
+				t.Fatalf("ScheduleDeletion() = %v, want %v", err, ErrConfirmationRequired)
+			}
+		})
+	}
+
+	result, err := reauthenticate(t, oidc, issuer, userId, "subject-1", time.Now())
+	if err != nil || result.ReauthenticatedUserId != userId || result.Login != nil {
+		t.Fatalf("CompleteAuthorization() = %+v, %v, want user %d reauthenticated without a login", result, err, userId)
+	}
+
+	if _, err = accounts.ScheduleDeletion(userId, "", "", "192.0.2.1"); err != nil {
+		t.Fatalf("ScheduleDeletion() = %v", err)
+	}
+
+	if err = accounts.CancelDeletion(userId); err != nil {
+		t.Fatalf("CancelDeletion() = %v", err)
+	}
+
+	// the login confirms one request
+	if _, err = accounts.ScheduleDeletion(userId, "", "", "192.0.2.1"); err != ErrConfirmationRequired {
+		t.Fatalf("second ScheduleDeletion() = %v, want %v", err, ErrConfirmationRequired)
+	}
+
+	if _, err = reauthenticate(t, oidc, issuer, userId, "subject-1", time.Now()); err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	if _, err = s.db.Exec(`UPDATE user_identities SET reauthenticated_at = ? WHERE user_id = ?`, time.Now().Add(-reauthWindow-time.Minute), userId); err != nil {
+		t.Fatalf("Cannot age reauthentication: %v", err)
+	}
+
+	if _, err = accounts.ScheduleDeletion(userId, "", "", "192.0.2.1"); err != ErrConfirmationRequired {
+		t.Fatalf("ScheduleDeletion() after the window = %v, want %v", err, ErrConfirmationRequired)
+	}
+
+	// disabling two-factor authentication is confirmed the same way
+	secret, _ := s.enableTwoFactor(t, userId)
+	s.currentTOTP(t, userId, secret)
+
+	if err = s.twoFactor.Disable(userId, "", "", "192.0.2.1"); err != ErrConfirmationRequired {
+		t.Fatalf("Disable() without a login = %v, want %v", err, ErrConfirmationRequired)
+	}
+
+	if _, err = reauthenticate(t, oidc, issuer, userId, "subject-1", time.Now()); err != nil {
+		t.Fatalf("CompleteAuthorization() = %v", err)
+	}
+
+	if err = s.twoFactor.Disable(userId, "", "", "192.0.2.1"); err != nil {
+		t.Fatalf("Disable() = %v", err)
+	}
+
+	if enabled, _ := s.twoFactor.IsEnabled(userId); enabled {
+		t.Fatalf("two-factor authentication is still enabled")
+	}
+}
+
+func TestPasswordlessUserConfirmsWithTwoFactorCode(t *testing.T) {
+	s, oidc, issuer := newTestOIDCService(t)
+	accounts := newTestAccountService(s)
+
+	userId := newOIDCUser(t, s, oidc, issuer, "subject-1")
+
+	if _, err := accounts.ScheduleDeletion(userId, "", "123456", "192.0.2.1"); err != ErrTwoFactorNotEnabled {
+		t.Fatalf("ScheduleDeletion() with a code before setup = %v, want %v", err, ErrTwoFactorNotEnabled)
+	}
+
+	secret, recoveryCodes := s.enableTwoFactor(t, userId)
+
+	if _, err := accounts.ScheduleDeletion(userId, "", "000000", "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("ScheduleDeletion() with a wrong code = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if _, err := accounts.ScheduleDeletion(userId, "", s.currentTOTP(t, userId, secret), "192.0.2.1"); err != nil {
+		t.Fatalf("ScheduleDeletion() = %v", err)
+	}
+
+	if err := s.twoFactor.Disable(userId, "", "000000", "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("Disable() with a wrong code = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if err := s.twoFactor.Disable(userId, "", recoveryCodes[0], "192.0.2.1"); err != nil {
+		t.Fatalf("Disable() with a recovery code = %v", err)
+	}
+
+	if err := s.twoFactor.Disable(userId, "", s.currentTOTP(t, userId, secret), "192.0.2.1"); err != ErrTwoFactorNotEnabled {
+		t.Fatalf("second Disable() = %v, want %v", err, ErrTwoFactorNotEnabled)
+	}
+}
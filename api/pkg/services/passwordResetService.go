@@ -0,0 +1,288 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/internal/mailer"
+	"SocialNetworkRestApi/api/pkg/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type IPasswordResetService interface {
+	RequestPasswordReset(email string, ip string) (time.Duration, error)
+	RunMailer(done <-chan struct{})
+	ResetPassword(token string, password string) ([]int64, error)
+}
+
+type PasswordResetService struct {
+	Logger            *log.Logger
+	UserRepo          models.IUserRepository
+	SessionRepo       models.ISessionRepository
+	PasswordResetRepo models.IPasswordResetRepository
+	Mailer            mailer.Mailer
+	// TokenTTL is how long a reset link stays valid
+	TokenTTL time.Duration
+	// FrontendURL is the base of the reset link sent to the user
+	FrontendURL string
+	// MaxRequests and MaxIPRequests limit the requests per email and per IP,
+	// counters are forgotten Window after the last request
+	MaxRequests   int
+	MaxIPRequests int
+	Window        time.Duration
+	// requests holds the emails whose reset is sent by RunMailer
+	requests chan string
+}
+
+func InitPasswordResetService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	sessionRepo *models.SessionRepository,
+	passwordResetRepo *models.PasswordResetRepository,
+	mailSender mailer.Mailer,
+	tokenTTL time.Duration,
+	frontendURL string,
+	maxRequests int,
+	maxIPRequests int,
+	window time.Duration,
+) *PasswordResetService {
+	return &PasswordResetService{
+		Logger:            logger,
+		UserRepo:          userRepo,
+		SessionRepo:       sessionRepo,
+		PasswordResetRepo: passwordResetRepo,
+		Mailer:            mailSender,
+		TokenTTL:          tokenTTL,
+		FrontendURL:       frontendURL,
+		MaxRequests:       maxRequests,
+		MaxIPRequests:     maxIPRequests,
+		Window:            window,
+		requests:          make(chan string, resetQueueSize),
+	}
+}
+
+// resetQueueSize is the number of reset emails that can wait for RunMailer
+const resetQueueSize = 256
+
+var (
+	ErrInvalidResetToken    = errors.New("invalid or expired reset token")
+	ErrTooManyResetRequests = errors.New("too many password reset requests, try again later")
+	ErrWeakPassword         = errors.New("password")
+)
+
+// newSecretToken returns a random url safe token and the hash that is stored instead of it
+func newSecretToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Queues a reset link for the user with the address, RunMailer sends it. The email is looked up
+// in the background and the requests are counted for every address, so that neither the result
+// nor the response time tells callers which emails are registered. Returns ErrTooManyResetRequests
+// and the time to wait once the email or the IP has used up its requests.
+func (s *PasswordResetService) RequestPasswordReset(email string, ip string) (time.Duration, error) {
+
+	wait, err := s.countRequest(email, ip)
+	if err != nil {
+		return wait, err
+	}
+
+	select {
+	case s.requests <- strings.TrimSpace(email):
+	default:
+		s.Logger.Printf("Password reset queue is full, request dropped")
+	}
+
+	return 0, nil
+}
+
+// Sends the queued reset emails until done is closed, the emails still queued then are sent before it returns
+func (s *PasswordResetService) RunMailer(done <-chan struct{}) {
+	for {
+		select {
+		case email := <-s.requests:
+			s.sendReset(email)
+		case <-done:
+			for {
+				select {
+				case email := <-s.requests:
+					s.sendReset(email)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// countRequest refuses the request if the email or the IP has no requests left, otherwise counts it
+func (s *PasswordResetService) countRequest(email string, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	counters := []struct {
+		scope string
+		key   string
+		max   int
+	}{
+		{models.ResetScopeEmail, strings.ToLower(strings.TrimSpace(email)), s.MaxRequests},
+		{models.ResetScopeIP, ip, s.MaxIPRequests},
+	}
+
+	requests := make([]*models.ResetRequests, 0, len(counters))
+	wait := time.Duration(0)
+
+	for _, counter := range counters {
+		request, err := s.PasswordResetRepo.GetRequests(counter.scope, counter.key)
+		if err != nil && err != sql.ErrNoRows {
+			s.Logger.Printf("Cannot get reset requests: %s", err)
+			return 0, err
+		}
+
+		if err == sql.ErrNoRows || now.Sub(request.LastRequestAt) > s.Window {
+			request = &models.ResetRequests{Scope: counter.scope, Key: counter.key}
+		}
+
+		if blocked := request.BlockedUntil.Sub(now); blocked > wait {
+			wait = blocked
+		}
+
+		request.Requests++
+		request.LastRequestAt = now
+
+		if request.Requests >= counter.max {
+			request.BlockedUntil = now.Add(s.Window)
+		}
+
+		requests = append(requests, request)
+	}
+
+	if wait > 0 {
+		return wait, ErrTooManyResetRequests
+	}
+
+	for _, request := range requests {
+		if err := s.PasswordResetRepo.SaveRequests(request); err != nil {
+			s.Logger.Printf("Cannot save reset requests: %s", err)
+			return 0, err
+		}
+	}
+
+	return 0, nil
+}
+
+// sendReset emails a reset link if the address belongs to a user, earlier links of the user stop working
+func (s *PasswordResetService) sendReset(email string) {
+
+	user, err := s.UserRepo.GetByEmail(email)
+	if err == sql.ErrNoRows {
+		s.Logger.Printf("Password reset requested for unknown email")
+		return
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot send reset email: %s", err)
+		return
+	}
+
+	token, tokenHash, err := newSecretToken()
+	if err != nil {
+		s.Logger.Printf("Cannot create reset token: %s", err)
+		return
+	}
+
+	err = s.PasswordResetRepo.DeleteByUserId(user.Id)
+	if err != nil {
+		s.Logger.Printf("Cannot send reset email: %s", err)
+		return
+	}
+
+	_, err = s.PasswordResetRepo.Insert(&models.PasswordReset{
+		UserId:    user.Id,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.TokenTTL),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot send reset email: %s", err)
+		return
+	}
+
+	link := strings.TrimRight(s.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+
+	err = s.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset the password of your account. "+
+			"Open the link below to choose a new password, it is valid for %s and can be used once.\n\n%s\n\n"+
+			"If it was not you, you can ignore this email.\n", user.FirstName, s.TokenTTL, link),
+	})
+	if err != nil {
+		s.Logger.Printf("Cannot send reset email: %s", err)
+		return
+	}
+
+	s.Logger.Printf("Password reset sent to user %d", user.Id)
+}
+
+// Sets a new password with a reset token and logs the user out everywhere,
+// returns the ids of the removed sessions
+func (s *PasswordResetService) ResetPassword(token string, password string) ([]int64, error) {
+
+	if !CheckPasswordStrength(password) {
+		return nil, ErrWeakPassword
+	}
+
+	userId, err := s.PasswordResetRepo.Consume(hashSecretToken(token))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidResetToken
+	}
+
+	if err != nil {
+		s.Logger.Printf("ResetPassword error: %s", err)
+		return nil, err
+	}
+
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		s.Logger.Printf("Cannot hash password: %s", err)
+		return nil, err
+	}
+
+	err = s.UserRepo.UpdatePassword(userId, passwordHash)
+	if err != nil {
+		s.Logger.Printf("ResetPassword error: %s", err)
+		return nil, err
+	}
+
+	sessionIds, err := s.SessionRepo.DeleteByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("ResetPassword error: %s", err)
+		return nil, err
+	}
+
+	err = s.PasswordResetRepo.DeleteByUserId(userId)
+	if err != nil {
+		s.Logger.Printf("ResetPassword error: %s", err)
+	}
+
+	s.Logger.Printf("User %d reset the password, %d session(s) revoked", userId, len(sessionIds))
+
+	return sessionIds, nil
+}
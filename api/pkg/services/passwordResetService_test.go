@@ -0,0 +1,185 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestPasswordResetService(s *testServices) *PasswordResetService {
+	return InitPasswordResetService(
+		s.logger,
+		s.repos.UserRepo,
+		s.repos.SessionRepo,
+		s.repos.PasswordResetRepo,
+		s.mails,
+		time.Hour,
+		"http://frontend.test",
+		3,
+		5,
+		time.Hour,
+	)
+}
+
+// sendQueued sends the queued reset emails
+func sendQueued(reset *PasswordResetService) {
+	done := make(chan struct{})
+	close(done)
+	reset.RunMailer(done)
+}
+
+// requestReset requests a reset for the email and returns the token of the link sent
+func requestReset(t *testing.T, s *testServices, reset *PasswordResetService, email string) string {
+	t.Helper()
+
+	if _, err := reset.RequestPasswordReset(email, "192.0.2.1"); err != nil {
+		t.Fatalf("RequestPasswordReset() = %v", err)
+	}
+
+	sendQueued(reset)
+
+	mails := s.mails.sent()
+	if len(mails) == 0 || mails[len(mails)-1].To != email {
+		t.Fatalf("sent %+v, want a reset email to %s", mails, email)
+	}
+
+	return linkToken(t, mails[len(mails)-1], "/reset-password")
+}
+
+func TestPasswordResetEmailIsSentInBackground(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+	reset := newTestPasswordResetService(s)
+
+	for _, email := range []string{" anna@example.com ", "nobody@example.com"} {
+		if _, err := reset.RequestPasswordReset(email, "192.0.2.1"); err != nil {
+			t.Fatalf("RequestPasswordReset(%q) = %v", email, err)
+		}
+	}
+
+	if mails := s.mails.sent(); len(mails) != 0 {
+		t.Fatalf("sent %d emails before the mailer ran", len(mails))
+	}
+
+	sendQueued(reset)
+
+	mails := s.mails.sent()
+	if len(mails) != 1 || mails[0].To != "anna@example.com" {
+		t.Fatalf("sent %+v, want only a reset email to anna@example.com", mails)
+	}
+
+	linkToken(t, mails[0], "/reset-password")
+}
+
+func TestResetPassword(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	reset := newTestPasswordResetService(s)
+
+	session := s.getSession(t, s.login(t, "anna@example.com"))
+
+	earlier := requestReset(t, s, reset, "anna@example.com")
+	token := requestReset(t, s, reset, "anna@example.com")
+
+	if _, err := reset.ResetPassword(earlier, "N3wPassw0rd!"); err != ErrInvalidResetToken {
+		t.Fatalf("earlier link: got %v, want %v", err, ErrInvalidResetToken)
+	}
+
+	if _, err := reset.ResetPassword(token, "weak"); err != ErrWeakPassword {
+		t.Fatalf("weak password: got %v, want %v", err, ErrWeakPassword)
+	}
+
+	revoked, err := reset.ResetPassword(token, "N3wPassw0rd!")
+	if err != nil || len(revoked) != 1 || revoked[0] != session.Id {
+		t.Fatalf("ResetPassword() = %v, %v, want session %d revoked", revoked, err, session.Id)
+	}
+
+	if _, err = reset.ResetPassword(token, "An0therPassw0rd!"); err != ErrInvalidResetToken {
+		t.Fatalf("used link: got %v, want %v", err, ErrInvalidResetToken)
+	}
+
+	if _, err = s.users.UserLogin(&models.User{Email: "anna@example.com", Password: testPassword}, newRequest("192.0.2.1")); err != ErrInvalidCredentials {
+		t.Fatalf("old password: got %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	result, err := s.users.UserLogin(&models.User{Email: "anna@example.com", Password: "N3wPassw0rd!"}, newRequest("192.0.2.1"))
+	if err != nil || s.getSession(t, result.SessionToken).UserId != anna {
+		t.Fatalf("new password: got %+v, %v", result, err)
+	}
+}
+
+func TestExpiredResetLink(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+	reset := newTestPasswordResetService(s)
+	reset.TokenTTL = -time.Minute
+
+	token := requestReset(t, s, reset, "anna@example.com")
+
+	if _, err := reset.ResetPassword(token, "N3wPassw0rd!"); err != ErrInvalidResetToken {
+		t.Fatalf("got %v, want %v", err, ErrInvalidResetToken)
+	}
+}
+
+func TestPasswordResetRequestsAreLimited(t *testing.T) {
+	s := newTestServices(t)
+	reset := newTestPasswordResetService(s)
+
+	for i := 0; i < reset.MaxRequests; i++ {
+		if _, err := reset.RequestPasswordReset("Anna@example.com", fmt.Sprintf("192.0.2.%d", i)); err != nil {
+			t.Fatalf("request %d: got %v", i+1, err)
+		}
+	}
+
+	wait, err := reset.RequestPasswordReset("anna@example.com", "198.51.100.1")
+	if err != ErrTooManyResetRequests || wait <= reset.Window-time.Minute || wait > reset.Window {
+		t.Fatalf("got %v, %v, want %v for the window", wait, err, ErrTooManyResetRequests)
+	}
+
+	// the refused request is not counted against the IP
+	for i := 0; i < reset.MaxIPRequests; i++ {
+		if _, err = reset.RequestPasswordReset(fmt.Sprintf("user%d@example.com", i), "198.51.100.1"); err != nil {
+			t.Fatalf("request %d from one IP: got %v", i+1, err)
+		}
+	}
+
+	if _, err = reset.RequestPasswordReset("bob@example.com", "198.51.100.1"); err != ErrTooManyResetRequests {
+		t.Fatalf("too many requests from one IP: got %v, want %v", err, ErrTooManyResetRequests)
+	}
+
+	if _, err = reset.RequestPasswordReset("bob@example.com", "198.51.100.2"); err != nil {
+		t.Fatalf("request from another IP: got %v", err)
+	}
+}
+
+func TestPasswordResetRequestsAreForgotten(t *testing.T) {
+	s := newTestServices(t)
+	reset := newTestPasswordResetService(s)
+
+	old := time.Now().Add(-reset.Window - time.Minute)
+	err := s.repos.PasswordResetRepo.SaveRequests(&models.ResetRequests{
+		Scope:         models.ResetScopeEmail,
+		Key:           "anna@example.com",
+		Requests:      reset.MaxRequests,
+		LastRequestAt: old,
+		BlockedUntil:  old,
+	})
+	if err != nil {
+		t.Fatalf("Cannot save requests: %v", err)
+	}
+
+	if _, err = reset.RequestPasswordReset("anna@example.com", "192.0.2.1"); err != nil {
+		t.Fatalf("request after the window: got %v", err)
+	}
+
+	requests, err := s.repos.PasswordResetRepo.GetRequests(models.ResetScopeEmail, "anna@example.com")
+	if err != nil || requests.Requests != 1 {
+		t.Fatalf("got %+v, %v, want the counter to start over", requests, err)
+	}
+
+	// reset requests are counted apart from failed logins
+	if _, err = s.repos.LoginFailureRepo.Get(models.LoginScopeEmail, "anna@example.com"); err == nil {
+		t.Fatalf("reset request counted as a failed login")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"log"
 	"mime/multipart"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -17,25 +18,48 @@ type IPostService interface {
 	CreateGroupPost(post *models.Post) error
 	GetFeedPosts(userId int64, offset int64) ([]*feedPostJSON, error)
 	GetProfilePosts(userId int64, offset int64) ([]*feedPostJSON, error)
-	GetGroupPosts(groupId int64, offset int64) ([]*feedPostJSON, error)
+	GetGroupPosts(groupId int64, offset int64, requestingUserId int64) ([]*feedPostJSON, error)
 	GetUserPosts(userId int64, offset int64, requestingUserId int64) ([]*feedPostJSON, error)
+	GetTagPosts(tag string, offset int64, requestingUserId int64) ([]*feedPostJSON, error)
+	GetTrendingTags(requestingUserId int64) ([]*trendingTagJSON, error)
 	SavePostImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error)
+	UpdatePost(userId int64, update *models.Post, removeImage bool) error
+	DeletePost(userId int64, postId int64) error
 }
 
+var (
+	ErrNotPostAuthor      = errors.New("user is not the author of the post")
+	ErrInvalidPrivacyType = errors.New("invalid privacy type")
+	ErrGroupPostPrivacy   = errors.New("group post privacy cannot be changed")
+	ErrInvalidTag         = errors.New("invalid tag")
+)
+
 // Controller contains the service, which contains database-related logic, as an injectable dependency, allowing us to decouple business logic from db logic.
 type PostService struct {
 	Logger                *log.Logger
 	GroupRepository       models.IGroupRepository
 	PostRepository        models.IPostRepository
 	AllowedPostRepository models.IAllowedPostRepository
+	CommentRepository     models.ICommentRepository
+	ReactionRepository    models.IReactionRepository
+	TagRepository         models.ITagRepository
+	ImageService          utils.IImageService
+	VerificationPolicy    IVerificationPolicy
+	TrendingTagsWindow    time.Duration
 }
 
-func InitPostService(logger *log.Logger, groupRepo *models.GroupRepository, postRepo *models.PostRepository, allowedPostRepo *models.AllowedPostRepository) *PostService {
+func InitPostService(logger *log.Logger, groupRepo *models.GroupRepository, postRepo *models.PostRepository, allowedPostRepo *models.AllowedPostRepository, commentRepo *models.CommentRepository, reactionRepo *models.ReactionRepository, tagRepo *models.TagRepository, imageService *utils.ImageService, verificationPolicy IVerificationPolicy, trendingTagsWindow time.Duration) *PostService {
 	return &PostService{
 		Logger:                logger,
 		GroupRepository:       groupRepo,
 		PostRepository:        postRepo,
 		AllowedPostRepository: allowedPostRepo,
+		CommentRepository:     commentRepo,
+		ReactionRepository:    reactionRepo,
+		TagRepository:         tagRepo,
+		ImageService:          imageService,
+		VerificationPolicy:    verificationPolicy,
+		TrendingTagsWindow:    trendingTagsWindow,
 	}
 }
 
@@ -49,10 +73,33 @@ type feedPostJSON struct {
 	CreatedAt    time.Time `json:"createdAt"`
 	GroupId      int64     `json:"groupId"`
 	GroupName    string    `json:"groupName"`
+	ReactionsJSON
+}
+
+// attachReactions fills in the reaction summaries of the posts as seen by the user
+func (s *PostService) attachReactions(userId int64, posts []*feedPostJSON) {
+
+	postIds := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		postIds = append(postIds, p.Id)
+	}
+
+	summaries, err := s.ReactionRepository.GetPostSummaries(postIds, userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get post reactions: %s", err)
+	}
+
+	for _, p := range posts {
+		p.ReactionsJSON = newReactionsJSON(summaries[p.Id])
+	}
 }
 
 func (s *PostService) CreatePost(post *models.Post) error {
 
+	if err := s.VerificationPolicy.CheckAllowed(post.UserId, ActionPost); err != nil {
+		return err
+	}
+
 	if len(post.Content) == 0 && len(post.ImagePath) == 0 {
 		err := errors.New("content too short")
 		log.Printf("CreatePost error: %s", err)
@@ -60,34 +107,56 @@ func (s *PostService) CreatePost(post *models.Post) error {
 	}
 
 	postId, err := s.PostRepository.Insert(post)
+	post.Id = postId
+
+	if err != nil {
+		log.Printf("CreatePost error: %s", err)
+		return err
+	}
 
 	if post.PrivacyType == enums.SubPrivate {
-		for _, receiver := range post.Receivers {
+		s.insertAllowedUsers(postId, post.Receivers)
+	}
 
-			receiverId, err := strconv.Atoi(receiver)
+	s.tagPost(post)
 
-			if err != nil {
-				s.Logger.Printf("CreatePost atoi parse error: %s", err)
-			}
+	return nil
+}
 
-			allowedPost := models.AllowedPost{
-				UserId: receiverId,
-				PostId: int(postId),
-			}
+// Stores the users allowed to see a sub-private post
+func (s *PostService) insertAllowedUsers(postId int64, receivers []string) {
+	for _, receiver := range receivers {
 
-			s.AllowedPostRepository.Insert(&allowedPost)
+		if receiver == "" {
+			continue
 		}
-	}
 
-	if err != nil {
-		log.Printf("CreatePost error: %s", err)
-	}
+		receiverId, err := strconv.Atoi(receiver)
 
-	return err
+		if err != nil {
+			s.Logger.Printf("CreatePost atoi parse error: %s", err)
+			continue
+		}
+
+		allowedPost := models.AllowedPost{
+			UserId: receiverId,
+			PostId: int(postId),
+		}
+
+		_, err = s.AllowedPostRepository.Insert(&allowedPost)
+
+		if err != nil {
+			s.Logger.Printf("Cannot insert allowed user %d for post %d: %s", receiverId, postId, err)
+		}
+	}
 }
 
 func (s *PostService) CreateGroupPost(post *models.Post) error {
 
+	if err := s.VerificationPolicy.CheckAllowed(post.UserId, ActionPost); err != nil {
+		return err
+	}
+
 	if len(post.Content) == 0 {
 		err := errors.New("content too short")
 		log.Printf("Create Group Post error: %s", err)
@@ -95,14 +164,18 @@ func (s *PostService) CreateGroupPost(post *models.Post) error {
 	}
 
 	postId, err := s.PostRepository.Insert(post)
+	post.Id = postId
 
 	if err != nil {
 		log.Printf("Create Group Post error: %s", err)
+		return err
 	}
 
 	s.Logger.Printf("Group post inserted: %d", postId)
 
-	return err
+	s.tagPost(post)
+
+	return nil
 }
 
 func (s *PostService) GetFeedPosts(userId int64, offset int64) ([]*feedPostJSON, error) {
@@ -153,6 +226,8 @@ func (s *PostService) GetFeedPosts(userId int64, offset int64) ([]*feedPostJSON,
 		})
 	}
 
+	s.attachReactions(userId, feedPosts)
+
 	s.Logger.Printf("Retrived feed posts: %d", len(feedPosts))
 
 	return feedPosts, nil
@@ -205,10 +280,12 @@ func (s *PostService) GetProfilePosts(userId int64, offset int64) ([]*feedPostJS
 		})
 	}
 
+	s.attachReactions(userId, feedPosts)
+
 	return feedPosts, nil
 }
 
-func (s *PostService) GetGroupPosts(groupId int64, offset int64) ([]*feedPostJSON, error) {
+func (s *PostService) GetGroupPosts(groupId int64, offset int64, requestingUserId int64) ([]*feedPostJSON, error) {
 
 	if offset == 0 {
 		lastPostId, err := s.PostRepository.GetLastPostId()
@@ -240,18 +317,20 @@ func (s *PostService) GetGroupPosts(groupId int64, offset int64) ([]*feedPostJSO
 		}
 
 		feedPosts = append(feedPosts, &feedPostJSON{
-			p.Id,
-			p.UserId,
-			p.Nickname,
-			p.Content,
-			p.ImagePath,
-			p.CommentCount,
-			p.CreatedAt,
-			groupId,
-			group.Title,
+			Id:           p.Id,
+			UserId:       p.UserId,
+			UserName:     p.Nickname,
+			Content:      p.Content,
+			ImagePath:    p.ImagePath,
+			CommentCount: p.CommentCount,
+			CreatedAt:    p.CreatedAt,
+			GroupId:      groupId,
+			GroupName:    group.Title,
 		})
 	}
 
+	s.attachReactions(requestingUserId, feedPosts)
+
 	return feedPosts, nil
 }
 
@@ -302,6 +381,8 @@ func (s *PostService) GetUserPosts(userId int64, offset int64, requestingUserId
 		})
 	}
 
+	s.attachReactions(requestingUserId, feedPosts)
+
 	return feedPosts, nil
 }
 
@@ -314,10 +395,264 @@ func (s *PostService) SavePostImage(file multipart.File, fileHeader *multipart.F
 	}
 
 	// save image
-	imagePath, err := utils.SaveImage(file, fileHeader)
+	imagePath, err := s.ImageService.SaveImage(file, fileHeader)
 	if err != nil {
 		s.Logger.Printf("UpdatePostImage error: %s", err)
 	}
 
 	return imagePath, err
 }
+
+// Updates content, image and privacy of a post, only the author may edit it.
+// An empty update.ImagePath keeps the current image unless removeImage is set,
+// enums.None as update.PrivacyType keeps the current privacy and nil
+// update.Receivers keeps the users allowed to see a sub-private post. A new image
+// is saved before the update and deleted again when the update fails.
+func (s *PostService) UpdatePost(userId int64, update *models.Post, removeImage bool) (err error) {
+
+	defer func() {
+		if err != nil && update.ImagePath != "" {
+			s.ImageService.DeleteImage(update.ImagePath)
+		}
+	}()
+
+	post, err := s.PostRepository.GetById(update.Id)
+	if err != nil {
+		s.Logger.Printf("UpdatePost error: %s", err)
+		return err
+	}
+
+	if post.UserId != userId {
+		s.Logger.Printf("User %d is not the author of post %d", userId, post.Id)
+		return ErrNotPostAuthor
+	}
+
+	previousPrivacy := post.PrivacyType
+	previousImage := post.ImagePath
+
+	if update.PrivacyType != enums.None {
+		if post.GroupId > 0 {
+			return ErrGroupPostPrivacy
+		}
+		if update.PrivacyType < enums.Public || update.PrivacyType > enums.SubPrivate {
+			return ErrInvalidPrivacyType
+		}
+		post.PrivacyType = update.PrivacyType
+	}
+
+	if update.ImagePath != "" {
+		post.ImagePath = update.ImagePath
+	} else if removeImage {
+		post.ImagePath = ""
+	}
+
+	post.Content = update.Content
+
+	if len(post.Content) == 0 && len(post.ImagePath) == 0 {
+		err := errors.New("content too short")
+		s.Logger.Printf("UpdatePost error: %s", err)
+		return err
+	}
+
+	err = s.PostRepository.Update(post)
+	if err != nil {
+		s.Logger.Printf("UpdatePost error: %s", err)
+		return err
+	}
+
+	// the allowed users are dropped when the post leaves sub-private and replaced
+	// only when new receivers are sent
+	leftSubPrivate := previousPrivacy == enums.SubPrivate && post.PrivacyType != enums.SubPrivate
+	newReceivers := post.PrivacyType == enums.SubPrivate && update.Receivers != nil
+
+	if leftSubPrivate || newReceivers {
+		err = s.AllowedPostRepository.DeleteByPostId(post.Id)
+		if err != nil {
+			s.Logger.Printf("UpdatePost error: %s", err)
+			return err
+		}
+	}
+
+	if newReceivers {
+		s.insertAllowedUsers(post.Id, update.Receivers)
+	}
+
+	s.tagPost(post)
+
+	if previousImage != post.ImagePath {
+		s.ImageService.DeleteImage(previousImage)
+	}
+
+	s.Logger.Printf("Post %d updated by user %d", post.Id, userId)
+
+	return nil
+}
+
+// Deletes a post together with its comments, allowed users and stored images
+func (s *PostService) DeletePost(userId int64, postId int64) error {
+
+	post, err := s.PostRepository.GetById(postId)
+	if err != nil {
+		s.Logger.Printf("DeletePost error: %s", err)
+		return err
+	}
+
+	if post.UserId != userId {
+		s.Logger.Printf("User %d is not the author of post %d", userId, post.Id)
+		return ErrNotPostAuthor
+	}
+
+	commentImages, err := s.CommentRepository.GetImagePathsByPostId(postId)
+	if err != nil {
+		s.Logger.Printf("DeletePost error: %s", err)
+		return err
+	}
+
+	err = s.PostRepository.Delete(postId)
+	if err != nil {
+		s.Logger.Printf("DeletePost error: %s", err)
+		return err
+	}
+
+	s.ImageService.DeleteImage(post.ImagePath)
+
+	for _, imagePath := range commentImages {
+		s.ImageService.DeleteImage(imagePath)
+	}
+
+	s.Logger.Printf("Post %d deleted by user %d", postId, userId)
+
+	return nil
+}
+
+const TrendingTagsLimit = 10
+
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+var tagNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_]{1,50}$`)
+var tagLetterPattern = regexp.MustCompile(`[\p{L}_]`)
+
+type trendingTagJSON struct {
+	Tag       string `json:"tag"`
+	PostCount int    `json:"postCount"`
+}
+
+// NormalizeTag lowercases a tag and strips a leading #, tags made of digits only are not accepted
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+
+	if !tagNamePattern.MatchString(tag) || !tagLetterPattern.MatchString(tag) {
+		return "", ErrInvalidTag
+	}
+
+	return tag, nil
+}
+
+// ParseHashtags returns the distinct normalized tags written as #tag in content
+func ParseHashtags(content string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag, err := NormalizeTag(match[1])
+
+		if err != nil || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// tagPost stores the hashtags of the post content, a failure does not undo the post
+func (s *PostService) tagPost(post *models.Post) {
+	err := s.TagRepository.SetPostTags(post.Id, ParseHashtags(post.Content))
+
+	if err != nil {
+		s.Logger.Printf("Cannot tag post %d: %s", post.Id, err)
+	}
+}
+
+// Returns the posts with the tag that the user may see, offset is the id of the last post already loaded
+func (s *PostService) GetTagPosts(tag string, offset int64, requestingUserId int64) ([]*feedPostJSON, error) {
+
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset == 0 {
+		lastPostId, err := s.PostRepository.GetLastPostId()
+		if err != nil {
+			s.Logger.Printf("GetTagPosts error: %s", err)
+			return nil, err
+		}
+		offset = lastPostId + 1
+	}
+
+	posts, err := s.PostRepository.GetAllByTag(tag, requestingUserId, offset)
+
+	if err != nil {
+		s.Logger.Printf("GetTagPosts error: %s", err)
+		return nil, err
+	}
+
+	feedPosts := []*feedPostJSON{}
+
+	for _, p := range posts {
+
+		group := &models.Group{}
+
+		if p.GroupId > 0 {
+			group, err = s.GroupRepository.GetById(p.GroupId)
+			if err != nil {
+				s.Logger.Printf("GetTagPosts error: %s", err)
+			}
+		}
+
+		if p.Nickname == "" {
+			p.Nickname = p.FirstName + " " + p.LastName
+		}
+
+		feedPosts = append(feedPosts, &feedPostJSON{
+			Id:           p.Id,
+			UserId:       p.UserId,
+			UserName:     p.Nickname,
+			GroupId:      p.GroupId,
+			GroupName:    group.Title,
+			Content:      p.Content,
+			ImagePath:    p.ImagePath,
+			CommentCount: p.CommentCount,
+			CreatedAt:    p.CreatedAt,
+		})
+	}
+
+	s.attachReactions(requestingUserId, feedPosts)
+
+	return feedPosts, nil
+}
+
+// Returns the tags used most in the posts of the trending window that the user may see
+func (s *PostService) GetTrendingTags(requestingUserId int64) ([]*trendingTagJSON, error) {
+
+	since := time.Now().Add(-s.TrendingTagsWindow)
+
+	tags, err := s.TagRepository.GetTrending(requestingUserId, since, TrendingTagsLimit)
+	if err != nil {
+		s.Logger.Printf("GetTrendingTags error: %s", err)
+		return nil, err
+	}
+
+	trending := []*trendingTagJSON{}
+
+	for _, t := range tags {
+		trending = append(trending, &trendingTagJSON{
+			Tag:       t.Name,
+			PostCount: t.PostCount,
+		})
+	}
+
+	return trending, nil
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPostService(s *testServices) *PostService {
+	return InitPostService(
+		s.logger,
+		s.repos.GroupRepo,
+		s.repos.PostRepo,
+		s.repos.AllowedPostRepo,
+		s.repos.CommentRepo,
+		s.repos.ReactionRepo,
+		s.repos.TagRepo,
+		s.images,
+		s.emailVerification,
+		24*time.Hour,
+	)
+}
+
+// newPost creates a post of the user and returns its id
+func newPost(t *testing.T, posts *PostService, userId int64, content string, privacy enums.PrivacyType, receivers ...int64) int64 {
+	t.Helper()
+
+	post := &models.Post{UserId: userId, Content: content, PrivacyType: privacy}
+	for _, receiver := range receivers {
+		post.Receivers = append(post.Receivers, strconv.FormatInt(receiver, 10))
+	}
+
+	if err := posts.CreatePost(post); err != nil {
+		t.Fatalf("CreatePost() = %v", err)
+	}
+
+	return post.Id
+}
+
+// feedContents returns the contents of the posts in the feed of the user, sorted
+func feedContents(t *testing.T, posts *PostService, userId int64) string {
+	t.Helper()
+
+	feed, err := posts.GetFeedPosts(userId, 0)
+	if err != nil {
+		t.Fatalf("GetFeedPosts() = %v", err)
+	}
+
+	contents := []string{}
+	for _, post := range feed {
+		contents = append(contents, post.Content)
+	}
+	sort.Strings(contents)
+
+	return strings.Join(contents, ",")
+}
+
+// newImage stores an image file and returns its name
+func newImage(t *testing.T, s *testServices, name string) string {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(s.images.Dir, name), []byte("image"), 0o644); err != nil {
+		t.Fatalf("Cannot write image: %v", err)
+	}
+
+	return name
+}
+
+func imageExists(s *testServices, name string) bool {
+	_, err := os.Stat(filepath.Join(s.images.Dir, name))
+	return err == nil
+}
+
+// postAudience is a post author with a follower who may see the sub-private post,
+// a follower who may not and a user who does not follow
+type postAudience struct {
+	anna, bob, carl, dave int64
+}
+
+func newPostAudience(t *testing.T, s *testServices) postAudience {
+	a := postAudience{
+		anna: s.newUser(t, "anna@example.com"),
+		bob:  s.newUser(t, "bob@example.com"),
+		carl: s.newUser(t, "carl@example.com"),
+		dave: s.newUser(t, "dave@example.com"),
+	}
+
+	s.follow(t, a.bob, a.anna)
+	s.follow(t, a.carl, a.anna)
+
+	return a
+}
+
+func TestPostPrivacy(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	a := newPostAudience(t, s)
+
+	newPost(t, posts, a.anna, "public", enums.Public)
+	newPost(t, posts, a.anna, "private", enums.Private)
+	newPost(t, posts, a.anna, "sub-private", enums.SubPrivate, a.bob)
+
+	tests := []struct {
+		name   string
+		viewer int64
+		want   string
+	}{
+		{"author", a.anna, "private,public,sub-private"},
+		{"allowed follower", a.bob, "private,public,sub-private"},
+		{"follower", a.carl, "private,public"},
+		{"not following", a.dave, "public"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feedContents(t, posts, tt.viewer); got != tt.want {
+				t.Fatalf("feed shows %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePostKeepsAudience(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "sub-private", enums.SubPrivate, a.bob)
+
+	update := func(privacy enums.PrivacyType, receivers []string) {
+		t.Helper()
+
+		err := posts.UpdatePost(a.anna, &models.Post{Id: postId, Content: "edited", PrivacyType: privacy, Receivers: receivers}, false)
+		if err != nil {
+			t.Fatalf("UpdatePost() = %v", err)
+		}
+	}
+
+	expectAudience := func(want map[int64]bool) {
+		t.Helper()
+
+		for _, viewer := range []int64{a.bob, a.carl, a.dave} {
+			if got := feedContents(t, posts, viewer) == "edited"; got != want[viewer] {
+				t.Fatalf("user %d sees the post: %v, want %v", viewer, got, want[viewer])
+			}
+		}
+	}
+
+	// editing the content only keeps the users allowed to see the post
+	update(enums.None, nil)
+	expectAudience(map[int64]bool{a.bob: true})
+
+	update(enums.Private, nil)
+	expectAudience(map[int64]bool{a.bob: true, a.carl: true})
+
+	// the allowed users were dropped when the post left sub-private
+	update(enums.SubPrivate, nil)
+	expectAudience(map[int64]bool{})
+
+	update(enums.None, []string{strconv.FormatInt(a.carl, 10)})
+	expectAudience(map[int64]bool{a.carl: true})
+
+	update(enums.Public, nil)
+	expectAudience(map[int64]bool{a.bob: true, a.carl: true, a.dave: true})
+}
+
+func TestUpdatePostChecksAuthorFirst(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	a := newPostAudience(t, s)
+
+	postId := newPost(t, posts, a.anna, "public", enums.Public)
+
+	// the handler saves the uploaded image before the update, a refused update removes it
+	image := newImage(t, s, "upload.png")
+
+	err := posts.UpdatePost(a.bob, &models.Post{Id: postId, Content: "edited", ImagePath: image}, false)
+	if err != ErrNotPostAuthor {
+		t.Fatalf("got %v, want %v", err, ErrNotPostAuthor)
+	}
+
+	if imageExists(s, image) {
+		t.Fatalf("image of the refused update was kept")
+	}
+
+	if err = posts.UpdatePost(a.anna, &models.Post{Id: postId, Content: "edited", PrivacyType: 7}, false); err != ErrInvalidPrivacyType {
+		t.Fatalf("invalid privacy: got %v, want %v", err, ErrInvalidPrivacyType)
+	}
+
+	if err = posts.UpdatePost(a.anna, &models.Post{Id: postId + 100, Content: "edited"}, false); err != sql.ErrNoRows {
+		t.Fatalf("missing post: got %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if got := feedContents(t, posts, a.dave); got != "public" {
+		t.Fatalf("feed shows %q after refused updates", got)
+	}
+}
+
+func TestUpdatePostImage(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	anna := s.newUser(t, "anna@example.com")
+
+	first := newImage(t, s, "first.png")
+	post := &models.Post{UserId: anna, Content: "public", ImagePath: first, PrivacyType: enums.Public}
+	if err := posts.CreatePost(post); err != nil {
+		t.Fatalf("CreatePost() = %v", err)
+	}
+
+	// an update without an image keeps the current one
+	if err := posts.UpdatePost(anna, &models.Post{Id: post.Id, Content: "edited"}, false); err != nil {
+		t.Fatalf("UpdatePost() = %v", err)
+	}
+
+	if !imageExists(s, first) {
+		t.Fatalf("image removed by an update without an image")
+	}
+
+	second := newImage(t, s, "second.png")
+	if err := posts.UpdatePost(anna, &models.Post{Id: post.Id, Content: "edited", ImagePath: second}, false); err != nil {
+		t.Fatalf("UpdatePost() = %v", err)
+	}
+
+	if imageExists(s, first) || !imageExists(s, second) {
+		t.Fatalf("replaced image was not removed")
+	}
+
+	if err := posts.UpdatePost(anna, &models.Post{Id: post.Id, Content: ""}, true); err == nil {
+		t.Fatalf("removed the image of a post without content")
+	}
+
+	if !imageExists(s, second) {
+		t.Fatalf("image removed by a refused update")
+	}
+
+	if err := posts.UpdatePost(anna, &models.Post{Id: post.Id, Content: "edited"}, true); err != nil {
+		t.Fatalf("UpdatePost() = %v", err)
+	}
+
+	if imageExists(s, second) {
+		t.Fatalf("removed image was kept")
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	a := newPostAudience(t, s)
+
+	image := newImage(t, s, "post.png")
+	post := &models.Post{UserId: a.anna, Content: "public", ImagePath: image, PrivacyType: enums.Public}
+	if err := posts.CreatePost(post); err != nil {
+		t.Fatalf("CreatePost() = %v", err)
+	}
+
+	commentImage := newImage(t, s, "comment.png")
+	commentId, err := s.repos.CommentRepo.Insert(&models.Comment{PostId: post.Id, UserId: a.bob, Content: "comment", ImagePath: commentImage})
+	if err != nil {
+		t.Fatalf("Cannot insert comment: %v", err)
+	}
+
+	if err = posts.DeletePost(a.bob, post.Id); err != ErrNotPostAuthor {
+		t.Fatalf("deleting another user's post: got %v, want %v", err, ErrNotPostAuthor)
+	}
+
+	if err = posts.DeletePost(a.anna, post.Id); err != nil {
+		t.Fatalf("DeletePost() = %v", err)
+	}
+
+	if _, err = s.repos.PostRepo.GetById(post.Id); err != sql.ErrNoRows {
+		t.Fatalf("post left after deleting: %v", err)
+	}
+
+	if _, err = s.repos.CommentRepo.GetById(commentId); err != sql.ErrNoRows {
+		t.Fatalf("comment left after deleting the post: %v", err)
+	}
+
+	if imageExists(s, image) || imageExists(s, commentImage) {
+		t.Fatalf("images left after deleting the post")
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Principal is the authenticated user of a request, resolved once by Authenticate
+type Principal struct {
+	UserID    int64
+	SessionID int64
+	// TokenID is set instead of SessionID when a personal access token was used
+	TokenID int64
+	Scopes  []string
+	Role    string
+	// CSRFToken has to be sent with state-changing requests made with the session cookie
+	CSRFToken string
+}
+
+// HasScope reports whether the principal may act with the scope, sessions have every scope
+func (p *Principal) HasScope(scope string) bool {
+	if p.TokenID == 0 {
+		return true
+	}
+
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAdmin reports whether the principal may use the moderation endpoints
+func (p *Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin
+}
+
+type principalKey struct{}
+
+var ErrNoPrincipal = errors.New("request is not authenticated")
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by Authenticate, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// RequestPrincipal returns the principal of an authenticated request
+func RequestPrincipal(r *http.Request) (*Principal, error) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, ErrNoPrincipal
+	}
+	return principal, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+type IReactionService interface {
+	TogglePostReaction(userId int64, postId int64, reactionName string) (*ReactionToggle, error)
+	ToggleCommentReaction(userId int64, commentId int64, reactionName string) (*ReactionToggle, error)
+}
+
+type ReactionService struct {
+	Logger             *log.Logger
+	PostRepository     models.IPostRepository
+	CommentRepository  models.ICommentRepository
+	ReactionRepository models.IReactionRepository
+}
+
+func InitReactionService(
+	logger *log.Logger,
+	postRepo *models.PostRepository,
+	commentRepo *models.CommentRepository,
+	reactionRepo *models.ReactionRepository,
+) *ReactionService {
+	return &ReactionService{
+		Logger:             logger,
+		PostRepository:     postRepo,
+		CommentRepository:  commentRepo,
+		ReactionRepository: reactionRepo,
+	}
+}
+
+var ErrUnknownReaction = errors.New("unknown reaction")
+
+// ReactionsJSON is the reaction summary attached to posts and comments
+type ReactionsJSON struct {
+	Reactions   map[string]int `json:"reactions"`
+	MyReaction  string         `json:"myReaction"`
+	ReactedByMe bool           `json:"reactedByMe"`
+}
+
+// ReactionToggle is the outcome of toggling a reaction, Added is false when the reaction was removed
+type ReactionToggle struct {
+	Summary   ReactionsJSON
+	Added     bool
+	Reaction  string
+	AuthorId  int64
+	PostId    int64
+	CommentId int64
+}
+
+func newReactionsJSON(summary *models.ReactionSummary) ReactionsJSON {
+	if summary == nil {
+		return ReactionsJSON{Reactions: map[string]int{}}
+	}
+
+	return ReactionsJSON{
+		Reactions:   summary.Counts,
+		MyReaction:  summary.MyReaction.String(),
+		ReactedByMe: summary.MyReaction != 0,
+	}
+}
+
+// Adds the reaction to a post visible to the user, removes it if it is already there
+// and replaces any other reaction of the user
+func (s *ReactionService) TogglePostReaction(userId int64, postId int64, reactionName string) (*ReactionToggle, error) {
+
+	reactionType, ok := enums.ParseReactionType(reactionName)
+	if !ok {
+		return nil, ErrUnknownReaction
+	}
+
+	post, err := s.visiblePost(postId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.ReactionRepository.GetPostReaction(userId, postId)
+	if err != nil && err != sql.ErrNoRows {
+		s.Logger.Printf("TogglePostReaction error: %s", err)
+		return nil, err
+	}
+	if err == sql.ErrNoRows {
+		current = nil
+	}
+
+	added, err := s.toggle(current, &models.Reaction{UserId: userId, PostId: postId, ReactionType: reactionType})
+	if err != nil {
+		s.Logger.Printf("TogglePostReaction error: %s", err)
+		return nil, err
+	}
+
+	summaries, err := s.ReactionRepository.GetPostSummaries([]int64{postId}, userId)
+	if err != nil {
+		s.Logger.Printf("TogglePostReaction error: %s", err)
+		return nil, err
+	}
+
+	return &ReactionToggle{
+		Summary:  newReactionsJSON(summaries[postId]),
+		Added:    added,
+		Reaction: reactionType.String(),
+		AuthorId: post.UserId,
+		PostId:   postId,
+	}, nil
+}
+
+// Adds the reaction to a comment of a post visible to the user, removes it if it is
+// already there and replaces any other reaction of the user
+func (s *ReactionService) ToggleCommentReaction(userId int64, commentId int64, reactionName string) (*ReactionToggle, error) {
+
+	reactionType, ok := enums.ParseReactionType(reactionName)
+	if !ok {
+		return nil, ErrUnknownReaction
+	}
+
+	comment, err := s.CommentRepository.GetById(commentId)
+	if err != nil {
+		s.Logger.Printf("ToggleCommentReaction error: %s", err)
+		return nil, err
+	}
+
+	if _, err := s.visiblePost(comment.PostId, userId); err != nil {
+		return nil, err
+	}
+
+	current, err := s.ReactionRepository.GetCommentReaction(userId, commentId)
+	if err != nil && err != sql.ErrNoRows {
+		s.Logger.Printf("ToggleCommentReaction error: %s", err)
+		return nil, err
+	}
+	if err == sql.ErrNoRows {
+		current = nil
+	}
+
+	added, err := s.toggle(current, &models.Reaction{UserId: userId, CommentId: commentId, ReactionType: reactionType})
+	if err != nil {
+		s.Logger.Printf("ToggleCommentReaction error: %s", err)
+		return nil, err
+	}
+
+	summaries, err := s.ReactionRepository.GetCommentSummaries([]int64{commentId}, userId)
+	if err != nil {
+		s.Logger.Printf("ToggleCommentReaction error: %s", err)
+		return nil, err
+	}
+
+	return &ReactionToggle{
+		Summary:   newReactionsJSON(summaries[commentId]),
+		Added:     added,
+		Reaction:  reactionType.String(),
+		AuthorId:  comment.UserId,
+		PostId:    comment.PostId,
+		CommentId: commentId,
+	}, nil
+}
+
+// visiblePost returns the post if the feed shows it to the user, hidden posts are reported as missing
+func (s *ReactionService) visiblePost(postId int64, userId int64) (*models.Post, error) {
+
+	post, err := s.PostRepository.GetById(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := s.PostRepository.IsVisibleToUser(postId, userId)
+	if err != nil {
+		s.Logger.Printf("Cannot check post visibility: %s", err)
+		return nil, err
+	}
+
+	if !visible {
+		s.Logger.Printf("Post %d is not visible to user %d", postId, userId)
+		return nil, sql.ErrNoRows
+	}
+
+	return post, nil
+}
+
+// toggle stores the wanted reaction in place of the current one, it returns false when
+// the wanted reaction was already there and has been removed
+func (s *ReactionService) toggle(current *models.Reaction, wanted *models.Reaction) (bool, error) {
+
+	switch {
+	case current == nil:
+		_, err := s.ReactionRepository.Insert(wanted)
+		return err == nil, err
+	case current.ReactionType == wanted.ReactionType:
+		return false, s.ReactionRepository.Delete(current.Id)
+	default:
+		err := s.ReactionRepository.UpdateType(current.Id, wanted.ReactionType)
+		return err == nil, err
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"errors"
+	"log"
+	"time"
+)
+
+type IReplayService interface {
+	Record(userId int64, eventType string, data []byte) (int64, error)
+	Replay(userId int64, lastSeq int64) ([]*models.ReplayEvent, int64, error)
+	CurrentSeq(userId int64) (int64, error)
+	Prune() (int64, error)
+	RunPruner(done <-chan struct{})
+	Enabled() bool
+}
+
+// ReplayService keeps the websocket events of every user for a while, numbered
+// per user, so a client that was offline can be sent what it missed
+type ReplayService struct {
+	Logger     *log.Logger
+	ReplayRepo models.IReplayRepository
+	// Retention is how long events are kept, 0 turns replay off
+	Retention time.Duration
+	// Limit is the most events replayed at once
+	Limit int
+}
+
+func InitReplayService(
+	logger *log.Logger,
+	replayRepo *models.ReplayRepository,
+	retention time.Duration,
+	limit int,
+) *ReplayService {
+	return &ReplayService{
+		Logger:     logger,
+		ReplayRepo: replayRepo,
+		Retention:  retention,
+		Limit:      limit,
+	}
+}
+
+// minReplayPruneInterval keeps short retentions from pruning in a busy loop
+const minReplayPruneInterval = time.Minute
+
+// ErrReplayGap means the missed events cannot be replayed, the client has to load
+// everything again
+var ErrReplayGap = errors.New("missed events are no longer kept")
+
+// Enabled reports whether events are kept for replay
+func (s *ReplayService) Enabled() bool {
+	return s.Retention > 0
+}
+
+// Record stores the event for the user and returns its sequence number, 0 when replay is off
+func (s *ReplayService) Record(userId int64, eventType string, data []byte) (int64, error) {
+	if s.Retention == 0 {
+		return 0, nil
+	}
+
+	return s.ReplayRepo.Append(&models.ReplayEvent{
+		UserId:    userId,
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CurrentSeq returns the sequence number of the last event of the user
+func (s *ReplayService) CurrentSeq(userId int64) (int64, error) {
+	return s.ReplayRepo.GetSeq(userId)
+}
+
+// Replay returns the events of the user after lastSeq, oldest first, and the sequence
+// number of the last event of the user. It returns ErrReplayGap when some of the
+// events were pruned, there are more than the limit or lastSeq is unknown.
+func (s *ReplayService) Replay(userId int64, lastSeq int64) ([]*models.ReplayEvent, int64, error) {
+	if s.Retention == 0 {
+		return nil, 0, ErrReplayGap
+	}
+
+	currentSeq, err := s.ReplayRepo.GetSeq(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get event sequence: %s", err)
+		return nil, 0, err
+	}
+
+	if lastSeq == currentSeq {
+		return []*models.ReplayEvent{}, currentSeq, nil
+	}
+
+	if lastSeq > currentSeq || currentSeq-lastSeq > int64(s.Limit) {
+		return nil, currentSeq, ErrReplayGap
+	}
+
+	events, err := s.ReplayRepo.GetSince(userId, lastSeq, s.Limit)
+	if err != nil {
+		s.Logger.Printf("Cannot get events: %s", err)
+		return nil, currentSeq, err
+	}
+
+	if len(events) == 0 || events[0].Seq != lastSeq+1 {
+		return nil, currentSeq, ErrReplayGap
+	}
+
+	return events, currentSeq, nil
+}
+
+// Prune deletes the events older than the retention and returns how many there were
+func (s *ReplayService) Prune() (int64, error) {
+	if s.Retention == 0 {
+		return 0, nil
+	}
+
+	return s.ReplayRepo.DeleteBefore(time.Now().Add(-s.Retention))
+}
+
+// PruneInterval is a tenth of the retention, so events are kept at most that much longer
+func (s *ReplayService) PruneInterval() time.Duration {
+	interval := s.Retention / 10
+	if interval < minReplayPruneInterval {
+		interval = minReplayPruneInterval
+	}
+
+	return interval
+}
+
+// RunPruner prunes the events every PruneInterval until done is closed, it returns at once when replay is off
+func (s *ReplayService) RunPruner(done <-chan struct{}) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.PruneInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			pruned, err := s.Prune()
+			if err != nil {
+				s.Logger.Printf("Cannot prune websocket events: %s", err)
+			} else if pruned > 0 {
+				s.Logger.Printf("Pruned %d websocket event(s)", pruned)
+			}
+		}
+	}
+}
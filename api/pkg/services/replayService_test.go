@@ -0,0 +1,55 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestReplayPruneInterval(t *testing.T) {
+	tests := []struct {
+		retention time.Duration
+		want      time.Duration
+	}{
+		{24 * time.Hour, 144 * time.Minute},
+		{time.Hour, 6 * time.Minute},
+		{5 * time.Minute, minReplayPruneInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.retention.String(), func(t *testing.T) {
+			replay := &ReplayService{Retention: tt.retention}
+			if got := replay.PruneInterval(); got != tt.want {
+				t.Fatalf("PruneInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayPruneKeepsRetention(t *testing.T) {
+	s := newTestServices(t)
+	replay := InitReplayService(s.logger, s.repos.ReplayRepo, time.Hour, 100)
+	anna := s.newUser(t, "anna@example.com")
+
+	for _, age := range []time.Duration{2 * time.Hour, 90 * time.Minute, 30 * time.Minute, 0} {
+		_, err := s.repos.ReplayRepo.Append(&models.ReplayEvent{UserId: anna, Type: "test", Data: []byte(`{}`), CreatedAt: time.Now().Add(-age)})
+		if err != nil {
+			t.Fatalf("Append() = %v", err)
+		}
+	}
+
+	if pruned, err := replay.Prune(); err != nil || pruned != 2 {
+		t.Fatalf("Prune() = %d, %v, want 2", pruned, err)
+	}
+
+	if pruned, err := InitReplayService(s.logger, s.repos.ReplayRepo, 0, 100).Prune(); err != nil || pruned != 0 {
+		t.Fatalf("Prune() with replay off = %d, %v, want 0", pruned, err)
+	}
+
+	done := make(chan struct{})
+	close(done)
+
+	// returns once done is closed, and at once when replay is off
+	replay.RunPruner(done)
+	InitReplayService(s.logger, s.repos.ReplayRepo, 0, 100).RunPruner(make(chan struct{}))
+}
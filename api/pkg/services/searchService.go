@@ -0,0 +1,166 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"encoding/base64"
+	"errors"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type ISearchService interface {
+	Search(userId int64, query string, searchType string, cursor string) (*SearchResultsJSON, error)
+}
+
+type SearchService struct {
+	Logger           *log.Logger
+	SearchRepository models.ISearchRepository
+}
+
+func InitSearchService(logger *log.Logger, searchRepo *models.SearchRepository) *SearchService {
+	return &SearchService{
+		Logger:           logger,
+		SearchRepository: searchRepo,
+	}
+}
+
+var (
+	ErrEmptySearch       = errors.New("search query has no words")
+	ErrInvalidSearchType = errors.New("invalid search type")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+)
+
+// maxSearchTerms limits the size of the match expression built from a query
+const maxSearchTerms = 10
+
+type SearchHitJSON struct {
+	Type      string `json:"type"`
+	Id        int64  `json:"id"`
+	PostId    int64  `json:"postId,omitempty"`
+	GroupId   int64  `json:"groupId,omitempty"`
+	UserId    int64  `json:"userId"`
+	Title     string `json:"title"`
+	Snippet   string `json:"snippet"`
+	ImagePath string `json:"imagePath"`
+}
+
+type SearchResultsJSON struct {
+	Results    []*SearchHitJSON `json:"results"`
+	NextCursor string           `json:"nextCursor"`
+}
+
+// Searches the posts and comments the user may see, groups and users.
+// searchType is one of models.SearchTypes, empty or "all" searches everything.
+// The cursor is empty for the first page and NextCursor of the previous page after that.
+func (s *SearchService) Search(userId int64, query string, searchType string, cursor string) (*SearchResultsJSON, error) {
+
+	match := searchMatchExpression(query)
+	if match == "" {
+		return nil, ErrEmptySearch
+	}
+
+	types, err := searchTypes(searchType)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := decodeSearchCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra hit tells whether there is a next page
+	hits, err := s.SearchRepository.Search(userId, match, types, offset, models.SearchLimit+1)
+	if err != nil {
+		s.Logger.Printf("Search error: %s", err)
+		return nil, err
+	}
+
+	results := &SearchResultsJSON{Results: []*SearchHitJSON{}}
+
+	if len(hits) > models.SearchLimit {
+		hits = hits[:models.SearchLimit]
+		results.NextCursor = encodeSearchCursor(offset + models.SearchLimit)
+	}
+
+	for _, hit := range hits {
+		results.Results = append(results.Results, &SearchHitJSON{
+			Type:      hit.Type,
+			Id:        hit.Id,
+			PostId:    hit.PostId,
+			GroupId:   hit.GroupId,
+			UserId:    hit.UserId,
+			Title:     hit.Title,
+			Snippet:   highlightSnippet(hit.Snippet),
+			ImagePath: hit.ImagePath,
+		})
+	}
+
+	return results, nil
+}
+
+// searchMatchExpression turns free text into an FTS5 expression matching
+// every word as a prefix, so that user input cannot use the query syntax
+func searchMatchExpression(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func searchTypes(searchType string) ([]string, error) {
+	if searchType == "" || searchType == "all" {
+		return models.SearchTypes, nil
+	}
+
+	for _, t := range models.SearchTypes {
+		if t == searchType {
+			return []string{t}, nil
+		}
+	}
+
+	return nil, ErrInvalidSearchType
+}
+
+// highlightSnippet escapes the snippet for HTML and marks the matched terms with <mark>
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, models.SnippetMatchStart, "<mark>")
+
+	return strings.ReplaceAll(snippet, models.SnippetMatchEnd, "</mark>")
+}
+
+func encodeSearchCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeSearchCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return offset, nil
+}
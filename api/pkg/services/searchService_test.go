@@ -0,0 +1,171 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/enums"
+	"SocialNetworkRestApi/api/pkg/models"
+	"sort"
+	"testing"
+	"time"
+)
+
+// searchIds returns the sorted ids of the hits of the user for the query and type
+func searchIds(t *testing.T, search *SearchService, userId int64, query string, searchType string) []int64 {
+	t.Helper()
+
+	results, err := search.Search(userId, query, searchType, "")
+	if err != nil {
+		t.Fatalf("Search() = %v", err)
+	}
+
+	ids := []int64{}
+	for _, hit := range results.Results {
+		ids = append(ids, hit.Id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func equalIds(a []int64, b ...int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSearchPostsAndComments(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	search := InitSearchService(s.logger, s.repos.SearchRepo)
+	a := newPostAudience(t, s)
+
+	public := newPost(t, posts, a.anna, "secret public", enums.Public)
+	private := newPost(t, posts, a.anna, "secret private", enums.Private)
+	subPrivate := newPost(t, posts, a.anna, "secret sub-private", enums.SubPrivate, a.bob)
+
+	publicComment := newComment(t, comments, a.carl, public, 0, "whisper public")
+	privateComment := newComment(t, comments, a.bob, private, 0, "whisper private")
+	subPrivateComment := newComment(t, comments, a.bob, subPrivate, 0, "whisper sub-private")
+	hiddenComment := newComment(t, comments, a.carl, public, 0, "whisper hidden")
+
+	if _, err := s.db.Exec(`UPDATE comments SET hidden = true WHERE id = ?`, hiddenComment); err != nil {
+		t.Fatalf("Cannot hide comment: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		userId   int64
+		posts    []int64
+		comments []int64
+	}{
+		{"author", a.anna, []int64{public, private, subPrivate}, []int64{publicComment, privateComment, subPrivateComment}},
+		{"allowed follower", a.bob, []int64{public, private, subPrivate}, []int64{publicComment, privateComment, subPrivateComment}},
+		{"follower", a.carl, []int64{public, private}, []int64{publicComment, privateComment}},
+		{"stranger", a.dave, []int64{public}, []int64{publicComment}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchIds(t, search, tt.userId, "secret", "posts"); !equalIds(got, tt.posts...) {
+				t.Fatalf("posts = %v, want %v", got, tt.posts)
+			}
+
+			if got := searchIds(t, search, tt.userId, "whisper", "comments"); !equalIds(got, tt.comments...) {
+				t.Fatalf("comments = %v, want %v", got, tt.comments)
+			}
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	comments := newTestCommentService(s)
+	blocks := newTestBlockService(s)
+	search := InitSearchService(s.logger, s.repos.SearchRepo)
+
+	anna := s.newUser(t, "anna@example.com")
+	annabel := s.newUser(t, "annabel@example.com")
+	annika := s.newUser(t, "annika@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	postId := newPost(t, posts, bob, "public", enums.Public)
+	newComment(t, comments, annabel, postId, 0, "whisper")
+
+	if err := blocks.BlockUser(annabel, anna); err != nil {
+		t.Fatalf("BlockUser() = %v", err)
+	}
+
+	if _, err := s.db.Exec(`UPDATE users SET deleted_at = ? WHERE id = ?`, time.Now(), annika); err != nil {
+		t.Fatalf("Cannot delete user: %v", err)
+	}
+
+	// the block works both ways, deleted users are found by nobody
+	if got := searchIds(t, search, anna, "ann", "users"); !equalIds(got, anna) {
+		t.Fatalf("users found by anna = %v, want %v", got, []int64{anna})
+	}
+
+	if got := searchIds(t, search, annabel, "ann", "users"); !equalIds(got, annabel) {
+		t.Fatalf("users found by annabel = %v, want %v", got, []int64{annabel})
+	}
+
+	if got := searchIds(t, search, bob, "ann", "users"); !equalIds(got, anna, annabel) {
+		t.Fatalf("users found by bob = %v, want %v", got, []int64{anna, annabel})
+	}
+
+	if got := searchIds(t, search, anna, "whisper", "comments"); len(got) != 0 {
+		t.Fatalf("comments of a blocked user found: %v", got)
+	}
+
+	if got := searchIds(t, search, bob, "whisper", "comments"); len(got) != 1 {
+		t.Fatalf("comments found by bob = %v, want 1", got)
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	s := newTestServices(t)
+	posts := newTestPostService(s)
+	search := InitSearchService(s.logger, s.repos.SearchRepo)
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	total := models.SearchLimit + 5
+	for i := 0; i < total; i++ {
+		newPost(t, posts, anna, "paged", enums.Public)
+	}
+
+	seen := map[int64]bool{}
+	cursor := ""
+
+	for page := 0; page < 2; page++ {
+		results, err := search.Search(bob, "paged", "", cursor)
+		if err != nil {
+			t.Fatalf("Search() page %d = %v", page, err)
+		}
+
+		for _, hit := range results.Results {
+			if seen[hit.Id] {
+				t.Fatalf("post %d is on two pages", hit.Id)
+			}
+			seen[hit.Id] = true
+		}
+
+		cursor = results.NextCursor
+	}
+
+	if len(seen) != total || cursor != "" {
+		t.Fatalf("got %d posts and cursor %q, want %d posts and no cursor", len(seen), cursor, total)
+	}
+
+	if _, err := search.Search(bob, "paged", "", "not a cursor"); err != ErrInvalidCursor {
+		t.Fatalf("invalid cursor: got %v, want %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/internal/mailer"
+	"SocialNetworkRestApi/api/internal/server/utils"
+	database "SocialNetworkRestApi/api/pkg/db/sqlite"
+	"SocialNetworkRestApi/api/pkg/models"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testPassword = "Passw0rd!"
+
+// captureMailer keeps the sent emails instead of sending them
+type captureMailer struct {
+	mu       sync.Mutex
+	messages []*mailer.Message
+}
+
+func (m *captureMailer) Send(message *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func (m *captureMailer) sent() []*mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*mailer.Message{}, m.messages...)
+}
+
+// testServices are the services of a test, wired like the application does on a new database
+type testServices struct {
+	db                *sql.DB
+	logger            *log.Logger
+	repos             *models.Repositories
+	mails             *captureMailer
+	images            *utils.ImageService
+	emailVerification *EmailVerificationService
+	loginThrottle     *LoginThrottleService
+	twoFactor         *TwoFactorService
+	users             *UserService
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	db, err := database.OpenDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Cannot open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err = database.RunMigrateScripts(db); err != nil {
+		if strings.Contains(err.Error(), "FTS5") {
+			t.Skip("sqlite was built without FTS5, run the tests with -tags sqlite_fts5")
+		}
+		t.Fatalf("Cannot migrate database: %v", err)
+	}
+
+	logger := log.New(io.Discard, "", 0)
+	repos := models.InitRepositories(db)
+	mails := &captureMailer{}
+
+	s := &testServices{db: db, logger: logger, repos: repos, mails: mails, images: utils.NewImageService(t.TempDir())}
+
+	s.emailVerification = InitEmailVerificationService(logger, repos.UserRepo, repos.EmailVerificationRepo, mails, 24*time.Hour, time.Minute, "http://frontend.test", nil)
+	s.loginThrottle = InitLoginThrottleService(logger, repos.LoginFailureRepo, 5, 20, 15*time.Minute)
+	s.twoFactor = InitTwoFactorService(logger, repos.UserRepo, repos.TwoFactorRepo, repos.OIDCRepo, s.loginThrottle, "test")
+	s.users = InitUserService(
+		logger,
+		repos.UserRepo,
+		repos.SessionRepo,
+		repos.AccessTokenRepo,
+		repos.FollowerRepo,
+		repos.NotificationRepo,
+		s.images,
+		s.emailVerification,
+		s.twoFactor,
+		s.loginThrottle,
+		24*time.Hour,
+		CookieOptions{},
+	)
+
+	return s
+}
+
+// newUser inserts a user with testPassword and returns its id
+func (s *testServices) newUser(t *testing.T, email string) int64 {
+	t.Helper()
+
+	hash, err := HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("Cannot hash password: %v", err)
+	}
+
+	id, err := s.repos.UserRepo.Insert(&models.User{
+		FirstName: strings.Split(email, "@")[0],
+		Email:     email,
+		Password:  hash,
+	})
+	if err != nil {
+		t.Fatalf("Cannot insert user: %v", err)
+	}
+
+	return id
+}
+
+// follow makes follower an accepted follower of the user
+func (s *testServices) follow(t *testing.T, follower int64, userId int64) {
+	t.Helper()
+
+	_, err := s.repos.FollowerRepo.Insert(&models.Follower{
+		FollowingId: userId,
+		FollowerId:  follower,
+		Accepted:    sql.NullBool{Bool: true, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Cannot insert follower: %v", err)
+	}
+}
+
+// newRequest is a request from the client at ip
+func newRequest(ip string) *http.Request {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.RemoteAddr = ip + ":1234"
+	return r
+}
+
+// login logs the user in with testPassword and returns the session token
+func (s *testServices) login(t *testing.T, email string) string {
+	t.Helper()
+
+	result, err := s.users.UserLogin(&models.User{Email: email, Password: testPassword}, newRequest("192.0.2.1"))
+	if err != nil || result.SessionToken == "" {
+		t.Fatalf("UserLogin() = %+v, %v, want a session", result, err)
+	}
+
+	return result.SessionToken
+}
+
+// serveAuthenticated sends the request through Authenticate and returns the response
+// and the principal the handler was called with, nil when it was not called
+func (s *testServices) serveAuthenticated(r *http.Request) (*httptest.ResponseRecorder, *Principal) {
+	var principal *Principal
+
+	w := httptest.NewRecorder()
+	s.users.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = PrincipalFromContext(r.Context())
+	})(w, r)
+
+	return w, principal
+}
+
+// withSession adds the session cookie to the request
+func withSession(r *http.Request, sessionToken string) *http.Request {
+	r.AddCookie(&http.Cookie{Name: "session", Value: sessionToken})
+	return r
+}
+
+// newAccessToken creates an access token of the user with the scopes and returns it
+func (s *testServices) newAccessToken(t *testing.T, userId int64, scopes ...string) *CreatedAccessTokenJSON {
+	t.Helper()
+
+	created, err := InitAccessTokenService(s.logger, s.repos.AccessTokenRepo).CreateToken(userId, AccessTokenRequestJSON{Name: "test", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("CreateToken() = %v", err)
+	}
+
+	return created
+}
+
+// withToken adds the access token to the request
+func withToken(r *http.Request, token string) *http.Request {
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// linkToken returns the token of the frontend link to path in the email
+func linkToken(t *testing.T, message *mailer.Message, path string) string {
+	t.Helper()
+
+	match := regexp.MustCompile(regexp.QuoteMeta("http://frontend.test"+path+"?token=") + `(\S+)`).FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no %s link in %q", path, message.Body)
+	}
+
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("Invalid token in link: %v", err)
+	}
+
+	return token
+}
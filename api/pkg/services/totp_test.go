@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// the SHA1 test vectors of RFC 6238, cut to six digits
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	secret := totpEncoding.EncodeToString(key)
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name string
+		code string
+		want int64
+		ok   bool
+	}{
+		{"current step", "081804", step, true},
+		{"with a space", "081 804", step, true},
+		{"one step earlier", totpCode(key, step-1), step - 1, true},
+		{"one step later", totpCode(key, step+1), step + 1, true},
+		{"two steps later", totpCode(key, step+2), 0, false},
+		{"too short", "81804", 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchTOTP(secret, tt.code, now)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("matchTOTP(%q) = %d, %v, want %d, %v", tt.code, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
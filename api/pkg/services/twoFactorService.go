@@ -0,0 +1,521 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+type ITwoFactorService interface {
+	GetStatus(userId int64) (*TwoFactorStatusJSON, error)
+	IsEnabled(userId int64) (bool, error)
+	BeginSetup(userId int64) (*TOTPSetupJSON, error)
+	ConfirmSetup(userId int64, code string, ip string) ([]string, error)
+	Disable(userId int64, password string, code string, ip string) error
+	ConfirmWithoutPassword(userId int64, code string, ip string) error
+	RegenerateRecoveryCodes(userId int64, code string, ip string) ([]string, error)
+	CreateChallenge(userId int64) (string, error)
+	ChallengeUser(challenge string) (int64, error)
+	VerifyChallenge(challenge string, code string) (int64, error)
+}
+
+type TwoFactorService struct {
+	Logger        *log.Logger
+	UserRepo      models.IUserRepository
+	TwoFactorRepo models.ITwoFactorRepository
+	// OIDCRepo keeps the logins at providers that confirm requests of users without a password
+	OIDCRepo models.IOIDCRepository
+	// LoginThrottle counts wrong codes of logged in users like failed logins
+	LoginThrottle ILoginThrottleService
+	// Issuer is the account name shown by authenticator apps
+	Issuer string
+}
+
+func InitTwoFactorService(
+	logger *log.Logger,
+	userRepo *models.UserRepository,
+	twoFactorRepo *models.TwoFactorRepository,
+	oidcRepo *models.OIDCRepository,
+	loginThrottle ILoginThrottleService,
+	issuer string,
+) *TwoFactorService {
+	return &TwoFactorService{
+		Logger:        logger,
+		UserRepo:      userRepo,
+		TwoFactorRepo: twoFactorRepo,
+		OIDCRepo:      oidcRepo,
+		LoginThrottle: loginThrottle,
+		Issuer:        issuer,
+	}
+}
+
+const (
+	recoveryCodeCount = 10
+	// loginChallengeTTL is how long the second login step may take
+	loginChallengeTTL = 5 * time.Minute
+	// maxChallengeAttempts is the number of wrong codes after which the login has to start over
+	maxChallengeAttempts = 5
+	// reauthWindow is how long a login at a linked provider confirms a request of a user without a password
+	reauthWindow = 5 * time.Minute
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending  = errors.New("two-factor setup was not started")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("invalid or expired login challenge")
+	ErrIncorrectPassword    = errors.New("incorrect password")
+	ErrConfirmationRequired = errors.New("confirm with a two-factor code or by logging in with a linked provider again")
+)
+
+// TooManyAttemptsError is returned instead of checking a code while the login
+// throttle blocks the user, Wait is the time until the next attempt
+type TooManyAttemptsError struct {
+	Wait time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string { return ErrTooManyLoginAttempts.Error() }
+
+func (e *TooManyAttemptsError) Unwrap() error { return ErrTooManyLoginAttempts }
+
+type TOTPSetupJSON struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauthUri"`
+}
+
+type TwoFactorStatusJSON struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted as xxxxx-xxxxx and the hashes that are stored,
+// they are hashed with bcrypt like passwords
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+
+		hash, err := HashPassword(code)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hash)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts codes with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *TwoFactorService) GetStatus(userId int64) (*TwoFactorStatusJSON, error) {
+	enabled, err := s.IsEnabled(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatusJSON{Enabled: enabled}
+
+	if enabled {
+		status.RecoveryCodesLeft, err = s.TwoFactorRepo.CountRecoveryCodes(userId)
+		if err != nil {
+			s.Logger.Printf("GetStatus error: %s", err)
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+func (s *TwoFactorService) IsEnabled(userId int64) (bool, error) {
+	totp, err := s.TwoFactorRepo.GetTOTP(userId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		s.Logger.Printf("IsEnabled error: %s", err)
+		return false, err
+	}
+
+	return totp.Enabled, nil
+}
+
+// Creates a new secret for the user, it protects logins after ConfirmSetup
+func (s *TwoFactorService) BeginSetup(userId int64) (*TOTPSetupJSON, error) {
+
+	enabled, err := s.IsEnabled(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("BeginSetup error: %s", err)
+		return nil, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		s.Logger.Printf("Cannot create TOTP secret: %s", err)
+		return nil, err
+	}
+
+	err = s.TwoFactorRepo.SavePendingTOTP(userId, secret)
+	if err != nil {
+		s.Logger.Printf("BeginSetup error: %s", err)
+		return nil, err
+	}
+
+	return &TOTPSetupJSON{
+		Secret: secret,
+		URI:    totpURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Enables two-factor authentication once the user proves the app produces codes,
+// returns the recovery codes, which are shown only this once
+func (s *TwoFactorService) ConfirmSetup(userId int64, code string, ip string) ([]string, error) {
+
+	totp, err := s.TwoFactorRepo.GetTOTP(userId)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	if err != nil {
+		s.Logger.Printf("ConfirmSetup error: %s", err)
+		return nil, err
+	}
+
+	if totp.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	err = s.throttled(userId, ip, func() error {
+		return s.checkTOTP(totp, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.TwoFactorRepo.EnableTOTP(userId)
+	if err != nil {
+		s.Logger.Printf("ConfirmSetup error: %s", err)
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Turns two-factor authentication off, the password and a code or recovery code are required.
+// Users without a password confirm with a code or a login at a linked provider.
+func (s *TwoFactorService) Disable(userId int64, password string, code string, ip string) error {
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("Disable error: %s", err)
+		return err
+	}
+
+	enabled, err := s.IsEnabled(userId)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if user.Password == "" {
+		err = s.ConfirmWithoutPassword(userId, code, ip)
+	} else {
+		err = s.throttled(userId, ip, func() error {
+			if !CheckPasswordHash(password, user.Password) {
+				return ErrIncorrectPassword
+			}
+
+			return s.checkCode(userId, code, true)
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = s.TwoFactorRepo.DeleteTOTP(userId)
+	if err != nil {
+		s.Logger.Printf("Disable error: %s", err)
+	}
+
+	return err
+}
+
+// Confirms a request of a user without a password, with a code or recovery code when
+// two-factor authentication is on, or else with a login at a linked provider in the last minutes
+func (s *TwoFactorService) ConfirmWithoutPassword(userId int64, code string, ip string) error {
+
+	if code != "" {
+		return s.throttled(userId, ip, func() error {
+			return s.checkCode(userId, code, true)
+		})
+	}
+
+	confirmed, err := s.OIDCRepo.ConsumeReauthentication(userId, time.Now().Add(-reauthWindow))
+	if err != nil {
+		s.Logger.Printf("Cannot get reauthentication: %s", err)
+		return err
+	}
+
+	if !confirmed {
+		return ErrConfirmationRequired
+	}
+
+	return nil
+}
+
+// Replaces all recovery codes of the user, a code from the app is required
+func (s *TwoFactorService) RegenerateRecoveryCodes(userId int64, code string, ip string) ([]string, error) {
+
+	err := s.throttled(userId, ip, func() error {
+		return s.checkCode(userId, code, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(userId)
+}
+
+// Starts the second login step and returns the challenge token the client answers with a code
+func (s *TwoFactorService) CreateChallenge(userId int64) (string, error) {
+
+	token, tokenHash, err := newSecretToken()
+	if err != nil {
+		s.Logger.Printf("Cannot create login challenge: %s", err)
+		return "", err
+	}
+
+	_, err = s.TwoFactorRepo.InsertChallenge(&models.LoginChallenge{
+		UserId:    userId,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	})
+	if err != nil {
+		s.Logger.Printf("CreateChallenge error: %s", err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Returns the user a login challenge was created for, so that the login can be
+// throttled before the code is checked
+func (s *TwoFactorService) ChallengeUser(challengeToken string) (int64, error) {
+
+	challenge, err := s.TwoFactorRepo.GetChallenge(hashSecretToken(challengeToken))
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidChallenge
+	}
+
+	if err != nil {
+		s.Logger.Printf("ChallengeUser error: %s", err)
+		return 0, err
+	}
+
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		return 0, ErrInvalidChallenge
+	}
+
+	return challenge.UserId, nil
+}
+
+// Completes a login challenge with a code from the app or a recovery code and returns the user id.
+// The challenge is used up by a correct code or after too many wrong ones.
+func (s *TwoFactorService) VerifyChallenge(challengeToken string, code string) (int64, error) {
+
+	challenge, err := s.TwoFactorRepo.GetChallenge(hashSecretToken(challengeToken))
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidChallenge
+	}
+
+	if err != nil {
+		s.Logger.Printf("VerifyChallenge error: %s", err)
+		return 0, err
+	}
+
+	if time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		s.TwoFactorRepo.DeleteChallenge(challenge.Id)
+		return 0, ErrInvalidChallenge
+	}
+
+	err = s.checkCode(challenge.UserId, code, true)
+
+	// the user id is returned with a wrong code so that the failure can be counted
+	if err == ErrInvalidTwoFactorCode {
+		if err := s.TwoFactorRepo.AddChallengeAttempt(challenge.Id); err != nil {
+			s.Logger.Printf("VerifyChallenge error: %s", err)
+		}
+		return challenge.UserId, err
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.TwoFactorRepo.DeleteChallenge(challenge.Id); err != nil {
+		s.Logger.Printf("VerifyChallenge error: %s", err)
+	}
+
+	return challenge.UserId, nil
+}
+
+// throttled runs a code check of a logged in user, wrong codes and passwords count
+// as failed logins so that a stolen session cannot guess codes without limit
+func (s *TwoFactorService) throttled(userId int64, ip string, check func() error) error {
+
+	user, err := s.UserRepo.GetById(userId)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return err
+	}
+
+	wait, err := s.LoginThrottle.Check(user.Email, ip)
+	if err != nil {
+		if err == ErrTooManyLoginAttempts {
+			return &TooManyAttemptsError{Wait: wait}
+		}
+		return err
+	}
+
+	err = check()
+
+	switch err {
+	case nil:
+		s.LoginThrottle.RecordSuccess(user.Email)
+	case ErrInvalidTwoFactorCode, ErrIncorrectPassword:
+		if _, recordErr := s.LoginThrottle.RecordFailure(user.Email, ip); recordErr != nil {
+			return recordErr
+		}
+	}
+
+	return err
+}
+
+// checkCode accepts a current code of the enabled secret, or an unused recovery code if allowed
+func (s *TwoFactorService) checkCode(userId int64, code string, allowRecovery bool) error {
+
+	totp, err := s.TwoFactorRepo.GetTOTP(userId)
+	if err == sql.ErrNoRows || (err == nil && !totp.Enabled) {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err != nil {
+		s.Logger.Printf("checkCode error: %s", err)
+		return err
+	}
+
+	recoveryCode := normalizeRecoveryCode(code)
+
+	if !allowRecovery || len(recoveryCode) == totpDigits {
+		return s.checkTOTP(totp, code)
+	}
+
+	unused, err := s.TwoFactorRepo.GetRecoveryCodes(userId)
+	if err != nil {
+		s.Logger.Printf("checkCode error: %s", err)
+		return err
+	}
+
+	// every code is compared so that the time does not tell which one matched
+	var match *models.RecoveryCode
+	for _, unusedCode := range unused {
+		if CheckPasswordHash(recoveryCode, unusedCode.CodeHash) && match == nil {
+			match = unusedCode
+		}
+	}
+
+	if match == nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.TwoFactorRepo.UseRecoveryCode(userId, match.Id)
+	if err != nil {
+		s.Logger.Printf("checkCode error: %s", err)
+		return err
+	}
+
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	s.Logger.Printf("User %d used a recovery code", userId)
+
+	return nil
+}
+
+// checkTOTP accepts each code once, so a code seen by someone else cannot be replayed
+func (s *TwoFactorService) checkTOTP(totp *models.TOTP, code string) error {
+
+	step, ok := matchTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := s.TwoFactorRepo.UseTOTPStep(totp.UserId, step)
+	if err != nil {
+		s.Logger.Printf("checkTOTP error: %s", err)
+		return err
+	}
+
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(userId int64) ([]string, error) {
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		s.Logger.Printf("Cannot create recovery codes: %s", err)
+		return nil, err
+	}
+
+	err = s.TwoFactorRepo.ReplaceRecoveryCodes(userId, hashes)
+	if err != nil {
+		s.Logger.Printf("Cannot store recovery codes: %s", err)
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// currentTOTP returns the code of the secret for now. Every time step is accepted once,
+// so the last used step is cleared for tests that need more than one code.
+func (s *testServices) currentTOTP(t *testing.T, userId int64, secret string) string {
+	t.Helper()
+
+	if _, err := s.db.Exec(`UPDATE user_totp SET last_used_step = 0 WHERE user_id = ?`, userId); err != nil {
+		t.Fatalf("Cannot reset used step: %v", err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Invalid secret: %v", err)
+	}
+
+	return totpCode(key, time.Now().Unix()/totpPeriod)
+}
+
+// enableTwoFactor sets up two-factor authentication and returns the secret and the recovery codes
+func (s *testServices) enableTwoFactor(t *testing.T, userId int64) (string, []string) {
+	t.Helper()
+
+	setup, err := s.twoFactor.BeginSetup(userId)
+	if err != nil {
+		t.Fatalf("BeginSetup() = %v", err)
+	}
+
+	codes, err := s.twoFactor.ConfirmSetup(userId, s.currentTOTP(t, userId, setup.Secret), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("ConfirmSetup() = %v", err)
+	}
+
+	return setup.Secret, codes
+}
+
+func TestTwoFactorSetup(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	if _, err := s.twoFactor.ConfirmSetup(anna, "123456", "192.0.2.1"); err != ErrTwoFactorNotPending {
+		t.Fatalf("confirming without setup: got %v, want %v", err, ErrTwoFactorNotPending)
+	}
+
+	setup, err := s.twoFactor.BeginSetup(anna)
+	if err != nil {
+		t.Fatalf("BeginSetup() = %v", err)
+	}
+
+	if !strings.HasPrefix(setup.URI, "otpauth://totp/test:anna@example.com?") || !strings.Contains(setup.URI, "secret="+setup.Secret) {
+		t.Fatalf("got URI %s", setup.URI)
+	}
+
+	if _, err = s.twoFactor.ConfirmSetup(anna, "000000", "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("wrong code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if enabled, _ := s.twoFactor.IsEnabled(anna); enabled {
+		t.Fatalf("enabled before the setup was confirmed")
+	}
+
+	codes, err := s.twoFactor.ConfirmSetup(anna, s.currentTOTP(t, anna, setup.Secret), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("ConfirmSetup() = %v", err)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Fatalf("recovery code %q is not formatted as xxxxx-xxxxx", code)
+		}
+	}
+
+	stored, err := s.repos.TwoFactorRepo.GetRecoveryCodes(anna)
+	if err != nil || len(stored) != recoveryCodeCount {
+		t.Fatalf("GetRecoveryCodes() = %d codes, %v", len(stored), err)
+	}
+	for _, code := range stored {
+		if !strings.HasPrefix(code.CodeHash, "$2") {
+			t.Fatalf("recovery code is not stored as a bcrypt hash: %q", code.CodeHash)
+		}
+	}
+
+	status, err := s.twoFactor.GetStatus(anna)
+	if err != nil || !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount {
+		t.Fatalf("GetStatus() = %+v, %v", status, err)
+	}
+
+	if _, err = s.twoFactor.BeginSetup(anna); err != ErrTwoFactorEnabled {
+		t.Fatalf("setting up again: got %v, want %v", err, ErrTwoFactorEnabled)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	secret, _ := s.enableTwoFactor(t, anna)
+
+	result, err := s.users.UserLogin(&models.User{Email: "anna@example.com", Password: testPassword}, newRequest("192.0.2.1"))
+	if err != nil || result.SessionToken != "" || result.TwoFactorChallenge == "" {
+		t.Fatalf("UserLogin() = %+v, %v, want only a challenge", result, err)
+	}
+
+	challenge := result.TwoFactorChallenge
+
+	if result, err = s.users.CompleteTwoFactorLogin(challenge, "000000", newRequest("192.0.2.1")); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("wrong code: got %+v, %v, want %v", result, err, ErrInvalidTwoFactorCode)
+	}
+
+	code := s.currentTOTP(t, anna, secret)
+
+	result, err = s.users.CompleteTwoFactorLogin(challenge, code, newRequest("192.0.2.1"))
+	if err != nil || result.SessionToken == "" {
+		t.Fatalf("CompleteTwoFactorLogin() = %+v, %v, want a session", result, err)
+	}
+
+	if session := s.getSession(t, result.SessionToken); session.UserId != anna {
+		t.Fatalf("logged in as user %d, want %d", session.UserId, anna)
+	}
+
+	if _, err = s.users.CompleteTwoFactorLogin(challenge, code, newRequest("192.0.2.1")); err != ErrInvalidChallenge {
+		t.Fatalf("reused challenge: got %v, want %v", err, ErrInvalidChallenge)
+	}
+
+	// a code is accepted once, even in another login
+	result, _ = s.users.UserLogin(&models.User{Email: "anna@example.com", Password: testPassword}, newRequest("192.0.2.1"))
+	if _, err = s.users.CompleteTwoFactorLogin(result.TwoFactorChallenge, code, newRequest("192.0.2.1")); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("replayed code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestRecoveryCodeLogin(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	_, codes := s.enableTwoFactor(t, anna)
+
+	// recovery codes are accepted without the dash and in any case
+	recoveryCode := strings.ToUpper(strings.ReplaceAll(codes[3], "-", ""))
+
+	challenge, err := s.twoFactor.CreateChallenge(anna)
+	if err != nil {
+		t.Fatalf("CreateChallenge() = %v", err)
+	}
+
+	if userId, err := s.twoFactor.VerifyChallenge(challenge, recoveryCode); err != nil || userId != anna {
+		t.Fatalf("VerifyChallenge() = %d, %v, want user %d", userId, err, anna)
+	}
+
+	challenge, _ = s.twoFactor.CreateChallenge(anna)
+	if _, err = s.twoFactor.VerifyChallenge(challenge, codes[3]); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("used recovery code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if status, _ := s.twoFactor.GetStatus(anna); status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Fatalf("got %d recovery codes left, want %d", status.RecoveryCodesLeft, recoveryCodeCount-1)
+	}
+}
+
+func TestLoginChallengeAttemptsAreLimited(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	secret, _ := s.enableTwoFactor(t, anna)
+
+	challenge, err := s.twoFactor.CreateChallenge(anna)
+	if err != nil {
+		t.Fatalf("CreateChallenge() = %v", err)
+	}
+
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if _, err = s.twoFactor.VerifyChallenge(challenge, "000000"); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("attempt %d: got %v, want %v", i+1, err, ErrInvalidTwoFactorCode)
+		}
+	}
+
+	if _, err = s.twoFactor.ChallengeUser(challenge); err != ErrInvalidChallenge {
+		t.Fatalf("ChallengeUser() = %v, want %v", err, ErrInvalidChallenge)
+	}
+
+	if _, err = s.twoFactor.VerifyChallenge(challenge, s.currentTOTP(t, anna, secret)); err != ErrInvalidChallenge {
+		t.Fatalf("correct code after too many attempts: got %v, want %v", err, ErrInvalidChallenge)
+	}
+}
+
+func TestTwoFactorCodeChecksAreThrottled(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	secret, _ := s.enableTwoFactor(t, anna)
+
+	// wrong passwords and codes count as failed logins
+	if err := s.twoFactor.Disable(anna, "wrong", s.currentTOTP(t, anna, secret), "192.0.2.1"); err != ErrIncorrectPassword {
+		t.Fatalf("Disable() with a wrong password = %v, want %v", err, ErrIncorrectPassword)
+	}
+
+	for i := 1; i <= loginFreeAttempts; i++ {
+		if _, err := s.twoFactor.RegenerateRecoveryCodes(anna, "000000", "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+			t.Fatalf("attempt %d: got %v, want %v", i, err, ErrInvalidTwoFactorCode)
+		}
+	}
+
+	var throttled *TooManyAttemptsError
+
+	_, err := s.twoFactor.RegenerateRecoveryCodes(anna, s.currentTOTP(t, anna, secret), "192.0.2.2")
+	if !errors.As(err, &throttled) || !errors.Is(err, ErrTooManyLoginAttempts) || throttled.Wait <= 0 {
+		t.Fatalf("correct code while throttled: got %v, want %v with a wait", err, ErrTooManyLoginAttempts)
+	}
+
+	err = s.twoFactor.Disable(anna, testPassword, s.currentTOTP(t, anna, secret), "192.0.2.2")
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Disable() while throttled = %v, want %v", err, ErrTooManyLoginAttempts)
+	}
+
+	// the second login step is throttled for the same account
+	challenge, _ := s.twoFactor.CreateChallenge(anna)
+
+	result, err := s.users.CompleteTwoFactorLogin(challenge, s.currentTOTP(t, anna, secret), newRequest("192.0.2.2"))
+	if err != ErrTooManyLoginAttempts || result.RetryAfter <= 0 {
+		t.Fatalf("CompleteTwoFactorLogin() = %+v, %v, want %v", result, err, ErrTooManyLoginAttempts)
+	}
+
+	if enabled, _ := s.twoFactor.IsEnabled(anna); !enabled {
+		t.Fatalf("two-factor authentication was disabled while throttled")
+	}
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	_, codes := s.enableTwoFactor(t, anna)
+
+	if err := s.twoFactor.Disable(anna, testPassword, "000000", "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("wrong code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if err := s.twoFactor.Disable(anna, testPassword, codes[0], "192.0.2.1"); err != nil {
+		t.Fatalf("Disable() with a recovery code = %v", err)
+	}
+
+	status, err := s.twoFactor.GetStatus(anna)
+	if err != nil || status.Enabled {
+		t.Fatalf("GetStatus() = %+v, %v, want disabled", status, err)
+	}
+
+	if count, _ := s.repos.TwoFactorRepo.CountRecoveryCodes(anna); count != 0 {
+		t.Fatalf("%d recovery codes left after disabling", count)
+	}
+
+	if err = s.twoFactor.Disable(anna, testPassword, codes[1], "192.0.2.1"); err != ErrTwoFactorNotEnabled {
+		t.Fatalf("disabling again: got %v, want %v", err, ErrTwoFactorNotEnabled)
+	}
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	secret, codes := s.enableTwoFactor(t, anna)
+
+	if _, err := s.twoFactor.RegenerateRecoveryCodes(anna, codes[0], "192.0.2.1"); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("with a recovery code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	newCodes, err := s.twoFactor.RegenerateRecoveryCodes(anna, s.currentTOTP(t, anna, secret), "192.0.2.1")
+	if err != nil || len(newCodes) != recoveryCodeCount {
+		t.Fatalf("RegenerateRecoveryCodes() = %d codes, %v", len(newCodes), err)
+	}
+
+	challenge, _ := s.twoFactor.CreateChallenge(anna)
+	if _, err = s.twoFactor.VerifyChallenge(challenge, codes[1]); err != ErrInvalidTwoFactorCode {
+		t.Fatalf("old recovery code: got %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	if _, err = s.twoFactor.VerifyChallenge(challenge, newCodes[1]); err != nil {
+		t.Fatalf("new recovery code: got %v", err)
+	}
+}
@@ -28,6 +28,8 @@ type ProfileJSON struct {
 	IsFollowed  bool      `json:"isFollowed"`
 	//IsPending    bool      `json:"isPending"`
 	IsOwnProfile bool `json:"isOwnProfile"`
+	// EmailVerified is only set on the own profile
+	EmailVerified bool `json:"emailVerified"`
 }
 
 type FollowerData struct {
@@ -39,17 +41,46 @@ type FollowerData struct {
 	Accepted    bool   `json:"accepted"`
 }
 
+type SessionJSON struct {
+	Id         int64     `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	UserAgent  string    `json:"userAgent"`
+	IpAddress  string    `json:"ipAddress"`
+	IsCurrent  bool      `json:"isCurrent"`
+}
+
+// sessionRenewInterval limits how often last_seen_at is written for an active session
+const sessionRenewInterval = time.Minute
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrAccountSuspended = errors.New("account is suspended")
+	ErrTokenScope       = errors.New("access token does not allow this request")
+)
+
 type IUserService interface {
 	Authenticate(handler http.HandlerFunc) http.HandlerFunc
+	AuthenticateScope(scope string, handler http.HandlerFunc) http.HandlerFunc
+	RequireAdmin(handler http.HandlerFunc) http.HandlerFunc
+	RequireSession(handler http.HandlerFunc) http.HandlerFunc
+	IsSuspended(userID int64) (bool, error)
 	UpdateUserData(userID int64, updateData ProfileJSON) error
 	GetUserData(requestingUserId int64, profileId int64) (*ProfileJSON, error)
 	GetUserByID(userID int64) (*models.User, error)
 	GetUserID(r *http.Request) (int64, error)
 	SetCookie(w http.ResponseWriter, sessionToken string)
 	ClearCookie(w http.ResponseWriter)
-	UserLogin(user *models.User) (string, error)
+	NewCookie(name string, value string, path string, maxAge int) *http.Cookie
+	UserLogin(user *models.User, r *http.Request) (*LoginResult, error)
+	CompleteTwoFactorLogin(challenge string, code string, r *http.Request) (*LoginResult, error)
+	CompleteExternalLogin(userID int64, r *http.Request) (*LoginResult, error)
 	UserLogout(r *http.Request) error
-	UserRegister(user *models.User) (string, error)
+	UserRegister(user *models.User, r *http.Request) (string, error)
+	GetUserSessions(userID int64, currentSessionID int64) ([]*SessionJSON, error)
+	RevokeSession(userID int64, sessionID int64) error
+	RevokeAllSessions(userID int64) ([]int64, error)
+	SweepExpiredSessions() ([]int64, error)
 	GetUserFollowers(userID int64) ([]*FollowerData, error)
 	GetUserFollowing(userID int64) ([]*FollowerData, error)
 	GetPublicUsers(userID int64) ([]*models.SimpleUserJSON, error)
@@ -63,8 +94,20 @@ type UserService struct {
 	Logger           *log.Logger
 	UserRepo         models.IUserRepository
 	SessionRepo      models.ISessionRepository
+	AccessTokenRepo  models.IAccessTokenRepository
 	FollowerRepo     models.IFollowerRepository
 	NotificationRepo models.INotificationRepository
+	ImageService     utils.IImageService
+	// EmailVerification sends the confirmation email to new users
+	EmailVerification IEmailVerificationService
+	// TwoFactor holds back the session of users with two-factor authentication until they enter a code
+	TwoFactor ITwoFactorService
+	// LoginThrottle slows down and locks repeated failed logins
+	LoginThrottle ILoginThrottleService
+	// SessionTTL is how long a session stays valid after its last use
+	SessionTTL time.Duration
+	// Cookie holds the attributes of the session cookie
+	Cookie CookieOptions
 }
 
 // InitUserService initializes the user controller.
@@ -72,15 +115,29 @@ func InitUserService(
 	logger *log.Logger,
 	userRepo *models.UserRepository,
 	sessionRepo *models.SessionRepository,
+	accessTokenRepo *models.AccessTokenRepository,
 	followerRepo *models.FollowerRepository,
 	notificationRepo *models.NotificationRepository,
+	imageService *utils.ImageService,
+	emailVerification *EmailVerificationService,
+	twoFactor *TwoFactorService,
+	loginThrottle *LoginThrottleService,
+	sessionTTL time.Duration,
+	cookie CookieOptions,
 ) *UserService {
 	return &UserService{
-		Logger:           logger,
-		UserRepo:         userRepo,
-		SessionRepo:      sessionRepo,
-		FollowerRepo:     followerRepo,
-		NotificationRepo: notificationRepo,
+		Logger:            logger,
+		UserRepo:          userRepo,
+		SessionRepo:       sessionRepo,
+		AccessTokenRepo:   accessTokenRepo,
+		FollowerRepo:      followerRepo,
+		NotificationRepo:  notificationRepo,
+		ImageService:      imageService,
+		EmailVerification: emailVerification,
+		TwoFactor:         twoFactor,
+		LoginThrottle:     loginThrottle,
+		SessionTTL:        sessionTTL,
+		Cookie:            cookie,
 	}
 }
 
@@ -162,10 +219,17 @@ func (s *UserService) GetUserData(requestingUserId int64, profileId int64) (*Pro
 		}
 	}
 
+	if userJSON.IsOwnProfile {
+		userJSON.EmailVerified, err = s.UserRepo.IsEmailVerified(profileId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return userJSON, nil
 }
 
-func (s *UserService) UserRegister(user *models.User) (string, error) {
+func (s *UserService) UserRegister(user *models.User, r *http.Request) (string, error) {
 
 	// check if user exists
 	_, err := s.UserRepo.GetByEmail(user.Email)
@@ -206,53 +270,216 @@ func (s *UserService) UserRegister(user *models.User) (string, error) {
 
 	s.Logger.Printf("User successfully registered (Last inserted ID: %v)", lastID)
 
-	// create session
-	sessionToken := uuid.NewV4().String()
-	session := models.Session{
-		UserId: lastID,
-		Token:  sessionToken,
+	// the account works without a confirmed email, a new link can be requested later
+	if err = s.EmailVerification.SendVerification(lastID); err != nil {
+		s.Logger.Printf("Cannot send verification email: %s", err)
 	}
 
-	// store session in DB
-	lastID, err = s.SessionRepo.Insert(&session)
-	if err != nil {
-		s.Logger.Printf("Cannot create session: %s", err)
-		return "", errors.New("cannot create session")
-	}
-	s.Logger.Printf("Session initiated, (last inserted ID %v:)", lastID)
+	return s.createSession(lastID, r)
+}
 
-	return sessionToken, nil
+// LoginResult holds the session token of a login, or the challenge to answer
+// with a two-factor code when the user has two-factor authentication enabled
+type LoginResult struct {
+	SessionToken       string
+	TwoFactorChallenge string
+	// RetryAfter is set with ErrTooManyLoginAttempts
+	RetryAfter time.Duration
+	// LockedUserId is the user whose account got locked by this failed login
+	LockedUserId int64
 }
 
-func (s *UserService) UserLogin(user *models.User) (string, error) {
+// dummyPasswordHash is compared against when the email is unknown, so that
+// the response time does not tell whether an account exists
+var dummyPasswordHash, _ = HashPassword("not a real password")
+
+func (s *UserService) UserLogin(user *models.User, r *http.Request) (*LoginResult, error) {
+
+	ip := utils.ClientIP(r)
+
+	wait, err := s.LoginThrottle.Check(user.Email, ip)
+	if err != nil {
+		return &LoginResult{RetryAfter: wait}, err
+	}
 
 	// check if user exists
 	dbUser, err := s.UserRepo.GetByEmail(user.Email)
-	if err != nil {
-		s.Logger.Printf("User email not found: %s", err)
-		return "", errors.New("user email not found")
+	if err != nil && err != sql.ErrNoRows {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return nil, err
+	}
+
+	if err == sql.ErrNoRows {
+		s.Logger.Printf("User email not found: %s", user.Email)
+		CheckPasswordHash(user.Password, dummyPasswordHash)
+		return s.loginFailed(user.Email, 0, ip)
 	}
 
 	// check if password is correct
 	if !CheckPasswordHash(user.Password, dbUser.Password) {
-		s.Logger.Printf("Incorrect password")
-		return "", errors.New("incorrect password")
+		s.Logger.Printf("Incorrect password for user %d", dbUser.Id)
+		return s.loginFailed(dbUser.Email, dbUser.Id, ip)
 	}
 
-	// create session
+	return s.finishLogin(dbUser, r)
+}
+
+// Logs in a user authenticated by an OpenID Connect provider, the same as
+// after a correct password
+func (s *UserService) CompleteExternalLogin(userID int64, r *http.Request) (*LoginResult, error) {
+
+	user, err := s.UserRepo.GetById(userID)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return nil, err
+	}
+
+	return s.finishLogin(user, r)
+}
+
+// finishLogin creates the session of a user whose first factor was accepted,
+// or the challenge for the second factor
+func (s *UserService) finishLogin(user *models.User, r *http.Request) (*LoginResult, error) {
+
+	if err := s.checkNotSuspended(user.Id); err != nil {
+		return nil, err
+	}
+
+	twoFactorEnabled, err := s.TwoFactor.IsEnabled(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// failures are only forgotten after the second step, otherwise knowing
+	// the password would allow guessing codes without limit
+	if twoFactorEnabled {
+		challenge, err := s.TwoFactor.CreateChallenge(user.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LoginResult{TwoFactorChallenge: challenge}, nil
+	}
+
+	s.LoginThrottle.RecordSuccess(user.Email)
+
+	sessionToken, err := s.createSession(user.Id, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{SessionToken: sessionToken}, nil
+}
+
+// Finishes a login started by UserLogin with a two-factor or recovery code
+func (s *UserService) CompleteTwoFactorLogin(challenge string, code string, r *http.Request) (*LoginResult, error) {
+
+	ip := utils.ClientIP(r)
+
+	userID, err := s.TwoFactor.ChallengeUser(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.GetById(userID)
+	if err != nil {
+		s.Logger.Printf("Cannot get user: %s", err)
+		return nil, err
+	}
+
+	// the challenge limits the codes per login, the throttle limits the logins
+	wait, err := s.LoginThrottle.Check(user.Email, ip)
+	if err != nil {
+		return &LoginResult{RetryAfter: wait}, err
+	}
+
+	_, err = s.TwoFactor.VerifyChallenge(challenge, code)
+
+	if err == ErrInvalidTwoFactorCode {
+		result, failErr := s.loginFailed(user.Email, user.Id, ip)
+		if result == nil {
+			return nil, failErr
+		}
+		return result, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.checkNotSuspended(userID); err != nil {
+		return nil, err
+	}
+
+	s.LoginThrottle.RecordSuccess(user.Email)
+
+	sessionToken, err := s.createSession(userID, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{SessionToken: sessionToken}, nil
+}
+
+// loginFailed counts the failure and returns ErrInvalidCredentials, userID is 0 for unknown emails
+func (s *UserService) loginFailed(email string, userID int64, ip string) (*LoginResult, error) {
+	result := &LoginResult{}
+
+	locked, err := s.LoginThrottle.RecordFailure(email, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked && userID > 0 {
+		result.LockedUserId = userID
+	}
+
+	return result, ErrInvalidCredentials
+}
+
+// checkNotSuspended returns ErrAccountSuspended for suspended users
+func (s *UserService) checkNotSuspended(userID int64) error {
+	suspended, err := s.IsSuspended(userID)
+	if err != nil {
+		return err
+	}
+
+	if suspended {
+		s.Logger.Printf("Suspended user %d tried to log in", userID)
+		return ErrAccountSuspended
+	}
+
+	return nil
+}
+
+func (s *UserService) IsSuspended(userID int64) (bool, error) {
+	status, err := s.UserRepo.GetAccountStatus(userID)
+	if err != nil {
+		s.Logger.Printf("Cannot get account status: %s", err)
+		return false, err
+	}
+
+	return status.Suspended, nil
+}
+
+// createSession stores a new session for the user and returns its token
+func (s *UserService) createSession(userID int64, r *http.Request) (string, error) {
 	sessionToken := uuid.NewV4().String()
 	session := models.Session{
-		UserId: dbUser.Id,
-		Token:  sessionToken,
+		UserId:    userID,
+		Token:     sessionToken,
+		UserAgent: r.UserAgent(),
+		IpAddress: utils.ClientIP(r),
 	}
 
 	// store session in DB
-	_, err = s.SessionRepo.Insert(&session)
+	lastID, err := s.SessionRepo.Insert(&session)
 	if err != nil {
 		s.Logger.Printf("Cannot create session: %s", err)
 		return "", errors.New("cannot create session")
 	}
-	//s.Logger.Println("Last inserted ID:", lastID)
+	s.Logger.Printf("Session initiated, (last inserted ID %v:)", lastID)
+
 	return sessionToken, nil
 }
 
@@ -272,67 +499,228 @@ func (s *UserService) UserLogout(r *http.Request) error {
 }
 
 func (s *UserService) Authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	return s.authenticate("", handler)
+}
+
+// AuthenticateScope is Authenticate for the endpoints personal access tokens with the scope may use
+func (s *UserService) AuthenticateScope(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return s.authenticate(scope, handler)
+}
+
+// authenticate resolves the principal from the session cookie or an Authorization: Bearer
+// access token. Without a scope, access tokens may only read.
+func (s *UserService) authenticate(scope string, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		cookie, err := r.Cookie("session")
-		if err != nil {
-			s.Logger.Printf("No cookie found: %s", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		var principal *Principal
+
+		if bearer, ok := bearerToken(r); ok {
+			principal = s.tokenPrincipal(w, bearer)
+		} else {
+			principal = s.sessionPrincipal(w, r)
+		}
+
+		if principal == nil {
 			return
 		}
 
-		_, err = s.SessionRepo.GetByToken(cookie.Value)
+		// the browser sends the cookie along with requests from any site, tokens are never sent implicitly
+		if principal.TokenID == 0 && !safeMethod(r.Method) && !validCSRFToken(r, principal) {
+			s.Logger.Printf("CSRF token missing on %s %s of user %d", r.Method, r.URL.Path, principal.UserID)
+			http.Error(w, ErrCSRFToken.Error(), http.StatusForbidden)
+			return
+		}
 
+		status, err := s.UserRepo.GetAccountStatus(principal.UserID)
 		if err != nil {
-			s.Logger.Printf("No session found: %s", err)
+			s.Logger.Printf("Cannot get account status: %s", err)
 			http.Error(w, "Invalid session", http.StatusUnauthorized)
 			return
 		}
 
+		if status.Suspended {
+			s.Logger.Printf("Request of suspended user %d", principal.UserID)
+			http.Error(w, ErrAccountSuspended.Error(), http.StatusForbidden)
+			return
+		}
+
+		principal.Role = status.Role
+
+		if principal.TokenID != 0 {
+			required := scope
+			if required == "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				required = models.ScopeRead
+			}
+
+			if required == "" || !principal.HasScope(required) {
+				s.Logger.Printf("Access token %d cannot %s %s", principal.TokenID, r.Method, r.URL.Path)
+				http.Error(w, ErrTokenScope.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
 		// required for auth handler
 		if handler == nil {
 			return
 		}
 
+		handler.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// bearerToken returns the token of an Authorization: Bearer header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// tokenPrincipal resolves an access token, it answers the request itself and returns nil when the token is not valid
+func (s *UserService) tokenPrincipal(w http.ResponseWriter, bearer string) *Principal {
+	if bearer == "" {
+		http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+		return nil
+	}
+
+	token, err := s.AccessTokenRepo.GetByHash(hashSecretToken(bearer))
+	if err != nil {
+		s.Logger.Printf("No access token found: %s", err)
+		http.Error(w, "Invalid access token", http.StatusUnauthorized)
+		return nil
+	}
+
+	if !token.LastUsedAt.Valid || time.Since(token.LastUsedAt.Time) > sessionRenewInterval {
+		err = s.AccessTokenRepo.UpdateLastUsed(token.Id, time.Now())
+		if err != nil {
+			s.Logger.Printf("Cannot update access token: %s", err)
+		}
+	}
+
+	return &Principal{
+		UserID:  token.UserId,
+		TokenID: token.Id,
+		Scopes:  token.Scopes,
+	}
+}
+
+// sessionPrincipal resolves the session cookie, it answers the request itself and returns nil when the session is not valid
+func (s *UserService) sessionPrincipal(w http.ResponseWriter, r *http.Request) *Principal {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		s.Logger.Printf("No cookie found: %s", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	session, err := s.SessionRepo.GetByToken(cookie.Value)
+
+	if err != nil {
+		s.Logger.Printf("No session found: %s", err)
+		http.Error(w, "Invalid session", http.StatusUnauthorized)
+		return nil
+	}
+
+	if time.Since(session.LastSeenAt) > s.SessionTTL {
+		s.Logger.Printf("Session %d expired", session.Id)
+		err = s.SessionRepo.DeleteById(session.Id)
+		if err != nil {
+			s.Logger.Printf("Cannot delete expired session: %s", err)
+		}
+		s.ClearCookie(w)
+		http.Error(w, "Session expired", http.StatusUnauthorized)
+		return nil
+	}
+
+	// sliding renewal of both the stored session and the cookie
+	if time.Since(session.LastSeenAt) > sessionRenewInterval {
+		err = s.SessionRepo.UpdateLastSeen(session.Id, time.Now())
+		if err != nil {
+			s.Logger.Printf("Cannot renew session: %s", err)
+		}
+		s.SetCookie(w, session.Token)
+	}
+
+	return &Principal{
+		UserID:    session.UserId,
+		SessionID: session.Id,
+		CSRFToken: csrfToken(session.Token),
+	}
+}
+
+// RequireAdmin only lets administrators in with a session through, personal access tokens
+// cannot moderate. It must be wrapped by Authenticate.
+func (s *UserService) RequireAdmin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := RequestPrincipal(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if principal.TokenID != 0 {
+			s.Logger.Printf("Access token %d cannot %s %s", principal.TokenID, r.Method, r.URL.Path)
+			http.Error(w, ErrTokenScope.Error(), http.StatusForbidden)
+			return
+		}
+
+		if !principal.IsAdmin() {
+			s.Logger.Printf("User %d is not an administrator", principal.UserID)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
 		handler.ServeHTTP(w, r)
 	}
 }
 
-func (s *UserService) SetCookie(w http.ResponseWriter, sessionToken string) {
-	cookie := http.Cookie{
-		Name:   "session",
-		Value:  sessionToken,
-		MaxAge: 3600,
+// RequireSession refuses personal access tokens, it guards the account and credential endpoints
+// and must be wrapped by Authenticate
+func (s *UserService) RequireSession(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := RequestPrincipal(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if principal.TokenID != 0 {
+			http.Error(w, ErrTokenScope.Error(), http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
 	}
-	http.SetCookie(w, &cookie)
+}
+
+func (s *UserService) SetCookie(w http.ResponseWriter, sessionToken string) {
+	http.SetCookie(w, s.NewCookie("session", sessionToken, "/", int(s.SessionTTL.Seconds())))
 }
 
 func (s *UserService) ClearCookie(w http.ResponseWriter) {
-	cookie := http.Cookie{
-		Name:   "session",
-		Value:  "",
-		MaxAge: -1,
-	}
-	http.SetCookie(w, &cookie)
+	http.SetCookie(w, s.NewCookie("session", "", "/", -1))
 }
 
 func (s *UserService) GetUserByID(id int64) (*models.User, error) {
 	return s.UserRepo.GetById(id)
 }
 
+// GetUserID returns the id of the user resolved by Authenticate
 func (s *UserService) GetUserID(r *http.Request) (int64, error) {
 
-	cookie, err := r.Cookie("session")
-	if err != nil {
-		return 0, err
-	}
-
-	session, err := s.SessionRepo.GetByToken(cookie.Value)
+	principal, err := RequestPrincipal(r)
 	if err != nil {
 		return 0, err
 	}
 
-	return session.UserId, nil
+	return principal.UserID, nil
 }
 
 func (s *UserService) GetUserFollowers(userID int64) ([]*FollowerData, error) {
@@ -474,7 +862,7 @@ func (s *UserService) UpdateUserImage(userID int64, imageFile multipart.File, he
 	}
 
 	// save image
-	imagePath, err := utils.SaveImage(imageFile, header)
+	imagePath, err := s.ImageService.SaveImage(imageFile, header)
 	if err != nil {
 		s.Logger.Printf("Cannot save image: %s", err)
 		return err
@@ -488,3 +876,74 @@ func (s *UserService) UpdateUserImage(userID int64, imageFile multipart.File, he
 
 	return nil
 }
+
+func (s *UserService) GetUserSessions(userID int64, currentSessionID int64) ([]*SessionJSON, error) {
+
+	sessions, err := s.SessionRepo.GetUserSessions(userID)
+	if err != nil {
+		s.Logger.Printf("Cannot get user sessions: %s", err)
+		return nil, err
+	}
+
+	sessionsJSON := []*SessionJSON{}
+
+	for _, session := range sessions {
+
+		if time.Since(session.LastSeenAt) > s.SessionTTL {
+			continue
+		}
+
+		sessionsJSON = append(sessionsJSON, &SessionJSON{
+			Id:         session.Id,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			UserAgent:  session.UserAgent,
+			IpAddress:  session.IpAddress,
+			IsCurrent:  session.Id == currentSessionID,
+		})
+	}
+
+	return sessionsJSON, nil
+}
+
+func (s *UserService) RevokeSession(userID int64, sessionID int64) error {
+
+	session, err := s.SessionRepo.GetById(sessionID)
+	if err == sql.ErrNoRows || (err == nil && session.UserId != userID) {
+		return ErrSessionNotFound
+	}
+
+	if err != nil {
+		s.Logger.Printf("Cannot get session: %s", err)
+		return err
+	}
+
+	err = s.SessionRepo.DeleteById(sessionID)
+	if err != nil {
+		s.Logger.Printf("Cannot delete session: %s", err)
+		return err
+	}
+
+	s.Logger.Printf("User %d revoked session %d", userID, sessionID)
+
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere and returns the ids of the removed sessions
+func (s *UserService) RevokeAllSessions(userID int64) ([]int64, error) {
+
+	sessionIDs, err := s.SessionRepo.DeleteByUserId(userID)
+	if err != nil {
+		s.Logger.Printf("Cannot delete user sessions: %s", err)
+		return nil, err
+	}
+
+	s.Logger.Printf("User %d revoked all %d session(s)", userID, len(sessionIDs))
+
+	return sessionIDs, nil
+}
+
+// SweepExpiredSessions removes sessions that outlived the session TTL and returns their ids
+func (s *UserService) SweepExpiredSessions() ([]int64, error) {
+	return s.SessionRepo.DeleteExpired(time.Now().Add(-s.SessionTTL))
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"SocialNetworkRestApi/api/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// getSession returns the stored session of the token
+func (s *testServices) getSession(t *testing.T, sessionToken string) *models.Session {
+	t.Helper()
+
+	session, err := s.repos.SessionRepo.GetByToken(sessionToken)
+	if err != nil {
+		t.Fatalf("Cannot get session: %v", err)
+	}
+
+	return session
+}
+
+// sessionCookie returns the session cookie set on the response, if any
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestUserLoginCreatesSession(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	r := newRequest("192.0.2.7")
+	r.Header.Set("User-Agent", "test-browser")
+
+	result, err := s.users.UserLogin(&models.User{Email: "anna@example.com", Password: testPassword}, r)
+	if err != nil {
+		t.Fatalf("UserLogin() = %v", err)
+	}
+
+	session := s.getSession(t, result.SessionToken)
+	if session.UserId != anna || session.UserAgent != "test-browser" || session.IpAddress != "192.0.2.7" {
+		t.Fatalf("stored %+v, want a session of user %d from test-browser at 192.0.2.7", session, anna)
+	}
+
+	if _, err = s.users.UserLogin(&models.User{Email: "anna@example.com", Password: "wrong"}, r); err != ErrInvalidCredentials {
+		t.Fatalf("wrong password: got %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	if _, err = s.users.UserLogin(&models.User{Email: "nobody@example.com", Password: testPassword}, r); err != ErrInvalidCredentials {
+		t.Fatalf("unknown email: got %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestAuthenticatePutsPrincipalInContext(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	token := s.login(t, "anna@example.com")
+	session := s.getSession(t, token)
+
+	w, principal := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), token))
+	if w.Code != http.StatusOK || principal == nil {
+		t.Fatalf("got %d, want the handler to be called", w.Code)
+	}
+
+	if principal.UserID != anna || principal.SessionID != session.Id || principal.TokenID != 0 || principal.Role != models.RoleUser {
+		t.Fatalf("got principal %+v, want session %d of user %d", principal, session.Id, anna)
+	}
+
+	tests := []struct {
+		name    string
+		request *http.Request
+	}{
+		{"no cookie", httptest.NewRequest("GET", "/posts", nil)},
+		{"unknown session", withSession(httptest.NewRequest("GET", "/posts", nil), "unknown")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, principal := s.serveAuthenticated(tt.request)
+			if w.Code != http.StatusUnauthorized || principal != nil {
+				t.Fatalf("got %d, want %d without calling the handler", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestExpiredSessionIsRemoved(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+	token := s.login(t, "anna@example.com")
+	session := s.getSession(t, token)
+
+	if err := s.repos.SessionRepo.UpdateLastSeen(session.Id, time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatalf("Cannot update session: %v", err)
+	}
+
+	w, principal := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), token))
+	if w.Code != http.StatusUnauthorized || principal != nil {
+		t.Fatalf("got %d, want %d without calling the handler", w.Code, http.StatusUnauthorized)
+	}
+
+	if cookie := sessionCookie(w); cookie == nil || cookie.MaxAge >= 0 {
+		t.Fatalf("got cookie %v, want the session cookie deleted", cookie)
+	}
+
+	if _, err := s.repos.SessionRepo.GetById(session.Id); err == nil {
+		t.Fatalf("expired session was not deleted")
+	}
+}
+
+func TestActiveSessionIsRenewed(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+	token := s.login(t, "anna@example.com")
+	session := s.getSession(t, token)
+
+	// recently renewed sessions are not written again
+	w, _ := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), token))
+	if cookie := sessionCookie(w); cookie != nil {
+		t.Fatalf("renewed a new session: %v", cookie)
+	}
+
+	lastSeen := time.Now().Add(-10 * time.Minute)
+	if err := s.repos.SessionRepo.UpdateLastSeen(session.Id, lastSeen); err != nil {
+		t.Fatalf("Cannot update session: %v", err)
+	}
+
+	w, principal := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), token))
+	if principal == nil {
+		t.Fatalf("got %d, want the handler to be called", w.Code)
+	}
+
+	cookie := sessionCookie(w)
+	if cookie == nil || cookie.Value != token || cookie.MaxAge != int((24*time.Hour).Seconds()) || !cookie.HttpOnly {
+		t.Fatalf("got cookie %v, want the session cookie renewed for a day", cookie)
+	}
+
+	if renewed := s.getSession(t, token); !renewed.LastSeenAt.After(lastSeen.Add(time.Minute)) {
+		t.Fatalf("last seen %v was not renewed", renewed.LastSeenAt)
+	}
+}
+
+func TestGetUserSessions(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+
+	current := s.getSession(t, s.login(t, "anna@example.com"))
+	other := s.getSession(t, s.login(t, "anna@example.com"))
+	expired := s.getSession(t, s.login(t, "anna@example.com"))
+
+	if err := s.repos.SessionRepo.UpdateLastSeen(expired.Id, time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatalf("Cannot update session: %v", err)
+	}
+
+	sessions, err := s.users.GetUserSessions(anna, current.Id)
+	if err != nil {
+		t.Fatalf("GetUserSessions() = %v", err)
+	}
+
+	listed := map[int64]bool{}
+	for _, session := range sessions {
+		listed[session.Id] = session.IsCurrent
+	}
+
+	if len(listed) != 2 || !listed[current.Id] || listed[other.Id] {
+		t.Fatalf("got sessions %v, want %d as the current one and %d", listed, current.Id, other.Id)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	s := newTestServices(t)
+	anna := s.newUser(t, "anna@example.com")
+	bob := s.newUser(t, "bob@example.com")
+
+	first := s.getSession(t, s.login(t, "anna@example.com"))
+	second := s.getSession(t, s.login(t, "anna@example.com"))
+	bobs := s.getSession(t, s.login(t, "bob@example.com"))
+
+	if err := s.users.RevokeSession(anna, bobs.Id); err != ErrSessionNotFound {
+		t.Fatalf("revoking another user's session: got %v, want %v", err, ErrSessionNotFound)
+	}
+
+	if err := s.users.RevokeSession(anna, first.Id); err != nil {
+		t.Fatalf("RevokeSession() = %v", err)
+	}
+
+	if err := s.users.RevokeSession(anna, first.Id); err != ErrSessionNotFound {
+		t.Fatalf("revoking again: got %v, want %v", err, ErrSessionNotFound)
+	}
+
+	w, _ := s.serveAuthenticated(withSession(httptest.NewRequest("GET", "/posts", nil), first.Token))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked session got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	revoked, err := s.users.RevokeAllSessions(anna)
+	if err != nil || len(revoked) != 1 || revoked[0] != second.Id {
+		t.Fatalf("RevokeAllSessions() = %v, %v, want [%d]", revoked, err, second.Id)
+	}
+
+	if sessions, _ := s.users.GetUserSessions(bob, bobs.Id); len(sessions) != 1 {
+		t.Fatalf("revoked the sessions of another user, %d left", len(sessions))
+	}
+}
+
+func TestSweepExpiredSessions(t *testing.T) {
+	s := newTestServices(t)
+	s.newUser(t, "anna@example.com")
+
+	active := s.getSession(t, s.login(t, "anna@example.com"))
+	expired := s.getSession(t, s.login(t, "anna@example.com"))
+
+	if err := s.repos.SessionRepo.UpdateLastSeen(expired.Id, time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatalf("Cannot update session: %v", err)
+	}
+
+	swept, err := s.users.SweepExpiredSessions()
+	if err != nil || len(swept) != 1 || swept[0] != expired.Id {
+		t.Fatalf("SweepExpiredSessions() = %v, %v, want [%d]", swept, err, expired.Id)
+	}
+
+	if _, err = s.repos.SessionRepo.GetById(active.Id); err != nil {
+		t.Fatalf("active session was swept: %v", err)
+	}
+}
@@ -45,7 +45,7 @@ const NotificationNavbarItem = () => {
   }, []);
 
   useEffect(() => {
-    const exceptions = ["message", "chatlist", "message_history"];
+    const exceptions = ["message", "chatlist", "message_history", "ack", "error", "resync"];
 
     if (!exceptions.includes(lastJsonMessage?.type)) {
       setNewNotification(lastJsonMessage?.data);
@@ -2,6 +2,7 @@ import React from "react";
 import ReactDOM from "react-dom/client";
 import App from "./App";
 import { AuthProvider } from "./context/AuthProvider";
+import "./services/csrf";
 import "bootstrap/dist/css/bootstrap.min.css";
 import "./index.css";
 
@@ -0,0 +1,47 @@
+import axios from "axios";
+import { CSRF_URL } from "../utils/routes";
+
+// The API refuses requests that change anything unless they carry the CSRF
+// token of the session, so it is fetched once and added to every such request.
+const CSRF_HEADER = "X-CSRF-Token";
+const SAFE_METHODS = ["get", "head", "options"];
+
+let csrfToken = null;
+
+async function fetchCsrfToken() {
+  const res = await axios.get(CSRF_URL, { withCredentials: true });
+  csrfToken = res.data.token;
+  return csrfToken;
+}
+
+axios.interceptors.request.use(async (config) => {
+  if (SAFE_METHODS.includes((config.method || "get").toLowerCase())) {
+    return config;
+  }
+
+  try {
+    config.headers[CSRF_HEADER] = csrfToken || (await fetchCsrfToken());
+  } catch (error) {
+    // not logged in, login and signup do not need a token
+  }
+
+  return config;
+});
+
+// the token changes with every login, fetch it again and retry once
+axios.interceptors.response.use(undefined, async (error) => {
+  const config = error.config;
+  if (
+    error.response?.status === 403 &&
+    typeof error.response.data === "string" &&
+    error.response.data.includes("CSRF") &&
+    config &&
+    !config.csrfRetried
+  ) {
+    config.csrfRetried = true;
+    config.headers[CSRF_HEADER] = await fetchCsrfToken();
+    return axios(config);
+  }
+
+  return Promise.reject(error);
+});
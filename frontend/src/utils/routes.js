@@ -3,6 +3,7 @@ export const NOTIFICATIONS_URL = "http://localhost:8000/notifications";
 export const AUTH_URL = "http://localhost:8000/auth";
 export const LOGOUT_URL = "http://localhost:8000/logout";
 export const LOGIN_URL = "http://localhost:8000/login";
+export const CSRF_URL = "http://localhost:8000/csrf";
 export const SIGNUP_URL = "http://localhost:8000/signup";
 //--------------------------Groups-----------------------//
 export const USER_GROUPS_URL = "http://localhost:8000/usergroups";